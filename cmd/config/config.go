@@ -59,10 +59,22 @@ func Default() *Config {
 			Address:     ":53",
 			Prefix:      ".",
 			Proto:       "udp",
+			TLSAddress:  ":853",
 			FallbackDNS: "1.1.1.1",
+			TTL:         3600,
+			CacheSize:   1024,
+
+			FallbackStrategy: "sequential",
+			FallbackTimeout:  "2s",
+			FallbackCooldown: "30s",
+			UDPSize:          1232,
+			ClientSubnet:     "strip",
+			BlocklistAnswer:  "nxdomain",
+			BlocklistRefresh: "24h",
 		},
 		Store: &StoreConfig{
-			Type: "memmap",
+			Type:      "memmap",
+			SyncDelay: "0s",
 		},
 		HTTP: &HTTPConfig{
 			Port: 8080,
@@ -101,6 +113,81 @@ func Merge(main, input *Config) *Config {
 	if input.DNS.FallbackDNS != "" {
 		main.DNS.FallbackDNS = input.DNS.FallbackDNS
 	}
+	if input.DNS.FallbackStrategy != "" {
+		main.DNS.FallbackStrategy = input.DNS.FallbackStrategy
+	}
+	if input.DNS.FallbackTimeout != "" {
+		main.DNS.FallbackTimeout = input.DNS.FallbackTimeout
+	}
+	if input.DNS.FallbackCooldown != "" {
+		main.DNS.FallbackCooldown = input.DNS.FallbackCooldown
+	}
+	if input.DNS.TTL != 0 {
+		main.DNS.TTL = input.DNS.TTL
+	}
+	if input.DNS.Reverse {
+		main.DNS.Reverse = input.DNS.Reverse
+	}
+	if input.DNS.TLSAddress != "" {
+		main.DNS.TLSAddress = input.DNS.TLSAddress
+	}
+	if input.DNS.TLSCert != "" {
+		main.DNS.TLSCert = input.DNS.TLSCert
+	}
+	if input.DNS.TLSKey != "" {
+		main.DNS.TLSKey = input.DNS.TLSKey
+	}
+	if input.DNS.CacheSize != 0 {
+		main.DNS.CacheSize = input.DNS.CacheSize
+	}
+	if input.DNS.RootHints != "" {
+		main.DNS.RootHints = input.DNS.RootHints
+	}
+	if input.DNS.UDPSize != 0 {
+		main.DNS.UDPSize = input.DNS.UDPSize
+	}
+	if input.DNS.ClientSubnet != "" {
+		main.DNS.ClientSubnet = input.DNS.ClientSubnet
+	}
+	if input.DNS.DNSSEC {
+		main.DNS.DNSSEC = input.DNS.DNSSEC
+	}
+	if input.DNS.DNSSECKeys != "" {
+		main.DNS.DNSSECKeys = input.DNS.DNSSECKeys
+	}
+	if input.DNS.DNSSECValidate {
+		main.DNS.DNSSECValidate = input.DNS.DNSSECValidate
+	}
+	if input.DNS.TrustAnchor != "" {
+		main.DNS.TrustAnchor = input.DNS.TrustAnchor
+	}
+	if input.DNS.BlocklistAnswer != "" {
+		main.DNS.BlocklistAnswer = input.DNS.BlocklistAnswer
+	}
+	if input.DNS.BlocklistRefresh != "" {
+		main.DNS.BlocklistRefresh = input.DNS.BlocklistRefresh
+	}
+	if len(input.DNS.Blocklists) > 0 {
+		main.DNS.Blocklists = input.DNS.Blocklists
+	}
+	if len(input.DNS.BlocklistAllow) > 0 {
+		main.DNS.BlocklistAllow = input.DNS.BlocklistAllow
+	}
+	if len(input.DNS.Forwards) > 0 {
+		main.DNS.Forwards = input.DNS.Forwards
+	}
+	if len(input.DNS.Zones) > 0 {
+		main.DNS.Zones = input.DNS.Zones
+	}
+	if len(input.DNS.TransferAllow) > 0 {
+		main.DNS.TransferAllow = input.DNS.TransferAllow
+	}
+	if len(input.DNS.Notify) > 0 {
+		main.DNS.Notify = input.DNS.Notify
+	}
+	if len(input.DNS.TSIGKeys) > 0 {
+		main.DNS.TSIGKeys = input.DNS.TSIGKeys
+	}
 
 	// Store
 	if input.Store.Type != "" {
@@ -109,6 +196,15 @@ func Merge(main, input *Config) *Config {
 	if input.Store.Path != "" {
 		main.Store.Path = input.Store.Path
 	}
+	if input.Store.Origin != "" {
+		main.Store.Origin = input.Store.Origin
+	}
+	if input.Store.Primary != "" {
+		main.Store.Primary = input.Store.Primary
+	}
+	if input.Store.SyncDelay != "" {
+		main.Store.SyncDelay = input.Store.SyncDelay
+	}
 
 	// HTTP
 	if input.HTTP.Port != 0 {
@@ -1,6 +1,11 @@
 package config
 
-import "net"
+import (
+	"encoding/base64"
+	"net"
+	"strings"
+	"time"
+)
 
 type DNSConfig struct {
 	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
@@ -8,10 +13,77 @@ type DNSConfig struct {
 	Address     string `json:"address,omitempty" yaml:"address,omitempty"`
 	Prefix      string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
 	Proto       string `json:"proto,omitempty" yaml:"proto,omitempty"`
+	TTL         uint32 `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Reverse     bool   `json:"reverse,omitempty" yaml:"reverse,omitempty"`
+	TLSAddress  string `json:"tls_address,omitempty" yaml:"tls_address,omitempty"`
+	TLSCert     string `json:"tls_cert,omitempty" yaml:"tls_cert,omitempty"`
+	TLSKey      string `json:"tls_key,omitempty" yaml:"tls_key,omitempty"`
+	CacheSize   int    `json:"cache_size,omitempty" yaml:"cache_size,omitempty"`
+
+	FallbackStrategy string `json:"fallback_strategy,omitempty" yaml:"fallback_strategy,omitempty"`
+	FallbackTimeout  string `json:"fallback_timeout,omitempty" yaml:"fallback_timeout,omitempty"`
+	FallbackCooldown string `json:"fallback_cooldown,omitempty" yaml:"fallback_cooldown,omitempty"`
+	RootHints        string `json:"root_hints,omitempty" yaml:"root_hints,omitempty"`
+	UDPSize          int    `json:"udp_size,omitempty" yaml:"udp_size,omitempty"`
+	ClientSubnet     string `json:"client_subnet,omitempty" yaml:"client_subnet,omitempty"`
+	DNSSEC           bool   `json:"dnssec,omitempty" yaml:"dnssec,omitempty"`
+	DNSSECKeys       string `json:"dnssec_keys,omitempty" yaml:"dnssec_keys,omitempty"`
+	DNSSECValidate   bool   `json:"dnssec_validate,omitempty" yaml:"dnssec_validate,omitempty"`
+	TrustAnchor      string `json:"trust_anchor,omitempty" yaml:"trust_anchor,omitempty"`
+	BlocklistAnswer  string `json:"blocklist_answer,omitempty" yaml:"blocklist_answer,omitempty"`
+	BlocklistRefresh string `json:"blocklist_refresh,omitempty" yaml:"blocklist_refresh,omitempty"`
+
+	Blocklists     []string `json:"blocklists,omitempty" yaml:"blocklists,omitempty"`
+	BlocklistAllow []string `json:"blocklist_allow,omitempty" yaml:"blocklist_allow,omitempty"`
+	TransferAllow  []string `json:"transfer_allow,omitempty" yaml:"transfer_allow,omitempty"`
+	Notify         []string `json:"notify,omitempty" yaml:"notify,omitempty"`
+
+	Forwards []*ForwardConfig `json:"forwards,omitempty" yaml:"forwards,omitempty"`
+	Zones    []*ZoneConfig    `json:"zones,omitempty" yaml:"zones,omitempty"`
+	TSIGKeys []*TSIGKeyConfig `json:"tsig_keys,omitempty" yaml:"tsig_keys,omitempty"`
+}
+
+// ForwardConfig describes a conditional forwarding rule, sending the queries for a
+// domain and its subdomains to its own fallback DNS addresses
+type ForwardConfig struct {
+	Domain    string   `json:"domain" yaml:"domain"`
+	Upstreams []string `json:"upstreams" yaml:"upstreams"`
+}
+
+// ZoneConfig describes a DNS zone owned by the DNS server, with the parameters for its
+// SOA record and its name servers. Unset values are populated with defaults
+type ZoneConfig struct {
+	Name    string   `json:"name" yaml:"name"`
+	NS      []string `json:"ns,omitempty" yaml:"ns,omitempty"`
+	Mbox    string   `json:"mbox,omitempty" yaml:"mbox,omitempty"`
+	Serial  uint32   `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Refresh uint32   `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+	Retry   uint32   `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Expire  uint32   `json:"expire,omitempty" yaml:"expire,omitempty"`
+	Minimum uint32   `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	TTL     uint32   `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// TSIGKeyConfig describes a TSIG key (RFC 8945), a secret shared with other DNS servers
+// or clients to sign the DNS messages exchanged with them. The algorithm defaults to
+// `hmac-sha256`, and the secret is base64-encoded
+//
+// The key is scoped to the zones in Zones: dynamic updates (RFC 2136) signed with it are
+// only allowed to those zones, and so are its zone transfers. A key without zones may
+// transfer any zone, but not update them
+type TSIGKeyConfig struct {
+	Name      string   `json:"name" yaml:"name"`
+	Algorithm string   `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Secret    string   `json:"secret" yaml:"secret"`
+	Zones     []string `json:"zones,omitempty" yaml:"zones,omitempty"`
 }
 
 // DNSType creates a ConfigOption setting the Config's DNS type to string `t`
 //
+// The `recursive` type resolves the queries which are not answered from the store
+// recursively, starting from the root name servers, instead of forwarding them to the
+// fallback DNS
+//
 // It defaults to `miekgdns`
 func DNSType(p string) ConfigOption {
 	switch p {
@@ -19,6 +91,10 @@ func DNSType(p string) ConfigOption {
 		return &dnsType{
 			t: "miekgdns",
 		}
+	case "recursive":
+		return &dnsType{
+			t: "recursive",
+		}
 	default:
 		return &dnsType{
 			t: "miekgdns",
@@ -39,6 +115,133 @@ func DNSFallback(f string) ConfigOption {
 	}
 }
 
+// DNSFallbackStrategy creates a ConfigOption setting the Config's strategy for querying
+// the fallback DNS servers to string `s`: `sequential`, `random`, `round-robin`, `fastest`
+// (querying all servers at once, taking the first answer) or `latency-weighted`
+//
+// It the string `s` is not a supported strategy, it returns `nil`
+func DNSFallbackStrategy(s string) ConfigOption {
+	s = strings.ToLower(s)
+	switch s {
+	case "sequential", "random", "round-robin", "fastest", "latency-weighted":
+		return &dnsFallbackStrategy{
+			s: s,
+		}
+	default:
+		return nil
+	}
+}
+
+// DNSFallbackTimeout creates a ConfigOption setting the Config's timeout for each query
+// to a fallback DNS server to the duration string `d` (e.g. `2s`, `500ms`)
+//
+// It the string `d` is not a valid, positive duration, it returns `nil`
+func DNSFallbackTimeout(d string) ConfigOption {
+	if dur, err := time.ParseDuration(d); err != nil || dur <= 0 {
+		return nil
+	}
+	return &dnsFallbackTimeout{
+		d: d,
+	}
+}
+
+// DNSFallbackCooldown creates a ConfigOption setting the Config's period for skipping a
+// fallback DNS server after it fails to reply, to the duration string `d` (e.g. `30s`)
+//
+// It the string `d` is not a valid, positive duration, it returns `nil`
+func DNSFallbackCooldown(d string) ConfigOption {
+	if dur, err := time.ParseDuration(d); err != nil || dur <= 0 {
+		return nil
+	}
+	return &dnsFallbackCooldown{
+		d: d,
+	}
+}
+
+// DNSForwards creates a ConfigOption setting the Config's conditional forwarding rules
+// to ForwardConfig `forwards`. Queries for a domain name are sent to the fallback DNS in
+// the rule with the longest matching domain, or to the default fallback DNS if none match
+//
+// Rules without a domain or upstreams are ignored; if no rules are left, it returns `nil`
+func DNSForwards(forwards ...*ForwardConfig) ConfigOption {
+	f := make([]*ForwardConfig, 0, len(forwards))
+	for _, fwd := range forwards {
+		if fwd == nil || fwd.Domain == "" || len(fwd.Upstreams) == 0 {
+			continue
+		}
+		f = append(f, fwd)
+	}
+	if len(f) == 0 {
+		return nil
+	}
+	return &dnsForwards{
+		f: f,
+	}
+}
+
+// DNSRootHints creates a ConfigOption setting the path to the root hints file used by
+// the `recursive` DNS type to string `p`
+//
+// It the string `p` is empty, it returns `nil`
+func DNSRootHints(p string) ConfigOption {
+	if p == "" {
+		return nil
+	}
+	return &dnsRootHints{
+		p: p,
+	}
+}
+
+// DNSUDPSize creates a ConfigOption setting the maximum UDP payload size for the DNS
+// server's responses to int `n` (in bytes), advertised to the clients in EDNS0 OPT records
+//
+// It the size `n` is under 512 or over 65535 bytes, it returns `nil`
+func DNSUDPSize(n int) ConfigOption {
+	if n < 512 || n > 65535 {
+		return nil
+	}
+	return &dnsUDPSize{
+		n: n,
+	}
+}
+
+// DNSClientSubnet creates a ConfigOption setting whether the EDNS0 Client Subnet option
+// in the clients' queries is forwarded to the fallback DNS (`forward`), or removed from
+// the queries (`strip`), to string `m`
+//
+// It the string `m` is not a supported mode, it returns `nil`
+func DNSClientSubnet(m string) ConfigOption {
+	m = strings.ToLower(m)
+	switch m {
+	case "strip", "forward":
+		return &dnsClientSubnet{
+			m: m,
+		}
+	default:
+		return nil
+	}
+}
+
+// DNSSEC creates a ConfigOption setting whether the DNS server signs the answers from
+// the zones it owns with DNSSEC, with the keys in the directory `keys`. Keys are generated
+// for the zones which have none; if `keys` is empty, they are only kept in memory
+func DNSSEC(enabled bool, keys string) ConfigOption {
+	return &dnsSEC{
+		enabled: enabled,
+		keys:    keys,
+	}
+}
+
+// DNSSECValidation creates a ConfigOption setting whether the DNSSEC signatures of the
+// fallback DNS answers are validated, trusting the DS or DNSKEY records in the file
+// `trustAnchor`. If it is empty, the root zone's key-signing keys are trusted
+func DNSSECValidation(enabled bool, trustAnchor string) ConfigOption {
+	return &dnsSECValidation{
+		enabled:     enabled,
+		trustAnchor: trustAnchor,
+	}
+}
+
 // DNSAddress creates a ConfigOption setting the Config's DNS address to string `a`
 //
 // It the string `a` is an invalid IP address, it returns `nil`
@@ -72,18 +275,264 @@ func DNSPrefix(p string) ConfigOption {
 
 // DNSProto creates a ConfigOption setting the Config's DNS proto to string `p`
 //
-// It defaults to `udp`
+// The string `p` can be a comma-separated list of protocols, to serve DNS queries
+// on several listeners: `udp` and `tcp` (on the DNS address), and `tcp-tls` for
+// DNS-over-TLS (on the DNS TLS address). E.g.: `udp,tcp,tcp-tls`
+//
+// Unsupported protocols are ignored. It defaults to `udp`
 func DNSProto(p string) ConfigOption {
-	switch p {
-	case "udp":
-		return &dnsProto{
-			p: p,
+	var (
+		protos = make([]string, 0, 3)
+		seen   = map[string]struct{}{}
+	)
+
+	for _, proto := range strings.Split(p, ",") {
+		proto = strings.ToLower(strings.TrimSpace(proto))
+		switch proto {
+		case "udp", "tcp", "tcp-tls":
+			if _, ok := seen[proto]; ok {
+				continue
+			}
+			seen[proto] = struct{}{}
+			protos = append(protos, proto)
 		}
-	default:
+	}
+
+	if len(protos) == 0 {
 		return &dnsProto{
 			p: "udp",
 		}
 	}
+	return &dnsProto{
+		p: strings.Join(protos, ","),
+	}
+}
+
+// DNSTLSAddress creates a ConfigOption setting the Config's DNS-over-TLS address
+// to string `a`, used when the DNS proto includes `tcp-tls`
+//
+// It the string `a` is empty, it returns `nil`
+func DNSTLSAddress(a string) ConfigOption {
+	if a == "" {
+		return nil
+	}
+	return &dnsTLSAddress{
+		a: a,
+	}
+}
+
+// DNSTLSCert creates a ConfigOption setting the path to the Config's DNS-over-TLS
+// certificate and key files, to strings `cert` and `key`
+//
+// It either of the strings is empty, it returns `nil`
+func DNSTLSCert(cert, key string) ConfigOption {
+	if cert == "" || key == "" {
+		return nil
+	}
+	return &dnsTLSCert{
+		cert: cert,
+		key:  key,
+	}
+}
+
+// DNSTTL creates a ConfigOption setting the Config's default TTL for DNS answers
+// to uint32 `ttl` (in seconds). This TTL is used for the records which do not define one
+//
+// It the TTL `ttl` is zero, it returns `nil`
+func DNSTTL(ttl uint32) ConfigOption {
+	if ttl == 0 {
+		return nil
+	}
+	return &dnsTTL{
+		ttl: ttl,
+	}
+}
+
+// DNSReverse creates a ConfigOption setting whether the DNS service should answer
+// PTR questions for reverse-lookup domains (in-addr.arpa, ip6.arpa) automatically,
+// from the A and AAAA records in the store
+//
+// When enabled, reverse queries for private addresses which are not in the store are
+// answered with NXDOMAIN instead of being sent to the fallback DNS
+func DNSReverse(r bool) ConfigOption {
+	return &dnsReverse{
+		r: r,
+	}
+}
+
+// DNSCacheSize creates a ConfigOption setting the maximum number of fallback DNS
+// answers kept in the Config's DNS cache to int `n`. A size of zero disables the cache
+//
+// It the size `n` is negative, it returns `nil`
+func DNSCacheSize(n int) ConfigOption {
+	if n < 0 {
+		return nil
+	}
+	return &dnsCacheSize{
+		n: n,
+	}
+}
+
+// DNSZones creates a ConfigOption setting the DNS zones owned by the DNS server to
+// ZoneConfig `zones`, which are answered authoritatively
+//
+// Zones without a name are ignored; if no zones are left, it returns `nil`
+func DNSZones(zones ...*ZoneConfig) ConfigOption {
+	z := make([]*ZoneConfig, 0, len(zones))
+	for _, zone := range zones {
+		if zone == nil || zone.Name == "" {
+			continue
+		}
+		z = append(z, zone)
+	}
+	if len(z) == 0 {
+		return nil
+	}
+	return &dnsZones{
+		z: z,
+	}
+}
+
+// DNSBlocklists creates a ConfigOption setting the Config's blocklists to the file paths
+// or http(s) URLs in `sources`. The queries for the domain names in these lists (hosts
+// files, plain domain lists or AdBlock-style filter lists) are answered before reaching
+// the store or the fallback DNS
+//
+// Empty sources are ignored; if no sources are left, it returns `nil`
+func DNSBlocklists(sources ...string) ConfigOption {
+	s := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if source = strings.TrimSpace(source); source != "" {
+			s = append(s, source)
+		}
+	}
+	if len(s) == 0 {
+		return nil
+	}
+	return &dnsBlocklists{
+		s: s,
+	}
+}
+
+// DNSBlocklistAllow creates a ConfigOption setting the Config's blocklist allowlist to the
+// domain names in `domains`, which are never blocked. Entries starting with a wildcard
+// label (`*.example.com`) allow the subdomains of a domain name
+//
+// Empty entries are ignored; if no entries are left, it returns `nil`
+func DNSBlocklistAllow(domains ...string) ConfigOption {
+	d := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			d = append(d, domain)
+		}
+	}
+	if len(d) == 0 {
+		return nil
+	}
+	return &dnsBlocklistAllow{
+		d: d,
+	}
+}
+
+// DNSBlocklistAnswer creates a ConfigOption setting how the queries for blocked domain
+// names are answered to string `a`: with an NXDOMAIN response (`nxdomain`), with the
+// `0.0.0.0` and `::` addresses (`null`), or with a comma-separated list of sinkhole IP
+// addresses
+//
+// It the string `a` is not a supported mode or a list of IP addresses, it returns `nil`
+func DNSBlocklistAnswer(a string) ConfigOption {
+	a = strings.ToLower(strings.TrimSpace(a))
+	switch a {
+	case "nxdomain", "null":
+	default:
+		for _, addr := range strings.Split(a, ",") {
+			if net.ParseIP(strings.TrimSpace(addr)) == nil {
+				return nil
+			}
+		}
+	}
+	return &dnsBlocklistAnswer{
+		a: a,
+	}
+}
+
+// DNSBlocklistRefresh creates a ConfigOption setting the period between the updates of
+// the Config's blocklists to the duration string `d` (e.g. `24h`)
+//
+// It the string `d` is not a valid, positive duration, it returns `nil`
+func DNSBlocklistRefresh(d string) ConfigOption {
+	if dur, err := time.ParseDuration(d); err != nil || dur <= 0 {
+		return nil
+	}
+	return &dnsBlocklistRefresh{
+		d: d,
+	}
+}
+
+// DNSTransferAllow creates a ConfigOption setting the IP addresses or networks (in CIDR
+// notation) in `addrs` as the ones allowed to transfer the zones owned by this server
+// (AXFR and IXFR), such as its secondary servers
+//
+// Invalid entries are ignored; if no entries are left, it returns `nil`
+func DNSTransferAllow(addrs ...string) ConfigOption {
+	a := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if _, _, err := net.ParseCIDR(addr); err == nil || net.ParseIP(addr) != nil {
+			a = append(a, addr)
+		}
+	}
+	if len(a) == 0 {
+		return nil
+	}
+	return &dnsTransferAllow{
+		a: a,
+	}
+}
+
+// DNSNotify creates a ConfigOption setting the secondary servers (as `host` or
+// `host:port`) in `targets` as the ones notified (RFC 1996) when the records of the zones
+// owned by this server change
+//
+// Empty entries are ignored; if no entries are left, it returns `nil`
+func DNSNotify(targets ...string) ConfigOption {
+	t := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if target = strings.TrimSpace(target); target != "" {
+			t = append(t, target)
+		}
+	}
+	if len(t) == 0 {
+		return nil
+	}
+	return &dnsNotify{
+		t: t,
+	}
+}
+
+// DNSTSIGKeys creates a ConfigOption setting the Config's TSIG keys to `keys`, which
+// verify the signed requests to this server (allowing zone transfers, and dynamic updates
+// to the keys' zones) and sign the NOTIFY messages to its secondary servers
+//
+// Keys without a name or with a secret which is not valid base64 are ignored; if no keys
+// are left, it returns `nil`
+func DNSTSIGKeys(keys ...*TSIGKeyConfig) ConfigOption {
+	k := make([]*TSIGKeyConfig, 0, len(keys))
+	for _, key := range keys {
+		if key == nil || key.Name == "" || key.Secret == "" {
+			continue
+		}
+		if _, err := base64.StdEncoding.DecodeString(key.Secret); err != nil {
+			continue
+		}
+		k = append(k, key)
+	}
+	if len(k) == 0 {
+		return nil
+	}
+	return &dnsTSIGKeys{
+		k: k,
+	}
 }
 
 type dnsType struct {
@@ -92,6 +541,56 @@ type dnsType struct {
 type dnsFallback struct {
 	f string
 }
+type dnsFallbackStrategy struct {
+	s string
+}
+type dnsFallbackTimeout struct {
+	d string
+}
+type dnsFallbackCooldown struct {
+	d string
+}
+type dnsForwards struct {
+	f []*ForwardConfig
+}
+type dnsRootHints struct {
+	p string
+}
+type dnsUDPSize struct {
+	n int
+}
+type dnsClientSubnet struct {
+	m string
+}
+type dnsSEC struct {
+	enabled bool
+	keys    string
+}
+type dnsSECValidation struct {
+	enabled     bool
+	trustAnchor string
+}
+type dnsBlocklists struct {
+	s []string
+}
+type dnsBlocklistAllow struct {
+	d []string
+}
+type dnsBlocklistAnswer struct {
+	a string
+}
+type dnsBlocklistRefresh struct {
+	d string
+}
+type dnsTransferAllow struct {
+	a []string
+}
+type dnsNotify struct {
+	t []string
+}
+type dnsTSIGKeys struct {
+	k []*TSIGKeyConfig
+}
 type dnsAddress struct {
 	a string
 }
@@ -101,6 +600,25 @@ type dnsPrefix struct {
 type dnsProto struct {
 	p string
 }
+type dnsTLSAddress struct {
+	a string
+}
+type dnsTLSCert struct {
+	cert string
+	key  string
+}
+type dnsCacheSize struct {
+	n int
+}
+type dnsTTL struct {
+	ttl uint32
+}
+type dnsReverse struct {
+	r bool
+}
+type dnsZones struct {
+	z []*ZoneConfig
+}
 
 // Apply implements the ConfigOption interface
 func (l *dnsType) Apply(c *Config) {
@@ -113,6 +631,88 @@ func (l *dnsFallback) Apply(c *Config) {
 	c.DNS.FallbackDNS = l.f
 }
 
+// Apply implements the ConfigOption interface
+func (l *dnsFallbackStrategy) Apply(c *Config) {
+	c.DNS.FallbackStrategy = l.s
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsFallbackTimeout) Apply(c *Config) {
+	c.DNS.FallbackTimeout = l.d
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsFallbackCooldown) Apply(c *Config) {
+	c.DNS.FallbackCooldown = l.d
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsForwards) Apply(c *Config) {
+	c.DNS.Forwards = l.f
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsRootHints) Apply(c *Config) {
+	c.DNS.RootHints = l.p
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsUDPSize) Apply(c *Config) {
+	c.DNS.UDPSize = l.n
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsClientSubnet) Apply(c *Config) {
+	c.DNS.ClientSubnet = l.m
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsSEC) Apply(c *Config) {
+	c.DNS.DNSSEC = l.enabled
+	c.DNS.DNSSECKeys = l.keys
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsSECValidation) Apply(c *Config) {
+	c.DNS.DNSSECValidate = l.enabled
+	c.DNS.TrustAnchor = l.trustAnchor
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsBlocklists) Apply(c *Config) {
+	c.DNS.Blocklists = l.s
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsBlocklistAllow) Apply(c *Config) {
+	c.DNS.BlocklistAllow = l.d
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsBlocklistAnswer) Apply(c *Config) {
+	c.DNS.BlocklistAnswer = l.a
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsBlocklistRefresh) Apply(c *Config) {
+	c.DNS.BlocklistRefresh = l.d
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsTransferAllow) Apply(c *Config) {
+	c.DNS.TransferAllow = l.a
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsNotify) Apply(c *Config) {
+	c.DNS.Notify = l.t
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsTSIGKeys) Apply(c *Config) {
+	c.DNS.TSIGKeys = l.k
+}
+
 // Apply implements the ConfigOption interface
 func (l *dnsAddress) Apply(c *Config) {
 	c.DNS.Address = l.a
@@ -127,3 +727,34 @@ func (l *dnsPrefix) Apply(c *Config) {
 func (l *dnsProto) Apply(c *Config) {
 	c.DNS.Proto = l.p
 }
+
+// Apply implements the ConfigOption interface
+func (l *dnsTLSAddress) Apply(c *Config) {
+	c.DNS.TLSAddress = l.a
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsTLSCert) Apply(c *Config) {
+	c.DNS.TLSCert = l.cert
+	c.DNS.TLSKey = l.key
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsCacheSize) Apply(c *Config) {
+	c.DNS.CacheSize = l.n
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsTTL) Apply(c *Config) {
+	c.DNS.TTL = l.ttl
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsReverse) Apply(c *Config) {
+	c.DNS.Reverse = l.r
+}
+
+// Apply implements the ConfigOption interface
+func (l *dnsZones) Apply(c *Config) {
+	c.DNS.Zones = l.z
+}
@@ -1,10 +1,16 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 type StoreConfig struct {
-	Type string `json:"type,omitempty" yaml:"type,omitempty"`
-	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	Type      string `json:"type,omitempty" yaml:"type,omitempty"`
+	Path      string `json:"path,omitempty" yaml:"path,omitempty"`
+	Origin    string `json:"origin,omitempty" yaml:"origin,omitempty"`
+	Primary   string `json:"primary,omitempty" yaml:"primary,omitempty"`
+	SyncDelay string `json:"sync_delay,omitempty" yaml:"sync_delay,omitempty"`
 }
 
 // StorePath creates a ConfigOption setting the Config's store path to string `p`
@@ -46,6 +52,14 @@ func StoreType(t string) ConfigOption {
 		return &storeType{
 			t: "jsonfile",
 		}
+	case "zonefile", "zone":
+		return &storeType{
+			t: "zonefile",
+		}
+	case "secondary":
+		return &storeType{
+			t: "secondary",
+		}
 	default:
 		return &storeType{
 			t: "memmap",
@@ -53,12 +67,64 @@ func StoreType(t string) ConfigOption {
 	}
 }
 
+// StoreOrigin creates a ConfigOption setting the Config's store origin to string `o`,
+// the domain name completing the relative names in a `zonefile` store, or the zone held
+// by a `secondary` store
+//
+// If `o` is empty, the returned ConfigOption is `nil`
+func StoreOrigin(o string) ConfigOption {
+	if o == "" {
+		return nil
+	}
+	return &storeOrigin{
+		o: o,
+	}
+}
+
+// StorePrimary creates a ConfigOption setting the Config's store primary to string `p`,
+// the primary DNS server (as `host` or `host:port`) a `secondary` store transfers its
+// zone (the store origin) from
+//
+// If `p` is empty, the returned ConfigOption is `nil`
+func StorePrimary(p string) ConfigOption {
+	if p == "" {
+		return nil
+	}
+	return &storePrimary{
+		p: p,
+	}
+}
+
+// StoreSyncDelay creates a ConfigOption setting the time a file store waits after a
+// change to its records before writing them to its file, to the duration string `d`
+// (e.g. `1s`), coalescing all of the changes within it into a single write
+//
+// A zero duration writes the file on each change. If the string `d` is not a valid,
+// non-negative duration, it returns `nil`
+func StoreSyncDelay(d string) ConfigOption {
+	if dur, err := time.ParseDuration(d); err != nil || dur < 0 {
+		return nil
+	}
+	return &storeSyncDelay{
+		d: d,
+	}
+}
+
 type storePath struct {
 	p string
 }
 type storeType struct {
 	t string
 }
+type storeOrigin struct {
+	o string
+}
+type storePrimary struct {
+	p string
+}
+type storeSyncDelay struct {
+	d string
+}
 
 // Apply implements the ConfigOption interface
 func (l *storePath) Apply(c *Config) {
@@ -69,3 +135,18 @@ func (l *storePath) Apply(c *Config) {
 func (l *storeType) Apply(c *Config) {
 	c.Store.Type = l.t
 }
+
+// Apply implements the ConfigOption interface
+func (l *storeOrigin) Apply(c *Config) {
+	c.Store.Origin = l.o
+}
+
+// Apply implements the ConfigOption interface
+func (l *storePrimary) Apply(c *Config) {
+	c.Store.Primary = l.p
+}
+
+// Apply implements the ConfigOption interface
+func (l *storeSyncDelay) Apply(c *Config) {
+	c.Store.SyncDelay = l.d
+}
@@ -15,14 +15,42 @@ func ParseFlags() *config.Config {
 
 	configPath := flag.String("file", "", "load a config from a file")
 
-	dnsType := flag.String("dns-type", "miekgdns", "use a specific domain-name server implementation")
+	dnsType := flag.String("dns-type", "miekgdns", "use a specific domain-name server implementation (miekgdns, recursive)")
+	dnsRootHints := flag.String("dns-root-hints", "", "the path to the root hints file, for the recursive DNS type")
 	dnsFallback := flag.String("dns-fallback", "", "use a secondary DNS to parse unsuccessful queries")
+	dnsFallbackStrategy := flag.String("dns-fallback-strategy", "sequential", "the strategy for querying the fallback DNS (sequential, random, round-robin, fastest, latency-weighted)")
+	dnsFallbackTimeout := flag.String("dns-fallback-timeout", "2s", "the timeout for each query to a fallback DNS")
+	dnsFallbackCooldown := flag.String("dns-fallback-cooldown", "30s", "the period for which a failing fallback DNS is skipped")
+	dnsForward := flag.String("dns-forward", "", "semicolon-separated forwarding rules, mapping a domain to its fallback DNS (e.g. corp.example=10.0.0.1,10.0.0.2;consul=127.0.0.1:8600)")
+	dnsClientSubnet := flag.String("dns-ecs", "strip", "whether to forward the EDNS0 Client Subnet option to the fallback DNS (strip, forward)")
 	dnsAddress := flag.String("dns-addr", ":53", "the address to listen to for DNS queries")
 	dnsPrefix := flag.String("dns-prefix", ".", "the prefix for DNS queries / answers. Usually it's a period (.)")
-	dnsProto := flag.String("dns-proto", "udp", "the protocol for the DNS server")
-
-	storeType := flag.String("store-type", "memmap", "the record store implementation to use (memmap, yamlfile, jsonfile)")
+	dnsProto := flag.String("dns-proto", "udp", "the protocol(s) for the DNS server, comma-separated (udp, tcp, tcp-tls)")
+	dnsTLSAddress := flag.String("dns-tls-addr", ":853", "the address to listen to for DNS-over-TLS queries (with tcp-tls proto)")
+	dnsTLSCert := flag.String("dns-tls-cert", "", "the path to the TLS certificate for DNS-over-TLS")
+	dnsTLSKey := flag.String("dns-tls-key", "", "the path to the TLS key for DNS-over-TLS")
+	dnsUDPSize := flag.Int("dns-udp-size", 1232, "the maximum UDP payload size (in bytes) for DNS responses, advertised over EDNS0")
+	dnsTTL := flag.Uint("dns-ttl", 3600, "the default TTL (in seconds) for answers from records without one")
+	dnsReverse := flag.Bool("dns-reverse", false, "answer reverse (PTR) queries from the stored A / AAAA records")
+	dnsCacheSize := flag.Int("dns-cache-size", 1024, "the maximum number of fallback DNS answers to cache (0 disables the cache)")
+	dnsSEC := flag.Bool("dns-dnssec", false, "sign the answers from the zones owned by this server with DNSSEC")
+	dnsSECKeys := flag.String("dns-dnssec-keys", "", "the directory with the DNSSEC keys for the zones owned by this server")
+	dnsSECValidate := flag.Bool("dns-dnssec-validate", false, "validate the DNSSEC signatures of the fallback DNS answers")
+	dnsTrustAnchor := flag.String("dns-trust-anchor", "", "the path to the file with the DNSSEC trust anchors (DS or DNSKEY records), defaults to the root zone's keys")
+	dnsBlocklist := flag.String("dns-blocklist", "", "comma-separated list of blocklist files or URLs (hosts files, domain lists or AdBlock-style lists)")
+	dnsBlocklistAllow := flag.String("dns-blocklist-allow", "", "comma-separated list of domains which are never blocked (*.domain allows its subdomains)")
+	dnsBlocklistAnswer := flag.String("dns-blocklist-answer", "nxdomain", "the answer for blocked domains (nxdomain, null, or comma-separated sinkhole IP addresses)")
+	dnsBlocklistRefresh := flag.String("dns-blocklist-refresh", "24h", "the period between the updates of the blocklists")
+	dnsTransferAllow := flag.String("dns-transfer-allow", "", "comma-separated list of IP addresses or networks allowed to transfer the zones owned by this server")
+	dnsNotify := flag.String("dns-notify", "", "comma-separated list of secondary servers to notify when the zones owned by this server change")
+	dnsTSIGKeys := flag.String("dns-tsig-keys", "", "comma-separated list of TSIG keys, as [algorithm:]name:secret[@zone+zone], with the zones they are scoped to")
+	dnsZones := flag.String("dns-zones", "", "comma-separated list of zones owned by this server, answered authoritatively")
+
+	storeType := flag.String("store-type", "memmap", "the record store implementation to use (memmap, yamlfile, jsonfile, zonefile, secondary)")
 	storePath := flag.String("store-path", "", "the record store file path, if stored to a file")
+	storeOrigin := flag.String("store-origin", "", "the origin for relative domain names in a zonefile store, or the zone in a secondary store (e.g. corp.lan)")
+	storePrimary := flag.String("store-primary", "", "the primary DNS server to transfer the zone in a secondary store from (e.g. 10.0.0.53:53)")
+	storeSyncDelay := flag.String("store-sync-delay", "0s", "the time to wait after a change to a file store before writing it, coalescing the changes within it (0 writes on each change)")
 
 	httpPort := flag.Int("http-port", 8080, "port to use for the HTTP API, defaults to :8080")
 
@@ -54,11 +82,36 @@ func ParseFlags() *config.Config {
 			config.StorePath(*configPath),
 			config.DNSType(*dnsType),
 			config.DNSFallback(*dnsFallback),
+			config.DNSFallbackStrategy(*dnsFallbackStrategy),
+			config.DNSFallbackTimeout(*dnsFallbackTimeout),
+			config.DNSFallbackCooldown(*dnsFallbackCooldown),
+			config.DNSForwards(forwardsFrom(*dnsForward)...),
+			config.DNSRootHints(*dnsRootHints),
+			config.DNSClientSubnet(*dnsClientSubnet),
 			config.DNSAddress(*dnsAddress),
 			config.DNSPrefix(*dnsPrefix),
 			config.DNSProto(*dnsProto),
+			config.DNSTLSAddress(*dnsTLSAddress),
+			config.DNSTLSCert(*dnsTLSCert, *dnsTLSKey),
+			config.DNSUDPSize(*dnsUDPSize),
+			config.DNSTTL(uint32(*dnsTTL)),
+			config.DNSReverse(*dnsReverse),
+			config.DNSCacheSize(*dnsCacheSize),
+			config.DNSZones(zonesFrom(*dnsZones)...),
+			config.DNSSEC(*dnsSEC, *dnsSECKeys),
+			config.DNSSECValidation(*dnsSECValidate, *dnsTrustAnchor),
+			config.DNSBlocklists(listFrom(*dnsBlocklist)...),
+			config.DNSBlocklistAllow(listFrom(*dnsBlocklistAllow)...),
+			config.DNSBlocklistAnswer(*dnsBlocklistAnswer),
+			config.DNSBlocklistRefresh(*dnsBlocklistRefresh),
+			config.DNSTransferAllow(listFrom(*dnsTransferAllow)...),
+			config.DNSNotify(listFrom(*dnsNotify)...),
+			config.DNSTSIGKeys(tsigKeysFrom(*dnsTSIGKeys)...),
 			config.StoreType(*storeType),
 			config.StorePath(*storePath),
+			config.StoreOrigin(*storeOrigin),
+			config.StorePrimary(*storePrimary),
+			config.StoreSyncDelay(*storeSyncDelay),
 			config.HTTPPort(*httpPort),
 			config.LoggerPath(*loggerPath),
 			config.LoggerType(*loggerType),
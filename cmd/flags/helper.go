@@ -4,6 +4,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/zalgonoise/dns/cmd/config"
 )
 
 func intFromEnv(s string) int {
@@ -11,7 +13,7 @@ func intFromEnv(s string) int {
 	if val == "" {
 		return 0
 	}
-	n, err := strconv.Atoi(s)
+	n, err := strconv.Atoi(val)
 	if err != nil {
 		return 0
 	}
@@ -25,3 +27,102 @@ func boolFromEnv(s string) bool {
 		strings.ToLower(val) != "false" &&
 		strings.ToLower(val) != "no"
 }
+
+// listFrom parses the comma-separated list in string `s`, skipping empty elements
+func listFrom(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var list []string
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
+
+// zonesFrom parses the comma-separated list of zone names in string `s` into
+// config.ZoneConfig with default SOA and NS values
+func zonesFrom(s string) []*config.ZoneConfig {
+	if s == "" {
+		return nil
+	}
+
+	var zones []*config.ZoneConfig
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		zones = append(zones, &config.ZoneConfig{Name: name})
+	}
+	return zones
+}
+
+// forwardsFrom parses the semicolon-separated list of forwarding rules in string `s`
+// into config.ForwardConfig. Each rule maps a domain to a comma-separated list of
+// fallback DNS addresses, e.g. `corp.example=10.0.0.1,10.0.0.2;consul=127.0.0.1:8600`
+func forwardsFrom(s string) []*config.ForwardConfig {
+	if s == "" {
+		return nil
+	}
+
+	var forwards []*config.ForwardConfig
+	for _, rule := range strings.Split(s, ";") {
+		domain, addrs, ok := strings.Cut(rule, "=")
+		domain = strings.TrimSpace(domain)
+		if !ok || domain == "" {
+			continue
+		}
+
+		var upstreams []string
+		for _, addr := range strings.Split(addrs, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				upstreams = append(upstreams, addr)
+			}
+		}
+		if len(upstreams) == 0 {
+			continue
+		}
+		forwards = append(forwards, &config.ForwardConfig{Domain: domain, Upstreams: upstreams})
+	}
+	return forwards
+}
+
+// tsigKeysFrom parses the comma-separated list of TSIG keys in string `s` into
+// config.TSIGKeyConfig. Each key is formatted as `[algorithm:]name:secret` (like in `dig
+// -y`), optionally followed by `@` and the `+`-separated zones it is scoped to, e.g.
+// `hmac-sha256:transfer:c2VjcmV0` or `dhcp:c2VjcmV0@corp.lan+10.in-addr.arpa`
+func tsigKeysFrom(s string) []*config.TSIGKeyConfig {
+	if s == "" {
+		return nil
+	}
+
+	var keys []*config.TSIGKeyConfig
+	for _, elem := range strings.Split(s, ",") {
+		elem, zones, _ := strings.Cut(strings.TrimSpace(elem), "@")
+		fields := strings.Split(elem, ":")
+
+		var key *config.TSIGKeyConfig
+		switch len(fields) {
+		case 2:
+			key = &config.TSIGKeyConfig{Name: fields[0], Secret: fields[1]}
+		case 3:
+			key = &config.TSIGKeyConfig{Algorithm: fields[0], Name: fields[1], Secret: fields[2]}
+		default:
+			continue
+		}
+		if key.Name == "" || key.Secret == "" {
+			continue
+		}
+		for _, zone := range strings.Split(zones, "+") {
+			if zone = strings.TrimSpace(zone); zone != "" {
+				key.Zones = append(key.Zones, zone)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
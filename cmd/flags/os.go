@@ -18,10 +18,39 @@ func ParseOSEnv() *config.Config {
 			Address:     os.Getenv("DNS_ADDRESS"),
 			Prefix:      os.Getenv("DNS_PREFIX"),
 			Proto:       os.Getenv("DNS_PROTO"),
+			TLSAddress:  os.Getenv("DNS_TLS_ADDRESS"),
+			TLSCert:     os.Getenv("DNS_TLS_CERT"),
+			TLSKey:      os.Getenv("DNS_TLS_KEY"),
+			TTL:         uint32(intFromEnv("DNS_TTL")),
+			Reverse:     boolFromEnv("DNS_REVERSE"),
+			CacheSize:   intFromEnv("DNS_CACHE_SIZE"),
+			Zones:       zonesFrom(os.Getenv("DNS_ZONES")),
+
+			FallbackStrategy: os.Getenv("DNS_FALLBACK_STRATEGY"),
+			FallbackTimeout:  os.Getenv("DNS_FALLBACK_TIMEOUT"),
+			FallbackCooldown: os.Getenv("DNS_FALLBACK_COOLDOWN"),
+			Forwards:         forwardsFrom(os.Getenv("DNS_FORWARD")),
+			RootHints:        os.Getenv("DNS_ROOT_HINTS"),
+			UDPSize:          intFromEnv("DNS_UDP_SIZE"),
+			ClientSubnet:     os.Getenv("DNS_ECS"),
+			DNSSEC:           boolFromEnv("DNS_DNSSEC"),
+			DNSSECKeys:       os.Getenv("DNS_DNSSEC_KEYS"),
+			DNSSECValidate:   boolFromEnv("DNS_DNSSEC_VALIDATE"),
+			TrustAnchor:      os.Getenv("DNS_TRUST_ANCHOR"),
+			Blocklists:       listFrom(os.Getenv("DNS_BLOCKLIST")),
+			BlocklistAllow:   listFrom(os.Getenv("DNS_BLOCKLIST_ALLOW")),
+			BlocklistAnswer:  os.Getenv("DNS_BLOCKLIST_ANSWER"),
+			BlocklistRefresh: os.Getenv("DNS_BLOCKLIST_REFRESH"),
+			TransferAllow:    listFrom(os.Getenv("DNS_TRANSFER_ALLOW")),
+			Notify:           listFrom(os.Getenv("DNS_NOTIFY")),
+			TSIGKeys:         tsigKeysFrom(os.Getenv("DNS_TSIG_KEYS")),
 		},
 		Store: &config.StoreConfig{
-			Type: os.Getenv("DNS_STORE_TYPE"),
-			Path: os.Getenv("DNS_STORE_PATH"),
+			Type:      os.Getenv("DNS_STORE_TYPE"),
+			Path:      os.Getenv("DNS_STORE_PATH"),
+			Origin:    os.Getenv("DNS_STORE_ORIGIN"),
+			Primary:   os.Getenv("DNS_STORE_PRIMARY"),
+			SyncDelay: os.Getenv("DNS_STORE_SYNC_DELAY"),
 		},
 		HTTP: &config.HTTPConfig{
 			Port: intFromEnv("DNS_API_PORT"),
@@ -3,6 +3,8 @@ package cmd
 import (
 	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/zalgonoise/attr"
 	"github.com/zalgonoise/dns/cmd/config"
@@ -16,7 +18,9 @@ import (
 // Run starts the DNS app based on the input configuration (file configuration,
 // CLI flags, and OS environment variables)
 //
-// Blocking call; will error out (with os.Exit(1)) if failed
+// Blocking call; will error out (with os.Exit(1)) if failed. On an interrupt or
+// termination signal, the servers are stopped gracefully and the pending changes
+// to the store are flushed before returning
 func Run() {
 	ctx, s := spanner.Start(context.Background(), "starting DNS server")
 
@@ -31,24 +35,32 @@ func Run() {
 	// create HTTP / UDP servers
 	svr = factory.From(conf)
 
-	// start HTTP server
-	// defer graceful closure
-	defer func() {
-		err := svr.Stop(ctx)
-		if err != nil {
-			s.Event("failed to stop HTTP server in the deferred function",
-				attr.String("error", err.Error()),
-			)
-			s.End()
-			os.Exit(1)
-		}
+	// graceful closure on SIGINT / SIGTERM
+	stopped := make(chan error, 1)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		signal.Stop(sig)
+
+		stopped <- svr.Stop(ctx)
 	}()
 
+	// start HTTP server
 	err := svr.Start(ctx)
 	if err != nil {
 		s.Event("failed to start HTTP server", attr.String("error", err.Error()))
 		s.End()
 		os.Exit(1)
 	}
+
+	err = <-stopped
+	if err != nil {
+		s.Event("failed to stop HTTP server gracefully",
+			attr.String("error", err.Error()),
+		)
+		s.End()
+		os.Exit(1)
+	}
 	s.End()
 }
@@ -0,0 +1,394 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+)
+
+const (
+	// DefaultRefresh is the default period between the updates of the lists
+	DefaultRefresh = 24 * time.Hour
+	// blockedTTL is the TTL of the records in the answers for blocked domain names
+	blockedTTL uint32 = 60
+	// fetchTimeout is the timeout for downloading a list from a URL
+	fetchTimeout = time.Minute
+)
+
+var (
+	ErrLoad   = errors.New("failed to load blocklist")
+	ErrStatus = errors.New("unexpected HTTP status")
+)
+
+// Blocklist filters the DNS queries for the domain names in a set of lists, answering
+// them as a DNS sinkhole: with an NXDOMAIN response, with unspecified addresses (`0.0.0.0`
+// and `::`) or with the custom IP addresses of a sinkhole server
+//
+// Lists are read from local files or downloaded from http(s) URLs, and are refreshed
+// periodically once the Blocklist is started. They can be hosts files, plain lists of
+// domain names or AdBlock-style filter lists, whose `@@` rules allow domain names instead.
+// Domain names in the allowlist are never blocked, overriding the lists
+type Blocklist struct {
+	// blocked is accessed atomically, and kept first for 64-bit alignment
+	blocked uint64
+
+	sources []string
+	allowed rules
+	null    bool
+	sinks   []net.IP
+	refresh time.Duration
+	client  *http.Client
+
+	mtx   sync.RWMutex
+	lists []*list
+	block rules
+	allow rules
+
+	stop chan struct{}
+	once sync.Once
+	now  func() time.Time
+}
+
+// list is the state of one of the Blocklist's sources
+type list struct {
+	source  string
+	block   rules
+	allow   rules
+	updated time.Time
+	err     error
+}
+
+// Stats describes the state of a Blocklist
+type Stats struct {
+	Lists   []ListStats
+	Domains int
+	Allowed int
+	Blocked uint64
+}
+
+// ListStats describes the state of one of the lists in a Blocklist: its number of
+// domain names, when it was last updated and the error in its last update, if any
+type ListStats struct {
+	Source  string
+	Domains int
+	Updated time.Time
+	Err     error
+}
+
+// Option describes setter types for a Blocklist
+type Option interface {
+	Apply(*Blocklist)
+}
+
+// Allow creates an Option adding the domain names `domains` to the Blocklist's allowlist,
+// which is checked before the lists. Each entry allows the domain name itself; or its
+// subdomains only, if it starts with a wildcard label (`*.example.com`); or both, as an
+// AdBlock-style rule (`||example.com^`)
+//
+// Invalid entries are ignored; if none are left, it returns nil
+func Allow(domains ...string) Option {
+	allowed := rules{}
+	for _, d := range domains {
+		if name, match, ok := parseRule(strings.TrimPrefix(strings.TrimSpace(d), "@@")); ok {
+			allowed.add(name, match)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return &allowOpt{
+		allowed: allowed,
+	}
+}
+
+// Answer creates an Option setting how the Blocklist answers the queries for blocked
+// domain names, from the string `mode`:
+//   - `nxdomain` answers with an NXDOMAIN response (the default)
+//   - `null` answers the A and AAAA queries with `0.0.0.0` and `::`
+//   - a comma-separated list of IP addresses answers the A and AAAA queries with them
+//
+// Queries for other record types are answered with no records. If `mode` is not valid,
+// it returns nil
+func Answer(mode string) Option {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "nxdomain":
+		return &answerOpt{}
+	case "null":
+		return &answerOpt{null: true}
+	}
+
+	var sinks []net.IP
+	for _, addr := range strings.Split(mode, ",") {
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		if ip == nil {
+			return nil
+		}
+		sinks = append(sinks, ip)
+	}
+	return &answerOpt{
+		sinks: sinks,
+	}
+}
+
+// Refresh creates an Option setting the period between the updates of the Blocklist's
+// lists to `d`
+//
+// If `d` is zero or negative, it returns nil
+func Refresh(d time.Duration) Option {
+	if d <= 0 {
+		return nil
+	}
+	return &refreshOpt{
+		d: d,
+	}
+}
+
+type allowOpt struct {
+	allowed rules
+}
+
+type answerOpt struct {
+	null  bool
+	sinks []net.IP
+}
+
+type refreshOpt struct {
+	d time.Duration
+}
+
+// Apply implements the Option interface
+func (o *allowOpt) Apply(b *Blocklist) {
+	b.allowed.merge(o.allowed)
+}
+
+// Apply implements the Option interface
+func (o *answerOpt) Apply(b *Blocklist) {
+	b.null = o.null
+	b.sinks = o.sinks
+}
+
+// Apply implements the Option interface
+func (o *refreshOpt) Apply(b *Blocklist) {
+	b.refresh = o.d
+}
+
+// New creates a Blocklist with the lists in `sources` (file paths or http(s) URLs),
+// applying all input Option `opts`
+//
+// The lists are only read when calling Load or Start
+func New(sources []string, opts ...Option) *Blocklist {
+	b := &Blocklist{
+		allowed: rules{},
+		refresh: DefaultRefresh,
+		client:  &http.Client{Timeout: fetchTimeout},
+		block:   rules{},
+		allow:   rules{},
+		stop:    make(chan struct{}),
+		now:     time.Now,
+	}
+
+	for _, source := range sources {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+		b.sources = append(b.sources, source)
+		b.lists = append(b.lists, &list{source: source})
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(b)
+		}
+	}
+
+	b.allow.merge(b.allowed)
+	return b
+}
+
+// Start loads the Blocklist's lists, and keeps refreshing them in the background until
+// the Blocklist is closed
+func (b *Blocklist) Start() {
+	go func() {
+		_ = b.Load(context.Background())
+
+		ticker := time.NewTicker(b.refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				_ = b.Load(context.Background())
+			}
+		}
+	}()
+}
+
+// Close stops refreshing the Blocklist's lists
+func (b *Blocklist) Close() {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+}
+
+// Load reads all of the Blocklist's lists, replacing their domain names
+//
+// A list which fails to load keeps the domain names from its last update. Returns an
+// error wrapping ErrLoad for the first list which failed to load, if any
+func (b *Blocklist) Load(ctx context.Context) error {
+	type result struct {
+		block, allow rules
+		err          error
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]result, len(b.sources))
+	)
+	for i := range b.sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			block, allow, err := b.fetch(ctx, b.sources[i])
+			results[i] = result{block, allow, err}
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		err   error
+		block = rules{}
+		allow = rules{}
+		now   = b.now()
+	)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for i, l := range b.lists {
+		l.err = results[i].err
+		if l.err != nil {
+			if err == nil {
+				err = fmt.Errorf("%w: %s: %v", ErrLoad, l.source, l.err)
+			}
+		} else {
+			l.block, l.allow, l.updated = results[i].block, results[i].allow, now
+		}
+		block.merge(l.block)
+		allow.merge(l.allow)
+	}
+	allow.merge(b.allowed)
+
+	b.block, b.allow = block, allow
+	return err
+}
+
+// fetch reads the list in `source`, from a local file or a http(s) URL
+func (b *Blocklist) fetch(ctx context.Context, source string) (rules, rules, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+
+		return parse(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, http.NoBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, nil, fmt.Errorf("%w: %s", ErrStatus, res.Status)
+	}
+	return parse(res.Body)
+}
+
+// Blocked returns true if the domain name `name` is in one of the Blocklist's lists,
+// and it is not allowed
+func (b *Blocklist) Blocked(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" {
+		return false
+	}
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	return b.block.matches(name) && !b.allow.matches(name)
+}
+
+// Answer replies to the query for the blocked domain name `name` and the record type
+// `qtype` in the dns.Msg `m`, according to the Blocklist's answer mode
+//
+// Returns dns.ErrNXDomain if the Blocklist answers with NXDOMAIN responses
+func (b *Blocklist) Answer(name string, qtype uint16, m *dns.Msg) error {
+	atomic.AddUint64(&b.blocked, 1)
+
+	if !b.null && len(b.sinks) == 0 {
+		return dnsrepo.ErrNXDomain
+	}
+
+	sinks := b.sinks
+	if b.null {
+		sinks = []net.IP{net.IPv4zero, net.IPv6zero}
+	}
+
+	name = dns.Fqdn(name)
+	for _, ip := range sinks {
+		hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: blockedTTL}
+		ip4 := ip.To4()
+
+		switch {
+		case ip4 != nil && (qtype == dns.TypeA || qtype == dns.TypeANY):
+			hdr.Rrtype = dns.TypeA
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: ip4})
+		case ip4 == nil && (qtype == dns.TypeAAAA || qtype == dns.TypeANY):
+			hdr.Rrtype = dns.TypeAAAA
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+		}
+	}
+	return nil
+}
+
+// Stats returns the state of the Blocklist and its lists
+func (b *Blocklist) Stats() *Stats {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	stats := &Stats{
+		Lists:   make([]ListStats, 0, len(b.lists)),
+		Domains: len(b.block),
+		Allowed: len(b.allow),
+		Blocked: atomic.LoadUint64(&b.blocked),
+	}
+	for _, l := range b.lists {
+		stats.Lists = append(stats.Lists, ListStats{
+			Source:  l.source,
+			Domains: len(l.block),
+			Updated: l.updated,
+			Err:     l.err,
+		})
+	}
+	return stats
+}
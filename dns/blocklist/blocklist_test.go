@@ -0,0 +1,248 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+)
+
+const (
+	hostsList = `# hosts file
+127.0.0.1 localhost
+::1 localhost ip6-localhost ip6-loopback
+0.0.0.0 ads.example.com tracker.example.com # inline comment
+0.0.0.0 0.0.0.0
+`
+	plainList = `# plain list
+telemetry.example.org
+*.metrics.example.org
+not a domain
+`
+	adblockList = `[Adblock Plus 2.0]
+! Title: test list
+||doubleclick.example^
+||cdn.example.net^$third-party
+||example.net/path^
+@@||good.doubleclick.example^
+`
+)
+
+// listServer serves the test lists from a local HTTP server. The plain list fails with a
+// server error once `fail` is set
+func listServer(t *testing.T, fail *atomic.Bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hosts", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(hostsList))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(plainList))
+	})
+	mux.HandleFunc("/adblock", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(adblockList))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestBlocklist(t *testing.T) {
+	var fail atomic.Bool
+	srv := listServer(t, &fail)
+	defer srv.Close()
+
+	b := New(
+		[]string{srv.URL + "/hosts", srv.URL + "/plain", srv.URL + "/adblock"},
+		Allow("tracker.example.com", "*.safe.doubleclick.example"),
+	)
+	if err := b.Load(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading lists: %v", err)
+	}
+
+	t.Run("Blocked", func(t *testing.T) {
+		for _, test := range []struct {
+			name    string
+			blocked bool
+		}{
+			{name: "ads.example.com", blocked: true},
+			{name: "ADS.example.com.", blocked: true},
+			{name: "sub.ads.example.com"},
+			{name: "localhost"},
+			{name: "telemetry.example.org", blocked: true},
+			{name: "example.org"},
+			{name: "metrics.example.org"},
+			{name: "eu.metrics.example.org", blocked: true},
+			{name: "doubleclick.example", blocked: true},
+			{name: "ad.doubleclick.example", blocked: true},
+			{name: "cdn.example.net"},
+			{name: "example.net"},
+		} {
+			if blocked := b.Blocked(test.name); blocked != test.blocked {
+				t.Errorf("unexpected result for %s: wanted %v ; got %v", test.name, test.blocked, blocked)
+			}
+		}
+	})
+
+	t.Run("Allowed", func(t *testing.T) {
+		for _, name := range []string{
+			"tracker.example.com",
+			"good.doubleclick.example",
+			"www.good.doubleclick.example",
+			"a.safe.doubleclick.example",
+		} {
+			if b.Blocked(name) {
+				t.Errorf("expected %s to be allowed", name)
+			}
+		}
+		if !b.Blocked("safe.doubleclick.example") {
+			t.Errorf("expected safe.doubleclick.example to be blocked")
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		b.Answer("ads.example.com", dns.TypeA, new(dns.Msg))
+
+		stats := b.Stats()
+		if len(stats.Lists) != 3 {
+			t.Fatalf("unexpected number of lists: wanted %v ; got %v", 3, len(stats.Lists))
+		}
+		for i, domains := range []int{2, 2, 1} {
+			if stats.Lists[i].Domains != domains {
+				t.Errorf("unexpected number of domains in %s: wanted %v ; got %v", stats.Lists[i].Source, domains, stats.Lists[i].Domains)
+			}
+			if stats.Lists[i].Updated.IsZero() || stats.Lists[i].Err != nil {
+				t.Errorf("unexpected list state for %s: %+v", stats.Lists[i].Source, stats.Lists[i])
+			}
+		}
+		if stats.Domains != 5 || stats.Allowed != 3 || stats.Blocked != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("FailedRefresh", func(t *testing.T) {
+		fail.Store(true)
+		defer fail.Store(false)
+
+		err := b.Load(context.Background())
+		if !errors.Is(err, ErrLoad) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrLoad, err)
+		}
+		if !b.Blocked("telemetry.example.org") {
+			t.Errorf("expected the failed list to keep its domains")
+		}
+		if stats := b.Stats(); stats.Lists[1].Err == nil || stats.Lists[1].Domains != 2 {
+			t.Errorf("unexpected list state: %+v", stats.Lists[1])
+		}
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(hostsList), 0o600); err != nil {
+		t.Fatalf("unexpected error writing list: %v", err)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		b := New([]string{path})
+		if err := b.Load(context.Background()); err != nil {
+			t.Fatalf("unexpected error loading list: %v", err)
+		}
+		if !b.Blocked("ads.example.com") {
+			t.Errorf("expected ads.example.com to be blocked")
+		}
+	})
+
+	t.Run("FailMissingFile", func(t *testing.T) {
+		b := New([]string{path + ".missing"})
+		if err := b.Load(context.Background()); !errors.Is(err, ErrLoad) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrLoad, err)
+		}
+	})
+}
+
+func TestAnswer(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		mode    string
+		qtype   uint16
+		err     error
+		answers []string
+	}{
+		{
+			name:  "NXDomain",
+			mode:  "nxdomain",
+			qtype: dns.TypeA,
+			err:   dnsrepo.ErrNXDomain,
+		},
+		{
+			name:    "NullA",
+			mode:    "null",
+			qtype:   dns.TypeA,
+			answers: []string{"0.0.0.0"},
+		},
+		{
+			name:    "NullAAAA",
+			mode:    "null",
+			qtype:   dns.TypeAAAA,
+			answers: []string{"::"},
+		},
+		{
+			name:  "NullNoData",
+			mode:  "null",
+			qtype: dns.TypeMX,
+		},
+		{
+			name:    "Sinkhole",
+			mode:    "10.0.0.53, fd00::53",
+			qtype:   dns.TypeA,
+			answers: []string{"10.0.0.53"},
+		},
+		{
+			name:    "SinkholeANY",
+			mode:    "10.0.0.53,fd00::53",
+			qtype:   dns.TypeANY,
+			answers: []string{"10.0.0.53", "fd00::53"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := new(dns.Msg)
+			err := New(nil, Answer(test.mode)).Answer("ads.example.com", test.qtype, m)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Errorf("unexpected error: wanted %v ; got %v", test.err, err)
+				return
+			}
+
+			if len(m.Answer) != len(test.answers) {
+				t.Fatalf("unexpected answers list length: wanted %v ; got %v", len(test.answers), len(m.Answer))
+			}
+			for i, rr := range m.Answer {
+				var ip net.IP
+				switch v := rr.(type) {
+				case *dns.A:
+					ip = v.A
+				case *dns.AAAA:
+					ip = v.AAAA
+				}
+				if !ip.Equal(net.ParseIP(test.answers[i])) || rr.Header().Name != "ads.example.com." {
+					t.Errorf("unexpected answer: wanted %v ; got %v", test.answers[i], rr)
+				}
+			}
+		})
+	}
+
+	t.Run("FailInvalidMode", func(t *testing.T) {
+		if opt := Answer("sinkhole"); opt != nil {
+			t.Errorf("expected a nil option for an invalid answer mode")
+		}
+	})
+}
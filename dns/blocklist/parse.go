@@ -0,0 +1,162 @@
+package blocklist
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// matchName matches the domain name itself
+	matchName uint8 = 1 << iota
+	// matchSubdomains matches the subdomains of the domain name
+	matchSubdomains
+)
+
+// maxLineLength is the longest line read from a list, in bytes
+const maxLineLength = 64 * 1024
+
+// hostsNames are the names in hosts files which refer to the host itself, and are never
+// blocked
+var hostsNames = map[string]struct{}{
+	"localhost":             {},
+	"localhost.localdomain": {},
+	"local":                 {},
+	"broadcasthost":         {},
+	"ip6-localhost":         {},
+	"ip6-loopback":          {},
+	"ip6-localnet":          {},
+	"ip6-mcastprefix":       {},
+	"ip6-allnodes":          {},
+	"ip6-allrouters":        {},
+	"ip6-allhosts":          {},
+}
+
+// rules maps the domain names in a list to what they match: the name itself, its
+// subdomains, or both
+type rules map[string]uint8
+
+// add adds the domain name `name` to the rules, matching `match`
+func (r rules) add(name string, match uint8) {
+	r[name] |= match
+}
+
+// merge adds all domain names in rules `in` to the rules
+func (r rules) merge(in rules) {
+	for name, match := range in {
+		r[name] |= match
+	}
+}
+
+// matches returns true if the (canonical) domain name `name` is matched by the rules,
+// either by itself or by one of its parent domains
+func (r rules) matches(name string) bool {
+	if r[name]&matchName != 0 {
+		return true
+	}
+	for idx := strings.IndexByte(name, '.'); idx >= 0; idx = strings.IndexByte(name, '.') {
+		name = name[idx+1:]
+		if r[name]&matchSubdomains != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads a list from the io.Reader `r`, returning its blocking and allowing rules
+//
+// Each line is either:
+//   - a hosts file entry (`0.0.0.0 ads.example.com`), blocking the names in it
+//   - a plain domain name (`ads.example.com`), blocking it; or its subdomains only, if it
+//     starts with a wildcard label (`*.example.com`)
+//   - an AdBlock-style rule, blocking a domain name and its subdomains (`||example.com^`),
+//     or allowing them (`@@||example.com^`)
+//
+// Comments (starting with `#` or `!`), section headers (`[Adblock Plus 2.0]`) and the
+// AdBlock rules which do not apply to whole domains are skipped
+func parse(r io.Reader) (block, allow rules, err error) {
+	block, allow = rules{}, rules{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineLength)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' || line[0] == '[' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@") {
+			if name, match, ok := parseRule(line[2:]); ok {
+				allow.add(name, match)
+			}
+			continue
+		}
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case len(fields) > 1 && net.ParseIP(fields[0]) != nil:
+			for _, field := range fields[1:] {
+				if _, ok := hostsNames[strings.ToLower(field)]; ok {
+					continue
+				}
+				if name, ok := domain(field); ok {
+					block.add(name, matchName)
+				}
+			}
+		case len(fields) == 1:
+			if name, match, ok := parseRule(fields[0]); ok {
+				block.add(name, match)
+			}
+		}
+	}
+
+	return block, allow, scanner.Err()
+}
+
+// parseRule parses the plain domain name or AdBlock-style rule `rule`, returning the
+// domain name and what it matches
+func parseRule(rule string) (string, uint8, bool) {
+	if strings.HasPrefix(rule, "||") {
+		name, rest, ok := strings.Cut(rule[2:], "^")
+		if !ok || (rest != "" && rest != "|") {
+			return "", 0, false
+		}
+		// AdBlock rules may block whole top-level domains (`||zip^`)
+		name, ok = domain(strings.TrimSuffix(name, ".") + ".")
+		return name, matchName | matchSubdomains, ok
+	}
+
+	if strings.HasPrefix(rule, "*.") {
+		name, ok := domain(rule[2:])
+		return name, matchSubdomains, ok
+	}
+
+	name, ok := domain(rule)
+	return name, matchName, ok
+}
+
+// domain returns the canonical form of the domain name `name` (lowercase, without the
+// trailing dot), and true if it is a valid domain name with at least two labels (or a
+// fully-qualified one)
+func domain(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if !strings.Contains(strings.TrimSuffix(name, "."), ".") && !strings.HasSuffix(name, ".") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || strings.ContainsAny(name, "*/:|^$@") {
+		return "", false
+	}
+	if _, ok := dns.IsDomainName(name); !ok || net.ParseIP(name) != nil {
+		return "", false
+	}
+	return name, true
+}
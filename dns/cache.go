@@ -0,0 +1,30 @@
+package dns
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoCache error = errors.New("DNS answers are not cached")
+)
+
+// Cache defines the set of operations that a cache for the fallback DNS answers should
+// expose, on top of the Repository it is placed in front of
+type Cache interface {
+	Repository
+
+	// Flush removes the cached answers for the domain name `name` (of any record type),
+	// or all cached answers if `name` is empty, returning the number of removed entries
+	Flush(ctx context.Context, name string) int
+	// Stats returns the current size, capacity and hit / miss counts of the Cache
+	Stats(ctx context.Context) *CacheStats
+}
+
+// CacheStats describes the usage of a Cache
+type CacheStats struct {
+	Size     int    `json:"size"`
+	Capacity int    `json:"capacity"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+}
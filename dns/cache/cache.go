@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dns "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/spanner"
+)
+
+const (
+	// DefaultSize is the default number of entries held by a Cache
+	DefaultSize = 1024
+	// maxTTL caps the time an answer is kept in the Cache, in seconds
+	maxTTL uint32 = 86400
+)
+
+// Cache is a bounded (least-recently-used), TTL-aware cache placed in front of a
+// dns.Repository's Fallback method
+//
+// Answers are kept for the lowest TTL in their records, and negative answers (NXDOMAIN
+// and NODATA) are kept for the TTL of the SOA record in their authority section, as per
+// RFC 2308; negative answers without an SOA record and failed queries are not cached.
+// When answering from the Cache, the records' TTLs are decremented by the time they have
+// been cached
+type Cache struct {
+	// hits and misses are accessed atomically, and kept first for 64-bit alignment
+	hits   uint64
+	misses uint64
+
+	r    dnsrepo.Repository
+	size int
+
+	mtx     sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+
+	now func() time.Time
+}
+
+var _ dnsrepo.Cache = (*Cache)(nil)
+
+type entry struct {
+	key     string
+	name    string
+	answer  []dns.RR
+	ns      []dns.RR
+	extra   []dns.RR
+	ad      bool
+	ra      bool
+	err     error
+	created time.Time
+	expires time.Time
+}
+
+// New returns a Cache in front of the dns.Repository `r`, holding up to `size` entries
+// (or DefaultSize, if zero or negative)
+func New(r dnsrepo.Repository, size int) *Cache {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Cache{
+		r:       r,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// Answer implements the dns.Repository interface, by calling the underlying
+// dns.Repository's Answer method
+func (c *Cache) Answer(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	return c.r.Answer(ctx, r, m)
+}
+
+// Authority implements the dns.Repository interface, by calling the underlying
+// dns.Repository's Authority method
+func (c *Cache) Authority(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	return c.r.Authority(ctx, r, m)
+}
+
+// Fallback implements the dns.Repository interface
+//
+// It answers the query from the Cache if it holds a valid entry for the domain name and
+// record type in store.Record `r`; otherwise it calls the underlying dns.Repository's
+// Fallback method and caches its answer
+func (c *Cache) Fallback(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	ctx, s := spanner.Start(ctx, "cache.Fallback")
+	defer s.End()
+
+	name := strings.ToLower(dns.Fqdn(r.Name))
+	key := cacheKey(ctx, name, r.Type)
+
+	if e, ok := c.get(key); ok {
+		atomic.AddUint64(&c.hits, 1)
+		s.Add(attr.String("cache", "hit"))
+
+		if len(m.Answer) == 0 {
+			m.AuthenticatedData = e.ad
+		}
+		m.RecursionAvailable = e.ra
+
+		elapsed := uint32(c.now().Sub(e.created) / time.Second)
+		m.Answer = append(m.Answer, age(e.answer, elapsed)...)
+		m.Ns = append(m.Ns, age(e.ns, elapsed)...)
+		m.Extra = append(m.Extra, age(e.extra, elapsed)...)
+		return e.err
+	}
+	atomic.AddUint64(&c.misses, 1)
+	s.Add(attr.String("cache", "miss"))
+
+	res := new(dns.Msg)
+	err := c.r.Fallback(ctx, r, res)
+
+	if len(m.Answer) == 0 {
+		m.AuthenticatedData = res.AuthenticatedData
+	}
+	m.RecursionAvailable = res.RecursionAvailable
+	m.Answer = append(m.Answer, res.Answer...)
+	m.Ns = append(m.Ns, res.Ns...)
+	m.Extra = append(m.Extra, res.Extra...)
+
+	if err != nil && !errors.Is(err, dnsrepo.ErrNXDomain) {
+		return err
+	}
+
+	if ttl, ok := cacheTTL(res, err); ok {
+		now := c.now()
+		c.set(&entry{
+			key:     key,
+			name:    name,
+			answer:  res.Answer,
+			ns:      res.Ns,
+			extra:   res.Extra,
+			ad:      res.AuthenticatedData,
+			ra:      res.RecursionAvailable,
+			err:     err,
+			created: now,
+			expires: now.Add(time.Duration(ttl) * time.Second),
+		})
+	}
+	return err
+}
+
+// Flush removes the cached answers for the domain name `name` (of any record type),
+// or all cached answers if `name` is empty, returning the number of removed entries
+func (c *Cache) Flush(ctx context.Context, name string) int {
+	_, s := spanner.Start(ctx, "cache.Flush")
+	defer s.End()
+	s.Add(attr.String("name", name))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if name == "" {
+		n := len(c.entries)
+		c.lru.Init()
+		c.entries = make(map[string]*list.Element, c.size)
+		s.Add(attr.Int("flushed", n))
+		return n
+	}
+
+	var n int
+	name = strings.ToLower(dns.Fqdn(name))
+	for _, elem := range c.entries {
+		if elem.Value.(*entry).name == name {
+			c.remove(elem)
+			n++
+		}
+	}
+	s.Add(attr.Int("flushed", n))
+	return n
+}
+
+// Stats returns the current size, capacity and hit / miss counts of the Cache
+func (c *Cache) Stats(ctx context.Context) *dnsrepo.CacheStats {
+	c.mtx.Lock()
+	size := len(c.entries)
+	c.mtx.Unlock()
+
+	return &dnsrepo.CacheStats{
+		Size:     size,
+		Capacity: c.size,
+		Hits:     atomic.LoadUint64(&c.hits),
+		Misses:   atomic.LoadUint64(&c.misses),
+	}
+}
+
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return e, true
+}
+
+func (c *Cache) set(e *entry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if elem, ok := c.entries[e.key]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove deletes the list.Element `elem` from the Cache; the caller must hold the lock
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
+
+// cacheKey returns the key for the answers to the query for the domain name `name` and
+// record type `rtype`. As the answers depend on the DO and CD bits of the client's query
+// (carried in the context), queries setting them are cached separately; as are queries
+// for each EDNS0 Client Subnet, which may be forwarded to the fallback DNS
+func cacheKey(ctx context.Context, name, rtype string) string {
+	key := name + " " + rtype
+
+	q := dnsrepo.QueryFrom(ctx)
+	if q == nil {
+		return key
+	}
+	if opt := q.IsEdns0(); opt != nil {
+		if opt.Do() {
+			key += " do"
+		}
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+				key += " ecs=" + subnet.String()
+			}
+		}
+	}
+	if q.CheckingDisabled {
+		key += " cd"
+	}
+	return key
+}
+
+// cacheTTL returns the time (in seconds) that the answer in dns.Msg `m` can be cached
+// for, and false if it should not be cached
+func cacheTTL(m *dns.Msg, err error) (uint32, bool) {
+	var (
+		ttl uint32
+		ok  bool
+	)
+
+	// positive answers
+	if err == nil && len(m.Answer) > 0 {
+		for _, rr := range m.Answer {
+			if !ok || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+				ok = true
+			}
+		}
+		return capTTL(ttl), ok && ttl > 0
+	}
+
+	// negative answers (NXDOMAIN and NODATA), with the TTL from their SOA record (RFC 2308)
+	for _, rr := range m.Ns {
+		soa, isSOA := rr.(*dns.SOA)
+		if !isSOA {
+			continue
+		}
+		ttl = soa.Hdr.Ttl
+		if soa.Minttl < ttl {
+			ttl = soa.Minttl
+		}
+		return capTTL(ttl), ttl > 0
+	}
+	return 0, false
+}
+
+func capTTL(ttl uint32) uint32 {
+	if ttl > maxTTL {
+		return maxTTL
+	}
+	return ttl
+}
+
+// age returns copies of the records in `rrs` with their TTLs decremented by `elapsed` seconds
+func age(rrs []dns.RR, elapsed uint32) []dns.RR {
+	if len(rrs) == 0 {
+		return nil
+	}
+
+	out := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		cp := dns.Copy(rr)
+		if cp.Header().Ttl > elapsed {
+			cp.Header().Ttl -= elapsed
+		} else {
+			cp.Header().Ttl = 0
+		}
+		out = append(out, cp)
+	}
+	return out
+}
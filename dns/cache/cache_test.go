@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+// upstream is a dns.Repository replying to Fallback calls with A records for
+// `ok.lan` (TTL 60), NXDOMAIN with an SOA record (minimum TTL 30) for `nx.lan`,
+// NXDOMAIN without an SOA record for `nosoa.lan`, and SERVFAIL otherwise
+type upstream struct {
+	calls int
+}
+
+func (u *upstream) Answer(context.Context, *store.Record, *dns.Msg) error    { return nil }
+func (u *upstream) Authority(context.Context, *store.Record, *dns.Msg) error { return nil }
+
+func (u *upstream) Fallback(_ context.Context, r *store.Record, m *dns.Msg) error {
+	u.calls++
+
+	switch r.Name {
+	case "ok.lan":
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: "ok.lan.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("10.0.0.5"),
+		})
+		return nil
+	case "nx.lan":
+		m.Ns = append(m.Ns, &dns.SOA{
+			Hdr:    dns.RR_Header{Name: "lan.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+			Ns:     "ns.lan.",
+			Mbox:   "admin.lan.",
+			Minttl: 30,
+		})
+		return dnsrepo.ErrNXDomain
+	case "nosoa.lan":
+		return dnsrepo.ErrNXDomain
+	default:
+		return dnsrepo.ErrServFail
+	}
+}
+
+func newTestCache(size int) (*Cache, *upstream, *time.Time) {
+	u := &upstream{}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(u, size)
+	c.now = func() time.Time { return now }
+	return c, u, &now
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CachePositiveAnswer", func(t *testing.T) {
+		c, u, now := newTestCache(0)
+		r := store.New().Type("A").Name("ok.lan").Build()
+
+		for i := 0; i < 3; i++ {
+			m := new(dns.Msg)
+			if err := c.Fallback(ctx, r, m); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(m.Answer) != 1 {
+				t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+				return
+			}
+		}
+		if u.calls != 1 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 1, u.calls)
+		}
+
+		stats := c.Stats(ctx)
+		if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+
+		// TTLs are decremented as the entry ages
+		*now = now.Add(45 * time.Second)
+		m := new(dns.Msg)
+		_ = c.Fallback(ctx, r, m)
+		if ttl := m.Answer[0].Header().Ttl; ttl != 15 {
+			t.Errorf("unexpected TTL: wanted %v ; got %v", 15, ttl)
+		}
+
+		// and the entry expires with its TTL
+		*now = now.Add(15 * time.Second)
+		_ = c.Fallback(ctx, r, new(dns.Msg))
+		if u.calls != 2 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 2, u.calls)
+		}
+	})
+
+	t.Run("CacheNegativeAnswer", func(t *testing.T) {
+		c, u, now := newTestCache(0)
+		r := store.New().Type("A").Name("nx.lan").Build()
+
+		for i := 0; i < 2; i++ {
+			m := new(dns.Msg)
+			err := c.Fallback(ctx, r, m)
+			if !errors.Is(err, dnsrepo.ErrNXDomain) {
+				t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrNXDomain, err)
+			}
+			if len(m.Ns) != 1 {
+				t.Errorf("unexpected authority length: wanted %v ; got %v", 1, len(m.Ns))
+			}
+		}
+		if u.calls != 1 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 1, u.calls)
+		}
+
+		// negative answers are kept for the SOA's minimum TTL
+		*now = now.Add(30 * time.Second)
+		_ = c.Fallback(ctx, r, new(dns.Msg))
+		if u.calls != 2 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 2, u.calls)
+		}
+	})
+
+	t.Run("SkipUncacheable", func(t *testing.T) {
+		c, u, _ := newTestCache(0)
+
+		for _, name := range []string{"nosoa.lan", "fail.lan"} {
+			r := store.New().Type("A").Name(name).Build()
+			_ = c.Fallback(ctx, r, new(dns.Msg))
+			_ = c.Fallback(ctx, r, new(dns.Msg))
+		}
+
+		if u.calls != 4 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 4, u.calls)
+		}
+		if size := c.Stats(ctx).Size; size != 0 {
+			t.Errorf("unexpected cache size: wanted %v ; got %v", 0, size)
+		}
+	})
+
+	t.Run("EvictLeastRecentlyUsed", func(t *testing.T) {
+		c, u, _ := newTestCache(2)
+		a := store.New().Type("A").Name("ok.lan").Build()
+		aaaa := store.New().Type("AAAA").Name("ok.lan").Build()
+		mx := store.New().Type("MX").Name("ok.lan").Build()
+
+		_ = c.Fallback(ctx, a, new(dns.Msg))
+		_ = c.Fallback(ctx, aaaa, new(dns.Msg))
+		_ = c.Fallback(ctx, a, new(dns.Msg))  // hit: A is now the most recently used
+		_ = c.Fallback(ctx, mx, new(dns.Msg)) // evicts AAAA
+		_ = c.Fallback(ctx, a, new(dns.Msg))  // hit
+
+		if u.calls != 3 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 3, u.calls)
+		}
+		if size := c.Stats(ctx).Size; size != 2 {
+			t.Errorf("unexpected cache size: wanted %v ; got %v", 2, size)
+		}
+	})
+
+	t.Run("SeparateDNSSECQueries", func(t *testing.T) {
+		c, u, _ := newTestCache(0)
+		r := store.New().Type("A").Name("ok.lan").Build()
+
+		q := new(dns.Msg)
+		q.SetQuestion("ok.lan.", dns.TypeA)
+		q.SetEdns0(4096, true)
+		doCtx := dnsrepo.WithQuery(ctx, q)
+
+		_ = c.Fallback(ctx, r, new(dns.Msg))
+		_ = c.Fallback(doCtx, r, new(dns.Msg))
+		_ = c.Fallback(doCtx, r, new(dns.Msg)) // hit
+
+		if u.calls != 2 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 2, u.calls)
+		}
+	})
+
+	t.Run("SeparateClientSubnets", func(t *testing.T) {
+		c, u, _ := newTestCache(0)
+		r := store.New().Type("A").Name("ok.lan").Build()
+
+		subnetCtx := func(addr string) context.Context {
+			q := new(dns.Msg)
+			q.SetQuestion("ok.lan.", dns.TypeA)
+			q.SetEdns0(4096, false)
+			q.IsEdns0().Option = append(q.IsEdns0().Option, &dns.EDNS0_SUBNET{
+				Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(addr).To4(),
+			})
+			return dnsrepo.WithQuery(ctx, q)
+		}
+
+		_ = c.Fallback(subnetCtx("192.0.2.0"), r, new(dns.Msg))
+		_ = c.Fallback(subnetCtx("198.51.100.0"), r, new(dns.Msg))
+		_ = c.Fallback(subnetCtx("192.0.2.0"), r, new(dns.Msg)) // hit
+
+		if u.calls != 2 {
+			t.Errorf("unexpected upstream calls: wanted %v ; got %v", 2, u.calls)
+		}
+	})
+}
+
+func TestFlush(t *testing.T) {
+	ctx := context.Background()
+	c, _, _ := newTestCache(0)
+
+	_ = c.Fallback(ctx, store.New().Type("A").Name("ok.lan").Build(), new(dns.Msg))
+	_ = c.Fallback(ctx, store.New().Type("AAAA").Name("ok.lan").Build(), new(dns.Msg))
+	_ = c.Fallback(ctx, store.New().Type("A").Name("nx.lan").Build(), new(dns.Msg))
+
+	t.Run("FlushName", func(t *testing.T) {
+		if n := c.Flush(ctx, "OK.lan."); n != 2 {
+			t.Errorf("unexpected flushed entries: wanted %v ; got %v", 2, n)
+		}
+		if size := c.Stats(ctx).Size; size != 1 {
+			t.Errorf("unexpected cache size: wanted %v ; got %v", 1, size)
+		}
+	})
+
+	t.Run("FlushAll", func(t *testing.T) {
+		if n := c.Flush(ctx, ""); n != 1 {
+			t.Errorf("unexpected flushed entries: wanted %v ; got %v", 1, n)
+		}
+		if size := c.Stats(ctx).Size; size != 0 {
+			t.Errorf("unexpected cache size: wanted %v ; got %v", 0, size)
+		}
+	})
+}
@@ -1,16 +1,17 @@
 package core
 
 import (
-	"strings"
+	"time"
 
 	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/dnssec"
 )
 
 const (
 	fallbackOneDot = "1.1.1.1:53"
 	fallbackGoogle = "8.8.8.8:53"
-	portSep        = ":"
-	portDNS        = ":53"
+	portDNS        = "53"
+	portDoT        = "853"
 )
 
 var (
@@ -25,31 +26,191 @@ var (
 //
 // It holds a list of strings which will be the fallback domain-name servers
 // to contact if a domain's IP is requested but there no records for it
+//
+// It also holds the default TTL for answers from records which do not set one;
+// if zero, the answers' TTL is 3600 seconds
+//
+// The fallback DNS servers are queried according to its Strategy (sequentially, by
+// default), and each of them is skipped for a cooldown period after failing to answer.
+// Queries for the domains in its forwarding rules are sent to their own fallback DNS servers.
+// The EDNS0 Client Subnet option of the client's query is only forwarded if enabled; and
+// the DNSSEC signatures of the answers are only validated if it has a dnssec.Validator
 type DNSCore struct {
 	fallbackDNS []string
+	ttl         uint32
+
+	upstreams []*upstream
+	strategy  Strategy
+	timeout   time.Duration
+	cooldown  time.Duration
+	next      uint32
+
+	forwards     []*forward
+	clientSubnet bool
+
+	validator *dnssec.Validator
+}
+
+// Option describes setter types for a DNSCore
+//
+// As new options / elements are added to the DNSCore, new data structures can
+// implement the Option interface to allow setting these options in the DNSCore
+type Option interface {
+	Apply(*DNSCore)
+}
+
+// DefaultTTL creates an Option setting the DNSCore's default TTL to `ttl` seconds,
+// used when answering with records which do not define their own TTL
+//
+// If `ttl` is zero, it returns nil
+func DefaultTTL(ttl uint32) Option {
+	if ttl == 0 {
+		return nil
+	}
+	return &defaultTTLOpt{
+		ttl: ttl,
+	}
+}
+
+type defaultTTLOpt struct {
+	ttl uint32
+}
+
+// Apply implements the Option interface
+func (o *defaultTTLOpt) Apply(d *DNSCore) {
+	d.ttl = o.ttl
+}
+
+// FallbackStrategy creates an Option setting the DNSCore's Strategy for querying
+// the fallback DNS servers to `strategy`
+func FallbackStrategy(strategy Strategy) Option {
+	return &strategyOpt{
+		strategy: strategy,
+	}
+}
+
+// FallbackTimeout creates an Option setting the DNSCore's default timeout for queries
+// to the fallback DNS servers to `timeout`. Fallback DNS addresses can also set their
+// own timeout, with a `?timeout=<duration>` suffix (e.g. `8.8.8.8:53?timeout=500ms`)
+//
+// If `timeout` is zero or negative, it returns nil
+func FallbackTimeout(timeout time.Duration) Option {
+	if timeout <= 0 {
+		return nil
+	}
+	return &timeoutOpt{
+		timeout: timeout,
+	}
+}
+
+// FallbackCooldown creates an Option setting the period for which the DNSCore skips
+// a fallback DNS server after it fails to answer a query, to `cooldown`
+//
+// If `cooldown` is zero or negative, it returns nil
+func FallbackCooldown(cooldown time.Duration) Option {
+	if cooldown <= 0 {
+		return nil
+	}
+	return &cooldownOpt{
+		cooldown: cooldown,
+	}
+}
+
+// ClientSubnet creates an Option setting whether the DNSCore forwards the EDNS0 Client
+// Subnet option (RFC 7871) in the client's query to the fallback DNS servers, so they can
+// tailor their answers to the client's network. It is stripped from the queries by default
+func ClientSubnet(forward bool) Option {
+	return &clientSubnetOpt{
+		forward: forward,
+	}
+}
+
+type strategyOpt struct {
+	strategy Strategy
+}
+
+type timeoutOpt struct {
+	timeout time.Duration
+}
+
+type cooldownOpt struct {
+	cooldown time.Duration
+}
+
+type clientSubnetOpt struct {
+	forward bool
+}
+
+// Apply implements the Option interface
+func (o *strategyOpt) Apply(d *DNSCore) {
+	d.strategy = o.strategy
+}
+
+// Apply implements the Option interface
+func (o *timeoutOpt) Apply(d *DNSCore) {
+	d.timeout = o.timeout
+}
+
+// Apply implements the Option interface
+func (o *cooldownOpt) Apply(d *DNSCore) {
+	d.cooldown = o.cooldown
+}
+
+// Apply implements the Option interface
+func (o *clientSubnetOpt) Apply(d *DNSCore) {
+	d.clientSubnet = o.forward
 }
 
 // New returns a new DNSCore as a dns.Repository
 func New(fallbackDNS ...string) dns.Repository {
-	var fbDNS []string
+	return NewWithOptions(fallbackDNS)
+}
+
+// NewWithOptions returns a new DNSCore as a dns.Repository, with the fallback
+// domain-name servers in `fallbackDNS`, applying all input Option `opts`
+//
+// Fallback servers are `ip[:port]` addresses, queried over UDP; or URLs such as
+// `tcp://8.8.8.8`, `tls://1.1.1.1:853` or `https://cloudflare-dns.com/dns-query` to
+// query them over TCP, TLS or HTTPS. Invalid addresses are ignored
+func NewWithOptions(fallbackDNS []string, opts ...Option) dns.Repository {
+	var (
+		fbDNS     []string
+		upstreams []*upstream
+	)
 
 	for _, fb := range fallbackDNS {
-		fb := fb
 		if fb == "" {
 			continue
 		}
-		if !strings.Contains(fb, portSep) {
-			fb += portDNS
+		up, err := newUpstream(fb)
+		if err != nil {
+			continue
 		}
-		fbDNS = append(fbDNS, fb)
+		fbDNS = append(fbDNS, up.String())
+		upstreams = append(upstreams, up)
 	}
 
 	if len(fbDNS) == 0 {
 		// add defaults
 		fbDNS = defaultFallback
+		for _, fb := range defaultFallback {
+			up, _ := newUpstream(fb)
+			upstreams = append(upstreams, up)
+		}
 	}
 
-	return &DNSCore{
+	d := &DNSCore{
 		fallbackDNS: fbDNS,
+		upstreams:   upstreams,
+		timeout:     defaultTimeout,
+		cooldown:    defaultCooldown,
 	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(d)
+		}
+	}
+
+	return d
 }
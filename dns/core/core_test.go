@@ -3,12 +3,19 @@ package core
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
 	t.Run("ZeroFallbackDNS", func(t *testing.T) {
 		wants := &DNSCore{
 			fallbackDNS: defaultFallback,
+			upstreams: []*upstream{
+				{addr: fallbackOneDot, net: netUDP},
+				{addr: fallbackGoogle, net: netUDP},
+			},
+			timeout:  defaultTimeout,
+			cooldown: defaultCooldown,
 		}
 
 		dnsCore := New()
@@ -21,6 +28,9 @@ func TestNew(t *testing.T) {
 	t.Run("OneFallbackDNS", func(t *testing.T) {
 		wants := &DNSCore{
 			fallbackDNS: []string{"1.1.1.1:53"},
+			upstreams:   []*upstream{{addr: "1.1.1.1:53", net: netUDP}},
+			timeout:     defaultTimeout,
+			cooldown:    defaultCooldown,
 		}
 
 		dnsCore := New("1.1.1.1")
@@ -30,9 +40,28 @@ func TestNew(t *testing.T) {
 		}
 	})
 
+	t.Run("WithDefaultTTL", func(t *testing.T) {
+		wants := &DNSCore{
+			fallbackDNS: []string{"1.1.1.1:53"},
+			ttl:         300,
+			upstreams:   []*upstream{{addr: "1.1.1.1:53", net: netUDP}},
+			timeout:     defaultTimeout,
+			cooldown:    defaultCooldown,
+		}
+
+		dnsCore := NewWithOptions([]string{"1.1.1.1"}, DefaultTTL(300))
+
+		if !reflect.DeepEqual(wants, dnsCore) {
+			t.Errorf("output mismatch error -- wanted %v ; got %v", wants, dnsCore)
+		}
+	})
+
 	t.Run("ManyFallbackDNS", func(t *testing.T) {
 		wants := &DNSCore{
 			fallbackDNS: []string{"1.1.1.1:53", "8.8.8.8:53"},
+			upstreams:   []*upstream{{addr: "1.1.1.1:53", net: netUDP}, {addr: "8.8.8.8:53", net: netUDP}},
+			timeout:     defaultTimeout,
+			cooldown:    defaultCooldown,
 		}
 
 		dnsCore := New("1.1.1.1:53", "8.8.8.8", "")
@@ -41,4 +70,29 @@ func TestNew(t *testing.T) {
 			t.Errorf("output mismatch error -- wanted %v ; got %v", wants, dnsCore)
 		}
 	})
+
+	t.Run("WithFallbackOptions", func(t *testing.T) {
+		wants := &DNSCore{
+			fallbackDNS: []string{"1.1.1.1:53", "8.8.8.8:53"},
+			upstreams: []*upstream{
+				{addr: "1.1.1.1:53", net: netUDP, timeout: 500 * time.Millisecond},
+				{addr: "8.8.8.8:53", net: netUDP},
+			},
+			strategy: RoundRobin,
+			timeout:  time.Second,
+			cooldown: time.Minute,
+		}
+
+		dnsCore := NewWithOptions(
+			[]string{"1.1.1.1?timeout=500ms", "8.8.8.8"},
+			FallbackStrategy(RoundRobin),
+			FallbackTimeout(time.Second),
+			FallbackCooldown(time.Minute),
+			FallbackTimeout(0),
+		)
+
+		if !reflect.DeepEqual(wants, dnsCore) {
+			t.Errorf("output mismatch error -- wanted %v ; got %v", wants, dnsCore)
+		}
+	})
 }
@@ -2,46 +2,319 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
 	"github.com/zalgonoise/dns/store"
 )
 
-// Answer will take the IP address populated in the store.Response `r`, and
-// append it as a DNS response to the dns.Msg `m`'s Answer slice
-func (d *DNSCore) Answer(ctx context.Context, r *store.Record, m *dns.Msg) {
-	response, err := dns.NewRR(
-		fmt.Sprintf("%s %s %s", r.Name, r.Type, r.Addr),
-	)
+var errNoFallback = errors.New("no fallback DNS servers configured")
+
+// Answer will take the address (and type-specific data) populated in the store.Record
+// `r`, and append it as a DNS response to the dns.Msg `m`'s Answer slice
+func (d *DNSCore) Answer(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	response, err := newRR(r, d.recordTTL(r))
 	if err != nil {
-		return
+		return err
 	}
 	m.Answer = append(m.Answer, response)
+	return nil
+}
+
+// Authority will take the store.Record `r` and append it as a DNS record to the dns.Msg
+// `m`'s authority (Ns) slice
+//
+// SOA records are written with the lesser of their TTL and their minimum TTL, which is
+// the TTL used for negative caching (RFC 2308)
+func (d *DNSCore) Authority(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	ttl := d.recordTTL(r)
+	if r.Type == store.TypeSOA.String() && r.Data != nil && r.Data.Minttl < ttl {
+		ttl = r.Data.Minttl
+	}
+
+	response, err := newRR(r, ttl)
+	if err != nil {
+		return err
+	}
+	m.Ns = append(m.Ns, response)
+	return nil
 }
 
 // Fallback will spawn a DNS client and issues a request to the fallback servers
 // with the same query for which there isn't a record in the store.
 //
-// If there is an answer, it is written to the dns.Msg `r`'s Answer slice; otherwise
-// the request is discarted until it times out
-func (d *DNSCore) Fallback(ctx context.Context, r *store.Record, m *dns.Msg) {
+// The fallback servers are the ones in the forwarding rule with the longest domain suffix
+// matching the query's domain name, if any; or the DNSCore's default fallback servers.
+// They are queried according to the DNSCore's Strategy, skipping the
+// ones in a cooldown period after failing to reply. The first fallback server to reply
+// with either an answer, an empty answer or an NXDOMAIN response has its answer,
+// authority and additional records written to the dns.Msg `m`. If it replies with
+// NXDOMAIN, a dns.ErrNXDomain error is returned; if none of the fallback servers reply,
+// a dns.ErrServFail error is returned (carrying the last fallback server's response
+// code, if it replied with an error such as REFUSED)
+//
+// The query keeps the original question type (even if unknown to the store), as well as
+// the flags and EDNS0 options of the client's query, when carried in the context
+//
+// If DNSSEC validation is enabled, the answer is validated unless the client set the CD bit:
+// bogus answers are replied to with a dns.ErrServFail error, and secure ones have the AD
+// flag set
+func (d *DNSCore) Fallback(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	message := newQuery(ctx, r, d.clientSubnet)
+
+	var (
+		validate = d.validator != nil && !message.CheckingDisabled
+		ad       = message.AuthenticatedData
+		do       bool
+	)
+	if validate {
+		do = dnssecQuery(message)
+	}
+
+	in, err := d.query(ctx, d.candidates(r.Name), message)
+	if err != nil {
+		// reply with the fallback server's response code, if it replied with an error
+		var rerr *rcodeError
+		if errors.As(err, &rerr) {
+			return dnsrepo.WithRcode(rerr.rcode, err)
+		}
+		return fmt.Errorf("%w: %v", dnsrepo.ErrServFail, err)
+	}
+
+	if validate {
+		if err := d.validate(ctx, in, do, ad); err != nil {
+			return err
+		}
+	}
+
+	copyResponse(in, m)
+	if in.Rcode == dns.RcodeNameError {
+		return dnsrepo.ErrNXDomain
+	}
+	return nil
+}
+
+// newQuery builds the query sent to the fallback servers for the store.Record `r`
+//
+// If the context carries the original query from the client, its flags (RD, CD and AD),
+// question class and EDNS0 OPT record (with the DO bit and its options) are kept. The
+// cookie, padding and keepalive options only apply to the client's connection, so they
+// are removed; as well as the Client Subnet option, unless `clientSubnet` is true
+func newQuery(ctx context.Context, r *store.Record, clientSubnet bool) *dns.Msg {
 	message := new(dns.Msg)
-	message.SetQuestion(dns.Fqdn(r.Name), store.RecordTypeInts[r.Type])
-	client := &dns.Client{
-		DialTimeout:  2 * time.Second,
-		ReadTimeout:  2 * time.Second,
-		WriteTimeout: 2 * time.Second,
-		Net:          "udp",
+	message.SetQuestion(dns.Fqdn(r.Name), dnsrepo.QType(r.Type))
+
+	q := dnsrepo.QueryFrom(ctx)
+	if q == nil {
+		return message
 	}
 
-	for _, fallback := range d.fallbackDNS {
-		in, _, err := client.Exchange(message, fallback)
-		if err != nil || len(in.Answer) == 0 {
+	message.RecursionDesired = q.RecursionDesired
+	message.CheckingDisabled = q.CheckingDisabled
+	message.AuthenticatedData = q.AuthenticatedData
+	for _, question := range q.Question {
+		if question.Qtype == message.Question[0].Qtype && strings.EqualFold(question.Name, message.Question[0].Name) {
+			message.Question[0].Qclass = question.Qclass
+			break
+		}
+	}
+	if opt := q.IsEdns0(); opt != nil {
+		message.Extra = append(message.Extra, upstreamOPT(opt, clientSubnet))
+	}
+	return message
+}
+
+// upstreamOPT returns a copy of the client's EDNS0 OPT record `opt` to send to the fallback
+// servers, without its hop-by-hop options
+func upstreamOPT(opt *dns.OPT, clientSubnet bool) *dns.OPT {
+	o := dns.Copy(opt).(*dns.OPT)
+	options := o.Option
+	o.Option = nil
+
+	for _, option := range options {
+		switch option.Option() {
+		case dns.EDNS0COOKIE, dns.EDNS0PADDING, dns.EDNS0TCPKEEPALIVE:
 			continue
+		case dns.EDNS0SUBNET:
+			if !clientSubnet {
+				continue
+			}
 		}
-		m.Answer = append(m.Answer, in.Answer...)
-		break
+		o.Option = append(o.Option, option)
+	}
+	return o
+}
+
+// copyResponse writes the answer, authority and additional records in the fallback server's
+// response `in` to the dns.Msg `m`, along with its RA flag; and its AD flag if the answer
+// comes entirely from the fallback server. The OPT and TSIG records are specific to each
+// connection, so they are not copied; except for the Client Subnet option in the OPT
+// record, which is kept in an OPT record of its own so that its scope is echoed to the
+// client
+func copyResponse(in, m *dns.Msg) {
+	if len(m.Answer) == 0 {
+		m.AuthenticatedData = in.AuthenticatedData
+	}
+	m.RecursionAvailable = in.RecursionAvailable
+
+	m.Answer = append(m.Answer, in.Answer...)
+	m.Ns = append(m.Ns, in.Ns...)
+	for _, rr := range in.Extra {
+		switch v := rr.(type) {
+		case *dns.OPT:
+			if o := subnetOPT(v); o != nil {
+				m.Extra = append(m.Extra, o)
+			}
+			continue
+		case *dns.TSIG:
+			continue
+		}
+		m.Extra = append(m.Extra, rr)
+	}
+}
+
+// subnetOPT returns an OPT record holding only the Client Subnet options in the fallback
+// server's OPT record `opt`, or nil if it has none
+func subnetOPT(opt *dns.OPT) *dns.OPT {
+	var o *dns.OPT
+	for _, option := range opt.Option {
+		if option.Option() != dns.EDNS0SUBNET {
+			continue
+		}
+		if o == nil {
+			o = &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+		}
+		o.Option = append(o.Option, option)
+	}
+	return o
+}
+
+// query sends the query in dns.Msg `message` to the upstreams `ups`, according to the
+// DNSCore's Strategy
+func (d *DNSCore) query(ctx context.Context, ups []*upstream, message *dns.Msg) (*dns.Msg, error) {
+	if d.strategy == Fastest {
+		return d.exchangeFastest(ctx, ups, message)
+	}
+	return d.exchangeEach(ctx, ups, message)
+}
+
+// exchangeEach sends the query in dns.Msg `message` to the upstreams `ups` one at
+// a time, returning the first valid reply
+func (d *DNSCore) exchangeEach(ctx context.Context, ups []*upstream, message *dns.Msg) (*dns.Msg, error) {
+	lastErr := errNoFallback
+	for _, up := range ups {
+		in, err := d.exchange(ctx, up, message)
+		if err == nil {
+			return in, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// exchangeFastest sends the query in dns.Msg `message` to all upstreams `ups` at once,
+// returning the first valid reply and cancelling the remaining queries
+func (d *DNSCore) exchangeFastest(ctx context.Context, ups []*upstream, message *dns.Msg) (*dns.Msg, error) {
+	type result struct {
+		in  *dns.Msg
+		err error
+	}
+
+	if len(ups) == 0 {
+		return nil, errNoFallback
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(ups))
+	for _, up := range ups {
+		go func(up *upstream) {
+			in, err := d.exchange(ctx, up, message.Copy())
+			results <- result{in, err}
+		}(up)
+	}
+
+	var lastErr error
+	for range ups {
+		res := <-results
+		if res.err == nil {
+			return res.in, nil
+		}
+		lastErr = res.err
+	}
+	return nil, lastErr
+}
+
+// exchange sends the query in dns.Msg `message` to the upstream `up`, registering the
+// outcome in its health and latency stats. Replies other than an answer, an empty answer
+// or an NXDOMAIN response are returned as errors
+func (d *DNSCore) exchange(ctx context.Context, up *upstream, message *dns.Msg) (*dns.Msg, error) {
+	timeout := up.timeout
+	if timeout == 0 {
+		timeout = d.timeout
+	}
+	var (
+		in  *dns.Msg
+		rtt time.Duration
+		err error
+	)
+	if up.net == netHTTPS {
+		in, rtt, err = up.exchangeHTTPS(ctx, timeout, message)
+	} else {
+		client := &dns.Client{
+			Net:       up.net,
+			Timeout:   timeout,
+			TLSConfig: up.tls,
+		}
+		in, rtt, err = client.ExchangeContext(ctx, message, up.addr)
+	}
+	if err != nil {
+		// queries cancelled by the caller do not count against the upstream
+		if ctx.Err() == nil {
+			up.fail(time.Now(), d.cooldown)
+		}
+		return nil, err
+	}
+
+	switch in.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+		up.succeed(rtt)
+		return in, nil
+	default:
+		up.fail(time.Now(), d.cooldown)
+		return nil, &rcodeError{addr: up.addr, rcode: in.Rcode}
+	}
+}
+
+// rcodeError is an error response from a fallback server
+type rcodeError struct {
+	addr  string
+	rcode int
+}
+
+// Error implements the error interface
+func (e *rcodeError) Error() string {
+	return fmt.Sprintf("%s replied with %s", e.addr, dns.RcodeToString[e.rcode])
+}
+
+// recordTTL returns the TTL for the store.Record `r`, which is the DNSCore's default TTL
+// if the record does not set one
+func (d *DNSCore) recordTTL(r *store.Record) uint32 {
+	switch {
+	case r.TTL != 0:
+		return r.TTL
+	case d.ttl != 0:
+		return d.ttl
+	default:
+		return defaultTTL
 	}
 }
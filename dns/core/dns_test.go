@@ -2,11 +2,20 @@ package core
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
 	"github.com/zalgonoise/dns/store"
 )
 
@@ -41,6 +50,341 @@ func TestAnswer(t *testing.T) {
 			t.Errorf("unexpected answer: should contain address %s ; got %s", testAddr, m.Answer[0].String())
 		}
 	})
+
+	t.Run("SuccessRecordTypes", func(t *testing.T) {
+		for _, test := range []struct {
+			name  string
+			r     *store.Record
+			wants string
+		}{
+			{
+				name:  "AAAA",
+				r:     store.New().Name(testName).Type("AAAA").Addr("fd00::10").Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tAAAA\tfd00::10",
+			},
+			{
+				name:  "CNAME",
+				r:     store.New().Name(testName).Type("CNAME").Addr("also.not.a.dom.ain").Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tCNAME\talso.not.a.dom.ain.",
+			},
+			{
+				name:  "NS",
+				r:     store.New().Name(testName).Type("NS").Addr("ns1.not.a.dom.ain").Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tNS\tns1.not.a.dom.ain.",
+			},
+			{
+				name:  "PTR",
+				r:     store.New().Name("10.0.168.192.in-addr.arpa").Type("PTR").Addr(testName).Build(),
+				wants: "10.0.168.192.in-addr.arpa.\t3600\tIN\tPTR\tnot.a.dom.ain.",
+			},
+			{
+				name: "MX",
+				r: store.New().Name(testName).Type("MX").Addr("mail.not.a.dom.ain").
+					Data(&store.Data{Preference: 10}).Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tMX\t10 mail.not.a.dom.ain.",
+			},
+			{
+				name: "TXT",
+				r: store.New().Name(testName).Type("TXT").
+					Data(&store.Data{Text: []string{"v=spf1 -all", "second string"}}).Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tTXT\t\"v=spf1 -all\" \"second string\"",
+			},
+			{
+				name: "SRV",
+				r: store.New().Name("_sip._tcp.not.a.dom.ain").Type("SRV").Addr("sip.not.a.dom.ain").
+					Data(&store.Data{Priority: 10, Weight: 5, Port: 5060}).Build(),
+				wants: "_sip._tcp.not.a.dom.ain.\t3600\tIN\tSRV\t10 5 5060 sip.not.a.dom.ain.",
+			},
+			{
+				name: "SOA",
+				r: store.New().Name(testName).Type("SOA").Addr("ns1.not.a.dom.ain").
+					Data(&store.Data{
+						Mbox:    "admin.not.a.dom.ain",
+						Serial:  2022121801,
+						Refresh: 7200,
+						Retry:   3600,
+						Expire:  1209600,
+						Minttl:  300,
+					}).Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tSOA\tns1.not.a.dom.ain. admin.not.a.dom.ain. 2022121801 7200 3600 1209600 300",
+			},
+			{
+				name: "CAA",
+				r: store.New().Name(testName).Type("CAA").Addr("letsencrypt.org").
+					Data(&store.Data{Tag: "issue"}).Build(),
+				wants: "not.a.dom.ain.\t3600\tIN\tCAA\t0 issue \"letsencrypt.org\"",
+			},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				m := new(dns.Msg)
+
+				core.Answer(ctx, test.r, m)
+
+				if len(m.Answer) != 1 {
+					t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+					return
+				}
+				if m.Answer[0].String() != test.wants {
+					t.Errorf("output mismatch error: wanted %s ; got %s", test.wants, m.Answer[0].String())
+				}
+			})
+		}
+	})
+
+	t.Run("SuccessRecordTTL", func(t *testing.T) {
+		r := store.New().Name(testName).Type(testType).Addr(testAddr).TTL(60).Build()
+		m := new(dns.Msg)
+
+		NewWithOptions(nil, DefaultTTL(300)).Answer(ctx, r, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+			return
+		}
+		if m.Answer[0].Header().Ttl != 60 {
+			t.Errorf("unexpected answer TTL: wanted %v ; got %v", 60, m.Answer[0].Header().Ttl)
+		}
+	})
+
+	t.Run("SuccessDefaultTTL", func(t *testing.T) {
+		r := store.New().Name(testName).Type(testType).Addr(testAddr).Build()
+		m := new(dns.Msg)
+
+		NewWithOptions(nil, DefaultTTL(300)).Answer(ctx, r, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+			return
+		}
+		if m.Answer[0].Header().Ttl != 300 {
+			t.Errorf("unexpected answer TTL: wanted %v ; got %v", 300, m.Answer[0].Header().Ttl)
+		}
+	})
+
+	t.Run("FailInvalidRecords", func(t *testing.T) {
+		for _, r := range []*store.Record{
+			store.New().Name(testName).Type("A").Addr("not.an.ip").Build(),
+			store.New().Name(testName).Type("AAAA").Addr(testAddr).Build(),
+			store.New().Name(testName).Type("TXT").Build(),
+			store.New().Name(testName).Type("CAA").Addr("letsencrypt.org").Build(),
+			store.New().Name(testName).Type("HINFO").Addr(testAddr).Build(),
+		} {
+			m := new(dns.Msg)
+
+			err := core.Answer(ctx, r, m)
+			if err == nil {
+				t.Errorf("expected error for %v ; got nil", r)
+			}
+
+			if len(m.Answer) != 0 {
+				t.Errorf("unexpected answer length for %v: wanted %v ; got %v", r, 0, len(m.Answer))
+			}
+		}
+	})
+}
+
+func TestAuthority(t *testing.T) {
+	ctx := context.Background()
+	core := New()
+
+	t.Run("SuccessNegativeTTL", func(t *testing.T) {
+		r := store.New().Name("lan").Type("SOA").Addr("ns.lan").Data(&store.Data{
+			Mbox:   "admin.lan",
+			Serial: 1,
+			Minttl: 60,
+		}).Build()
+		m := new(dns.Msg)
+
+		err := core.Authority(ctx, r, m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 0 || len(m.Ns) != 1 {
+			t.Errorf("unexpected answer / authority length: wanted %v / %v ; got %v / %v", 0, 1, len(m.Answer), len(m.Ns))
+			return
+		}
+		if m.Ns[0].Header().Ttl != 60 {
+			t.Errorf("unexpected authority TTL: wanted %v ; got %v", 60, m.Ns[0].Header().Ttl)
+		}
+	})
+}
+
+// serveRcode starts a local DNS server replying to all queries with the response code `rcode`,
+// returning its address and a function to stop it
+func serveRcode(t *testing.T, rcode int) (string, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+
+	srv := &dns.Server{
+		PacketConn: conn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetRcode(r, rcode)
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+
+	return conn.LocalAddr().String(), func() { _ = srv.Shutdown() }
+}
+
+func TestFallbackRcodes(t *testing.T) {
+	ctx := context.Background()
+	r := store.New().Name(testName).Type(testType).Build()
+
+	t.Run("NXDomain", func(t *testing.T) {
+		addr, stop := serveRcode(t, dns.RcodeNameError)
+		defer stop()
+
+		err := New(addr).Fallback(ctx, r, new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrNXDomain, err)
+		}
+	})
+
+	t.Run("NoData", func(t *testing.T) {
+		addr, stop := serveRcode(t, dns.RcodeSuccess)
+		defer stop()
+
+		m := new(dns.Msg)
+		err := New(addr).Fallback(ctx, r, m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(m.Answer) != 0 {
+			t.Errorf("unexpected answer length: wanted %v ; got %v", 0, len(m.Answer))
+		}
+	})
+
+	t.Run("ServFail", func(t *testing.T) {
+		addr, stop := serveRcode(t, dns.RcodeRefused)
+		defer stop()
+
+		err := New(addr).Fallback(ctx, r, new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+	})
+}
+
+func TestFallbackStrategies(t *testing.T) {
+	ctx := context.Background()
+	r := store.New().Name(testName).Type(testType).Build()
+
+	refused, stopRefused := serveRcode(t, dns.RcodeRefused)
+	defer stopRefused()
+	success, stopSuccess := serveRcode(t, dns.RcodeSuccess)
+	defer stopSuccess()
+
+	t.Run("SkipFailedUpstream", func(t *testing.T) {
+		d := NewWithOptions([]string{refused, success}).(*DNSCore)
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		ups := d.candidates(testName)
+		if len(ups) != 1 || ups[0].addr != success {
+			t.Errorf("expected the failed upstream to be skipped; got %v candidate(s)", len(ups))
+		}
+	})
+
+	t.Run("RetryAfterCooldown", func(t *testing.T) {
+		d := NewWithOptions([]string{refused, success}, FallbackCooldown(time.Millisecond)).(*DNSCore)
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if ups := d.candidates(testName); len(ups) != 2 {
+			t.Errorf("unexpected candidates length: wanted %v ; got %v", 2, len(ups))
+		}
+	})
+
+	t.Run("AllUpstreamsDown", func(t *testing.T) {
+		d := NewWithOptions([]string{refused}).(*DNSCore)
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+
+		if ups := d.candidates(testName); len(ups) != 1 {
+			t.Errorf("unexpected candidates length: wanted %v ; got %v", 1, len(ups))
+		}
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		d := NewWithOptions([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, FallbackStrategy(RoundRobin)).(*DNSCore)
+
+		for i := 0; i < 6; i++ {
+			wants := d.upstreams[i%3].addr
+			if ups := d.candidates(testName); ups[0].addr != wants {
+				t.Errorf("output mismatch error on query #%v: wanted %v ; got %v", i, wants, ups[0].addr)
+			}
+		}
+	})
+
+	t.Run("LatencyWeighted", func(t *testing.T) {
+		d := NewWithOptions([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, FallbackStrategy(LatencyWeighted)).(*DNSCore)
+		d.upstreams[0].succeed(500 * time.Millisecond)
+		d.upstreams[1].succeed(time.Millisecond)
+		d.upstreams[2].succeed(200 * time.Millisecond)
+
+		var firsts = map[string]int{}
+		for i := 0; i < 100; i++ {
+			ups := d.candidates(testName)
+			if len(ups) != 3 {
+				t.Errorf("unexpected candidates length: wanted %v ; got %v", 3, len(ups))
+				return
+			}
+			firsts[ups[0].addr]++
+		}
+
+		if firsts[d.upstreams[1].addr] < 90 {
+			t.Errorf("expected the fastest upstream to be picked first in most queries; got %v", firsts)
+		}
+	})
+
+	t.Run("Fastest", func(t *testing.T) {
+		d := NewWithOptions([]string{refused, success}, FallbackStrategy(Fastest)).(*DNSCore)
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("UpstreamTimeout", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error listening: %v", err)
+		}
+		defer conn.Close()
+
+		// the silent upstream never replies, and must time out before the next one is queried
+		d := NewWithOptions([]string{conn.LocalAddr().String() + "?timeout=50ms", success}).(*DNSCore)
+
+		start := time.Now()
+		err = d.Fallback(ctx, r, new(dns.Msg))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the upstream's timeout to be used; query took %v", elapsed)
+		}
+	})
 }
 
 func TestFallback(t *testing.T) {
@@ -79,3 +423,344 @@ func TestFallback(t *testing.T) {
 		}
 	})
 }
+
+func TestForward(t *testing.T) {
+	ctx := context.Background()
+
+	corp, stopCorp := serveRcode(t, dns.RcodeNameError)
+	defer stopCorp()
+	public, stopPublic := serveRcode(t, dns.RcodeSuccess)
+	defer stopPublic()
+
+	d := NewWithOptions([]string{public},
+		Forward("example", "10.0.0.1"),
+		Forward("corp.example.", corp),
+		Forward("", "10.0.0.2"),
+		Forward("consul"),
+	).(*DNSCore)
+
+	t.Run("LongestSuffix", func(t *testing.T) {
+		r := store.New().Name("host.corp.example").Type(testType).Build()
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrNXDomain, err)
+		}
+	})
+
+	t.Run("SuffixMatchesLabels", func(t *testing.T) {
+		for name, wants := range map[string]string{
+			"CORP.example.":    corp,
+			"www.example":      "10.0.0.1:53",
+			"notcorp.example":  "10.0.0.1:53",
+			"corp.example.com": public,
+		} {
+			if ups := d.candidates(name); ups[0].addr != wants {
+				t.Errorf("output mismatch error for %s: wanted %v ; got %v", name, wants, ups[0].addr)
+			}
+		}
+	})
+
+	t.Run("DefaultFallback", func(t *testing.T) {
+		r := store.New().Name(testName).Type(testType).Build()
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("IgnoreInvalidRules", func(t *testing.T) {
+		if len(d.forwards) != 2 {
+			t.Errorf("unexpected forwarding rules length: wanted %v ; got %v", 2, len(d.forwards))
+		}
+	})
+}
+
+func answerA(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.ParseIP(testAddr),
+	})
+	_ = w.WriteMsg(m)
+}
+
+func TestFallbackTLS(t *testing.T) {
+	ctx := context.Background()
+	r := store.New().Name(testName).Type(testType).Build()
+
+	// borrow the test certificate (valid for 127.0.0.1 and example.com) from httptest
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+	roots := ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: ts.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	srv := &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(answerA)}
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+	defer srv.Shutdown()
+
+	for _, test := range []struct {
+		name  string
+		input string
+		fails bool
+	}{
+		{name: "Success", input: "tls://" + l.Addr().String()},
+		{name: "SuccessWithSNI", input: "tls://" + l.Addr().String() + "?sni=example.com"},
+		{name: "FailHostnameMismatch", input: "tls://" + l.Addr().String() + "?sni=dns.example.org", fails: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewWithOptions([]string{test.input}).(*DNSCore)
+			d.upstreams[0].tls.RootCAs = roots
+
+			m := new(dns.Msg)
+			err := d.Fallback(ctx, r, m)
+			if test.fails {
+				if !errors.Is(err, dnsrepo.ErrServFail) {
+					t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(m.Answer) != 1 {
+				t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+			}
+		})
+	}
+}
+
+func TestFallbackHTTPS(t *testing.T) {
+	ctx := context.Background()
+	r := store.New().Name(testName).Type(testType).Build()
+
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/dns-query" || req.Method != http.MethodPost || req.Header.Get("Content-Type") != mimeDNSMessage {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		q := new(dns.Msg)
+		if err := q.Unpack(body); err != nil || q.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		m := new(dns.Msg)
+		m.SetReply(q)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: q.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(testAddr),
+		})
+		out, _ := m.Pack()
+		w.Header().Set("Content-Type", mimeDNSMessage)
+		_, _ = w.Write(out)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	d := NewWithOptions([]string{ts.URL + "/dns-query"}).(*DNSCore)
+	d.upstreams[0].client = ts.Client()
+
+	t.Run("Success", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			m := new(dns.Msg)
+			m.Id = dns.Id()
+
+			err := d.Fallback(ctx, r, m)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(m.Answer) != 1 {
+				t.Errorf("unexpected answer length: wanted %v ; got %v", 1, len(m.Answer))
+				return
+			}
+		}
+	})
+
+	t.Run("ReuseConnection", func(t *testing.T) {
+		if n := atomic.LoadInt32(&conns); n != 1 {
+			t.Errorf("unexpected number of connections: wanted %v ; got %v", 1, n)
+		}
+	})
+
+	t.Run("FailHTTPStatus", func(t *testing.T) {
+		d := NewWithOptions([]string{ts.URL + "/not-found"}).(*DNSCore)
+		d.upstreams[0].client = ts.Client()
+
+		err := d.Fallback(ctx, r, new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+	})
+}
+
+func TestFallbackForwardQuery(t *testing.T) {
+	var received = make(chan *dns.Msg, 1)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	srv := &dns.Server{
+		PacketConn: conn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			received <- r
+
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.RecursionAvailable = true
+			m.AuthenticatedData = true
+			m.Answer = append(m.Answer, &dns.RFC3597{
+				Hdr:   dns.RR_Header{Name: r.Question[0].Name, Rrtype: r.Question[0].Qtype, Class: dns.ClassINET, Ttl: 60},
+				Rdata: "00",
+			})
+			m.Ns = append(m.Ns, &dns.NS{
+				Hdr: dns.RR_Header{Name: "dom.ain.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+				Ns:  "ns.dom.ain.",
+			})
+			m.Extra = append(m.Extra, &dns.A{
+				Hdr: dns.RR_Header{Name: "ns.dom.ain.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(testAddr),
+			})
+			m.SetEdns0(1232, true)
+			// the client subnet is echoed with a scope, as if the answer was tailored to it
+			for _, option := range r.IsEdns0().Option {
+				if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+					scoped := *subnet
+					scoped.SourceScope = 16
+					m.IsEdns0().Option = append(m.IsEdns0().Option, &scoped)
+				}
+			}
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+	defer srv.Shutdown()
+
+	q := new(dns.Msg)
+	q.SetQuestion(dns.Fqdn(testName), 65280)
+	q.CheckingDisabled = true
+	q.RecursionDesired = false
+	q.SetEdns0(4096, true)
+	q.IsEdns0().Option = append(q.IsEdns0().Option, &dns.EDNS0_LOCAL{Code: dns.EDNS0LOCALSTART, Data: []byte("test")})
+
+	ctx := dnsrepo.WithQuery(context.Background(), q)
+	m := new(dns.Msg)
+	m.SetReply(q)
+
+	err = New(conn.LocalAddr().String()).Fallback(ctx, store.New().Name(testName).Type("TYPE65280").Build(), m)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("ForwardQuestionAndFlags", func(t *testing.T) {
+		r := <-received
+
+		if r.Question[0].Qtype != 65280 {
+			t.Errorf("unexpected question type: wanted %v ; got %v", 65280, r.Question[0].Qtype)
+		}
+		if !r.CheckingDisabled || r.RecursionDesired {
+			t.Errorf("unexpected flags: wanted CD and no RD ; got CD=%v RD=%v", r.CheckingDisabled, r.RecursionDesired)
+		}
+
+		opt := r.IsEdns0()
+		if opt == nil || !opt.Do() || len(opt.Option) != 1 {
+			t.Errorf("expected the EDNS0 OPT record to be forwarded, with the DO bit and its options; got %v", opt)
+		}
+	})
+
+	t.Run("ClientSubnet", func(t *testing.T) {
+		q := q.Copy()
+		q.IsEdns0().Option = append(q.IsEdns0().Option,
+			&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0102030405060708"},
+			&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()},
+		)
+		ctx := dnsrepo.WithQuery(context.Background(), q)
+
+		for _, test := range []struct {
+			name    string
+			forward bool
+			wants   int
+		}{
+			{"Strip", false, 1},
+			{"Forward", true, 2},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				repo := NewWithOptions([]string{conn.LocalAddr().String()}, ClientSubnet(test.forward))
+
+				m := new(dns.Msg)
+				err := repo.Fallback(ctx, store.New().Name(testName).Type("TYPE65280").Build(), m)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+
+				opt := (<-received).IsEdns0()
+				if opt == nil || len(opt.Option) != test.wants {
+					t.Errorf("unexpected EDNS0 options: wanted %v ; got %v", test.wants, opt)
+					return
+				}
+				for _, option := range opt.Option {
+					if option.Option() == dns.EDNS0COOKIE {
+						t.Errorf("expected the client's cookie not to be forwarded")
+					}
+				}
+
+				// the upstream's client subnet is kept, so that its scope is echoed
+				var subnet *dns.EDNS0_SUBNET
+				if opt := m.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+					subnet, _ = opt.Option[0].(*dns.EDNS0_SUBNET)
+				}
+				if (subnet != nil) != test.forward || (subnet != nil && subnet.SourceScope != 16) {
+					t.Errorf("unexpected upstream client subnet: %v", subnet)
+				}
+			})
+		}
+	})
+
+	t.Run("CopyResponse", func(t *testing.T) {
+		if len(m.Answer) != 1 || len(m.Ns) != 1 || len(m.Extra) != 1 {
+			t.Errorf("unexpected sections length: wanted 1 / 1 / 1 ; got %v / %v / %v", len(m.Answer), len(m.Ns), len(m.Extra))
+		}
+		if _, ok := m.Extra[0].(*dns.A); !ok {
+			t.Errorf("expected the upstream's OPT record not to be copied; got %v", m.Extra[0])
+		}
+		if !m.RecursionAvailable || !m.AuthenticatedData {
+			t.Errorf("unexpected flags: wanted RA and AD ; got RA=%v AD=%v", m.RecursionAvailable, m.AuthenticatedData)
+		}
+	})
+
+	t.Run("CopyRcode", func(t *testing.T) {
+		addr, stop := serveRcode(t, dns.RcodeRefused)
+		defer stop()
+
+		err := New(addr).Fallback(ctx, store.New().Name(testName).Type(testType).Build(), new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+		if rcode := dnsrepo.Rcode(err); rcode != dns.RcodeRefused {
+			t.Errorf("unexpected response code: wanted %v ; got %v", dns.RcodeToString[dns.RcodeRefused], dns.RcodeToString[rcode])
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	dns "github.com/miekg/dns"
+)
+
+const (
+	mimeDNSMessage = "application/dns-message"
+	maxDNSMessage  = 65535
+)
+
+// newHTTPSClient returns the http.Client used to query a DNS-over-HTTPS server,
+// which keeps its connections open to be reused across queries. If `sni` is set,
+// it is the hostname verified in the server's TLS certificate
+func newHTTPSClient(sni string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 8,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: defaultTimeout,
+			TLSClientConfig: &tls.Config{
+				ServerName: sni,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}
+}
+
+// exchangeHTTPS sends the query in dns.Msg `message` to the DNS-over-HTTPS upstream
+// as a POST request (RFC 8484), waiting up to `timeout` for its reply
+func (u *upstream) exchangeHTTPS(ctx context.Context, timeout time.Duration, message *dns.Msg) (*dns.Msg, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the message ID is zero in DNS-over-HTTPS, so the replies can be cached
+	query := message.Copy()
+	query.Id = 0
+	buf, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.addr, bytes.NewReader(buf))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", mimeDNSMessage)
+	req.Header.Set("Accept", mimeDNSMessage)
+
+	start := time.Now()
+	res, err := u.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxDNSMessage))
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, rtt, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, rtt, fmt.Errorf("%s replied with HTTP status %s", u.addr, res.Status)
+	}
+
+	in := new(dns.Msg)
+	if err := in.Unpack(body); err != nil {
+		return nil, rtt, err
+	}
+	in.Id = message.Id
+	return in, rtt, nil
+}
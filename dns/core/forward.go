@@ -0,0 +1,89 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// forward is a conditional forwarding rule, sending the queries for the domain names
+// under its domain suffix to its own set of upstreams
+type forward struct {
+	suffix    string
+	upstreams []*upstream
+	next      uint32
+}
+
+// Forward creates an Option adding a conditional forwarding rule to the DNSCore, so that
+// queries for the domain `domain` and its subdomains are sent to the fallback DNS servers
+// in `fallbackDNS` instead of the default ones. When several rules match a domain name,
+// the one with the longest domain suffix is used
+//
+// If the domain is empty or if there are no fallback DNS servers, it returns nil
+func Forward(domain string, fallbackDNS ...string) Option {
+	suffix := normalizeName(domain)
+	if suffix == "" {
+		return nil
+	}
+
+	ups := make([]*upstream, 0, len(fallbackDNS))
+	for _, fb := range fallbackDNS {
+		if fb = strings.TrimSpace(fb); fb == "" {
+			continue
+		}
+		up, err := newUpstream(fb)
+		if err != nil {
+			continue
+		}
+		ups = append(ups, up)
+	}
+	if len(ups) == 0 {
+		return nil
+	}
+
+	return &forwardOpt{
+		fwd: &forward{
+			suffix:    suffix,
+			upstreams: ups,
+		},
+	}
+}
+
+type forwardOpt struct {
+	fwd *forward
+}
+
+// Apply implements the Option interface
+func (o *forwardOpt) Apply(d *DNSCore) {
+	for idx, fwd := range d.forwards {
+		if fwd.suffix == o.fwd.suffix {
+			d.forwards[idx] = o.fwd
+			return
+		}
+	}
+
+	d.forwards = append(d.forwards, o.fwd)
+	// keep the longest suffixes first, so the first match is the longest one
+	sort.SliceStable(d.forwards, func(i, j int) bool {
+		return len(d.forwards[i].suffix) > len(d.forwards[j].suffix)
+	})
+}
+
+// forwardFor returns the forwarding rule with the longest domain suffix matching the
+// domain name `name`, or nil if there is none
+func (d *DNSCore) forwardFor(name string) *forward {
+	if len(d.forwards) == 0 {
+		return nil
+	}
+
+	name = normalizeName(name)
+	for _, fwd := range d.forwards {
+		if name == fwd.suffix || strings.HasSuffix(name, "."+fwd.suffix) {
+			return fwd
+		}
+	}
+	return nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(name), "."))
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	dns "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+const defaultTTL uint32 = 3600
+
+var (
+	ErrUnsupportedType = store.ErrUnsupportedType
+	ErrInvalidAddr     = store.ErrInvalidAddr
+	ErrNoData          = store.ErrNoData
+)
+
+// newRR converts the store.Record `r` into a dns.RR, according to its record type,
+// with a TTL of `ttl` seconds (or the default TTL if zero)
+//
+// Returns an error if the record type is not supported, or if the record does not
+// hold the fields its type requires
+func newRR(r *store.Record, ttl uint32) (dns.RR, error) {
+	rr, err := store.ToRR(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	rr.Header().Ttl = ttl
+	return rr, nil
+}
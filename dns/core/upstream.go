@@ -0,0 +1,295 @@
+package core
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultCooldown = 30 * time.Second
+
+	// timeoutParam is the parameter in a fallback DNS address setting its timeout,
+	// e.g. `8.8.8.8:53?timeout=500ms`
+	timeoutParam = "timeout="
+	// sniParam is the parameter in a fallback DNS address setting the hostname to verify
+	// in the server's TLS certificate, e.g. `tls://1.1.1.1?sni=one.one.one.one`
+	sniParam = "sni="
+
+	schemeUDP   = "udp"
+	schemeTCP   = "tcp"
+	schemeTLS   = "tls"
+	schemeHTTPS = "https"
+
+	netUDP   = "udp"
+	netTCP   = "tcp"
+	netTLS   = "tcp-tls"
+	netHTTPS = "https"
+	// rttWeight is the weight of a new sample in an upstream's average round-trip time
+	rttWeight = 0.3
+)
+
+var (
+	ErrInvalidUpstream = errors.New("invalid fallback DNS address")
+)
+
+// Strategy defines how the fallback DNS servers are picked when forwarding a query
+type Strategy uint8
+
+const (
+	// Sequential queries the fallback DNS servers one at a time, in the configured order
+	Sequential Strategy = iota
+	// Random queries the fallback DNS servers one at a time, in a random order
+	Random
+	// RoundRobin queries the fallback DNS servers one at a time, starting with the
+	// next server on each query
+	RoundRobin
+	// Fastest queries all fallback DNS servers at once, taking the first valid answer
+	Fastest
+	// LatencyWeighted queries the fallback DNS servers one at a time, picking the first
+	// at random with a probability inversely proportional to its average round-trip time
+	LatencyWeighted
+)
+
+var strategyNames = map[string]Strategy{
+	"sequential":       Sequential,
+	"random":           Random,
+	"round-robin":      RoundRobin,
+	"fastest":          Fastest,
+	"latency-weighted": LatencyWeighted,
+}
+
+// ParseStrategy returns the Strategy named `s` (sequential, random, round-robin, fastest
+// or latency-weighted), and false if there is no such Strategy
+func ParseStrategy(s string) (Strategy, bool) {
+	strategy, ok := strategyNames[strings.ToLower(s)]
+	return strategy, ok
+}
+
+// upstream is a fallback DNS server, keeping track of its health and latency
+//
+// Its address is a `host:port` pair, or the URL of a DNS-over-HTTPS server
+type upstream struct {
+	addr    string
+	net     string
+	timeout time.Duration
+
+	tls    *tls.Config
+	client *http.Client
+
+	mtx       sync.Mutex
+	downUntil time.Time
+	rtt       time.Duration
+}
+
+// newUpstream parses the fallback DNS address `s`, which is either an `ip[:port]` address
+// (queried over UDP) or a URL with a `udp://`, `tcp://`, `tls://` (DNS-over-TLS) or
+// `https://` (DNS-over-HTTPS) scheme
+//
+// The address may set its own timeout with a `timeout=<duration>` parameter; and the
+// hostname verified in the TLS server's certificate with a `sni=<hostname>` parameter,
+// which defaults to the address' host. E.g. `tls://1.1.1.1?sni=one.one.one.one&timeout=1s`
+func newUpstream(s string) (*upstream, error) {
+	up := &upstream{}
+
+	s, params, _ := strings.Cut(s, "?")
+	var (
+		sni  string
+		keep []string
+	)
+	for _, param := range strings.Split(params, "&") {
+		switch {
+		case param == "":
+		case strings.HasPrefix(param, timeoutParam):
+			if timeout, err := time.ParseDuration(strings.TrimPrefix(param, timeoutParam)); err == nil && timeout > 0 {
+				up.timeout = timeout
+			}
+		case strings.HasPrefix(param, sniParam):
+			sni = strings.TrimPrefix(param, sniParam)
+		default:
+			keep = append(keep, param)
+		}
+	}
+
+	scheme, hostport, ok := strings.Cut(s, "://")
+	if !ok {
+		scheme, hostport = schemeUDP, s
+	}
+
+	switch strings.ToLower(scheme) {
+	case schemeUDP:
+		up.net = netUDP
+		up.addr = withPort(hostport, portDNS)
+	case schemeTCP:
+		up.net = netTCP
+		up.addr = withPort(hostport, portDNS)
+	case schemeTLS:
+		up.net = netTLS
+		up.addr = withPort(hostport, portDoT)
+		if sni == "" {
+			sni, _, _ = net.SplitHostPort(up.addr)
+		}
+		up.tls = &tls.Config{
+			ServerName: sni,
+			MinVersion: tls.VersionTLS12,
+		}
+	case schemeHTTPS:
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUpstream, s)
+		}
+		u.RawQuery = strings.Join(keep, "&")
+
+		up.net = netHTTPS
+		up.addr = u.String()
+		up.client = newHTTPSClient(sni)
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidUpstream, scheme)
+	}
+
+	return up, nil
+}
+
+// String returns the upstream's address, prefixed with its scheme if it is not
+// queried over UDP
+func (u *upstream) String() string {
+	switch u.net {
+	case netTCP:
+		return schemeTCP + "://" + u.addr
+	case netTLS:
+		return schemeTLS + "://" + u.addr
+	default:
+		return u.addr
+	}
+}
+
+// withPort appends the port `port` to the address `addr` if it does not set one
+func withPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
+
+// healthy returns true if the upstream is not in a cooldown period after failing
+func (u *upstream) healthy(now time.Time) bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	return !now.Before(u.downUntil)
+}
+
+// succeed registers a successful exchange with the upstream, taking `rtt` to complete
+func (u *upstream) succeed(rtt time.Duration) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.downUntil = time.Time{}
+	if u.rtt == 0 {
+		u.rtt = rtt
+		return
+	}
+	u.rtt = time.Duration(rttWeight*float64(rtt) + (1-rttWeight)*float64(u.rtt))
+}
+
+// fail registers a failed exchange with the upstream, skipping it for the `cooldown` period
+func (u *upstream) fail(now time.Time, cooldown time.Duration) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.downUntil = now.Add(cooldown)
+}
+
+func (u *upstream) latency() time.Duration {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	return u.rtt
+}
+
+// candidates returns the upstreams to query for the domain name `name`, in order,
+// according to the DNSCore's Strategy
+//
+// Upstreams in a cooldown period are skipped, unless all of them are
+func (d *DNSCore) candidates(name string) []*upstream {
+	all, next := d.upstreams, &d.next
+	if fwd := d.forwardFor(name); fwd != nil {
+		all, next = fwd.upstreams, &fwd.next
+	}
+
+	now := time.Now()
+	ups := make([]*upstream, 0, len(all))
+	for _, up := range all {
+		if up.healthy(now) {
+			ups = append(ups, up)
+		}
+	}
+	if len(ups) == 0 {
+		ups = append(ups, all...)
+	}
+	if len(ups) == 0 {
+		return ups
+	}
+
+	switch d.strategy {
+	case Random:
+		rand.Shuffle(len(ups), func(i, j int) {
+			ups[i], ups[j] = ups[j], ups[i]
+		})
+	case RoundRobin:
+		offset := int(atomic.AddUint32(next, 1)-1) % len(ups)
+		ups = append(ups[offset:], ups[:offset]...)
+	case LatencyWeighted:
+		sort.SliceStable(ups, func(i, j int) bool {
+			return ups[i].latency() < ups[j].latency()
+		})
+		idx := pickWeighted(ups)
+		ups[0], ups[idx] = ups[idx], ups[0]
+	}
+	return ups
+}
+
+// pickWeighted returns the index of an upstream in `ups` picked at random, with a
+// probability inversely proportional to its average round-trip time. Upstreams without
+// a known round-trip time are weighted as the fastest one, so that they are also tried
+func pickWeighted(ups []*upstream) int {
+	var fastest time.Duration
+	for _, up := range ups {
+		if rtt := up.latency(); rtt > 0 && (fastest == 0 || rtt < fastest) {
+			fastest = rtt
+		}
+	}
+	if fastest == 0 {
+		return rand.Intn(len(ups))
+	}
+
+	weights := make([]float64, len(ups))
+	var total float64
+	for i, up := range ups {
+		rtt := up.latency()
+		if rtt == 0 {
+			rtt = fastest
+		}
+		weights[i] = 1 / float64(rtt)
+		total += weights[i]
+	}
+
+	pick := rand.Float64() * total
+	for i, w := range weights {
+		if pick < w {
+			return i
+		}
+		pick -= w
+	}
+	return len(ups) - 1
+}
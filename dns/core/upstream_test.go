@@ -0,0 +1,62 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewUpstream(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		input   string
+		addr    string
+		net     string
+		sni     string
+		timeout time.Duration
+	}{
+		{name: "PlainAddress", input: "1.1.1.1", addr: "1.1.1.1:53", net: netUDP},
+		{name: "PlainAddressWithPort", input: "127.0.0.1:8600", addr: "127.0.0.1:8600", net: netUDP},
+		{name: "PlainIPv6Address", input: "2606:4700:4700::1111", addr: "[2606:4700:4700::1111]:53", net: netUDP},
+		{name: "UDP", input: "udp://9.9.9.9", addr: "9.9.9.9:53", net: netUDP},
+		{name: "TCP", input: "tcp://8.8.8.8", addr: "8.8.8.8:53", net: netTCP},
+		{name: "TLS", input: "tls://1.1.1.1", addr: "1.1.1.1:853", net: netTLS, sni: "1.1.1.1"},
+		{name: "TLSWithSNI", input: "tls://1.1.1.1:853?sni=one.one.one.one&timeout=1s", addr: "1.1.1.1:853", net: netTLS, sni: "one.one.one.one", timeout: time.Second},
+		{name: "TLSHostname", input: "tls://dns.quad9.net", addr: "dns.quad9.net:853", net: netTLS, sni: "dns.quad9.net"},
+		{name: "HTTPS", input: "https://cloudflare-dns.com/dns-query", addr: "https://cloudflare-dns.com/dns-query", net: netHTTPS},
+		{name: "HTTPSWithParams", input: "https://dns.example/dns-query?timeout=500ms&key=abc", addr: "https://dns.example/dns-query?key=abc", net: netHTTPS, timeout: 500 * time.Millisecond},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			up, err := newUpstream(test.input)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if up.addr != test.addr || up.net != test.net || up.timeout != test.timeout {
+				t.Errorf("output mismatch error: wanted %s (%s, %v) ; got %s (%s, %v)",
+					test.addr, test.net, test.timeout, up.addr, up.net, up.timeout)
+			}
+			if test.sni != "" && (up.tls == nil || up.tls.ServerName != test.sni) {
+				t.Errorf("unexpected TLS server name: wanted %s ; got %v", test.sni, up.tls)
+			}
+			if test.net == netHTTPS && up.client == nil {
+				t.Errorf("expected an HTTP client for a DNS-over-HTTPS upstream")
+			}
+		})
+	}
+
+	t.Run("FailUnsupportedScheme", func(t *testing.T) {
+		_, err := newUpstream("quic://dns.adguard.com")
+		if !errors.Is(err, ErrInvalidUpstream) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrInvalidUpstream, err)
+		}
+	})
+
+	t.Run("FailHTTPSWithoutHost", func(t *testing.T) {
+		_, err := newUpstream("https:///dns-query")
+		if !errors.Is(err, ErrInvalidUpstream) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrInvalidUpstream, err)
+		}
+	})
+}
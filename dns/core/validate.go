@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	dns "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/spanner"
+)
+
+// validateUDPSize is the EDNS0 UDP payload size advertised in the queries sent to the
+// fallback servers for DNSSEC validation
+const validateUDPSize = 1232
+
+// Validate creates an Option enabling the DNSSEC validation of the fallback DNS answers,
+// trusting the keys in the DS or DNSKEY records `anchors`; or the root zone's key-signing
+// keys if there are none
+//
+// The DS and DNSKEY records along the chain of trust are queried from the fallback DNS
+// servers as well
+func Validate(anchors ...dns.RR) Option {
+	return &validateOpt{
+		anchors: anchors,
+	}
+}
+
+type validateOpt struct {
+	anchors []dns.RR
+}
+
+// Apply implements the Option interface
+func (o *validateOpt) Apply(d *DNSCore) {
+	d.validator = dnssec.NewValidator(d.lookup, o.anchors...)
+}
+
+// lookup queries the fallback servers for the domain name `name` and record type `qtype`
+// with the DO and CD bits set, to validate the chain of trust for their answers
+func (d *DNSCore) lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(dns.Fqdn(name), qtype)
+	message.CheckingDisabled = true
+	message.SetEdns0(validateUDPSize, true)
+
+	return d.query(ctx, d.candidates(name), message)
+}
+
+// dnssecQuery sets the DO and CD bits in the query `message` to the fallback servers, so
+// that they reply with the DNSSEC records without validating them. It returns whether
+// the client asked for the DNSSEC records (with the DO bit)
+func dnssecQuery(message *dns.Msg) bool {
+	message.CheckingDisabled = true
+
+	opt := message.IsEdns0()
+	if opt == nil {
+		message.SetEdns0(validateUDPSize, true)
+		return false
+	}
+	do := opt.Do()
+	opt.SetDo()
+	return do
+}
+
+// validate checks the DNSSEC signatures in the fallback server's response `in`, setting its
+// AD flag if it is secure (and the client set either the DO or the AD bit). The DNSSEC
+// records are removed from the response if the client did not ask for them
+//
+// Bogus responses are returned as a dns.ErrServFail error
+func (d *DNSCore) validate(ctx context.Context, in *dns.Msg, do, ad bool) error {
+	ctx, s := spanner.Start(ctx, "core.Validate")
+	defer s.End()
+
+	security, err := d.validator.Validate(ctx, in)
+	s.Add(attr.String("dnssec", security.String()))
+	if err != nil {
+		s.Event("bogus DNSSEC answer", attr.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", dnsrepo.ErrServFail, err)
+	}
+
+	in.AuthenticatedData = security == dnssec.Secure && (do || ad)
+	if !do {
+		qtype := in.Question[0].Qtype
+		in.Answer = withoutDNSSEC(in.Answer, qtype)
+		in.Ns = withoutDNSSEC(in.Ns, 0)
+		in.Extra = withoutDNSSEC(in.Extra, 0)
+	}
+	return nil
+}
+
+// withoutDNSSEC returns the records in `rrs` which are not RRSIG, NSEC or NSEC3 records,
+// unless they are of the queried type `qtype`
+func withoutDNSSEC(rrs []dns.RR, qtype uint16) []dns.RR {
+	out := rrs[:0]
+	for _, rr := range rrs {
+		switch rtype := rr.Header().Rrtype; rtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if rtype != qtype {
+				continue
+			}
+		}
+		out = append(out, rr)
+	}
+	return out
+}
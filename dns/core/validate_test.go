@@ -0,0 +1,360 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/dns/store"
+)
+
+// testZones are the zones served by signedUpstream, from the deepest one. The `insecure.lan.`
+// zone is not signed, and its delegation in the `lan.` zone has no DS records
+var testZones = map[string][]string{
+	"insecure.lan.": {
+		"insecure.lan. 300 IN SOA ns.insecure.lan. admin.insecure.lan. 1 7200 3600 1209600 300",
+		"host.insecure.lan. 300 IN A 10.0.0.2",
+	},
+	"lan.": {
+		"lan. 300 IN SOA ns.lan. admin.lan. 1 7200 3600 1209600 300",
+		"www.lan. 300 IN A 10.0.0.1",
+		"alias.lan. 300 IN CNAME www.lan.",
+		"*.wild.lan. 300 IN A 10.0.0.3",
+		"insecure.lan. 300 IN NS ns.insecure.lan.",
+	},
+	".": {
+		". 300 IN SOA a.root. admin.root. 1 7200 3600 1209600 300",
+		"lan. 300 IN NS ns.lan.",
+	},
+}
+
+var testZoneOrder = []string{"insecure.lan.", "lan.", "."}
+
+// signedUpstream starts a local DNS server answering for a signed root zone, which delegates
+// the signed `lan.` zone, which delegates the unsigned `insecure.lan.` zone; as a recursive
+// resolver would with the CD bit set. Its responses are passed to `tamper` before being
+// written. It returns its address, the trust anchor for its root zone and a function to
+// stop it
+func signedUpstream(t *testing.T, tamper func(r, m *dns.Msg)) (string, []dns.RR, func()) {
+	signer := dnssec.New("")
+
+	zones := map[string][]dns.RR{}
+	for zone, records := range testZones {
+		for _, record := range records {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				t.Fatalf("unexpected error parsing record: %v", err)
+			}
+			zones[zone] = append(zones[zone], rr)
+		}
+	}
+	ds, err := signer.DS("lan.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, rr := range ds {
+		zones["."] = append(zones["."], rr)
+	}
+
+	sign := func(zone string, rrs []dns.RR) []dns.RR {
+		if zone == "insecure.lan." || len(rrs) == 0 {
+			return rrs
+		}
+		signed, err := signer.Sign(zone, rrs)
+		if err != nil {
+			t.Errorf("unexpected error signing records: %v", err)
+		}
+		return signed
+	}
+	lookup := func(zone, name string, qtype uint16) []dns.RR {
+		var rrs []dns.RR
+		for _, rr := range zones[zone] {
+			if rr.Header().Name == name && (rr.Header().Rrtype == qtype || rr.Header().Rrtype == dns.TypeCNAME) {
+				rrs = append(rrs, rr)
+			}
+		}
+		return rrs
+	}
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		q := r.Question[0]
+		name := dns.CanonicalName(q.Name)
+		do := r.IsEdns0() != nil && r.IsEdns0().Do()
+
+		var zone string
+		for _, z := range testZoneOrder {
+			if dns.IsSubDomain(z, name) && (q.Qtype != dns.TypeDS || name != z || z == ".") {
+				zone = z
+				break
+			}
+		}
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+
+		answer := lookup(zone, name, q.Qtype)
+		if q.Qtype == dns.TypeDNSKEY && name == zone && zone != "insecure.lan." {
+			answer, _ = signer.DNSKEY(zone)
+		}
+		for _, rr := range answer {
+			if cname, ok := rr.(*dns.CNAME); ok {
+				answer = append(answer, lookup(zone, cname.Target, q.Qtype)...)
+			}
+		}
+
+		var wildcard []dns.RR
+		if len(answer) == 0 {
+			wildcard = lookup(zone, "*."+name[dns.Split(name)[1]:], q.Qtype)
+		}
+
+		names := map[string][]uint16{}
+		for _, rr := range zones[zone] {
+			names[rr.Header().Name] = append(names[rr.Header().Name], rr.Header().Rrtype)
+		}
+		_, exists := names[name]
+
+		switch {
+		case len(answer) > 0:
+			if do {
+				answer = sign(zone, answer)
+			}
+			m.Answer = answer
+		case len(wildcard) > 0:
+			if do {
+				wildcard = sign(zone, wildcard)
+				m.Ns = sign(zone, dnssec.Denial(zone, names, name, false, 300))
+			}
+			for _, rr := range wildcard {
+				rr = dns.Copy(rr)
+				rr.Header().Name = name
+				m.Answer = append(m.Answer, rr)
+			}
+		default:
+			if !exists {
+				m.Rcode = dns.RcodeNameError
+			}
+			m.Ns = lookup(zone, zone, dns.TypeSOA)
+			if do {
+				m.Ns = sign(zone, append(m.Ns, dnssec.Denial(zone, names, name, !exists, 300)...))
+			}
+		}
+
+		if tamper != nil {
+			tamper(r, m)
+		}
+		_ = w.WriteMsg(m)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	srv := &dns.Server{
+		PacketConn: conn,
+		UDPSize:    dns.MaxMsgSize,
+		Handler:    dns.HandlerFunc(handler),
+	}
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+
+	anchors, err := signer.DS(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rrs := make([]dns.RR, 0, len(anchors))
+	for _, rr := range anchors {
+		rrs = append(rrs, rr)
+	}
+	return conn.LocalAddr().String(), rrs, func() { _ = srv.Shutdown() }
+}
+
+func TestValidate(t *testing.T) {
+	// tamper changes the address in the answers for `www.lan.` after they are signed (for
+	// queries with an EDNS0 option), and removes the NSEC records from the answers for
+	// `noproof.lan.`
+	tamper := func(r, m *dns.Msg) {
+		switch r.Question[0].Name {
+		case "www.lan.":
+			if r.IsEdns0() == nil || len(r.IsEdns0().Option) == 0 {
+				return
+			}
+			for _, rr := range m.Answer {
+				if a, ok := rr.(*dns.A); ok {
+					a.A = net.ParseIP("10.0.0.66")
+				}
+			}
+		case "noproof.lan.":
+			var ns []dns.RR
+			for _, rr := range m.Ns {
+				if rr.Header().Rrtype == dns.TypeSOA {
+					ns = append(ns, rr)
+				}
+			}
+			m.Ns = ns
+		}
+	}
+
+	addr, anchors, stop := signedUpstream(t, tamper)
+	defer stop()
+
+	core := NewWithOptions([]string{addr}, Validate(anchors...))
+
+	// query builds a client query for `name` and `qtype`, setting the DO bit if `do` is true;
+	// the `bogus` flag adds an EDNS0 option which makes the upstream tamper with the answer
+	query := func(name string, qtype uint16, do, bogus bool) *dns.Msg {
+		q := new(dns.Msg)
+		q.SetQuestion(name, qtype)
+		q.AuthenticatedData = true
+		if do || bogus {
+			q.SetEdns0(4096, do)
+		}
+		if bogus {
+			q.IsEdns0().Option = append(q.IsEdns0().Option, &dns.EDNS0_LOCAL{Code: dns.EDNS0LOCALSTART, Data: []byte("tamper")})
+		}
+		return q
+	}
+
+	for _, test := range []struct {
+		name    string
+		q       *dns.Msg
+		r       *store.Record
+		err     error
+		ad      bool
+		answers int
+		sigs    bool
+	}{
+		{
+			name:    "Secure",
+			q:       query("www.lan.", dns.TypeA, true, false),
+			r:       store.New().Name("www.lan").Type("A").Build(),
+			ad:      true,
+			answers: 2,
+			sigs:    true,
+		},
+		{
+			name:    "SecureWithoutDO",
+			q:       query("www.lan.", dns.TypeA, false, false),
+			r:       store.New().Name("www.lan").Type("A").Build(),
+			ad:      true,
+			answers: 1,
+		},
+		{
+			name:    "SecureCNAME",
+			q:       query("alias.lan.", dns.TypeA, true, false),
+			r:       store.New().Name("alias.lan").Type("A").Build(),
+			ad:      true,
+			answers: 4,
+			sigs:    true,
+		},
+		{
+			name:    "SecureWildcard",
+			q:       query("any.wild.lan.", dns.TypeA, true, false),
+			r:       store.New().Name("any.wild.lan").Type("A").Build(),
+			ad:      true,
+			answers: 2,
+			sigs:    true,
+		},
+		{
+			name: "SecureNoData",
+			q:    query("www.lan.", dns.TypeAAAA, true, false),
+			r:    store.New().Name("www.lan").Type("AAAA").Build(),
+			ad:   true,
+			sigs: true,
+		},
+		{
+			name: "SecureNXDomain",
+			q:    query("missing.lan.", dns.TypeA, true, false),
+			r:    store.New().Name("missing.lan").Type("A").Build(),
+			err:  dnsrepo.ErrNXDomain,
+			ad:   true,
+			sigs: true,
+		},
+		{
+			name:    "Insecure",
+			q:       query("host.insecure.lan.", dns.TypeA, true, false),
+			r:       store.New().Name("host.insecure.lan").Type("A").Build(),
+			answers: 1,
+		},
+		{
+			name: "BogusAnswer",
+			q:    query("www.lan.", dns.TypeA, true, true),
+			r:    store.New().Name("www.lan").Type("A").Build(),
+			err:  dnsrepo.ErrServFail,
+		},
+		{
+			name: "BogusMissingProof",
+			q:    query("noproof.lan.", dns.TypeA, true, false),
+			r:    store.New().Name("noproof.lan").Type("A").Build(),
+			err:  dnsrepo.ErrServFail,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := new(dns.Msg)
+			m.SetReply(test.q)
+
+			err := core.Fallback(dnsrepo.WithQuery(context.Background(), test.q), test.r, m)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Errorf("unexpected error: wanted %v ; got %v", test.err, err)
+				return
+			}
+
+			if m.AuthenticatedData != test.ad {
+				t.Errorf("unexpected AD flag: wanted %v ; got %v", test.ad, m.AuthenticatedData)
+			}
+			if len(m.Answer) != test.answers {
+				t.Errorf("unexpected answers list length: wanted %v ; got %v", test.answers, len(m.Answer))
+			}
+
+			var sigs bool
+			for _, rr := range append(m.Answer, m.Ns...) {
+				if rr.Header().Rrtype == dns.TypeRRSIG {
+					sigs = true
+				}
+			}
+			if sigs != test.sigs {
+				t.Errorf("unexpected RRSIG records: wanted %v ; got %v", test.sigs, sigs)
+			}
+		})
+	}
+
+	t.Run("CheckingDisabled", func(t *testing.T) {
+		q := query("www.lan.", dns.TypeA, true, true)
+		q.CheckingDisabled = true
+		m := new(dns.Msg)
+		m.SetReply(q)
+
+		err := core.Fallback(dnsrepo.WithQuery(context.Background(), q), store.New().Name("www.lan").Type("A").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if m.AuthenticatedData {
+			t.Errorf("unexpected AD flag in an unvalidated answer")
+		}
+		if len(m.Answer) == 0 || !strings.Contains(m.Answer[0].String(), "10.0.0.66") {
+			t.Errorf("expected the unvalidated answer to be returned as-is: %v", m.Answer)
+		}
+	})
+
+	t.Run("BogusTrustAnchor", func(t *testing.T) {
+		ds := dns.Copy(anchors[0]).(*dns.DS)
+		ds.Digest = strings.Repeat("0", len(ds.Digest))
+
+		q := query("www.lan.", dns.TypeA, true, false)
+		m := new(dns.Msg)
+		m.SetReply(q)
+
+		err := NewWithOptions([]string{addr}, Validate(ds)).
+			Fallback(dnsrepo.WithQuery(context.Background(), q), store.New().Name("www.lan").Type("A").Build(), m)
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+	})
+}
@@ -21,23 +21,49 @@ func WithTrace(r Repository) Repository {
 
 // Answer will write the IP address present in the store.Record in the dns.Msg
 // slice of Answers
-func (t withTrace) Answer(ctx context.Context, r *store.Record, m *dns.Msg) {
+func (t withTrace) Answer(ctx context.Context, r *store.Record, m *dns.Msg) error {
 	ctx, s := spanner.Start(ctx, "dns.Answer")
 	defer s.End()
 	s.Add(attr.New("record", r))
 
-	t.r.Answer(ctx, r, m)
+	err := t.r.Answer(ctx, r, m)
+	if err != nil {
+		s.Event("error answering query", attr.New("error", err.Error()))
+		return err
+	}
 	s.Add(attr.New("answer", m.Answer))
+	return nil
+}
+
+// Authority will write the store.Record in the dns.Msg slice of authority records,
+// such as the SOA of the zone a domain name belongs to
+func (t withTrace) Authority(ctx context.Context, r *store.Record, m *dns.Msg) error {
+	ctx, s := spanner.Start(ctx, "dns.Authority")
+	defer s.End()
+	s.Add(attr.New("record", r))
+
+	err := t.r.Authority(ctx, r, m)
+	if err != nil {
+		s.Event("error writing authority record", attr.New("error", err.Error()))
+		return err
+	}
+	s.Add(attr.New("authority", m.Ns))
+	return nil
 }
 
 // Fallback is called when the DNS store does not hold a record for the requested
 // domain, so the DNS service spawns a DNS client that will query the fallback server
 // and write that answer to the dns.Msg
-func (t withTrace) Fallback(ctx context.Context, r *store.Record, m *dns.Msg) {
+func (t withTrace) Fallback(ctx context.Context, r *store.Record, m *dns.Msg) error {
 	ctx, s := spanner.Start(ctx, "dns.Fallback")
 	defer s.End()
 	s.Add(attr.New("record", r))
 
-	t.r.Fallback(ctx, r, m)
+	err := t.r.Fallback(ctx, r, m)
+	if err != nil {
+		s.Event("error querying fallback DNS", attr.New("error", err.Error()))
+		return err
+	}
 	s.Add(attr.New("answer", m.Answer))
+	return nil
 }
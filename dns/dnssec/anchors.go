@@ -0,0 +1,62 @@
+package dnssec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	ErrInvalidAnchor = errors.New("invalid DNSSEC trust anchor")
+)
+
+// rootAnchors are the DS records for the root zone's key-signing keys (KSK-2017 and
+// KSK-2024), as published by IANA
+var rootAnchors = []string{
+	". 172800 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". 172800 IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// RootAnchors returns the DS records for the root zone's key-signing keys, used as the
+// trust anchors for DNSSEC validation by default
+func RootAnchors() []dns.RR {
+	rrs := make([]dns.RR, 0, len(rootAnchors))
+	for _, anchor := range rootAnchors {
+		rr, err := dns.NewRR(anchor)
+		if err != nil {
+			panic(err)
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// ReadAnchors reads the trust anchors (DS or DNSKEY records) in the zone file `path`,
+// such as the root zone's `root.key` file
+func ReadAnchors(path string) ([]dns.RR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		rrs []dns.RR
+		zp  = dns.NewZoneParser(f, ".", path)
+	)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			rrs = append(rrs, rr)
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnchor, err)
+	}
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("%w: no DS or DNSKEY records in %s", ErrInvalidAnchor, path)
+	}
+	return rrs, nil
+}
@@ -0,0 +1,210 @@
+package dnssec
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// sigInception is how far in the past signatures start being valid, to allow for
+	// clock differences with the validating resolvers
+	sigInception = time.Hour
+	// sigValidity is how long signatures are valid for
+	sigValidity = 7 * 24 * time.Hour
+	// sigRefresh is the age after which a cached signature is replaced with a new one
+	sigRefresh = 3 * 24 * time.Hour
+	// maxCachedSigs is the maximum number of signatures kept in the cache
+	maxCachedSigs = 10000
+)
+
+// Signer signs the RRsets in the zones owned by the DNS server with their DNSSEC keys,
+// as they are answered (online signing)
+//
+// The keys for each zone are read from its BIND-formatted key files in the Signer's key
+// directory (`K<zone>.+<algorithm>+<key tag>.key` and `.private`). If there are none, a
+// key-signing key and a zone-signing key are generated with ECDSA P-256 (algorithm 13)
+// and written to the key directory; if it is not set, they are only kept in memory
+//
+// Signatures are valid for 7 days, and are cached for 3 days
+type Signer struct {
+	dir string
+
+	mtx   sync.Mutex
+	zones map[string]*keyring
+	sigs  map[string]*dns.RRSIG
+	now   func() time.Time
+}
+
+// New creates a Signer keeping the zones' DNSSEC keys in the directory `dir`
+func New(dir string) *Signer {
+	return &Signer{
+		dir:   dir,
+		zones: map[string]*keyring{},
+		sigs:  map[string]*dns.RRSIG{},
+		now:   time.Now,
+	}
+}
+
+// keyring returns the keys for the zone `zone`, loading or generating them on first use
+func (s *Signer) keyring(zone string) (*keyring, error) {
+	zone = dns.CanonicalName(zone)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if k, ok := s.zones[zone]; ok {
+		return k, nil
+	}
+
+	var (
+		k   *keyring
+		err error
+	)
+	if s.dir != "" {
+		k, err = loadKeys(s.dir, zone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if k == nil {
+		k, err = generateKeys(s.dir, zone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.zones[zone] = k
+	return k, nil
+}
+
+// DNSKEY returns the DNSKEY records for the zone `zone`
+func (s *Signer) DNSKEY(zone string) ([]dns.RR, error) {
+	k, err := s.keyring(zone)
+	if err != nil {
+		return nil, err
+	}
+	return k.dnskeys(), nil
+}
+
+// DS returns the DS records (with a SHA-256 digest) for the key-signing keys of the zone
+// `zone`, to be published in its parent zone
+func (s *Signer) DS(zone string) ([]*dns.DS, error) {
+	k, err := s.keyring(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds []*dns.DS
+	for _, key := range k.keys {
+		if key.dnskey.Flags&dns.SEP == 0 && key != k.ksk {
+			continue
+		}
+		ds = append(ds, key.dnskey.ToDS(dns.SHA256))
+	}
+	return ds, nil
+}
+
+// Sign returns the records `rrs` of the zone `zone` followed by their RRSIG records, one
+// for each RRset in `rrs`. The DNSKEY RRset is signed with the zone's key-signing key, and
+// all other RRsets with its zone-signing key
+func (s *Signer) Sign(zone string, rrs []dns.RR) ([]dns.RR, error) {
+	if len(rrs) == 0 {
+		return rrs, nil
+	}
+
+	k, err := s.keyring(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := make([]dns.RR, 0, len(rrs))
+	for _, rrset := range rrsets(rrs) {
+		key := k.zsk
+		if rrset[0].Header().Rrtype == dns.TypeDNSKEY {
+			key = k.ksk
+		}
+
+		sig, err := s.sign(zone, key, rrset)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+
+	return append(rrs, sigs...), nil
+}
+
+// sign returns the RRSIG record for the RRset `rrset` signed with the key `key`, reusing
+// a cached signature while it is not due for a refresh
+func (s *Signer) sign(zone string, key *key, rrset []dns.RR) (*dns.RRSIG, error) {
+	id := sigKey(key, rrset)
+	now := s.now()
+
+	s.mtx.Lock()
+	cached, ok := s.sigs[id]
+	s.mtx.Unlock()
+	if ok && now.Before(time.Unix(int64(cached.Inception), 0).Add(sigInception+sigRefresh)) {
+		return dns.Copy(cached).(*dns.RRSIG), nil
+	}
+
+	sig := &dns.RRSIG{
+		Algorithm:  key.dnskey.Algorithm,
+		KeyTag:     key.dnskey.KeyTag(),
+		SignerName: dns.CanonicalName(zone),
+		Inception:  uint32(now.Add(-sigInception).Unix()),
+		Expiration: uint32(now.Add(sigValidity).Unix()),
+	}
+	if err := sig.Sign(key.priv, rrset); err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	if len(s.sigs) >= maxCachedSigs {
+		s.sigs = map[string]*dns.RRSIG{}
+	}
+	s.sigs[id] = sig
+	s.mtx.Unlock()
+
+	return dns.Copy(sig).(*dns.RRSIG), nil
+}
+
+// sigKey returns the key for the signature of the RRset `rrset` with the key `key` in
+// the cache, made of the key tag and the RRset's records in their text format
+func sigKey(key *key, rrset []dns.RR) string {
+	records := make([]string, 0, len(rrset))
+	for _, rr := range rrset {
+		records = append(records, strings.ToLower(rr.String()))
+	}
+	sort.Strings(records)
+
+	return strconv.Itoa(int(key.dnskey.KeyTag())) + "\n" + strings.Join(records, "\n")
+}
+
+// rrsets groups the records `rrs` by owner name, class and type, keeping their order
+func rrsets(rrs []dns.RR) [][]dns.RR {
+	var (
+		sets  [][]dns.RR
+		index = map[string]int{}
+	)
+
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeRRSIG || rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+
+		h := rr.Header()
+		id := dns.CanonicalName(h.Name) + " " + dns.Class(h.Class).String() + " " + dns.Type(h.Rrtype).String()
+		if i, ok := index[id]; ok {
+			sets[i] = append(sets[i], rr)
+			continue
+		}
+		index[id] = len(sets)
+		sets = append(sets, []dns.RR{rr})
+	}
+	return sets
+}
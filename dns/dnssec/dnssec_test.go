@@ -0,0 +1,308 @@
+package dnssec
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestSigner(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+
+	a := &dns.A{
+		Hdr: dns.RR_Header{Name: "web.corp.lan.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.ParseIP("10.0.0.5"),
+	}
+
+	keys, err := s.DNSKEY("corp.lan")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("GeneratedKeys", func(t *testing.T) {
+		if len(keys) != 2 {
+			t.Errorf("unexpected keys length: wanted %v ; got %v", 2, len(keys))
+			return
+		}
+		if flags := keys[0].(*dns.DNSKEY).Flags; flags != flagsKSK {
+			t.Errorf("unexpected KSK flags: wanted %v ; got %v", flagsKSK, flags)
+		}
+		if flags := keys[1].(*dns.DNSKEY).Flags; flags != flagsZSK {
+			t.Errorf("unexpected ZSK flags: wanted %v ; got %v", flagsZSK, flags)
+		}
+	})
+
+	t.Run("Sign", func(t *testing.T) {
+		for _, test := range []struct {
+			name  string
+			rrs   []dns.RR
+			keyID int
+		}{
+			{name: "WithZSK", rrs: []dns.RR{a}, keyID: 1},
+			{name: "DNSKEYWithKSK", rrs: keys, keyID: 0},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				rrs, err := s.Sign("corp.lan.", test.rrs)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if len(rrs) != len(test.rrs)+1 {
+					t.Errorf("unexpected records length: wanted %v ; got %v", len(test.rrs)+1, len(rrs))
+					return
+				}
+
+				sig, ok := rrs[len(rrs)-1].(*dns.RRSIG)
+				if !ok {
+					t.Errorf("expected an RRSIG record, got %v", rrs[len(rrs)-1])
+					return
+				}
+				key := keys[test.keyID].(*dns.DNSKEY)
+				if err := sig.Verify(key, test.rrs); err != nil {
+					t.Errorf("unexpected error verifying the signature: %v", err)
+				}
+				if !sig.ValidityPeriod(time.Now()) {
+					t.Errorf("signature is not valid now: %v", sig)
+				}
+			})
+		}
+	})
+
+	t.Run("CachedSignatures", func(t *testing.T) {
+		first, err := s.Sign("corp.lan.", []dns.RR{a})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		second, err := s.Sign("corp.lan.", []dns.RR{a})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if first[1].String() != second[1].String() {
+			t.Errorf("output mismatch error: wanted %v ; got %v", first[1], second[1])
+		}
+
+		s.now = func() time.Time { return time.Now().Add(sigRefresh + time.Hour) }
+		defer func() { s.now = time.Now }()
+
+		refreshed, err := s.Sign("corp.lan.", []dns.RR{a})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if refreshed[1].String() == first[1].String() {
+			t.Errorf("expected the signature to be refreshed: %v", refreshed[1])
+		}
+	})
+
+	t.Run("LoadKeys", func(t *testing.T) {
+		ds, err := s.DS("corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		loaded, err := New(dir).DS("corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(ds) != 1 || len(loaded) != 1 || ds[0].String() != loaded[0].String() {
+			t.Errorf("output mismatch error: wanted %v ; got %v", ds, loaded)
+		}
+	})
+}
+
+func TestDenial(t *testing.T) {
+	names := map[string][]uint16{
+		"corp.lan.":      {dns.TypeSOA, dns.TypeNS},
+		"web.corp.lan.":  {dns.TypeA},
+		"a.b.corp.lan.":  {dns.TypeA},
+		"mail.corp.lan.": {dns.TypeMX},
+	}
+
+	for _, test := range []struct {
+		name     string
+		qname    string
+		nxdomain bool
+		wants    []string
+	}{
+		{
+			name:  "NoData",
+			qname: "web.corp.lan",
+			wants: []string{"web.corp.lan. corp.lan."},
+		},
+		{
+			name:     "NXDomain",
+			qname:    "c.corp.lan",
+			nxdomain: true,
+			wants:    []string{"a.b.corp.lan. mail.corp.lan.", "corp.lan. a.b.corp.lan."},
+		},
+		{
+			name:     "NXDomainCoveringWildcard",
+			qname:    "www.corp.lan",
+			nxdomain: true,
+			wants:    []string{"web.corp.lan. corp.lan.", "corp.lan. a.b.corp.lan."},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rrs := Denial("corp.lan", names, test.qname, test.nxdomain, 300)
+
+			if len(rrs) != len(test.wants) {
+				t.Errorf("unexpected records length: wanted %v ; got %v", len(test.wants), len(rrs))
+				return
+			}
+			for i, rr := range rrs {
+				nsec := rr.(*dns.NSEC)
+				if got := nsec.Hdr.Name + " " + nsec.NextDomain; got != test.wants[i] {
+					t.Errorf("output mismatch error: wanted %v ; got %v", test.wants[i], got)
+				}
+			}
+		})
+	}
+
+	t.Run("ApexTypes", func(t *testing.T) {
+		rrs := Denial("corp.lan", names, "corp.lan", false, 300)
+		wants := []uint16{dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY}
+
+		bitmap := rrs[0].(*dns.NSEC).TypeBitMap
+		if len(bitmap) != len(wants) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, bitmap)
+			return
+		}
+		for i := range wants {
+			if bitmap[i] != wants[i] {
+				t.Errorf("output mismatch error: wanted %v ; got %v", wants, bitmap)
+				return
+			}
+		}
+	})
+
+	t.Run("NoDataWildcard", func(t *testing.T) {
+		names := map[string][]uint16{
+			"preview.lan.":      {dns.TypeSOA, dns.TypeNS},
+			"*.preview.lan.":    {dns.TypeA},
+			"main.preview.lan.": {dns.TypeA},
+		}
+		wants := []string{"main.preview.lan. preview.lan.", "*.preview.lan. main.preview.lan."}
+
+		rrs := Denial("preview.lan", names, "pr-123.preview.lan", false, 300)
+		if len(rrs) != len(wants) {
+			t.Errorf("unexpected records length: wanted %v ; got %v", len(wants), len(rrs))
+			return
+		}
+		nsecs := make([]*dns.NSEC, 0, len(rrs))
+		for i, rr := range rrs {
+			nsec := rr.(*dns.NSEC)
+			if got := nsec.Hdr.Name + " " + nsec.NextDomain; got != wants[i] {
+				t.Errorf("output mismatch error: wanted %v ; got %v", wants[i], got)
+			}
+			nsecs = append(nsecs, nsec)
+		}
+
+		if _, ok := provesNoData(nsecs, nil, "pr-123.preview.lan.", dns.TypeAAAA); !ok {
+			t.Errorf("expected the NSEC records to prove that pr-123.preview.lan. has no AAAA records")
+		}
+	})
+}
+
+func TestReadAnchors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(dir, "root.key")
+		if err := os.WriteFile(path, []byte(rootAnchors[0]+"\n"+rootAnchors[1]+"\n"), 0o644); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		anchors, err := ReadAnchors(path)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		wants := RootAnchors()
+		if len(anchors) != len(wants) {
+			t.Errorf("unexpected anchors length: wanted %v ; got %v", len(wants), len(anchors))
+			return
+		}
+		for i := range wants {
+			if anchors[i].String() != wants[i].String() {
+				t.Errorf("output mismatch error: wanted %v ; got %v", wants[i], anchors[i])
+			}
+		}
+	})
+
+	t.Run("FailNoAnchors", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.key")
+		if err := os.WriteFile(path, []byte(". 3600 IN NS a.root-servers.net.\n"), 0o644); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		_, err := ReadAnchors(path)
+		if !errors.Is(err, ErrInvalidAnchor) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrInvalidAnchor, err)
+		}
+	})
+}
+
+func TestSynthesized(t *testing.T) {
+	rrs := func(t *testing.T, records ...string) []dns.RR {
+		out := make([]dns.RR, 0, len(records))
+		for _, record := range records {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", record, err)
+			}
+			out = append(out, rr)
+		}
+		return out
+	}
+	dname := "corp.lan. 300 IN DNAME corp.example."
+
+	t.Run("Success", func(t *testing.T) {
+		section := rrs(t, dname, "www.corp.lan. 300 IN CNAME www.corp.example.")
+
+		ok, err := synthesized(section[1:], section)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !ok {
+			t.Errorf("expected the CNAME record to be synthesized from the DNAME record")
+		}
+	})
+
+	t.Run("NotBelowDNAME", func(t *testing.T) {
+		section := rrs(t, dname, "www.other.lan. 300 IN CNAME www.corp.example.")
+
+		ok, err := synthesized(section[1:], section)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if ok {
+			t.Errorf("expected the CNAME record not to be synthesized")
+		}
+	})
+
+	t.Run("FailForgedTarget", func(t *testing.T) {
+		section := rrs(t, dname, "www.corp.lan. 300 IN CNAME www.insecure.example.")
+
+		_, err := synthesized(section[1:], section)
+		if !errors.Is(err, ErrBogus) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrBogus, err)
+		}
+	})
+}
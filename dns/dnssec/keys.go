@@ -0,0 +1,196 @@
+package dnssec
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// algorithm is the algorithm of the generated keys (ECDSA P-256 with SHA-256)
+	algorithm = dns.ECDSAP256SHA256
+	algBits   = 256
+	keyTTL    = 3600
+
+	flagsZSK = dns.ZONE
+	flagsKSK = dns.ZONE | dns.SEP
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid DNSSEC key")
+)
+
+// key is a DNSSEC key pair
+type key struct {
+	dnskey *dns.DNSKEY
+	priv   crypto.Signer
+}
+
+// keyring holds the DNSSEC keys of a zone: the key-signing key signs the zone's
+// DNSKEY RRset, and the zone-signing key signs all other RRsets. A single key with the
+// SEP flag is used as both (a combined signing key)
+type keyring struct {
+	ksk  *key
+	zsk  *key
+	keys []*key
+}
+
+// dnskeys returns the DNSKEY records of all the keys in the keyring
+func (k *keyring) dnskeys() []dns.RR {
+	rrs := make([]dns.RR, 0, len(k.keys))
+	for _, key := range k.keys {
+		rrs = append(rrs, dns.Copy(key.dnskey))
+	}
+	return rrs
+}
+
+// loadKeys reads the keys for the zone `zone` from the BIND-formatted key files in the
+// directory `dir` (`K<zone>.+<algorithm>+<key tag>.key` and `.private`)
+//
+// It returns a nil keyring if there are no key files for the zone
+func loadKeys(dir, zone string) (*keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "K"+zone+"+*.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	k := &keyring{}
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(key.dnskey.Hdr.Name, zone) {
+			return nil, fmt.Errorf("%w: %s is not a key for %s", ErrInvalidKey, path, zone)
+		}
+		k.add(key)
+	}
+
+	if len(k.keys) == 0 {
+		return nil, nil
+	}
+	if k.zsk == nil {
+		k.zsk = k.ksk
+	}
+	if k.ksk == nil {
+		k.ksk = k.zsk
+	}
+	return k, nil
+}
+
+// add adds the key `key` to the keyring, as its key-signing key if it has the SEP flag
+// and the keyring has none; or as its zone-signing key otherwise
+func (k *keyring) add(key *key) {
+	k.keys = append(k.keys, key)
+
+	switch {
+	case key.dnskey.Flags&dns.SEP != 0 && k.ksk == nil:
+		k.ksk = key
+	case key.dnskey.Flags&dns.SEP == 0 && k.zsk == nil:
+		k.zsk = key
+	}
+}
+
+// readKey reads the DNSKEY record in the file `path`, and its private key in the
+// matching `.private` file
+func readKey(path string) (*key, error) {
+	pub, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rr, err := dns.NewRR(string(pub))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKey, path, err)
+	}
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a DNSKEY record", ErrInvalidKey, path)
+	}
+
+	privPath := strings.TrimSuffix(path, ".key") + ".private"
+	f, err := os.Open(privPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	priv, err := dnskey.ReadPrivateKey(f, privPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKey, privPath, err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: unsupported private key", ErrInvalidKey, privPath)
+	}
+
+	return &key{
+		dnskey: dnskey,
+		priv:   signer,
+	}, nil
+}
+
+// generateKeys creates a key-signing key and a zone-signing key for the zone `zone`,
+// writing them to the directory `dir` in the BIND format, if set
+func generateKeys(dir, zone string) (*keyring, error) {
+	k := &keyring{}
+	for _, flags := range []uint16{flagsKSK, flagsZSK} {
+		key, err := generateKey(zone, flags)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			if err := writeKey(dir, key); err != nil {
+				return nil, err
+			}
+		}
+		k.add(key)
+	}
+	return k, nil
+}
+
+func generateKey(zone string, flags uint16) (*key, error) {
+	dnskey := &dns.DNSKEY{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeDNSKEY,
+			Class:  dns.ClassINET,
+			Ttl:    keyTTL,
+		},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: algorithm,
+	}
+
+	priv, err := dnskey.Generate(algBits)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key", ErrInvalidKey)
+	}
+
+	return &key{
+		dnskey: dnskey,
+		priv:   signer,
+	}, nil
+}
+
+// writeKey writes the key `key` to the directory `dir`, as a `.key` file with its
+// DNSKEY record and a `.private` file with its private key
+func writeKey(dir string, key *key) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("K%s+%03d+%05d", key.dnskey.Hdr.Name, key.dnskey.Algorithm, key.dnskey.KeyTag()))
+	if err := os.WriteFile(base+".key", []byte(key.dnskey.String()+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".private", []byte(key.dnskey.PrivateKeyString(key.priv)), 0o600)
+}
@@ -0,0 +1,334 @@
+package dnssec
+
+import (
+	"sort"
+
+	"github.com/miekg/dns"
+)
+
+// Less returns true if the domain name `a` sorts before the domain name `b` in the
+// canonical DNS name order (RFC 4034, section 6.1): comparing their labels from the
+// rightmost one, case-insensitively
+func Less(a, b string) bool {
+	la := dns.SplitDomainName(dns.CanonicalName(a))
+	lb := dns.SplitDomainName(dns.CanonicalName(b))
+
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if la[i] != lb[j] {
+			return la[i] < lb[j]
+		}
+	}
+	return len(la) < len(lb)
+}
+
+// Chain is the NSEC chain of a zone: the domain names in the zone, sorted in the canonical
+// DNS name order, along with the record types of each of them
+//
+// A Chain is built once for the zone's records by NewChain, so that the negative answers
+// for the zone don't have to sort its domain names again
+type Chain struct {
+	zone  string
+	names []string
+	types map[string][]uint16
+}
+
+// NewChain returns the NSEC Chain for the zone `zone`, where the map `names` holds the
+// record types of each domain name in the zone. The domain names outside of the zone are
+// left out of the Chain
+func NewChain(zone string, names map[string][]uint16) *Chain {
+	zone = dns.CanonicalName(zone)
+
+	chain := make([]string, 0, len(names)+1)
+	types := make(map[string][]uint16, len(names)+1)
+	for name, t := range names {
+		name = dns.CanonicalName(name)
+		if !dns.IsSubDomain(zone, name) {
+			continue
+		}
+		if _, ok := types[name]; !ok {
+			chain = append(chain, name)
+		}
+		types[name] = append(types[name], t...)
+	}
+	if _, ok := types[zone]; !ok {
+		chain = append(chain, zone)
+	}
+	types[zone] = append(types[zone], dns.TypeDNSKEY)
+	sort.Slice(chain, func(i, j int) bool {
+		return Less(chain[i], chain[j])
+	})
+
+	return &Chain{
+		zone:  zone,
+		names: chain,
+		types: types,
+	}
+}
+
+// Denial returns the NSEC records proving that the domain name `qname` does not exist in
+// the zone `zone` (if `nxdomain` is true), or that it holds no records of the queried
+// type. The map `names` holds the record types of each domain name in the zone
+//
+// It is a shorthand for building the zone's Chain with NewChain, and calling its Denial
+// method
+func Denial(zone string, names map[string][]uint16, qname string, nxdomain bool, ttl uint32) []dns.RR {
+	return NewChain(zone, names).Denial(qname, nxdomain, ttl)
+}
+
+// Denial returns the NSEC records proving that the domain name `qname` does not exist in
+// the Chain's zone (if `nxdomain` is true), or that it holds no records of the queried type
+//
+// A nonexistent domain name is covered by the NSEC record of the name preceding it, along
+// with the NSEC record covering the wildcard at its closest encloser; an existing domain
+// name is proved to hold no records of the queried type by its own NSEC record. A domain
+// name answered from a wildcard (with no records of the queried type) is covered by the
+// NSEC record of the name preceding it, along with the wildcard's own NSEC record. The
+// records have a TTL of `ttl` seconds
+func (c *Chain) Denial(qname string, nxdomain bool, ttl uint32) []dns.RR {
+	qname = dns.CanonicalName(qname)
+	zone, chain, types := c.zone, c.names, c.types
+
+	nsec := func(idx int) dns.RR {
+		name := chain[idx]
+		return &dns.NSEC{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeNSEC,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			NextDomain: chain[(idx+1)%len(chain)],
+			TypeBitMap: typeBitMap(types[name]),
+		}
+	}
+	// covering returns the index of the name in the chain preceding (or matching) `name`
+	covering := func(name string) int {
+		idx := sort.Search(len(chain), func(i int) bool {
+			return Less(name, chain[i])
+		})
+		if idx == 0 {
+			return len(chain) - 1
+		}
+		return idx - 1
+	}
+
+	if _, ok := types[qname]; ok && !nxdomain {
+		return []dns.RR{nsec(covering(qname))}
+	}
+
+	idx := covering(qname)
+	rrs := []dns.RR{nsec(idx)}
+
+	encloser := qname
+	for encloser != zone {
+		if _, ok := types[encloser]; ok {
+			break
+		}
+		labels := dns.Split(encloser)
+		if len(labels) < 2 {
+			encloser = zone
+			break
+		}
+		encloser = encloser[labels[1]:]
+	}
+
+	if wildcard := covering("*." + encloser); wildcard != idx {
+		rrs = append(rrs, nsec(wildcard))
+	}
+	return rrs
+}
+
+// typeBitMap returns the sorted, unique record types in `types`, along with the RRSIG
+// and NSEC types
+func typeBitMap(types []uint16) []uint16 {
+	seen := map[uint16]struct{}{
+		dns.TypeRRSIG: {},
+		dns.TypeNSEC:  {},
+	}
+	for _, t := range types {
+		seen[t] = struct{}{}
+	}
+
+	bitmap := make([]uint16, 0, len(seen))
+	for t := range seen {
+		bitmap = append(bitmap, t)
+	}
+	sort.Slice(bitmap, func(i, j int) bool {
+		return bitmap[i] < bitmap[j]
+	})
+	return bitmap
+}
+
+// optOut is the NSEC3 flag for the ranges which may cover unsigned delegations (RFC 5155)
+const optOut = 0x01
+
+// hasType returns true if the type bitmap `bitmap` lists the record type `rtype`
+func hasType(bitmap []uint16, rtype uint16) bool {
+	for _, t := range bitmap {
+		if t == rtype {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if the domain name `name` sorts between the owner name and the next
+// domain name of the NSEC record `nsec`, proving that it does not exist. Names below a
+// delegation (or a DNAME record) are not covered by its NSEC record, as they are not
+// part of the zone
+func covers(nsec *dns.NSEC, name string) bool {
+	owner, next := dns.CanonicalName(nsec.Hdr.Name), dns.CanonicalName(nsec.NextDomain)
+	name = dns.CanonicalName(name)
+
+	if owner == name {
+		return false
+	}
+	if dns.IsSubDomain(owner, name) && (hasType(nsec.TypeBitMap, dns.TypeDNAME) ||
+		(hasType(nsec.TypeBitMap, dns.TypeNS) && !hasType(nsec.TypeBitMap, dns.TypeSOA))) {
+		return false
+	}
+
+	// the last NSEC record in the zone points back to its apex
+	return Less(owner, name) && (Less(name, next) || !Less(owner, next))
+}
+
+// ancestor returns the ancestor of the domain name `name` with `labels` labels
+func ancestor(name string, labels int) string {
+	idx := dns.Split(name)
+	switch {
+	case labels >= len(idx):
+		return name
+	case labels <= 0:
+		return "."
+	default:
+		return name[idx[len(idx)-labels]:]
+	}
+}
+
+// wildcardOf returns the wildcard domain name at the domain name `name`
+func wildcardOf(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+// encloserNSEC returns the closest encloser of the domain name `name` covered by the NSEC
+// record `nsec`: the longest ancestor it shares with either its owner or next domain name
+func encloserNSEC(nsec *dns.NSEC, name string) string {
+	labels := dns.CompareDomainName(name, nsec.Hdr.Name)
+	if n := dns.CompareDomainName(name, nsec.NextDomain); n > labels {
+		labels = n
+	}
+	return ancestor(name, labels)
+}
+
+// encloserNSEC3 returns the closest encloser of the domain name `name` proven by the NSEC3
+// records `nsec3s`: its longest ancestor with a matching NSEC3 record, along with the next
+// closer name (the ancestor one label longer than it), if `name` is not the closest encloser
+func encloserNSEC3(nsec3s []*dns.NSEC3, name string) (string, string) {
+	var nextCloser string
+
+	for candidate := name; ; {
+		for _, nsec3 := range nsec3s {
+			if nsec3.Match(candidate) {
+				return candidate, nextCloser
+			}
+		}
+		if candidate == "." {
+			return "", ""
+		}
+		nextCloser = candidate
+		candidate = ancestor(candidate, dns.CountLabel(candidate)-1)
+	}
+}
+
+// provesNXDomain returns true if the NSEC or NSEC3 records `nsecs` and `nsec3s` prove that
+// the domain name `name` does not exist, nor a wildcard at its closest encloser; along with
+// the Security of the proof, which is Insecure if it is in an NSEC3 opt-out range
+func provesNXDomain(nsecs []*dns.NSEC, nsec3s []*dns.NSEC3, name string) (Security, bool) {
+	for _, nsec := range nsecs {
+		if !covers(nsec, name) {
+			continue
+		}
+		wildcard := wildcardOf(encloserNSEC(nsec, name))
+		for _, w := range nsecs {
+			if covers(w, wildcard) {
+				return Secure, true
+			}
+		}
+	}
+
+	encloser, nextCloser := encloserNSEC3(nsec3s, name)
+	if encloser == "" || nextCloser == "" {
+		return Bogus, false
+	}
+	for _, nsec3 := range nsec3s {
+		if !nsec3.Cover(nextCloser) {
+			continue
+		}
+		if nsec3.Flags&optOut != 0 {
+			return Insecure, true
+		}
+		for _, w := range nsec3s {
+			if w.Cover(wildcardOf(encloser)) {
+				return Secure, true
+			}
+		}
+	}
+	return Bogus, false
+}
+
+// provesNoData returns true if the NSEC or NSEC3 records `nsecs` and `nsec3s` prove that
+// the domain name `name` (or the wildcard it was expanded from) has no records of type
+// `qtype`; along with the Security of the proof, which is Insecure for DS records in an
+// NSEC3 opt-out range
+func provesNoData(nsecs []*dns.NSEC, nsec3s []*dns.NSEC3, name string, qtype uint16) (Security, bool) {
+	noType := func(bitmap []uint16) bool {
+		return !hasType(bitmap, qtype) && !hasType(bitmap, dns.TypeCNAME)
+	}
+
+	for _, nsec := range nsecs {
+		if dns.CanonicalName(nsec.Hdr.Name) == name && noType(nsec.TypeBitMap) {
+			return Secure, true
+		}
+		if !covers(nsec, name) {
+			continue
+		}
+		// an empty non-terminal has no records at all
+		if dns.IsSubDomain(name, dns.CanonicalName(nsec.NextDomain)) {
+			return Secure, true
+		}
+		wildcard := wildcardOf(encloserNSEC(nsec, name))
+		for _, w := range nsecs {
+			if dns.CanonicalName(w.Hdr.Name) == wildcard && noType(w.TypeBitMap) {
+				return Secure, true
+			}
+		}
+	}
+
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(name) && noType(nsec3.TypeBitMap) {
+			return Secure, true
+		}
+	}
+
+	encloser, nextCloser := encloserNSEC3(nsec3s, name)
+	if encloser == "" || nextCloser == "" {
+		return Bogus, false
+	}
+	for _, nsec3 := range nsec3s {
+		if !nsec3.Cover(nextCloser) {
+			continue
+		}
+		if qtype == dns.TypeDS && nsec3.Flags&optOut != 0 {
+			return Insecure, true
+		}
+		for _, w := range nsec3s {
+			if w.Match(wildcardOf(encloser)) && noType(w.TypeBitMap) {
+				return Secure, true
+			}
+		}
+	}
+	return Bogus, false
+}
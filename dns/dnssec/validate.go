@@ -0,0 +1,706 @@
+package dnssec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// maxLinkTTL is the maximum time a link in the chain of trust is cached for
+	maxLinkTTL = time.Hour
+	// maxCachedLinks is the maximum number of links kept in the cache
+	maxCachedLinks = 10000
+)
+
+var (
+	ErrBogus = errors.New("DNSSEC validation failed")
+)
+
+// Security is the outcome of the DNSSEC validation of a DNS response
+type Security uint8
+
+const (
+	// Insecure means the response is not covered by a chain of trust: its zone is proven
+	// to be unsigned by its parent, or there is no trust anchor for it
+	Insecure Security = iota
+	// Secure means the response's signatures were verified up to a trust anchor
+	Secure
+	// Bogus means the response's signatures, or its chain of trust, failed to verify
+	Bogus
+)
+
+// String implements the fmt.Stringer interface
+func (s Security) String() string {
+	switch s {
+	case Secure:
+		return "secure"
+	case Bogus:
+		return "bogus"
+	default:
+		return "insecure"
+	}
+}
+
+// Lookup sends a query for the domain name `name` and record type `qtype`, with the DO and
+// CD bits set, returning its response
+type Lookup func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+// link is a domain name in the chain of trust: either a zone cut (with the zone's keys
+// if it is signed), or a name which is not a zone cut
+type link struct {
+	zone     string
+	security Security
+	keys     []*dns.DNSKEY
+	cut      bool
+	expires  time.Time
+}
+
+// Validator verifies the DNSSEC signatures in DNS responses, following the chain of trust
+// from its trust anchors (DS or DNSKEY records) down to the zone of each RRset
+//
+// The DS and DNSKEY records along the chain are queried with its Lookup function, and the
+// validated zones (or the ones proven to be unsigned) are cached for the TTL of their
+// records, for up to one hour
+type Validator struct {
+	anchors map[string][]dns.RR
+	lookup  Lookup
+
+	mtx   sync.Mutex
+	links map[string]*link
+	now   func() time.Time
+}
+
+// NewValidator creates a Validator querying the DS and DNSKEY records with the Lookup
+// function `lookup`, trusting the keys in the DS or DNSKEY records `anchors`. If there are
+// none, the root zone's key-signing keys are used
+func NewValidator(lookup Lookup, anchors ...dns.RR) *Validator {
+	if len(anchors) == 0 {
+		anchors = RootAnchors()
+	}
+
+	v := &Validator{
+		anchors: map[string][]dns.RR{},
+		lookup:  lookup,
+		links:   map[string]*link{},
+		now:     time.Now,
+	}
+	for _, rr := range anchors {
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			zone := dns.CanonicalName(rr.Header().Name)
+			v.anchors[zone] = append(v.anchors[zone], rr)
+		}
+	}
+	return v
+}
+
+// Validate verifies the DNSSEC signatures in the response `m`, returning its Security. Bogus
+// responses are returned with an ErrBogus error describing the failure
+//
+// Each RRset in the answer must be signed by its zone, unless the zone is proven to be
+// unsigned. Answers expanded from a wildcard, as well as negative answers (NXDOMAIN and
+// NODATA), must carry the NSEC or NSEC3 records proving that the domain name (or the
+// record type) does not exist
+func (v *Validator) Validate(ctx context.Context, m *dns.Msg) (Security, error) {
+	if len(m.Question) == 0 {
+		return Insecure, nil
+	}
+	q := m.Question[0]
+	security := Secure
+
+	for _, rrset := range rrsets(m.Answer) {
+		ok, err := synthesized(rrset, m.Answer)
+		if err != nil {
+			return Bogus, err
+		}
+		if ok {
+			continue
+		}
+
+		s, sig, err := v.verify(ctx, rrset, m.Answer)
+		if err != nil {
+			return Bogus, err
+		}
+		if s == Insecure {
+			security = Insecure
+			continue
+		}
+
+		owner := dns.CanonicalName(rrset[0].Header().Name)
+		if int(sig.Labels) < dns.CountLabel(owner) {
+			if s, err = v.wildcard(ctx, m, owner, sig); err != nil {
+				return Bogus, err
+			}
+			if s == Insecure {
+				security = Insecure
+			}
+		}
+	}
+
+	target := dns.CanonicalName(q.Name)
+	if q.Qtype == dns.TypeCNAME || q.Qtype == dns.TypeANY || q.Qtype == dns.TypeRRSIG {
+		return security, nil
+	}
+	target, answered := follow(m.Answer, target, q.Qtype)
+	if answered {
+		return security, nil
+	}
+
+	s, err := v.deny(ctx, m, target, q.Qtype, m.Rcode == dns.RcodeNameError)
+	if err != nil {
+		return Bogus, err
+	}
+	if s == Insecure {
+		security = Insecure
+	}
+	return security, nil
+}
+
+// verify checks the signatures of the RRset `rrset`, with the RRSIG records in `section`,
+// returning the one which was verified if it is secure
+func (v *Validator) verify(ctx context.Context, rrset, section []dns.RR) (Security, *dns.RRSIG, error) {
+	h := rrset[0].Header()
+	owner := dns.CanonicalName(h.Name)
+
+	sigs := sigsFor(section, owner, h.Rrtype)
+	if len(sigs) == 0 {
+		l, err := v.walk(ctx, owner)
+		if err != nil {
+			return Bogus, nil, err
+		}
+		if l.security == Insecure {
+			return Insecure, nil, nil
+		}
+		return Bogus, nil, fmt.Errorf("%w: no signature for %s %s", ErrBogus, owner, dns.TypeToString[h.Rrtype])
+	}
+
+	var lastErr error
+	for _, sig := range sigs {
+		signer := dns.CanonicalName(sig.SignerName)
+		if !dns.IsSubDomain(signer, owner) {
+			lastErr = fmt.Errorf("%w: %s is signed by %s, outside of its zone", ErrBogus, owner, signer)
+			continue
+		}
+
+		l, err := v.walk(ctx, signer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if l.security == Insecure {
+			return Insecure, nil, nil
+		}
+		if l.zone != signer {
+			lastErr = fmt.Errorf("%w: %s is not a signed zone", ErrBogus, signer)
+			continue
+		}
+
+		if err := v.verifyWith(l.keys, sig, rrset); err != nil {
+			lastErr = err
+			continue
+		}
+		return Secure, sig, nil
+	}
+	return Bogus, nil, lastErr
+}
+
+// verifyWith checks the signature `sig` of the RRset `rrset` with the matching key in `keys`
+func (v *Validator) verifyWith(keys []*dns.DNSKEY, sig *dns.RRSIG, rrset []dns.RR) error {
+	err := fmt.Errorf("no key with tag %d", sig.KeyTag)
+
+	for _, key := range keys {
+		if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm || key.Flags&dns.ZONE == 0 {
+			continue
+		}
+		if err = sig.Verify(key, rrset); err != nil {
+			continue
+		}
+		if !sig.ValidityPeriod(v.now()) {
+			err = errors.New("signature is expired or not yet valid")
+			continue
+		}
+		return nil
+	}
+
+	h := rrset[0].Header()
+	return fmt.Errorf("%w: %s %s: %v", ErrBogus, dns.CanonicalName(h.Name), dns.TypeToString[h.Rrtype], err)
+}
+
+// verifySet checks that the RRset `rrset` is signed by the zone in the link `l`, with the
+// RRSIG records in `section`
+func (v *Validator) verifySet(l *link, rrset, section []dns.RR) error {
+	h := rrset[0].Header()
+
+	err := fmt.Errorf("%w: no signature by %s for %s %s", ErrBogus, l.zone, dns.CanonicalName(h.Name), dns.TypeToString[h.Rrtype])
+	for _, sig := range sigsFor(section, dns.CanonicalName(h.Name), h.Rrtype) {
+		if dns.CanonicalName(sig.SignerName) != l.zone {
+			continue
+		}
+		if err = v.verifyWith(l.keys, sig, rrset); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// walk follows the chain of trust from the closest trust anchor down to the domain name
+// `name`, returning the link for the closest signed zone enclosing it; or the link for the
+// unsigned zone cut above it, if there is one
+func (v *Validator) walk(ctx context.Context, name string) (*link, error) {
+	name = dns.CanonicalName(name)
+
+	anchor := ""
+	for zone := range v.anchors {
+		if dns.IsSubDomain(zone, name) && (anchor == "" || dns.CountLabel(zone) > dns.CountLabel(anchor)) {
+			anchor = zone
+		}
+	}
+	if anchor == "" {
+		return &link{security: Insecure}, nil
+	}
+
+	cur, err := v.cached(anchor, func() (*link, error) {
+		return v.trustAnchor(ctx, anchor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := dns.Split(name)
+	for i := len(labels) - dns.CountLabel(anchor) - 1; i >= 0; i-- {
+		child := name[labels[i]:]
+		parent := cur
+
+		l, err := v.cached(child, func() (*link, error) {
+			return v.delegation(ctx, parent, child)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !l.cut {
+			continue
+		}
+		if l.security == Insecure {
+			return l, nil
+		}
+		cur = l
+	}
+	return cur, nil
+}
+
+// cached returns the link for the domain name `name` from the cache, or the one returned
+// by `fn` (which is then cached, unless it is an error)
+func (v *Validator) cached(name string, fn func() (*link, error)) (*link, error) {
+	now := v.now()
+
+	v.mtx.Lock()
+	l, ok := v.links[name]
+	v.mtx.Unlock()
+	if ok && now.Before(l.expires) {
+		return l, nil
+	}
+
+	l, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	v.mtx.Lock()
+	if len(v.links) >= maxCachedLinks {
+		v.links = map[string]*link{}
+	}
+	v.links[name] = l
+	v.mtx.Unlock()
+
+	return l, nil
+}
+
+// expiry returns the time until which a link built from the records `rrs` is cached
+func (v *Validator) expiry(rrs ...dns.RR) time.Time {
+	ttl := maxLinkTTL
+	for _, rr := range rrs {
+		if d := time.Duration(rr.Header().Ttl) * time.Second; d < ttl {
+			ttl = d
+		}
+	}
+	return v.now().Add(ttl)
+}
+
+// trustAnchor returns the link for the trust anchor's zone `zone`, with its DNSKEY records
+// verified by the keys matching the trust anchor
+func (v *Validator) trustAnchor(ctx context.Context, zone string) (*link, error) {
+	m, err := v.lookup(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	keys, set := dnskeys(m.Answer, zone)
+
+	var trusted []*dns.DNSKEY
+	for _, anchor := range v.anchors[zone] {
+		switch a := anchor.(type) {
+		case *dns.DS:
+			trusted = append(trusted, matchDS(keys, a)...)
+		case *dns.DNSKEY:
+			for _, key := range keys {
+				if key.Flags == a.Flags && key.Algorithm == a.Algorithm && key.PublicKey == a.PublicKey {
+					trusted = append(trusted, key)
+				}
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, fmt.Errorf("%w: no DNSKEY records for %s match its trust anchor", ErrBogus, zone)
+	}
+
+	if err := v.verifySet(&link{zone: zone, keys: trusted}, set, m.Answer); err != nil {
+		return nil, err
+	}
+
+	return &link{
+		zone:     zone,
+		security: Secure,
+		keys:     keys,
+		cut:      true,
+		expires:  v.expiry(set...),
+	}, nil
+}
+
+// delegation returns the link for the domain name `child` under the signed zone in the
+// link `parent`, from its DS records
+//
+// If it has DS records, they must be signed by the parent zone, and the child zone's
+// DNSKEY records must be signed by a key matching them. If not, the parent zone must
+// prove their absence with NSEC or NSEC3 records: an unsigned zone cut is insecure, and
+// any other domain name is not a zone cut
+func (v *Validator) delegation(ctx context.Context, parent *link, child string) (*link, error) {
+	m, err := v.lookup(ctx, child, dns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds []dns.RR
+	for _, rr := range m.Answer {
+		if d, ok := rr.(*dns.DS); ok && dns.CanonicalName(d.Hdr.Name) == child {
+			ds = append(ds, d)
+		}
+	}
+	if len(ds) == 0 {
+		return v.noDelegation(parent, child, m)
+	}
+	if err := v.verifySet(parent, ds, m.Answer); err != nil {
+		return nil, err
+	}
+
+	// zones with DS records for unsupported algorithms only are treated as unsigned (RFC 4035, section 5.2)
+	var supported []*dns.DS
+	for _, rr := range ds {
+		if d := rr.(*dns.DS); supportedDS(d) {
+			supported = append(supported, d)
+		}
+	}
+	if len(supported) == 0 {
+		return &link{zone: child, security: Insecure, cut: true, expires: v.expiry(ds...)}, nil
+	}
+
+	km, err := v.lookup(ctx, child, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	keys, set := dnskeys(km.Answer, child)
+
+	var trusted []*dns.DNSKEY
+	for _, d := range supported {
+		trusted = append(trusted, matchDS(keys, d)...)
+	}
+	if len(trusted) == 0 {
+		return nil, fmt.Errorf("%w: no DNSKEY records for %s match its DS records", ErrBogus, child)
+	}
+	if err := v.verifySet(&link{zone: child, keys: trusted}, set, km.Answer); err != nil {
+		return nil, err
+	}
+
+	return &link{
+		zone:     child,
+		security: Secure,
+		keys:     keys,
+		cut:      true,
+		expires:  v.expiry(append(ds, set...)...),
+	}, nil
+}
+
+// noDelegation returns the link for the domain name `child` without DS records in the
+// response `m`, from the NSEC or NSEC3 records proving their absence
+func (v *Validator) noDelegation(parent *link, child string, m *dns.Msg) (*link, error) {
+	// an alias (CNAME) at the domain name means it is not a zone cut
+	for _, rrset := range rrsets(m.Answer) {
+		if err := v.verifySet(parent, rrset, m.Answer); err != nil {
+			return nil, err
+		}
+	}
+
+	nsecs, nsec3s, err := v.proofs(parent, m.Ns)
+	if err != nil {
+		return nil, err
+	}
+
+	insecure := &link{zone: child, security: Insecure, cut: true, expires: v.expiry(m.Ns...)}
+	notCut := &link{expires: v.expiry(append(m.Answer, m.Ns...)...)}
+
+	if len(m.Answer) > 0 {
+		return notCut, nil
+	}
+
+	var bitmap []uint16
+	for _, nsec := range nsecs {
+		if dns.CanonicalName(nsec.Hdr.Name) == child {
+			bitmap = nsec.TypeBitMap
+		}
+	}
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(child) {
+			bitmap = nsec3.TypeBitMap
+		}
+	}
+	if bitmap != nil {
+		switch {
+		case hasType(bitmap, dns.TypeDS):
+			return nil, fmt.Errorf("%w: the denial of DS records for %s lists them", ErrBogus, child)
+		case hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA):
+			return insecure, nil
+		default:
+			return notCut, nil
+		}
+	}
+
+	for _, nsec := range nsecs {
+		if covers(nsec, child) {
+			return notCut, nil
+		}
+	}
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(child) {
+			if nsec3.Flags&optOut != 0 {
+				return insecure, nil
+			}
+			return notCut, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no proof of the absence of DS records for %s", ErrBogus, child)
+}
+
+// proofs returns the NSEC and NSEC3 records in `section`, checking that they are signed
+// by the zone in the link `l`
+func (v *Validator) proofs(l *link, section []dns.RR) ([]*dns.NSEC, []*dns.NSEC3, error) {
+	var (
+		nsecs  []*dns.NSEC
+		nsec3s []*dns.NSEC3
+	)
+
+	for _, rrset := range rrsets(section) {
+		switch rrset[0].Header().Rrtype {
+		case dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		if err := v.verifySet(l, rrset, section); err != nil {
+			return nil, nil, err
+		}
+
+		for _, rr := range rrset {
+			switch r := rr.(type) {
+			case *dns.NSEC:
+				nsecs = append(nsecs, r)
+			case *dns.NSEC3:
+				nsec3s = append(nsec3s, r)
+			}
+		}
+	}
+	return nsecs, nsec3s, nil
+}
+
+// deny checks the NSEC or NSEC3 records in the response `m` proving that the domain name
+// `name` does not exist (if `nxdomain` is true), or that it has no records of type `qtype`
+func (v *Validator) deny(ctx context.Context, m *dns.Msg, name string, qtype uint16, nxdomain bool) (Security, error) {
+	l, err := v.walk(ctx, name)
+	if err != nil {
+		return Bogus, err
+	}
+	if l.security == Insecure {
+		return Insecure, nil
+	}
+
+	nsecs, nsec3s, err := v.proofs(l, m.Ns)
+	if err != nil {
+		return Bogus, err
+	}
+
+	if nxdomain {
+		if s, ok := provesNXDomain(nsecs, nsec3s, name); ok {
+			return s, nil
+		}
+		return Bogus, fmt.Errorf("%w: no proof that %s does not exist", ErrBogus, name)
+	}
+
+	if s, ok := provesNoData(nsecs, nsec3s, name, qtype); ok {
+		return s, nil
+	}
+	return Bogus, fmt.Errorf("%w: no proof that %s has no %s records", ErrBogus, name, dns.TypeToString[qtype])
+}
+
+// wildcard checks the NSEC or NSEC3 records in the response `m` proving that the domain
+// name `owner` does not exist, for an answer expanded from a wildcard with the signature `sig`
+func (v *Validator) wildcard(ctx context.Context, m *dns.Msg, owner string, sig *dns.RRSIG) (Security, error) {
+	l, err := v.walk(ctx, sig.SignerName)
+	if err != nil {
+		return Bogus, err
+	}
+	if l.security == Insecure {
+		return Insecure, nil
+	}
+
+	nsecs, nsec3s, err := v.proofs(l, m.Ns)
+	if err != nil {
+		return Bogus, err
+	}
+
+	for _, nsec := range nsecs {
+		if covers(nsec, owner) {
+			return Secure, nil
+		}
+	}
+	nextCloser := ancestor(owner, int(sig.Labels)+1)
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(nextCloser) {
+			return Secure, nil
+		}
+	}
+	return Bogus, fmt.Errorf("%w: no proof that %s does not exist for its wildcard answer", ErrBogus, owner)
+}
+
+// sigsFor returns the RRSIG records in `section` covering the RRset with owner name `owner`
+// and type `rtype`
+func sigsFor(section []dns.RR, owner string, rtype uint16) []*dns.RRSIG {
+	var sigs []*dns.RRSIG
+	for _, rr := range section {
+		sig, ok := rr.(*dns.RRSIG)
+		if ok && sig.TypeCovered == rtype && dns.CanonicalName(sig.Hdr.Name) == owner {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// dnskeys returns the DNSKEY records for the zone `zone` in `section`, both as DNSKEY
+// records and as an RRset
+func dnskeys(section []dns.RR, zone string) ([]*dns.DNSKEY, []dns.RR) {
+	var (
+		keys []*dns.DNSKEY
+		set  []dns.RR
+	)
+	for _, rr := range section {
+		if key, ok := rr.(*dns.DNSKEY); ok && dns.CanonicalName(key.Hdr.Name) == zone {
+			keys = append(keys, key)
+			set = append(set, key)
+		}
+	}
+	return keys, set
+}
+
+// matchDS returns the keys in `keys` matching the DS record `ds`
+func matchDS(keys []*dns.DNSKEY, ds *dns.DS) []*dns.DNSKEY {
+	var matches []*dns.DNSKEY
+	for _, key := range keys {
+		if key.Algorithm != ds.Algorithm || key.KeyTag() != ds.KeyTag {
+			continue
+		}
+		if d := key.ToDS(ds.DigestType); d != nil && strings.EqualFold(d.Digest, ds.Digest) {
+			matches = append(matches, key)
+		}
+	}
+	return matches
+}
+
+// supportedDS returns true if the DS record `ds` is for a key algorithm and with a digest
+// type which can be verified
+func supportedDS(ds *dns.DS) bool {
+	switch ds.Algorithm {
+	case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512,
+		dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+	default:
+		return false
+	}
+
+	switch ds.DigestType {
+	case dns.SHA1, dns.SHA256, dns.SHA384:
+		return true
+	default:
+		return false
+	}
+}
+
+// synthesized returns true if the RRset `rrset` is a CNAME record synthesized from a DNAME
+// record in `section`, which is not signed
+//
+// The CNAME record's target must be the DNAME substitution of its owner (replacing the
+// DNAME's owner with its target), otherwise an ErrBogus error is returned
+func synthesized(rrset, section []dns.RR) (bool, error) {
+	cname, ok := rrset[0].(*dns.CNAME)
+	if !ok || len(rrset) != 1 {
+		return false, nil
+	}
+	owner := dns.CanonicalName(cname.Hdr.Name)
+
+	for _, rr := range section {
+		d, ok := rr.(*dns.DNAME)
+		if !ok {
+			continue
+		}
+		name := dns.CanonicalName(d.Hdr.Name)
+		if name == owner || !dns.IsSubDomain(name, owner) {
+			continue
+		}
+
+		substituted := strings.TrimSuffix(owner, name)
+		if target := dns.CanonicalName(d.Target); target != "." {
+			substituted += target
+		}
+		if target := dns.CanonicalName(cname.Target); target != substituted {
+			return false, fmt.Errorf("%w: CNAME %s to %s does not match the DNAME substitution %s",
+				ErrBogus, owner, target, substituted)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// follow follows the chain of CNAME records in `section` from the domain name `name`,
+// returning its final target and whether it holds records of type `qtype` in `section`
+func follow(section []dns.RR, name string, qtype uint16) (string, bool) {
+	for i := 0; i <= len(section); i++ {
+		var next string
+		for _, rr := range section {
+			h := rr.Header()
+			if dns.CanonicalName(h.Name) != name {
+				continue
+			}
+			if h.Rrtype == qtype {
+				return name, true
+			}
+			if cname, ok := rr.(*dns.CNAME); ok {
+				next = dns.CanonicalName(cname.Target)
+			}
+		}
+		if next == "" {
+			break
+		}
+		name = next
+	}
+	return name, false
+}
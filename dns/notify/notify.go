@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+const (
+	// DefaultRetries is the default number of times a NOTIFY message is retried
+	DefaultRetries = 3
+	// DefaultTimeout is the default time to wait for the reply to a NOTIFY message
+	DefaultTimeout = 2 * time.Second
+	// tsigFudge is the allowed time difference (in seconds) for the TSIG signatures
+	tsigFudge = 300
+)
+
+var (
+	ErrResponse = errors.New("unexpected response to NOTIFY message")
+)
+
+// Notifier sends NOTIFY messages (RFC 1996) to the secondary servers of the zones owned by
+// this server when their records change, so that they transfer the zones right away
+// instead of waiting for their refresh timer
+//
+// Each message is retried until the secondary server replies to it, or until it has been
+// sent `retries` times. Messages are signed with TSIG (RFC 8945) if the Notifier has a key
+// for the zone
+type Notifier struct {
+	targets []string
+	retries int
+	timeout time.Duration
+	keys    []*key
+}
+
+// key is a TSIG key, with its name, algorithm and zones in canonical form
+type key struct {
+	name      string
+	algorithm string
+	secret    string
+	zones     []string
+}
+
+// Option describes setter types for a Notifier
+type Option interface {
+	Apply(*Notifier)
+}
+
+// TSIG creates an Option adding a TSIG key used to sign the NOTIFY messages, with the
+// name `name`, the algorithm `algorithm` (`hmac-sha256` if empty) and the base64-encoded
+// secret `secret`
+//
+// A key scoped to the zones `zones` only signs the messages for those zones; and a key
+// without zones signs the messages for the zones which no other key is scoped to. If the
+// name or the secret are empty, it returns nil
+func TSIG(name, algorithm, secret string, zones ...string) Option {
+	if name == "" || secret == "" {
+		return nil
+	}
+	if algorithm == "" {
+		algorithm = dns.HmacSHA256
+	}
+
+	canonical := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		canonical = append(canonical, dns.CanonicalName(zone))
+	}
+	return &tsigOpt{
+		key: &key{
+			name:      dns.CanonicalName(name),
+			algorithm: dns.CanonicalName(algorithm),
+			secret:    secret,
+			zones:     canonical,
+		},
+	}
+}
+
+// Retries creates an Option setting the number of times a NOTIFY message is sent to a
+// secondary server which does not reply to it
+//
+// If `n` is zero or negative, it returns nil
+func Retries(n int) Option {
+	if n <= 0 {
+		return nil
+	}
+	return &retriesOpt{
+		n: n,
+	}
+}
+
+// Timeout creates an Option setting the time to wait for the reply to a NOTIFY message
+//
+// If `d` is zero or negative, it returns nil
+func Timeout(d time.Duration) Option {
+	if d <= 0 {
+		return nil
+	}
+	return &timeoutOpt{
+		d: d,
+	}
+}
+
+type tsigOpt struct {
+	key *key
+}
+
+type retriesOpt struct {
+	n int
+}
+
+type timeoutOpt struct {
+	d time.Duration
+}
+
+// Apply implements the Option interface
+func (o *tsigOpt) Apply(n *Notifier) {
+	n.keys = append(n.keys, o.key)
+}
+
+// Apply implements the Option interface
+func (o *retriesOpt) Apply(n *Notifier) {
+	n.retries = o.n
+}
+
+// Apply implements the Option interface
+func (o *timeoutOpt) Apply(n *Notifier) {
+	n.timeout = o.d
+}
+
+// New creates a Notifier for the secondary servers in `targets` (as `host` or `host:port`,
+// with port 53 by default), applying all input Option `opts`
+func New(targets []string, opts ...Option) *Notifier {
+	n := &Notifier{
+		retries: DefaultRetries,
+		timeout: DefaultTimeout,
+	}
+
+	for _, target := range targets {
+		if target = strings.TrimSpace(target); target == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			target = net.JoinHostPort(strings.Trim(target, "[]"), "53")
+		}
+		n.targets = append(n.targets, target)
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(n)
+		}
+	}
+
+	return n
+}
+
+// Notify sends a NOTIFY message for the zone `zone` to all of the Notifier's secondary
+// servers, in the background. The serial `serial` is the zone's new serial
+//
+// The messages are not bound to the context `ctx`, so that they are still sent (and
+// retried) once the change to the zone is complete
+func (n *Notifier) Notify(ctx context.Context, zone string, serial uint32) {
+	for _, target := range n.targets {
+		go func(target string) {
+			ctx, s := spanner.Start(context.Background(), "notify.Notify")
+			defer s.End()
+			s.Add(
+				attr.String("zone", zone),
+				attr.Int("serial", int(serial)),
+				attr.String("target", target),
+			)
+
+			if err := n.Send(ctx, target, zone); err != nil {
+				s.Event("failed to notify secondary server", attr.String("error", err.Error()))
+			}
+		}(target)
+	}
+}
+
+// Send sends a NOTIFY message for the zone `zone` to the secondary server `target`,
+// retrying it until the server replies or the Notifier's retries run out
+//
+// Returns an error wrapping ErrResponse if the server replies with an error, or the
+// error from the last attempt if it does not reply
+func (n *Notifier) Send(ctx context.Context, target, zone string) error {
+	client := &dns.Client{Net: "udp", Timeout: n.timeout}
+	key := n.keyFor(zone)
+	if key != nil {
+		client.TsigSecret = map[string]string{key.name: key.secret}
+	}
+
+	var err error
+	for i := 0; i < n.retries; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m := new(dns.Msg)
+		m.SetNotify(dns.Fqdn(zone))
+		if key != nil {
+			m.SetTsig(key.name, key.algorithm, tsigFudge, time.Now().Unix())
+		}
+
+		var r *dns.Msg
+		r, _, err = client.ExchangeContext(ctx, m, target)
+		if err != nil {
+			continue
+		}
+		if r.Opcode != dns.OpcodeNotify || r.Rcode != dns.RcodeSuccess {
+			return fmt.Errorf("%w: %s from %s", ErrResponse, dns.RcodeToString[r.Rcode], target)
+		}
+		return nil
+	}
+	return err
+}
+
+// keyFor returns the TSIG key to sign the NOTIFY messages for the zone `zone` with: the
+// first key scoped to it, or else the first key without zones; or nil if there is none
+func (n *Notifier) keyFor(zone string) *key {
+	zone = dns.CanonicalName(zone)
+
+	var unscoped *key
+	for _, k := range n.keys {
+		if len(k.zones) == 0 {
+			if unscoped == nil {
+				unscoped = k
+			}
+			continue
+		}
+		for _, z := range k.zones {
+			if z == zone {
+				return k
+			}
+		}
+	}
+	return unscoped
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testKey    = "transfer."
+	testSecret = "so6ZGir4GPAqINNh9U5c3A=="
+)
+
+// secondary starts a DNS server replying to NOTIFY messages with the response code
+// `rcode`, sending the received messages to the returned channel
+func secondary(t *testing.T, rcode int) (string, chan *dns.Msg) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := make(chan *dns.Msg, 8)
+	srv := &dns.Server{
+		PacketConn: pc,
+		TsigSecret: map[string]string{testKey: testSecret},
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			if r.IsTsig() != nil && w.TsigStatus() != nil {
+				t.Errorf("unexpected TSIG status: %v", w.TsigStatus())
+			}
+			received <- r
+
+			m := new(dns.Msg)
+			m.SetRcode(r, rcode)
+			w.WriteMsg(m)
+		}),
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return pc.LocalAddr().String(), received
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		addr, received := secondary(t, dns.RcodeSuccess)
+
+		err := New([]string{addr}).Send(ctx, addr, "corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		r := <-received
+		if r.Opcode != dns.OpcodeNotify || !r.Authoritative || r.Question[0].Name != "corp.lan." || r.Question[0].Qtype != dns.TypeSOA {
+			t.Errorf("unexpected NOTIFY message: %v", r)
+		}
+	})
+
+	t.Run("SuccessWithTSIG", func(t *testing.T) {
+		addr, received := secondary(t, dns.RcodeSuccess)
+
+		err := New([]string{addr}, TSIG("transfer", "", testSecret)).Send(ctx, addr, "corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		r := <-received
+		if tsig := r.IsTsig(); tsig == nil || tsig.Hdr.Name != testKey || tsig.Algorithm != dns.HmacSHA256 {
+			t.Errorf("expected NOTIFY message to be signed: %v", r)
+		}
+	})
+
+	t.Run("SuccessWithZoneScopedTSIG", func(t *testing.T) {
+		addr, received := secondary(t, dns.RcodeSuccess)
+
+		n := New([]string{addr},
+			TSIG("other", "", testSecret, "example.com"),
+			TSIG("transfer", "", testSecret, "corp.lan"),
+		)
+		if err := n.Send(ctx, addr, "corp.lan"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		r := <-received
+		if tsig := r.IsTsig(); tsig == nil || tsig.Hdr.Name != testKey {
+			t.Errorf("expected NOTIFY message to be signed with the key scoped to the zone: %v", r)
+		}
+	})
+
+	t.Run("UnsignedWithoutKeyForZone", func(t *testing.T) {
+		addr, received := secondary(t, dns.RcodeSuccess)
+
+		if err := New([]string{addr}, TSIG("transfer", "", testSecret, "example.com")).Send(ctx, addr, "corp.lan"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if r := <-received; r.IsTsig() != nil {
+			t.Errorf("expected NOTIFY message not to be signed with a key scoped to other zones: %v", r)
+		}
+	})
+
+	t.Run("FailRefused", func(t *testing.T) {
+		addr, _ := secondary(t, dns.RcodeRefused)
+
+		err := New([]string{addr}).Send(ctx, addr, "corp.lan")
+		if !errors.Is(err, ErrResponse) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrResponse, err)
+		}
+	})
+
+	t.Run("FailNoReply", func(t *testing.T) {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer pc.Close()
+
+		n := New([]string{pc.LocalAddr().String()}, Retries(2), Timeout(50*time.Millisecond))
+		if err := n.Send(ctx, pc.LocalAddr().String(), "corp.lan"); err == nil {
+			t.Errorf("expected an error from a secondary server which does not reply")
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	n := New([]string{"10.0.0.2", " 10.0.0.3:5353", "", "fd00::2"})
+
+	wants := []string{"10.0.0.2:53", "10.0.0.3:5353", "[fd00::2]:53"}
+	if len(n.targets) != len(wants) {
+		t.Fatalf("unexpected targets: wanted %v ; got %v", wants, n.targets)
+	}
+	for idx := range wants {
+		if n.targets[idx] != wants[idx] {
+			t.Errorf("unexpected target: wanted %v ; got %v", wants[idx], n.targets[idx])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+type queryKey struct{}
+
+// WithQuery returns a copy of the context.Context `ctx` carrying the DNS query `q`
+// received by the transport, so that a Repository can forward its flags and EDNS0
+// options along with its questions
+func WithQuery(ctx context.Context, q *dns.Msg) context.Context {
+	return context.WithValue(ctx, queryKey{}, q)
+}
+
+// QueryFrom returns the DNS query carried by the context.Context `ctx`, or nil if
+// it holds none
+func QueryFrom(ctx context.Context) *dns.Msg {
+	q, _ := ctx.Value(queryKey{}).(*dns.Msg)
+	return q
+}
+
+// QType returns the numeric DNS record type for the record type `t`, which can be any
+// type known to the store or to miekg/dns, or an unknown type in its generic `TYPEnnn`
+// format (RFC 3597). It returns zero if the record type is not valid
+func QType(t string) uint16 {
+	if rtype, ok := store.RecordTypeInts[t]; ok {
+		return rtype
+	}
+	upper := strings.ToUpper(t)
+	if rtype, ok := dns.StringToType[upper]; ok {
+		return rtype
+	}
+	if strings.HasPrefix(upper, "TYPE") {
+		if n, err := strconv.ParseUint(upper[len("TYPE"):], 10, 16); err == nil {
+			return uint16(n)
+		}
+	}
+	return 0
+}
+
+// rcodeError is a failed query replied to with a specific response code
+type rcodeError struct {
+	rcode int
+	err   error
+}
+
+// Error implements the error interface
+func (e *rcodeError) Error() string {
+	return fmt.Sprintf("%s: %v", dns.RcodeToString[e.rcode], e.err)
+}
+
+// Unwrap returns ErrServFail, as the query could not be resolved
+func (e *rcodeError) Unwrap() error {
+	return ErrServFail
+}
+
+// WithRcode wraps the error `err` so that the query which failed with it is replied to with
+// the response code `rcode` (such as the one from a fallback DNS), instead of SERVFAIL
+//
+// The returned error still matches ErrServFail
+func WithRcode(rcode int, err error) error {
+	return &rcodeError{
+		rcode: rcode,
+		err:   err,
+	}
+}
+
+func rcodeOf(err error) (int, bool) {
+	var rerr *rcodeError
+	if errors.As(err, &rerr) {
+		return rerr.rcode, true
+	}
+	return 0, false
+}
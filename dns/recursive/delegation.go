@@ -0,0 +1,283 @@
+package recursive
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	dns "github.com/miekg/dns"
+)
+
+// delegation lists the name servers (by hostname) of a zone
+type delegation struct {
+	zone    string
+	ns      []string
+	expires time.Time
+}
+
+// hostAddrs lists the IPv4 addresses of a name server
+type hostAddrs struct {
+	ips     []string
+	expires time.Time
+}
+
+// closest returns the closest zone enclosing the domain name `name` for which the
+// Resolver knows the name servers' addresses, and those addresses; or the root zone
+// and the root name servers if it knows none
+func (r *Resolver) closest(ctx context.Context, name string, depth int) (string, []string) {
+	for zone := name; zone != "."; zone = parent(zone) {
+		d := r.delegation(zone)
+		if d == nil {
+			continue
+		}
+		if servers := r.serversFor(ctx, d, depth); len(servers) > 0 {
+			return zone, servers
+		}
+	}
+	return ".", r.roots
+}
+
+// delegation returns the cached delegation for the zone `zone`, or nil if it is not
+// cached or has expired
+func (r *Resolver) delegation(zone string) *delegation {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	d, ok := r.zones[zone]
+	if !ok {
+		return nil
+	}
+	if !r.now().Before(d.expires) {
+		delete(r.zones, zone)
+		return nil
+	}
+	return d
+}
+
+// serversFor returns the addresses of the name servers in the delegation `d`
+//
+// Addresses come from the glue records cached with the delegation; if there are none,
+// the name servers' hostnames are resolved until one of them has an address
+func (r *Resolver) serversFor(ctx context.Context, d *delegation, depth int) []string {
+	var servers []string
+	for _, ns := range d.ns {
+		for _, ip := range r.hostAddrs(ns) {
+			servers = append(servers, net.JoinHostPort(ip, r.port))
+		}
+	}
+	if len(servers) > 0 {
+		return servers
+	}
+
+	for _, ns := range d.ns {
+		in, err := r.resolve(ctx, ns, dns.TypeA, depth+1)
+		if err != nil || in.Rcode != dns.RcodeSuccess {
+			continue
+		}
+
+		var (
+			ips = make([]string, 0, len(in.Answer))
+			ttl = ^uint32(0)
+		)
+		for _, rr := range in.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				ips = append(ips, a.A.String())
+				ttl = min(ttl, a.Hdr.Ttl)
+			}
+		}
+		if len(ips) == 0 {
+			continue
+		}
+
+		r.cacheHost(ns, ips, ttl)
+		for _, ip := range ips {
+			servers = append(servers, net.JoinHostPort(ip, r.port))
+		}
+		return servers
+	}
+	return nil
+}
+
+// hostAddrs returns the cached addresses for the name server `host`
+func (r *Resolver) hostAddrs(host string) []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	h, ok := r.addrs[host]
+	if !ok {
+		return nil
+	}
+	if !r.now().Before(h.expires) {
+		delete(r.addrs, host)
+		return nil
+	}
+	return h.ips
+}
+
+func (r *Resolver) cacheHost(host string, ips []string, ttl uint32) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.prune()
+	r.addrs[host] = &hostAddrs{
+		ips:     ips,
+		expires: r.now().Add(time.Duration(ttl) * time.Second),
+	}
+}
+
+// referral returns the delegation in the dns.Msg `in`, received from a name server for
+// the zone `zone` when querying for the domain name `name`, or nil if `in` is not a
+// referral to a zone below `zone`. The delegation and its glue records are cached
+func (r *Resolver) referral(in *dns.Msg, zone, name string) *delegation {
+	var (
+		d   *delegation
+		ttl = ^uint32(0)
+	)
+
+	for _, rr := range in.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		owner := strings.ToLower(dns.Fqdn(ns.Hdr.Name))
+		if d == nil {
+			// referrals must lead down the tree, towards the domain name
+			if owner == zone || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, name) {
+				return nil
+			}
+			d = &delegation{zone: owner}
+		}
+		if owner != d.zone {
+			continue
+		}
+
+		d.ns = append(d.ns, strings.ToLower(dns.Fqdn(ns.Ns)))
+		ttl = min(ttl, ns.Hdr.Ttl)
+	}
+	if d == nil {
+		return nil
+	}
+
+	now := r.now()
+	d.expires = now.Add(time.Duration(ttl) * time.Second)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.prune()
+	r.zones[d.zone] = d
+
+	glue := map[string][]string{}
+	glueTTL := map[string]uint32{}
+	for _, rr := range in.Extra {
+		a, ok := rr.(*dns.A)
+		if !ok {
+			continue
+		}
+		host := strings.ToLower(dns.Fqdn(a.Hdr.Name))
+		// only accept glue records for the delegation's name servers, inside the zone of the
+		// name server which sent them; other name servers' addresses are resolved separately
+		if !dns.IsSubDomain(zone, host) {
+			continue
+		}
+		for _, ns := range d.ns {
+			if ns == host {
+				glue[host] = append(glue[host], a.A.String())
+				if t, ok := glueTTL[host]; !ok || a.Hdr.Ttl < t {
+					glueTTL[host] = a.Hdr.Ttl
+				}
+				break
+			}
+		}
+	}
+	for host, ips := range glue {
+		r.addrs[host] = &hostAddrs{
+			ips:     ips,
+			expires: now.Add(time.Duration(glueTTL[host]) * time.Second),
+		}
+	}
+
+	return d
+}
+
+// isLame returns true if the name server at `addr` was found to be lame for `zone`
+func (r *Resolver) isLame(zone, addr string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	until, ok := r.lame[zone+" "+addr]
+	if !ok {
+		return false
+	}
+	if !r.now().Before(until) {
+		delete(r.lame, zone+" "+addr)
+		return false
+	}
+	return true
+}
+
+func (r *Resolver) setLame(zone, addr string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.prune()
+	r.lame[zone+" "+addr] = r.now().Add(lameTTL)
+}
+
+// prune removes the expired delegations, addresses and lame name servers once any of them
+// reaches the Resolver's cache size, followed by arbitrary entries if that isn't enough to make room for a new one
+//
+// It expects the caller to hold the Resolver's lock
+func (r *Resolver) prune() {
+	if len(r.zones) < r.size && len(r.addrs) < r.size && len(r.lame) < r.size {
+		return
+	}
+
+	now := r.now()
+	for zone, d := range r.zones {
+		if !now.Before(d.expires) {
+			delete(r.zones, zone)
+		}
+	}
+	for host, h := range r.addrs {
+		if !now.Before(h.expires) {
+			delete(r.addrs, host)
+		}
+	}
+	for key, until := range r.lame {
+		if !now.Before(until) {
+			delete(r.lame, key)
+		}
+	}
+
+	trim(r.zones, r.size)
+	trim(r.addrs, r.size)
+	trim(r.lame, r.size)
+}
+
+// trim removes arbitrary entries from the map `m` until it holds less than `size` entries
+func trim[V any](m map[string]V, size int) {
+	for key := range m {
+		if len(m) < size {
+			return
+		}
+		delete(m, key)
+	}
+}
+
+// parent returns the parent domain of the domain name `name`
+func parent(name string) string {
+	if idx := strings.IndexByte(name, '.'); idx >= 0 && idx < len(name)-1 {
+		return name[idx+1:]
+	}
+	return "."
+}
+
+func min(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,64 @@
+package recursive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	dns "github.com/miekg/dns"
+)
+
+var ErrNoRootServers = errors.New("no root name server addresses in the root hints")
+
+// defaultRoots lists the IPv4 addresses of the root name servers (a to m.root-servers.net)
+var defaultRoots = []string{
+	"198.41.0.4",
+	"199.9.14.201",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+// ParseRootHints reads the root hints in zone file format from the io.Reader `r`
+// (`file` is only used in error messages), returning the IPv4 addresses of the
+// root name servers listed in its NS records
+func ParseRootHints(r io.Reader, file string) ([]string, error) {
+	var (
+		servers = map[string]struct{}{}
+		glue    = map[string][]string{}
+		zp      = dns.NewZoneParser(r, ".", file)
+	)
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch v := rr.(type) {
+		case *dns.NS:
+			if v.Hdr.Name == "." {
+				servers[strings.ToLower(v.Ns)] = struct{}{}
+			}
+		case *dns.A:
+			name := strings.ToLower(v.Hdr.Name)
+			glue[name] = append(glue[name], v.A.String())
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse root hints: %w", err)
+	}
+
+	var roots []string
+	for server := range servers {
+		roots = append(roots, glue[server]...)
+	}
+	if len(roots) == 0 {
+		return nil, ErrNoRootServers
+	}
+	return roots, nil
+}
@@ -0,0 +1,208 @@
+package recursive
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/core"
+)
+
+const (
+	defaultPort    = "53"
+	defaultTimeout = 2 * time.Second
+	// lameTTL is the period for which a name server which is lame for a zone is skipped
+	lameTTL = 5 * time.Minute
+	// defaultCacheSize is the maximum number of delegations, name server addresses and lame
+	// name servers the Resolver keeps, each
+	defaultCacheSize = 10000
+)
+
+// Resolver is a dns.Repository which resolves the queries for domain names which are
+// not in the store recursively, starting from the root name servers and following the
+// referrals down to the authoritative name servers for each domain name, instead of
+// forwarding them to a fallback DNS
+//
+// The name servers of the zones it learns about (and their addresses) are cached for
+// the TTL of their records, so that later queries start from the closest known zone.
+// Name servers which do not answer for their zone (lame delegations) are skipped. Each of
+// these caches is bounded to the Resolver's cache size, evicting expired entries first
+type Resolver struct {
+	local   dnsrepo.Repository
+	roots   []string
+	hints   string
+	port    string
+	timeout time.Duration
+	ttl     uint32
+	size    int
+
+	mtx   sync.Mutex
+	zones map[string]*delegation
+	addrs map[string]*hostAddrs
+	lame  map[string]time.Time
+	now   func() time.Time
+}
+
+var _ dnsrepo.Repository = (*Resolver)(nil)
+
+// Option describes setter types for a Resolver
+type Option interface {
+	Apply(*Resolver)
+}
+
+// RootHints creates an Option setting the path to the root hints file used by the
+// Resolver (in zone file format, like the `named.root` file published by IANA), which
+// lists the root name servers and their addresses
+//
+// If the path is empty, it returns nil, and the Resolver uses its built-in root hints
+func RootHints(path string) Option {
+	if path == "" {
+		return nil
+	}
+	return &rootHintsOpt{
+		path: path,
+	}
+}
+
+// Port creates an Option setting the port the Resolver uses to query the name servers,
+// which is 53 by default
+//
+// If the port is empty, it returns nil
+func Port(port string) Option {
+	if port == "" {
+		return nil
+	}
+	return &portOpt{
+		port: port,
+	}
+}
+
+// Timeout creates an Option setting the Resolver's timeout for each query to a name server
+//
+// If the timeout is zero or negative, it returns nil
+func Timeout(timeout time.Duration) Option {
+	if timeout <= 0 {
+		return nil
+	}
+	return &timeoutOpt{
+		timeout: timeout,
+	}
+}
+
+// DefaultTTL creates an Option setting the Resolver's default TTL for answers from
+// records which do not set one
+//
+// If the TTL is zero, it returns nil
+func DefaultTTL(ttl uint32) Option {
+	if ttl == 0 {
+		return nil
+	}
+	return &defaultTTLOpt{
+		ttl: ttl,
+	}
+}
+
+// CacheSize creates an Option setting the maximum number of delegations, name server
+// addresses and lame name servers the Resolver keeps (each), which is 10000 by default
+//
+// If the size is zero or negative, it returns nil
+func CacheSize(size int) Option {
+	if size <= 0 {
+		return nil
+	}
+	return &cacheSizeOpt{
+		size: size,
+	}
+}
+
+type rootHintsOpt struct {
+	path string
+}
+
+type portOpt struct {
+	port string
+}
+
+type timeoutOpt struct {
+	timeout time.Duration
+}
+
+type defaultTTLOpt struct {
+	ttl uint32
+}
+
+type cacheSizeOpt struct {
+	size int
+}
+
+// Apply implements the Option interface
+func (o *rootHintsOpt) Apply(r *Resolver) {
+	r.hints = o.path
+}
+
+// Apply implements the Option interface
+func (o *portOpt) Apply(r *Resolver) {
+	r.port = o.port
+}
+
+// Apply implements the Option interface
+func (o *timeoutOpt) Apply(r *Resolver) {
+	r.timeout = o.timeout
+}
+
+// Apply implements the Option interface
+func (o *defaultTTLOpt) Apply(r *Resolver) {
+	r.ttl = o.ttl
+}
+
+// Apply implements the Option interface
+func (o *cacheSizeOpt) Apply(r *Resolver) {
+	r.size = o.size
+}
+
+// New returns a new Resolver as a dns.Repository, applying all input Option `opts`
+//
+// The answers from the store are written as in the core.DNSCore. If a root hints file
+// is set and it cannot be read or holds no root name server addresses, the function will
+// panic since the Resolver will not be able to resolve any query
+func New(opts ...Option) *Resolver {
+	r := &Resolver{
+		port:    defaultPort,
+		timeout: defaultTimeout,
+		size:    defaultCacheSize,
+		zones:   map[string]*delegation{},
+		addrs:   map[string]*hostAddrs{},
+		lame:    map[string]time.Time{},
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(r)
+		}
+	}
+
+	r.local = core.NewWithOptions(nil, core.DefaultTTL(r.ttl))
+
+	roots := defaultRoots
+	if r.hints != "" {
+		f, err := os.Open(r.hints)
+		if err != nil {
+			panic(err) // panic on init if the root hints can't be read
+		}
+		defer f.Close()
+
+		roots, err = ParseRootHints(f, r.hints)
+		if err != nil {
+			panic(err) // panic on init if the root hints are invalid
+		}
+	}
+
+	r.roots = make([]string, 0, len(roots))
+	for _, ip := range roots {
+		r.roots = append(r.roots, net.JoinHostPort(ip, r.port))
+	}
+	return r
+}
@@ -0,0 +1,338 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+// authority is a fake authoritative name server for a zone, serving the records in rrs
+type authority struct {
+	zone    string
+	rrs     []dns.RR
+	refuse  bool
+	queries int32
+}
+
+func (a *authority) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	atomic.AddInt32(&a.queries, 1)
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	if a.refuse {
+		m.Rcode = dns.RcodeRefused
+		_ = w.WriteMsg(m)
+		return
+	}
+
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+
+	// referral to a child zone
+	for _, rr := range a.rrs {
+		owner := strings.ToLower(rr.Header().Name)
+		if rr.Header().Rrtype == dns.TypeNS && owner != a.zone && dns.IsSubDomain(owner, name) {
+			for _, ns := range a.rrs {
+				if ns.Header().Rrtype == dns.TypeNS && strings.EqualFold(ns.Header().Name, owner) {
+					m.Ns = append(m.Ns, ns)
+					for _, glue := range a.rrs {
+						if glue.Header().Rrtype == dns.TypeA && strings.EqualFold(glue.Header().Name, ns.(*dns.NS).Ns) {
+							m.Extra = append(m.Extra, glue)
+						}
+					}
+				}
+			}
+			_ = w.WriteMsg(m)
+			return
+		}
+	}
+
+	m.Authoritative = true
+	var exists bool
+	for _, rr := range a.rrs {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		exists = true
+		if rr.Header().Rrtype == q.Qtype || rr.Header().Rrtype == dns.TypeCNAME {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	// add the records for the CNAME targets it holds, like most name servers do
+	for _, rr := range m.Answer {
+		cname, ok := rr.(*dns.CNAME)
+		if !ok {
+			continue
+		}
+		for _, target := range a.rrs {
+			if target.Header().Rrtype == q.Qtype && strings.EqualFold(target.Header().Name, cname.Target) {
+				m.Answer = append(m.Answer, target)
+			}
+		}
+	}
+	if len(m.Answer) == 0 {
+		if !exists {
+			m.Rcode = dns.RcodeNameError
+		}
+		for _, rr := range a.rrs {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				m.Ns = append(m.Ns, rr)
+			}
+		}
+	}
+	_ = w.WriteMsg(m)
+}
+
+func mustRRs(t *testing.T, records ...string) []dns.RR {
+	rrs := make([]dns.RR, 0, len(records))
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", record, err)
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// serveAuthorities starts the fake name servers in `auths` on the loopback addresses
+// 127.0.0.1, 127.0.0.2 (and so on), all listening on the same port
+func serveAuthorities(t *testing.T, auths ...*authority) string {
+	var port string
+
+	for idx, auth := range auths {
+		addr := net.JoinHostPort("127.0.0."+string(rune('1'+idx)), "0")
+		if port != "" {
+			addr = net.JoinHostPort("127.0.0."+string(rune('1'+idx)), port)
+		}
+
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			t.Skipf("unable to listen on %s: %v", addr, err)
+		}
+		if port == "" {
+			_, port, _ = net.SplitHostPort(conn.LocalAddr().String())
+		}
+
+		srv := &dns.Server{PacketConn: conn, Handler: auth}
+		go func() {
+			_ = srv.ActivateAndServe()
+		}()
+		t.Cleanup(func() { _ = srv.Shutdown() })
+	}
+	return port
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+
+	root := &authority{zone: ".", rrs: mustRRs(t,
+		"test. 86400 IN NS ns.test.",
+		"ns.test. 86400 IN A 127.0.0.2",
+	)}
+	tld := &authority{zone: "test.", rrs: mustRRs(t,
+		"test. 3600 IN SOA ns.test. admin.test. 1 7200 3600 1209600 300",
+		"example.test. 3600 IN NS lame.test.",
+		"example.test. 3600 IN NS ns.example.test.",
+		"lame.test. 3600 IN A 127.0.0.5",
+		"ns.example.test. 3600 IN A 127.0.0.3",
+		// glueless delegation, to a name server inside another zone
+		"other.test. 3600 IN NS ns.other.example.test.",
+	)}
+	example := &authority{zone: "example.test.", rrs: mustRRs(t,
+		"example.test. 3600 IN SOA ns.example.test. admin.example.test. 1 7200 3600 1209600 60",
+		"example.test. 3600 IN NS ns.example.test.",
+		"ns.example.test. 3600 IN A 127.0.0.3",
+		"ns.other.example.test. 3600 IN A 127.0.0.4",
+		"www.example.test. 300 IN A 10.0.0.1",
+		"api.example.test. 300 IN A 10.0.0.2",
+		"alias.example.test. 300 IN CNAME www.other.test.",
+		// out-of-bailiwick record, which must not be trusted
+		"www.other.test. 300 IN A 6.6.6.6",
+	)}
+	other := &authority{zone: "other.test.", rrs: mustRRs(t,
+		"other.test. 3600 IN SOA ns.other.example.test. admin.other.test. 1 7200 3600 1209600 60",
+		"www.other.test. 300 IN A 10.0.0.4",
+	)}
+	lame := &authority{zone: "example.test.", refuse: true}
+
+	port := serveAuthorities(t, root, tld, example, other, lame)
+
+	hints := filepath.Join(t.TempDir(), "named.root")
+	err := os.WriteFile(hints, []byte(". 3600000 NS a.root-servers.net.\na.root-servers.net. 3600000 A 127.0.0.1\n"), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error writing root hints: %v", err)
+	}
+	r := New(RootHints(hints), Port(port))
+
+	t.Run("FollowReferrals", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := r.Fallback(ctx, store.New().Name("www.example.test").Type("A").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 1 || m.Answer[0].(*dns.A).A.String() != "10.0.0.1" {
+			t.Errorf("unexpected answer: %v", m.Answer)
+		}
+	})
+
+	t.Run("SkipLameDelegation", func(t *testing.T) {
+		if atomic.LoadInt32(&lame.queries) == 0 {
+			t.Skip("the lame name server was not queried first")
+		}
+		if !r.isLame("example.test.", net.JoinHostPort("127.0.0.5", port)) {
+			t.Errorf("expected the name server to be flagged as lame")
+		}
+	})
+
+	t.Run("CacheDelegations", func(t *testing.T) {
+		before := atomic.LoadInt32(&root.queries)
+
+		m := new(dns.Msg)
+		err := r.Fallback(ctx, store.New().Name("api.example.test").Type("A").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+		}
+		if after := atomic.LoadInt32(&root.queries); after != before {
+			t.Errorf("expected the root name servers not to be queried again; got %v queries", after-before)
+		}
+	})
+
+	t.Run("FollowCNAME", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := r.Fallback(ctx, store.New().Name("alias.example.test").Type("A").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 2 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 2, len(m.Answer))
+			return
+		}
+		if a, ok := m.Answer[1].(*dns.A); !ok || a.A.String() != "10.0.0.4" {
+			t.Errorf("unexpected answer: %v", m.Answer[1])
+		}
+	})
+
+	t.Run("NXDomain", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := r.Fallback(ctx, store.New().Name("missing.example.test").Type("A").Build(), m)
+		if !errors.Is(err, dnsrepo.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrNXDomain, err)
+		}
+
+		if len(m.Ns) != 1 {
+			t.Errorf("unexpected authority list length: wanted %v ; got %v", 1, len(m.Ns))
+		}
+	})
+
+	t.Run("NoData", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := r.Fallback(ctx, store.New().Name("www.example.test").Type("AAAA").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if len(m.Answer) != 0 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 0, len(m.Answer))
+		}
+	})
+
+	t.Run("FailUnreachable", func(t *testing.T) {
+		hints := filepath.Join(t.TempDir(), "named.root")
+		err := os.WriteFile(hints, []byte(". 3600000 NS a.root-servers.net.\na.root-servers.net. 3600000 A 127.0.0.5\n"), 0o600)
+		if err != nil {
+			t.Fatalf("unexpected error writing root hints: %v", err)
+		}
+
+		err = New(RootHints(hints), Port(port)).Fallback(ctx, store.New().Name("www.example.test").Type("A").Build(), new(dns.Msg))
+		if !errors.Is(err, dnsrepo.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsrepo.ErrServFail, err)
+		}
+	})
+}
+
+func TestReferral(t *testing.T) {
+	t.Run("RejectOutOfBailiwickGlue", func(t *testing.T) {
+		r := New()
+		in := new(dns.Msg)
+		in.Ns = mustRRs(t,
+			"example.test. 3600 IN NS ns.example.test.",
+			"example.test. 3600 IN NS ns1.google.com.",
+		)
+		in.Extra = mustRRs(t,
+			"ns.example.test. 3600 IN A 127.0.0.3",
+			"ns1.google.com. 3600 IN A 6.6.6.6",
+		)
+
+		d := r.referral(in, "test.", "www.example.test.")
+		if d == nil || len(d.ns) != 2 {
+			t.Errorf("unexpected delegation: %v", d)
+			return
+		}
+		if ips := r.hostAddrs("ns.example.test."); len(ips) != 1 || ips[0] != "127.0.0.3" {
+			t.Errorf("unexpected addresses for ns.example.test.: %v", ips)
+		}
+		if ips := r.hostAddrs("ns1.google.com."); len(ips) != 0 {
+			t.Errorf("expected the out-of-bailiwick glue not to be cached; got %v", ips)
+		}
+	})
+
+	t.Run("BoundedCache", func(t *testing.T) {
+		r := New(CacheSize(2))
+		for _, host := range []string{"a.test.", "b.test.", "c.test.", "d.test."} {
+			r.cacheHost(host, []string{"127.0.0.1"}, 3600)
+		}
+
+		if len(r.addrs) > 2 {
+			t.Errorf("unexpected cache length: wanted at most %v ; got %v", 2, len(r.addrs))
+		}
+		if ips := r.hostAddrs("d.test."); len(ips) != 1 {
+			t.Errorf("expected the last address to be cached; got %v", ips)
+		}
+	})
+}
+
+func TestParseRootHints(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		roots, err := ParseRootHints(strings.NewReader(`
+;       This file holds the information on root name servers
+.                        3600000      NS    A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.      3600000      A     198.41.0.4
+A.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:ba3e::2:30
+OTHER.EXAMPLE.           3600000      A     192.0.2.1
+`), "named.root")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(roots) != 1 || roots[0] != "198.41.0.4" {
+			t.Errorf("unexpected root servers: %v", roots)
+		}
+	})
+
+	t.Run("FailNoAddresses", func(t *testing.T) {
+		_, err := ParseRootHints(strings.NewReader(". 3600000 NS a.root-servers.net.\n"), "named.root")
+		if !errors.Is(err, ErrNoRootServers) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrNoRootServers, err)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	dns "github.com/miekg/dns"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+const (
+	// maxDepth is the maximum number of nested resolutions for a query, when following
+	// CNAME records or resolving the addresses of name servers without glue records
+	maxDepth = 8
+	// maxReferrals is the maximum number of referrals followed when resolving a domain name
+	maxReferrals = 16
+	// udpSize is the EDNS0 UDP buffer size advertised to the name servers
+	udpSize = 1232
+)
+
+var (
+	errMaxDepth     = errors.New("too many nested resolutions")
+	errMaxReferrals = errors.New("too many referrals")
+)
+
+// Answer implements the dns.Repository interface, writing the answer in the store.Record
+// `r` to the dns.Msg `m` as the core.DNSCore does
+func (r *Resolver) Answer(ctx context.Context, rec *store.Record, m *dns.Msg) error {
+	return r.local.Answer(ctx, rec, m)
+}
+
+// Authority implements the dns.Repository interface, writing the store.Record `r` to
+// the dns.Msg `m`'s authority section as the core.DNSCore does
+func (r *Resolver) Authority(ctx context.Context, rec *store.Record, m *dns.Msg) error {
+	return r.local.Authority(ctx, rec, m)
+}
+
+// Fallback implements the dns.Repository interface, resolving the query for the domain
+// name and record type in store.Record `r` recursively
+//
+// The answer and authority records from the authoritative name server are written to the
+// dns.Msg `m`. If the domain name does not exist, a dns.ErrNXDomain error is returned; if
+// it cannot be resolved, an error wrapping dns.ErrServFail is returned
+func (r *Resolver) Fallback(ctx context.Context, rec *store.Record, m *dns.Msg) error {
+	name := strings.ToLower(dns.Fqdn(rec.Name))
+
+	in, err := r.resolve(ctx, name, dnsrepo.QType(rec.Type), 0)
+	if err != nil {
+		return fmt.Errorf("%w: %v", dnsrepo.ErrServFail, err)
+	}
+
+	if in.Rcode == dns.RcodeNameError {
+		m.Ns = append(m.Ns, in.Ns...)
+		return dnsrepo.ErrNXDomain
+	}
+	m.Answer = append(m.Answer, in.Answer...)
+	m.Ns = append(m.Ns, in.Ns...)
+	return nil
+}
+
+// resolve looks up the records of type `qtype` for the domain name `name`, starting from
+// the closest known zone and following the referrals until a name server answers
+func (r *Resolver) resolve(ctx context.Context, name string, qtype uint16, depth int) (*dns.Msg, error) {
+	if depth > maxDepth {
+		return nil, errMaxDepth
+	}
+
+	zone, servers := r.closest(ctx, name, depth)
+
+	for i := 0; i < maxReferrals; i++ {
+		in, next, err := r.queryZone(ctx, zone, servers, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return r.followCNAME(ctx, inBailiwick(in, zone), name, qtype, depth)
+		}
+
+		zone = next.zone
+		servers = r.serversFor(ctx, next, depth)
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("no addresses for the name servers of %s", zone)
+		}
+	}
+	return nil, errMaxReferrals
+}
+
+// queryZone asks the name servers at `servers`, authoritative for the zone `zone`, for
+// the records of type `qtype` for the domain name `name`, one at a time
+//
+// It returns the first answer (positive, NODATA or NXDOMAIN), or the delegation if the
+// name server replies with a referral. Name servers which fail to reply are skipped, and
+// the ones which reply without an answer nor a referral are flagged as lame for the zone
+func (r *Resolver) queryZone(ctx context.Context, zone string, servers []string, name string, qtype uint16) (*dns.Msg, *delegation, error) {
+	candidates := make([]string, 0, len(servers))
+	for _, addr := range servers {
+		if !r.isLame(zone, addr) {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = servers
+	}
+
+	var lastErr error = fmt.Errorf("no name servers for %s", zone)
+	for _, addr := range candidates {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		in, err := r.exchange(ctx, addr, name, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch in.Rcode {
+		case dns.RcodeNameError:
+			return in, nil, nil
+		case dns.RcodeSuccess:
+			if len(in.Answer) > 0 {
+				return in, nil, nil
+			}
+			if d := r.referral(in, zone, name); d != nil {
+				return in, d, nil
+			}
+			if in.Authoritative {
+				return in, nil, nil
+			}
+			lastErr = fmt.Errorf("%s is lame for %s", addr, zone)
+		default:
+			lastErr = fmt.Errorf("%s replied with %s", addr, dns.RcodeToString[in.Rcode])
+		}
+		r.setLame(zone, addr)
+	}
+	return nil, nil, lastErr
+}
+
+// followCNAME resolves the target of the CNAME chain in the answer `in`, if the chain
+// does not end with a record of type `qtype`; appending its answer to the chain
+func (r *Resolver) followCNAME(ctx context.Context, in *dns.Msg, name string, qtype uint16, depth int) (*dns.Msg, error) {
+	if in.Rcode != dns.RcodeSuccess || qtype == dns.TypeCNAME || qtype == dns.TypeANY {
+		return in, nil
+	}
+
+	target := name
+	for i := 0; i <= len(in.Answer); i++ {
+		var next string
+		for _, rr := range in.Answer {
+			if !strings.EqualFold(rr.Header().Name, target) {
+				continue
+			}
+			if rr.Header().Rrtype == qtype {
+				return in, nil
+			}
+			if cname, ok := rr.(*dns.CNAME); ok {
+				next = strings.ToLower(dns.Fqdn(cname.Target))
+			}
+		}
+		if next == "" {
+			break
+		}
+		target = next
+	}
+
+	if target == name {
+		return in, nil
+	}
+
+	res, err := r.resolve(ctx, target, qtype, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := in.Copy()
+	out.Rcode = res.Rcode
+	out.Answer = append(out.Answer, res.Answer...)
+	out.Ns = res.Ns
+	return out, nil
+}
+
+// inBailiwick returns the dns.Msg `in` without the answer records which are outside of
+// the zone `zone` of the name server which sent it, as it has no authority over them
+func inBailiwick(in *dns.Msg, zone string) *dns.Msg {
+	answer := make([]dns.RR, 0, len(in.Answer))
+	for _, rr := range in.Answer {
+		if dns.IsSubDomain(zone, strings.ToLower(rr.Header().Name)) {
+			answer = append(answer, rr)
+		}
+	}
+	if len(answer) == len(in.Answer) {
+		return in
+	}
+
+	out := in.Copy()
+	out.Answer = answer
+	return out
+}
+
+// exchange sends a non-recursive query for the records of type `qtype` for the domain
+// name `name` to the name server at `addr`, retrying over TCP if the reply is truncated
+func (r *Resolver) exchange(ctx context.Context, addr, name string, qtype uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(name, qtype)
+	message.RecursionDesired = false
+	message.SetEdns0(udpSize, false)
+
+	client := &dns.Client{
+		Net:     "udp",
+		Timeout: r.timeout,
+	}
+
+	in, _, err := client.ExchangeContext(ctx, message, addr)
+	if err == nil && in.Truncated {
+		client.Net = "tcp"
+		in, _, err = client.ExchangeContext(ctx, message, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
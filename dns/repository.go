@@ -2,11 +2,20 @@ package dns
 
 import (
 	"context"
+	"errors"
 
 	"github.com/miekg/dns"
 	"github.com/zalgonoise/dns/store"
 )
 
+var (
+	// ErrNXDomain is returned when the queried domain name does not exist
+	ErrNXDomain error = errors.New("domain name does not exist")
+	// ErrServFail is returned when the query could not be resolved, such as when
+	// none of the fallback DNS servers could be reached
+	ErrServFail error = errors.New("failed to resolve the query")
+)
+
 // Repository defines the set of operations that a DNS answerer should expose
 //
 // This will consist in building answers for DNS messages based on store.Records,
@@ -14,9 +23,37 @@ import (
 type Repository interface {
 	// Answer will write the IP address present in the store.Record in the dns.Msg
 	// slice of Answers
-	Answer(context.Context, *store.Record, *dns.Msg)
+	//
+	// Returns an error if the store.Record cannot be converted into a DNS answer
+	Answer(context.Context, *store.Record, *dns.Msg) error
+	// Authority will write the store.Record in the dns.Msg slice of authority records,
+	// such as the SOA of the zone a domain name belongs to
+	//
+	// Returns an error if the store.Record cannot be converted into a DNS record
+	Authority(context.Context, *store.Record, *dns.Msg) error
 	// Fallback is called when the DNS store does not hold a record for the requested
 	// domain, so the DNS service spawns a DNS client that will query the fallback server
 	// and write that answer to the dns.Msg
-	Fallback(context.Context, *store.Record, *dns.Msg)
+	//
+	// Returns ErrNXDomain if the fallback server replies that the domain name does not
+	// exist, or ErrServFail if no fallback server could answer the query. An empty answer
+	// with a nil error means the domain name exists but holds no records of that type
+	Fallback(context.Context, *store.Record, *dns.Msg) error
+}
+
+// Rcode returns the DNS response code for the error `err` returned when answering
+// a DNS query
+func Rcode(err error) int {
+	if rcode, ok := rcodeOf(err); ok {
+		return rcode
+	}
+
+	switch {
+	case err == nil:
+		return dns.RcodeSuccess
+	case errors.Is(err, ErrNXDomain):
+		return dns.RcodeNameError
+	default:
+		return dns.RcodeServerFailure
+	}
 }
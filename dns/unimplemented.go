@@ -15,10 +15,19 @@ var (
 type unimplemented struct{}
 
 // Answer implements the dns.Repository interface
-func (u unimplemented) Answer(context.Context, *store.Record, *dnsr.Msg) {}
+func (u unimplemented) Answer(context.Context, *store.Record, *dnsr.Msg) error {
+	return ErrUnimplemented
+}
+
+// Authority implements the dns.Repository interface
+func (u unimplemented) Authority(context.Context, *store.Record, *dnsr.Msg) error {
+	return ErrUnimplemented
+}
 
 // Fallback implements the dns.Repository interface
-func (u unimplemented) Fallback(context.Context, *store.Record, *dnsr.Msg) {}
+func (u unimplemented) Fallback(context.Context, *store.Record, *dnsr.Msg) error {
+	return ErrUnimplemented
+}
 
 // Unimplemented returns an unimplemented (and invalid) dns.Repository
 func Unimplemented() unimplemented {
@@ -1,19 +1,151 @@
 package factory
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
 	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/blocklist"
+	"github.com/zalgonoise/dns/dns/cache"
 	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/dns/dns/notify"
+	"github.com/zalgonoise/dns/dns/recursive"
 )
 
-func DNSRepository(rtype string, fallbackDNS ...string) dns.Repository {
+// ErrRecursiveOption is raised when the `recursive` DNS type is combined with a setting
+// which only applies to the fallback DNS servers
+var ErrRecursiveOption = errors.New("setting is not supported by the recursive DNS type")
+
+// DNSRepository returns the dns.Repository of type `rtype`, answering the queries outside
+// of the store from the fallback DNS servers `fallbackDNS` or recursively
+//
+// The DNSSEC validation (`validate`), the forwarding rules (`forwards`) and the forwarding
+// of the client's subnet (`forwardECS`) only apply to the fallback DNS servers; if any of
+// them is set along with the `recursive` type, the function will panic, instead of
+// starting a server without them
+func DNSRepository(
+	rtype, rootHints string,
+	ttl uint32,
+	strategy, timeout, cooldown string,
+	forwardECS bool,
+	validate bool,
+	trustAnchor string,
+	forwards map[string][]string,
+	fallbackDNS ...string,
+) dns.Repository {
 	var dnsRepo dns.Repository
 
+	opts := []core.Option{core.DefaultTTL(ttl), core.ClientSubnet(forwardECS)}
+	if s, ok := core.ParseStrategy(strategy); ok {
+		opts = append(opts, core.FallbackStrategy(s))
+	}
+	if d, err := time.ParseDuration(timeout); err == nil {
+		opts = append(opts, core.FallbackTimeout(d))
+	}
+	if d, err := time.ParseDuration(cooldown); err == nil {
+		opts = append(opts, core.FallbackCooldown(d))
+	}
+	for domain, upstreams := range forwards {
+		opts = append(opts, core.Forward(domain, upstreams...))
+	}
+	if validate {
+		opts = append(opts, core.Validate(DNSSECAnchors(trustAnchor)...))
+	}
+
 	switch rtype {
+	case "recursive":
+		switch {
+		case validate:
+			panic(fmt.Errorf("%w: DNSSEC validation", ErrRecursiveOption)) // panic on init
+		case len(forwards) > 0:
+			panic(fmt.Errorf("%w: forwarding rules", ErrRecursiveOption)) // panic on init
+		case forwardECS:
+			panic(fmt.Errorf("%w: forwarding the client subnet", ErrRecursiveOption)) // panic on init
+		}
+
+		recOpts := []recursive.Option{recursive.DefaultTTL(ttl), recursive.RootHints(rootHints)}
+		if d, err := time.ParseDuration(timeout); err == nil {
+			recOpts = append(recOpts, recursive.Timeout(d))
+		}
+		dnsRepo = recursive.New(recOpts...)
 	case "miekgdns":
-		dnsRepo = core.New(fallbackDNS...)
+		dnsRepo = core.NewWithOptions(fallbackDNS, opts...)
 	default:
-		dnsRepo = core.New(fallbackDNS...)
+		dnsRepo = core.NewWithOptions(fallbackDNS, opts...)
 	}
 
 	return dnsRepo
 }
+
+// DNSSECSigner returns a dnssec.Signer for the zones owned by this server, keeping their
+// keys in the directory `keyDir`; or nil if DNSSEC signing is not `enabled`
+func DNSSECSigner(enabled bool, keyDir string) *dnssec.Signer {
+	if !enabled {
+		return nil
+	}
+	return dnssec.New(keyDir)
+}
+
+// DNSSECAnchors returns the DNSSEC trust anchors (DS or DNSKEY records) in the file `path`.
+// If it is empty or cannot be read, it returns nil so that the root zone's key-signing
+// keys are used instead
+func DNSSECAnchors(path string) []dnsr.RR {
+	if path == "" {
+		return nil
+	}
+	anchors, err := dnssec.ReadAnchors(path)
+	if err != nil {
+		return nil
+	}
+	return anchors
+}
+
+// DNSCache places a cache for up to `size` fallback DNS answers in front of the
+// dns.Repository `r`. If `size` is zero or negative, `r` is returned as-is
+func DNSCache(r dns.Repository, size int) dns.Repository {
+	if size <= 0 {
+		return r
+	}
+	return cache.New(r, size)
+}
+
+// DNSBlocklist returns a blocklist.Blocklist for the lists in `sources`, never blocking the
+// domain names in `allow` and answering the blocked queries according to `answer`. The
+// lists are loaded in the background and refreshed every `refresh` period
+//
+// If there are no `sources`, it returns nil
+func DNSBlocklist(sources, allow []string, answer, refresh string) *blocklist.Blocklist {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	opts := []blocklist.Option{blocklist.Allow(allow...), blocklist.Answer(answer)}
+	if d, err := time.ParseDuration(refresh); err == nil {
+		opts = append(opts, blocklist.Refresh(d))
+	}
+
+	list := blocklist.New(sources, opts...)
+	list.Start()
+	return list
+}
+
+// DNSNotifier returns a notify.Notifier for the secondary servers in `targets`, signing
+// the NOTIFY messages for each zone with the TSIG key in `keys` scoped to it, or with the
+// first key without zones
+//
+// If there are no `targets`, it returns nil
+func DNSNotifier(targets []string, keys []*config.TSIGKeyConfig) *notify.Notifier {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	opts := make([]notify.Option, 0, len(keys))
+	for _, key := range keys {
+		opts = append(opts, notify.TSIG(key.Name, key.Algorithm, key.Secret, key.Zones...))
+	}
+	return notify.New(targets, opts...)
+}
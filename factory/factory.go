@@ -25,18 +25,44 @@ func From(conf *config.Config) httpapi.Server {
 	spanner.To(export.Logger(logger))
 	s.Event("initialized logger")
 
-	// initialize DNS repository
-	dnsRepo := dns.WithTrace(DNSRepository(
-		conf.DNS.Type,
-		strings.Split(conf.DNS.FallbackDNS, ",")...,
-	))
+	// initialize DNS repository, with a cache for its fallback answers
+	forwards := make(map[string][]string, len(conf.DNS.Forwards))
+	for _, fwd := range conf.DNS.Forwards {
+		forwards[fwd.Domain] = fwd.Upstreams
+	}
+	dnsRepo := DNSCache(
+		dns.WithTrace(DNSRepository(
+			conf.DNS.Type,
+			conf.DNS.RootHints,
+			conf.DNS.TTL,
+			conf.DNS.FallbackStrategy,
+			conf.DNS.FallbackTimeout,
+			conf.DNS.FallbackCooldown,
+			conf.DNS.ClientSubnet == "forward",
+			conf.DNS.DNSSECValidate,
+			conf.DNS.TrustAnchor,
+			forwards,
+			strings.Split(conf.DNS.FallbackDNS, ",")...,
+		)),
+		conf.DNS.CacheSize,
+	)
 	s.Event("initialized DNS repository")
 
-	// initialize store repository
-	storeRepo := store.WithTrace(StoreRepository(
-		conf.Store.Type,
-		conf.Store.Path,
-	))
+	// initialize store repository, with a journal of the changes to its zones if they are
+	// transferred to secondary servers (unless it is a read-only secondary store itself)
+	storeRepo := StoreJournal(
+		store.WithTrace(StoreRepository(
+			conf.Store.Type,
+			conf.Store.Path,
+			conf.Store.Origin,
+			conf.Store.Primary,
+			conf.Store.SyncDelay,
+			conf.DNS.TSIGKeys,
+		)),
+		conf.Store.Type != "secondary" &&
+			(len(conf.DNS.TransferAllow) > 0 || len(conf.DNS.TSIGKeys) > 0 || len(conf.DNS.Notify) > 0),
+		DNSNotifier(conf.DNS.Notify, conf.DNS.TSIGKeys),
+	)
 	s.Event("initialized Store repository")
 
 	// initialize health repository
@@ -48,19 +74,56 @@ func From(conf *config.Config) httpapi.Server {
 	// intialize service
 	svc := service.WithLogger(
 		service.WithTrace(
-			service.New(dnsRepo, storeRepo, healthRepo, conf),
+			service.WithBlocklist(
+				service.WithDNSSEC(
+					service.New(dnsRepo, storeRepo, healthRepo, conf),
+					DNSSECSigner(conf.DNS.DNSSEC, conf.DNS.DNSSECKeys),
+				),
+				DNSBlocklist(
+					conf.DNS.Blocklists,
+					conf.DNS.BlocklistAllow,
+					conf.DNS.BlocklistAnswer,
+					conf.DNS.BlocklistRefresh,
+				),
+			),
 		),
 		logger,
 	)
 	s.Event("initialized service")
 
+	// add the zones owned by this server to the store
+	for _, zone := range conf.DNS.Zones {
+		err := svc.AddZone(context.Background(), &store.Zone{
+			Name:    zone.Name,
+			NS:      zone.NS,
+			Mbox:    zone.Mbox,
+			Serial:  zone.Serial,
+			Refresh: zone.Refresh,
+			Retry:   zone.Retry,
+			Expire:  zone.Expire,
+			Minimum: zone.Minimum,
+			TTL:     zone.TTL,
+		})
+		if err != nil {
+			s.Event("error adding zone", attr.String("zone", zone.Name), attr.String("error", err.Error()))
+			continue
+		}
+		s.Event("added zone", attr.String("zone", zone.Name))
+	}
+
 	// initialize HTTP and DNS servers
 	https, udps := Server(
 		conf.DNS.Type,
 		conf.DNS.Address,
 		conf.DNS.Prefix,
 		conf.DNS.Proto,
+		conf.DNS.TLSAddress,
+		conf.DNS.TLSCert,
+		conf.DNS.TLSKey,
+		conf.DNS.UDPSize,
 		conf.HTTP.Port,
+		conf.DNS.TransferAllow,
+		conf.DNS.TSIGKeys,
 		svc,
 	)
 	s.Event("initialized HTTP and UDP servers")
@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"github.com/zalgonoise/dns/cmd/config"
 	"github.com/zalgonoise/dns/service"
 	"github.com/zalgonoise/dns/transport/httpapi"
 	"github.com/zalgonoise/dns/transport/httpapi/endpoints"
@@ -8,28 +9,33 @@ import (
 	"github.com/zalgonoise/dns/transport/udp/miekgdns"
 )
 
-func UDPServer(stype, address, prefix, proto string, svc service.Service) udp.Server {
+func UDPServer(
+	stype, address, prefix, proto, tlsAddress, tlsCert, tlsKey string,
+	udpSize int,
+	transferAllow []string,
+	tsigKeys []*config.TSIGKeyConfig,
+	svc service.Service,
+) udp.Server {
 	var udps udp.Server
 
+	conf := udp.NewDNS().
+		Addr(address).
+		Prefix(prefix).
+		Proto(proto).
+		TLSAddr(tlsAddress).
+		CertFile(tlsCert).
+		KeyFile(tlsKey).
+		UDPSize(uint16(udpSize)).
+		TransferAllow(transferAllow...)
+	for _, key := range tsigKeys {
+		conf.TSIG(key.Name, key.Secret, key.Zones...)
+	}
+
 	switch stype {
 	case "miekgdns":
-		udps = miekgdns.NewServer(
-			udp.NewDNS().
-				Addr(address).
-				Prefix(prefix).
-				Proto(proto).
-				Build(),
-			svc,
-		)
+		udps = miekgdns.NewServer(conf.Build(), svc)
 	default:
-		udps = miekgdns.NewServer(
-			udp.NewDNS().
-				Addr(address).
-				Prefix(prefix).
-				Proto(proto).
-				Build(),
-			svc,
-		)
+		udps = miekgdns.NewServer(conf.Build(), svc)
 	}
 
 	return udp.WithTrace(udps)
@@ -37,11 +43,14 @@ func UDPServer(stype, address, prefix, proto string, svc service.Service) udp.Se
 
 func Server(
 	dnstype, dnsAddress, dnsPrefix, dnsProto string,
-	httpPort int,
+	tlsAddress, tlsCert, tlsKey string,
+	udpSize, httpPort int,
+	transferAllow []string,
+	tsigKeys []*config.TSIGKeyConfig,
 	svc service.Service,
 ) (httpapi.Server, udp.Server) {
-	udps := UDPServer(dnstype, dnsAddress, dnsPrefix, dnsProto, svc)
-	apis := endpoints.NewAPI(svc, udps)
+	udps := UDPServer(dnstype, dnsAddress, dnsPrefix, dnsProto, tlsAddress, tlsCert, tlsKey, udpSize, transferAllow, tsigKeys, svc)
+	apis := endpoints.NewAPI(svc, udps, dnsPrefix)
 	https := httpapi.NewServer(apis, httpPort)
 
 	return https, udps
@@ -1,24 +1,85 @@
 package factory
 
 import (
+	"time"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	"github.com/zalgonoise/dns/dns/notify"
 	"github.com/zalgonoise/dns/store"
 	"github.com/zalgonoise/dns/store/file"
+	"github.com/zalgonoise/dns/store/journal"
 	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/store/secondary"
 )
 
-func StoreRepository(rtype string, path string) store.Repository {
+func StoreRepository(rtype, path, origin, primary, syncDelay string, keys []*config.TSIGKeyConfig) store.Repository {
 	var storeRepo store.Repository
 
+	// file stores coalesce the changes within the sync delay into a single write
+	var fileOpts []file.Option
+	if d, err := time.ParseDuration(syncDelay); err == nil {
+		fileOpts = append(fileOpts, file.SyncDelay(d))
+	}
+
 	switch rtype {
 	case "memmap", "memory", "in-memory":
 		storeRepo = memmap.New()
 	case "jsonfile", "json":
-		storeRepo = file.New("json", path)
+		storeRepo = file.New("json", path, fileOpts...)
 	case "yamlfile", "yaml":
-		storeRepo = file.New("yaml", path)
+		storeRepo = file.New("yaml", path, fileOpts...)
+	case "zonefile", "zone":
+		storeRepo = file.NewZone(path, origin, fileOpts...)
+	case "secondary":
+		// zone transfers from the primary DNS server are signed with the TSIG key for the zone
+		var opts []secondary.Option
+		if key := tsigKeyFor(keys, origin); key != nil {
+			opts = append(opts, secondary.TSIG(key.Name, key.Algorithm, key.Secret))
+		}
+		storeRepo = secondary.New(primary, origin, opts...)
 	default:
 		storeRepo = memmap.New()
 	}
 
 	return storeRepo
 }
+
+// StoreJournal wraps the store.Repository `r` with a journal of the changes to its zones,
+// for incremental zone transfers, notifying their secondary servers through the
+// notify.Notifier `notifier` (if not nil) when they change
+//
+// If zone transfers are not `enabled`, `r` is returned as-is
+func StoreJournal(r store.Repository, enabled bool, notifier *notify.Notifier) store.Repository {
+	if !enabled {
+		return r
+	}
+
+	var opts []journal.Option
+	if notifier != nil {
+		opts = append(opts, journal.OnChange(notifier.Notify))
+	}
+	return journal.New(r, opts...)
+}
+
+// tsigKeyFor returns the TSIG key in `keys` for the zone `zone`: the first key scoped to it
+// (with the zone in its Zones), or else the first key without zones; or nil if there is none
+func tsigKeyFor(keys []*config.TSIGKeyConfig, zone string) *config.TSIGKeyConfig {
+	zone = dnsr.CanonicalName(zone)
+
+	var unscoped *config.TSIGKeyConfig
+	for _, key := range keys {
+		if len(key.Zones) == 0 {
+			if unscoped == nil {
+				unscoped = key
+			}
+			continue
+		}
+		for _, z := range key.Zones {
+			if dnsr.CanonicalName(z) == zone {
+				return key
+			}
+		}
+	}
+	return unscoped
+}
@@ -3,21 +3,25 @@ package health
 // Report contains a full perspective of the app's health
 //
 // Consists of a StoreReport, a DNSReport and a HTTPReport,
-// as well as a general Status for the app
+// as well as a general Status for the app. If the DNS queries are
+// filtered with blocklists, it also includes a BlocklistReport
 type Report struct {
-	*StoreReport `json:"store,omitempty"`
-	*DNSReport   `json:"dns,omitempty"`
-	*HTTPReport  `json:"http,omitempty"`
-	Status       `json:"status,omitempty"`
+	*StoreReport     `json:"store,omitempty"`
+	*DNSReport       `json:"dns,omitempty"`
+	*HTTPReport      `json:"http,omitempty"`
+	*BlocklistReport `json:"blocklist,omitempty"`
+	Status           `json:"status,omitempty"`
 }
 
 // StoreReport defines the health of the embeded store in this service
 // by returning information on the number of items, the duration it took to
-// perform a store.List operation and its derived status
+// perform a store.List operation, the error writing its records to its
+// backing file (if any) and its derived status
 type StoreReport struct {
-	Len      int     `json:"num_items,omitempty"`
-	Duration float64 `json:"query_ms,omitempty"`
-	Status   `json:"status,omitempty"`
+	Len       int     `json:"num_items,omitempty"`
+	Duration  float64 `json:"query_ms,omitempty"`
+	SyncError string  `json:"sync_error,omitempty"`
+	Status    `json:"status,omitempty"`
 }
 
 // DNSReport defines the health of the embeded DNS in this service
@@ -39,6 +43,28 @@ type HTTPReport struct {
 	Status `json:"status,omitempty"`
 }
 
+// BlocklistReport defines the health of the blocklists filtering the DNS
+// queries in this service, by returning information on the number of
+// blocked and allowed domains, the number of blocked queries, the state
+// of each list and its derived status
+type BlocklistReport struct {
+	Lists   []*BlocklistSource `json:"lists,omitempty"`
+	Domains int                `json:"num_domains,omitempty"`
+	Allowed int                `json:"num_allowed,omitempty"`
+	Blocked uint64             `json:"num_blocked,omitempty"`
+	Status  `json:"status,omitempty"`
+}
+
+// BlocklistSource defines the state of one of the blocklists, with its
+// number of domains, the time of its last update and the error in its
+// last update, if any
+type BlocklistSource struct {
+	Source  string `json:"source"`
+	Domains int    `json:"num_domains"`
+	Updated string `json:"updated_at,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
 // Status is a reserved type to list status types
 //
 // This is not an enum as it is used so little,
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/blocklist"
+	"github.com/zalgonoise/dns/health"
+	"github.com/zalgonoise/dns/store"
+)
+
+// withBlocklist is a Service filtering the DNS queries with a blocklist.Blocklist
+type withBlocklist struct {
+	Service
+	list *blocklist.Blocklist
+}
+
+// WithBlocklist wraps the input Service `s`, filtering the DNS queries with the
+// blocklist.Blocklist `list` before they reach the store or the fallback DNS
+//
+// Queries for blocked domain names are answered by the blocklist (with NXDOMAIN, null
+// addresses or a sinkhole's addresses), and the blocklist's state is added to the
+// health.Report
+func WithBlocklist(s Service, list *blocklist.Blocklist) Service {
+	if list == nil {
+		return s
+	}
+	return withBlocklist{
+		Service: s,
+		list:    list,
+	}
+}
+
+// AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
+// in store.Record `r`, unless its domain name is blocked
+//
+// Returns dns.ErrNXDomain if the domain name does not exist or is blocked (and the
+// blocklist answers with NXDOMAIN), or an error wrapping dns.ErrServFail if the query
+// could not be resolved
+func (b withBlocklist) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
+	if !b.list.Blocked(r.Name) {
+		return b.Service.AnswerDNS(ctx, r, m)
+	}
+
+	qtype := dnsr.TypeANY
+	if r.Type != "" {
+		qtype = dns.QType(r.Type)
+	}
+	return b.list.Answer(r.Name, qtype, m)
+}
+
+// Health uses the health.Repository to generate a health.Report, including the
+// state of the blocklists in a health.BlocklistReport
+//
+// A blocklist failing to update degrades a Healthy status to Running
+func (b withBlocklist) Health(ctx context.Context) *health.Report {
+	report := b.Service.Health(ctx)
+	report.BlocklistReport = blocklistReport(b.list.Stats())
+
+	if report.BlocklistReport.Status == health.Unhealthy && report.Status == health.Healthy {
+		report.Status = health.Running
+	}
+	return report
+}
+
+// blocklistReport converts the blocklist.Stats `stats` into a health.BlocklistReport,
+// which is Unhealthy if any of the lists failed to update, Running if any of them was
+// not loaded yet, or Healthy otherwise
+func blocklistReport(stats *blocklist.Stats) *health.BlocklistReport {
+	report := &health.BlocklistReport{
+		Lists:   make([]*health.BlocklistSource, 0, len(stats.Lists)),
+		Domains: stats.Domains,
+		Allowed: stats.Allowed,
+		Blocked: stats.Blocked,
+		Status:  health.Healthy,
+	}
+
+	for _, l := range stats.Lists {
+		source := &health.BlocklistSource{
+			Source:  l.Source,
+			Domains: l.Domains,
+		}
+		if !l.Updated.IsZero() {
+			source.Updated = l.Updated.Format(time.RFC3339)
+		}
+
+		switch {
+		case l.Err != nil:
+			source.Error = l.Err.Error()
+			report.Status = health.Unhealthy
+		case l.Updated.IsZero() && report.Status == health.Healthy:
+			report.Status = health.Running
+		}
+		report.Lists = append(report.Lists, source)
+	}
+	return report
+}
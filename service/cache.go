@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zalgonoise/dns/dns"
+)
+
+// FlushCache removes the cached fallback DNS answers for the domain name `name`, or all
+// of them if `name` is empty, returning the number of removed entries
+//
+// Returns a dns.ErrNoCache error if the fallback DNS answers are not cached
+func (s *service) FlushCache(ctx context.Context, name string) (int, error) {
+	c, ok := s.dns.(dns.Cache)
+	if !ok {
+		return 0, dns.ErrNoCache
+	}
+
+	return c.Flush(ctx, name), nil
+}
+
+// CacheStats returns the usage statistics of the cache for fallback DNS answers
+//
+// Returns a dns.ErrNoCache error if the fallback DNS answers are not cached
+func (s *service) CacheStats(ctx context.Context) (*dns.CacheStats, error) {
+	c, ok := s.dns.(dns.Cache)
+	if !ok {
+		return nil, dns.ErrNoCache
+	}
+
+	return c.Stats(ctx), nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/dns"
+)
+
+// FlushCache removes the cached fallback DNS answers for the domain name `name`, or all
+// of them if `name` is empty, returning the number of removed entries
+func (l withLogger) FlushCache(ctx context.Context, name string) (int, error) {
+	n, err := l.s.FlushCache(ctx, name)
+	if err != nil {
+		l.log.Error("failed to flush cache",
+			attr.String("error", err.Error()),
+			attr.String("input", name),
+		)
+	}
+
+	return n, err
+}
+
+// CacheStats returns the usage statistics of the cache for fallback DNS answers
+func (l withLogger) CacheStats(ctx context.Context) (*dns.CacheStats, error) {
+	stats, err := l.s.CacheStats(ctx)
+	if err != nil {
+		l.log.Error("failed to fetch cache stats",
+			attr.String("error", err.Error()),
+		)
+	}
+
+	return stats, err
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/spanner"
+)
+
+// FlushCache removes the cached fallback DNS answers for the domain name `name`, or all
+// of them if `name` is empty, returning the number of removed entries
+func (t withTrace) FlushCache(ctx context.Context, name string) (int, error) {
+	ctx, s := spanner.Start(ctx, "service.FlushCache")
+	defer s.End()
+	s.Add(attr.String("name", name))
+
+	n, err := t.s.FlushCache(ctx, name)
+	if err != nil {
+		s.Event("error flushing cache", attr.New("error", err.Error()))
+		return n, err
+	}
+	s.Add(attr.Int("flushed", n))
+
+	return n, nil
+}
+
+// CacheStats returns the usage statistics of the cache for fallback DNS answers
+func (t withTrace) CacheStats(ctx context.Context) (*dns.CacheStats, error) {
+	ctx, s := spanner.Start(ctx, "service.CacheStats")
+	defer s.End()
+
+	stats, err := t.s.CacheStats(ctx)
+	if err != nil {
+		s.Event("error fetching cache stats", attr.New("error", err.Error()))
+		return nil, err
+	}
+	s.Add(attr.New("stats", stats))
+
+	return stats, nil
+}
@@ -2,33 +2,180 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns"
 	"github.com/zalgonoise/dns/store"
 )
 
+// maxCNAMEChain is the maximum number of CNAME records followed when answering
+// a question from the store
+const maxCNAMEChain = 8
+
+var (
+	ErrCNAMEChain = fmt.Errorf("%w: CNAME chain loops or exceeds the maximum depth", dns.ErrServFail)
+)
+
 // AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
 // in store.Record `r`
-func (s *service) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) {
+//
+// Domain names inside a zone owned by this server (with an SOA record in the store) are
+// answered authoritatively, and are never sent to the fallback DNS. If such a domain name
+// does not exist or holds no records of the requested type, the zone's SOA record is added
+// to the authority section of the dns.Msg `m`
+//
+// Returns dns.ErrNXDomain if the domain name does not exist, or an error wrapping
+// dns.ErrServFail if the query could not be resolved
+func (s *service) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
+	soa := s.findSOA(ctx, r.Name)
+	if soa != nil {
+		m.Authoritative = true
+	}
+
+	err := s.answerDNS(ctx, r, m)
+
+	if (err == nil && len(m.Answer) == 0) || errors.Is(err, dns.ErrNXDomain) {
+		if soa != nil && len(m.Ns) == 0 {
+			_ = s.dns.Authority(ctx, soa, m)
+		}
+	}
+	return err
+}
+
+func (s *service) answerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
 	switch r.Type {
 	case "", "ANY":
 		answers, err := s.store.FilterByDomain(ctx, r.Name)
 		if err != nil || len(answers) == 0 {
 			r.Type = "ANY"
-			s.dns.Fallback(ctx, r, m)
-			return
+			return s.answerMissing(ctx, r, m)
 		}
 
-		for _, ans := range answers {
-			s.dns.Answer(ctx, ans, m)
-		}
+		return s.answerAll(ctx, answers, 0, m)
 	default:
-		answer, err := s.store.FindByTypeAndDomain(ctx, r.Type, r.Name)
-		if err != nil || answer.Addr == "" {
-			s.dns.Fallback(ctx, r, m)
-			return
+		name, ok, err := s.answerChain(ctx, r, m)
+		if ok {
+			return err
+		}
+
+		// the CNAME chain leads to a domain which is not in the store
+		if name != r.Name {
+			return s.answerMissing(ctx, store.New().Type(r.Type).Name(name).Build(), m)
+		}
+
+		if r.Type == store.TypePTR.String() {
+			if ok, err := s.answerReverse(ctx, r, m); ok {
+				return err
+			}
 		}
 
-		s.dns.Answer(ctx, answer, m)
+		return s.answerMissing(ctx, r, m)
+	}
+}
+
+// answerMissing answers the question in store.Record `r` when the store holds no records
+// for it. If the domain name is inside a zone owned by this server, the question is answered
+// with no records if the domain name exists in the store (NODATA), or with dns.ErrNXDomain
+// if it does not; otherwise it is sent to the fallback DNS
+func (s *service) answerMissing(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
+	if s.findSOA(ctx, r.Name) == nil {
+		return s.dns.Fallback(ctx, r, m)
+	}
+
+	records, err := s.store.FilterByDomain(ctx, r.Name)
+	if err == nil && len(records) > 0 {
+		return nil
+	}
+	return dns.ErrNXDomain
+}
+
+// answerChain answers the question in store.Record `r` from the store, following any
+// CNAME records for its domain name (and their targets) until a record of the requested
+// type is found. The CNAME records which are followed are also added to the answer.
+//
+// It returns the last domain name in the chain, and true if the question was handled:
+// either answered, or failed with ErrCNAMEChain if the chain loops or is too long. If it
+// returns false, the question is meant to be answered by the fallback DNS
+func (s *service) answerChain(ctx context.Context, r *store.Record, m *dnsr.Msg) (string, bool, error) {
+	var (
+		name    = r.Name
+		visited = map[string]struct{}{name: {}}
+	)
+
+	for {
+		answers, err := s.store.FindByTypeAndDomain(ctx, r.Type, name)
+		if err == nil && len(answers) > 0 {
+			// rotate the record set on each query to spread the load across its members
+			offset := s.rr.next(setKey(r.Type, answers), len(answers))
+			return name, true, s.answerAll(ctx, answers, offset, m)
+		}
+
+		if r.Type == store.TypeCNAME.String() {
+			return name, false, nil
+		}
+
+		cnames, err := s.store.FindByTypeAndDomain(ctx, store.TypeCNAME.String(), name)
+		if err != nil || len(cnames) == 0 {
+			return name, false, nil
+		}
+
+		target := strings.TrimSuffix(cnames[0].Addr, ".")
+		if _, ok := visited[target]; ok || len(visited) > maxCNAMEChain {
+			return name, true, ErrCNAMEChain
+		}
+		visited[target] = struct{}{}
+
+		if err := s.dns.Answer(ctx, cnames[0], m); err != nil {
+			return name, true, fmt.Errorf("%w: %v", dns.ErrServFail, err)
+		}
+		name = target
+	}
+}
+
+// answerAll writes the answers for the store.Records `records` to the dns.Msg `m`,
+// starting from the record at index `offset`
+//
+// Records which cannot be converted into answers are skipped; an error wrapping
+// dns.ErrServFail is only returned if none of them can
+func (s *service) answerAll(ctx context.Context, records []*store.Record, offset int, m *dnsr.Msg) error {
+	var (
+		answered bool
+		lastErr  error
+	)
+
+	for i := range records {
+		if err := s.dns.Answer(ctx, records[(i+offset)%len(records)], m); err != nil {
+			lastErr = err
+			continue
+		}
+		answered = true
+	}
+
+	if !answered && lastErr != nil {
+		return fmt.Errorf("%w: %v", dns.ErrServFail, lastErr)
+	}
+	return nil
+}
+
+// findSOA returns the SOA record of the closest zone in the store enclosing the
+// domain name `name`, or nil if there is none
+func (s *service) findSOA(ctx context.Context, name string) *store.Record {
+	name = strings.TrimSuffix(name, ".")
+
+	for name != "" {
+		records, err := s.store.FindByTypeAndDomain(ctx, store.TypeSOA.String(), name)
+		if err == nil && len(records) > 0 {
+			return records[0]
+		}
+
+		idx := strings.IndexByte(name, '.')
+		if idx < 0 {
+			break
+		}
+		name = name[idx+1:]
 	}
+	return nil
 }
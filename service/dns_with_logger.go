@@ -2,13 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/dns"
 	"github.com/zalgonoise/dns/store"
 )
 
 // AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
 // in store.Record `r`
-func (l withLogger) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) {
-	l.s.AnswerDNS(ctx, r, m)
+func (l withLogger) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
+	err := l.s.AnswerDNS(ctx, r, m)
+	// a non-existing domain name is a valid answer, not a failure
+	if err != nil && !errors.Is(err, dns.ErrNXDomain) {
+		l.log.Error("failed to answer query",
+			attr.String("error", err.Error()),
+			attr.New("input", r),
+		)
+	}
+
+	return err
 }
@@ -11,10 +11,14 @@ import (
 
 // AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
 // in store.Record `r`
-func (t withTrace) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) {
+func (t withTrace) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
 	ctx, s := spanner.Start(ctx, "service.AnswerDNS")
 	defer s.End()
 	s.Add(attr.New("record", r))
 
-	t.s.AnswerDNS(ctx, r, m)
+	err := t.s.AnswerDNS(ctx, r, m)
+	if err != nil {
+		s.Event("error answering query", attr.New("error", err.Error()))
+	}
+	return err
 }
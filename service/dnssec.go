@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/dns/store"
+)
+
+// defaultNegativeTTL is the TTL of the NSEC records in negative answers, if the zone's
+// SOA record sets neither a TTL nor a minimum TTL
+const defaultNegativeTTL uint32 = 3600
+
+var (
+	ErrNoDNSSEC = errors.New("DNSSEC signing is not enabled")
+	ErrNoZone   = errors.New("zone is not owned by this server")
+)
+
+// DS returns the DS records for the DNSSEC keys of the zone `zone`, to be published
+// in its parent zone
+//
+// Returns an ErrNoDNSSEC error, as the service does not sign its zones unless it is
+// wrapped with WithDNSSEC
+func (s *service) DS(ctx context.Context, zone string) ([]*dnsr.DS, error) {
+	return nil, ErrNoDNSSEC
+}
+
+// withDNSSEC is a Service signing the answers from the zones it owns
+type withDNSSEC struct {
+	Service
+	signer *dnssec.Signer
+	chains *nsecChains
+}
+
+// WithDNSSEC wraps the input Service `s`, signing the answers from the zones it owns
+// (with an SOA record in the store) with the dnssec.Signer `signer`
+//
+// The DNSKEY records of the zones are answered from the signer. For queries with the
+// DO bit set, the answer and authority records are followed by their RRSIG records; and
+// negative answers carry the NSEC records proving that the domain name (or the record
+// type) does not exist
+func WithDNSSEC(s Service, signer *dnssec.Signer) Service {
+	if signer == nil {
+		return s
+	}
+	return withDNSSEC{
+		Service: s,
+		signer:  signer,
+		chains:  newNSECChains(),
+	}
+}
+
+// AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
+// in store.Record `r`, signing it if the query has the DO bit set
+func (d withDNSSEC) AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error {
+	soa := d.findSOA(ctx, r.Name)
+	if soa == nil {
+		return d.Service.AnswerDNS(ctx, r, m)
+	}
+	zone := dnsr.CanonicalName(soa.Name)
+	do := dnssecOK(ctx)
+
+	if r.Type == dnsr.TypeToString[dnsr.TypeDNSKEY] && dnsr.CanonicalName(r.Name) == zone {
+		keys, err := d.signer.DNSKEY(zone)
+		if err != nil {
+			return fmt.Errorf("%w: %v", dns.ErrServFail, err)
+		}
+		m.Authoritative = true
+		if do {
+			if keys, err = d.signer.Sign(zone, keys); err != nil {
+				return fmt.Errorf("%w: %v", dns.ErrServFail, err)
+			}
+		}
+		m.Answer = append(m.Answer, keys...)
+		return nil
+	}
+
+	answers, authority := len(m.Answer), len(m.Ns)
+	err := d.Service.AnswerDNS(ctx, r, m)
+	if !do {
+		return err
+	}
+
+	// the denial is for the last domain name in the CNAME chain, if any
+	target, answered := answerTarget(m.Answer[answers:], r.Name, r.Type)
+	if (err == nil && !answered) || errors.Is(err, dns.ErrNXDomain) {
+		targetSOA := soa
+		if target != dnsr.CanonicalName(r.Name) {
+			targetSOA = d.findSOA(ctx, target)
+		}
+		if targetSOA != nil {
+			if chain, chainErr := d.chain(ctx, targetSOA); chainErr == nil {
+				m.Ns = append(m.Ns, chain.Denial(
+					target,
+					errors.Is(err, dns.ErrNXDomain),
+					negativeTTL(targetSOA),
+				)...)
+			}
+		}
+	}
+
+	signed, signErr := d.sign(ctx, m.Answer[answers:])
+	if signErr != nil {
+		return fmt.Errorf("%w: %v", dns.ErrServFail, signErr)
+	}
+	m.Answer = append(m.Answer[:answers], signed...)
+
+	signed, signErr = d.sign(ctx, m.Ns[authority:])
+	if signErr != nil {
+		return fmt.Errorf("%w: %v", dns.ErrServFail, signErr)
+	}
+	m.Ns = append(m.Ns[:authority], signed...)
+
+	return err
+}
+
+// DS returns the DS records for the DNSSEC keys of the zone `zone`, to be published
+// in its parent zone
+//
+// Returns an ErrNoZone error if the zone is not owned by this server
+func (d withDNSSEC) DS(ctx context.Context, zone string) ([]*dnsr.DS, error) {
+	if zone == "" {
+		return nil, ErrNoName
+	}
+
+	soa := d.findSOA(ctx, zone)
+	if soa == nil || dnsr.CanonicalName(soa.Name) != dnsr.CanonicalName(zone) {
+		return nil, fmt.Errorf("%w: %s", ErrNoZone, zone)
+	}
+
+	return d.signer.DS(zone)
+}
+
+// sign returns the records `rrs` followed by the RRSIG records for the ones inside
+// a zone owned by this server
+func (d withDNSSEC) sign(ctx context.Context, rrs []dnsr.RR) ([]dnsr.RR, error) {
+	var (
+		zones  []string
+		byZone = map[string][]dnsr.RR{}
+		soas   = map[string]string{}
+	)
+
+	for _, rr := range rrs {
+		name := rr.Header().Name
+		zone, ok := soas[name]
+		if !ok {
+			if soa := d.findSOA(ctx, name); soa != nil {
+				zone = dnsr.CanonicalName(soa.Name)
+			}
+			soas[name] = zone
+		}
+		if zone == "" {
+			continue
+		}
+
+		if _, ok := byZone[zone]; !ok {
+			zones = append(zones, zone)
+		}
+		byZone[zone] = append(byZone[zone], rr)
+	}
+
+	signed := append(make([]dnsr.RR, 0, 2*len(rrs)), rrs...)
+	for _, zone := range zones {
+		records, err := d.signer.Sign(zone, byZone[zone])
+		if err != nil {
+			return nil, err
+		}
+		signed = append(signed, records[len(byZone[zone]):]...)
+	}
+	return signed, nil
+}
+
+// findSOA returns the SOA record of the closest zone in the store enclosing the
+// domain name `name`, or nil if there is none
+func (d withDNSSEC) findSOA(ctx context.Context, name string) *store.Record {
+	name = strings.TrimSuffix(name, ".")
+
+	for name != "" {
+		records, err := d.GetRecordByTypeAndDomain(ctx, store.TypeSOA.String(), name)
+		if err == nil && len(records) > 0 {
+			return records[0]
+		}
+
+		idx := strings.IndexByte(name, '.')
+		if idx < 0 {
+			break
+		}
+		name = name[idx+1:]
+	}
+	return nil
+}
+
+// dnssecOK returns true if the DNS query carried in the context has the DO bit set
+func dnssecOK(ctx context.Context) bool {
+	q := dns.QueryFrom(ctx)
+	if q == nil {
+		return false
+	}
+	opt := q.IsEdns0()
+	return opt != nil && opt.Do()
+}
+
+// typesByName returns the domain names of the store.Records `records`, with their
+// record types
+func typesByName(records []*store.Record) map[string][]uint16 {
+	names := make(map[string][]uint16, len(records))
+	for _, r := range records {
+		name := dnsr.CanonicalName(r.Name)
+		names[name] = append(names[name], dns.QType(r.Type))
+	}
+	return names
+}
+
+// answerTarget returns the last domain name in the chain of CNAME records in `rrs`,
+// starting at the domain name `name`, and true if `rrs` holds records of the type `rtype`
+// (or of any type, if empty or ANY) for it
+func answerTarget(rrs []dnsr.RR, name, rtype string) (string, bool) {
+	target := dnsr.CanonicalName(name)
+	qtype := dns.QType(rtype)
+
+	if qtype != dnsr.TypeCNAME {
+		for range rrs {
+			var next string
+			for _, rr := range rrs {
+				if cname, ok := rr.(*dnsr.CNAME); ok && dnsr.CanonicalName(cname.Hdr.Name) == target {
+					next = dnsr.CanonicalName(cname.Target)
+					break
+				}
+			}
+			if next == "" || next == target {
+				break
+			}
+			target = next
+		}
+	}
+
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if dnsr.CanonicalName(hdr.Name) != target {
+			continue
+		}
+		if qtype == dnsr.TypeANY || qtype == 0 || hdr.Rrtype == qtype {
+			return target, true
+		}
+	}
+	return target, false
+}
+
+// negativeTTL returns the TTL for the negative answers in the zone with the SOA record
+// `soa`, which is the lesser of its TTL and its minimum TTL (RFC 9077)
+func negativeTTL(soa *store.Record) uint32 {
+	ttl := soa.TTL
+	if soa.Data != nil && soa.Data.Minttl != 0 && (ttl == 0 || soa.Data.Minttl < ttl) {
+		ttl = soa.Data.Minttl
+	}
+	if ttl == 0 {
+		ttl = defaultNegativeTTL
+	}
+	return ttl
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/dns/store"
+)
+
+// nsecChains caches the NSEC chains of the zones owned by this server, so that negative
+// answers don't list (and sort) all of the records in the store
+//
+// A zone's chain is rebuilt when its serial changes (such as when a secondary zone is
+// refreshed from its primary), or after any change to the records through the Service
+type nsecChains struct {
+	mtx        sync.Mutex
+	generation uint64
+	zones      map[string]nsecChain
+}
+
+// nsecChain is the cached NSEC chain of a zone, for the serial `serial`
+type nsecChain struct {
+	serial uint32
+	chain  *dnssec.Chain
+}
+
+func newNSECChains() *nsecChains {
+	return &nsecChains{
+		zones: map[string]nsecChain{},
+	}
+}
+
+// reset discards all of the cached NSEC chains
+func (c *nsecChains) reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.generation++
+	c.zones = map[string]nsecChain{}
+}
+
+// chain returns the NSEC chain of the zone with the SOA record `soa`, building it from the
+// zone's records if it isn't cached for its current serial
+func (d withDNSSEC) chain(ctx context.Context, soa *store.Record) (*dnssec.Chain, error) {
+	zone := dnsr.CanonicalName(soa.Name)
+	var serial uint32
+	if soa.Data != nil {
+		serial = soa.Data.Serial
+	}
+
+	d.chains.mtx.Lock()
+	cached, ok := d.chains.zones[zone]
+	generation := d.chains.generation
+	d.chains.mtx.Unlock()
+	if ok && cached.serial == serial {
+		return cached.chain, nil
+	}
+
+	records, err := d.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chain := dnssec.NewChain(zone, typesByName(store.ZoneRecords(zone, records)))
+
+	d.chains.mtx.Lock()
+	defer d.chains.mtx.Unlock()
+	// the records changed while building the chain, so it isn't cached
+	if d.chains.generation == generation {
+		d.chains.zones[zone] = nsecChain{serial: serial, chain: chain}
+	}
+	return chain, nil
+}
+
+// AddRecord uses the store.Repository to create a DNS Record, discarding the cached
+// NSEC chains
+func (d withDNSSEC) AddRecord(ctx context.Context, r *store.Record) error {
+	defer d.chains.reset()
+	return d.Service.AddRecord(ctx, r)
+}
+
+// AddRecords uses the store.Repository to create a set of DNS Records, discarding the
+// cached NSEC chains
+func (d withDNSSEC) AddRecords(ctx context.Context, rs ...*store.Record) error {
+	defer d.chains.reset()
+	return d.Service.AddRecords(ctx, rs...)
+}
+
+// UpdateRecord uses the store.Repository to update the record with domain name `domain`,
+// discarding the cached NSEC chains
+func (d withDNSSEC) UpdateRecord(ctx context.Context, domain string, r *store.Record) error {
+	defer d.chains.reset()
+	return d.Service.UpdateRecord(ctx, domain, r)
+}
+
+// DeleteRecord uses the store.Repository to remove the store.Record based on input `r`,
+// discarding the cached NSEC chains
+func (d withDNSSEC) DeleteRecord(ctx context.Context, r *store.Record) error {
+	defer d.chains.reset()
+	return d.Service.DeleteRecord(ctx, r)
+}
+
+// AddZone uses the store.Repository to create the SOA and NS records for the store.Zone
+// `z`, discarding the cached NSEC chains
+func (d withDNSSEC) AddZone(ctx context.Context, z *store.Zone) error {
+	defer d.chains.reset()
+	return d.Service.AddZone(ctx, z)
+}
+
+// ImportZone uses the store.Repository to create the DNS Records in the master zone file
+// read from io.Reader `r`, discarding the cached NSEC chains
+func (d withDNSSEC) ImportZone(ctx context.Context, origin string, r io.Reader) ([]*store.Record, error) {
+	defer d.chains.reset()
+	return d.Service.ImportZone(ctx, origin, r)
+}
+
+// UpdateZone applies the dynamic update (RFC 2136) with the prerequisites `prereqs` and
+// the updates `updates` to the zone `zone`, discarding the cached NSEC chains
+func (d withDNSSEC) UpdateZone(ctx context.Context, zone string, prereqs, updates []dnsr.RR) error {
+	defer d.chains.reset()
+	return d.Service.UpdateZone(ctx, zone, prereqs, updates)
+}
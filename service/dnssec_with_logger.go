@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+)
+
+// DS returns the DS records for the DNSSEC keys of the zone `zone`, to be published
+// in its parent zone
+func (l withLogger) DS(ctx context.Context, zone string) ([]*dnsr.DS, error) {
+	ds, err := l.s.DS(ctx, zone)
+	if err != nil {
+		l.log.Error("failed to fetch DS records",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+		)
+	}
+
+	return ds, err
+}
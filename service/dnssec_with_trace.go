@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+// DS returns the DS records for the DNSSEC keys of the zone `zone`, to be published
+// in its parent zone
+func (t withTrace) DS(ctx context.Context, zone string) ([]*dnsr.DS, error) {
+	ctx, s := spanner.Start(ctx, "service.DS")
+	defer s.End()
+	s.Add(attr.String("zone", zone))
+
+	ds, err := t.s.DS(ctx, zone)
+	if err != nil {
+		s.Event("error fetching DS records", attr.New("error", err.Error()))
+		return nil, err
+	}
+	s.Add(attr.Int("len", len(ds)))
+
+	return ds, nil
+}
@@ -2,10 +2,23 @@ package e2e
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	dnsr "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/dns/blocklist"
+	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/dns/dnssec"
+	"github.com/zalgonoise/dns/health"
+	"github.com/zalgonoise/dns/health/simplehealth"
+	"github.com/zalgonoise/dns/service"
 	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
 )
 
 func TestDNS(t *testing.T) {
@@ -35,4 +48,772 @@ func TestDNS(t *testing.T) {
 			return
 		}
 	})
+
+	t.Run("AnswerDNSRecordSet", func(t *testing.T) {
+		member := store.New().Type(record1.Type).Name(record1.Name).Addr("192.168.0.11").Build()
+		err := s.AddRecord(ctx, member)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		var firsts = map[string]bool{}
+		for i := 0; i < 2; i++ {
+			input := store.New().Type(record1.Type).Name(record1.Name).Build()
+			m := new(dns.Msg)
+
+			s.AnswerDNS(ctx, input, m)
+
+			if len(m.Answer) != 2 {
+				t.Errorf("unexpected answers list length: wanted %v ; got %v", 2, len(m.Answer))
+				return
+			}
+			firsts[m.Answer[0].(*dns.A).A.String()] = true
+		}
+
+		if !firsts[record1.Addr] || !firsts[member.Addr] {
+			t.Errorf("expected record set to rotate between queries; got first answers %v", firsts)
+		}
+	})
+
+	t.Run("AnswerDNSWildcardRecordSet", func(t *testing.T) {
+		err := s.AddRecords(ctx,
+			store.New().Type("A").Name("*.pool.lan").Addr("192.168.1.10").Build(),
+			store.New().Type("A").Name("*.pool.lan").Addr("192.168.1.11").Build(),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		// each expanded name shares the wildcard's counter, so the rotation carries over
+		var firsts = map[string]bool{}
+		for _, name := range []string{"one.pool.lan", "two.pool.lan"} {
+			input := store.New().Type("A").Name(name).Build()
+			m := new(dns.Msg)
+
+			s.AnswerDNS(ctx, input, m)
+
+			if len(m.Answer) != 2 {
+				t.Errorf("unexpected answers list length: wanted %v ; got %v", 2, len(m.Answer))
+				return
+			}
+			firsts[m.Answer[0].(*dns.A).A.String()] = true
+		}
+
+		if !firsts["192.168.1.10"] || !firsts["192.168.1.11"] {
+			t.Errorf("expected wildcard record set to rotate between names; got first answers %v", firsts)
+		}
+	})
+}
+
+func TestReverseDNS(t *testing.T) {
+	ctx := context.Background()
+	s := service.New(
+		core.New(),
+		memmap.New(),
+		simplehealth.New(),
+		config.New(config.DNSReverse(true)),
+	)
+	v6 := store.New().Type("AAAA").Name("v6.not.a.dom.ain").Addr("fd00::10").Build()
+
+	err := s.AddRecords(context.Background(), record1, record2, v6)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("AnswerIPv4", func(t *testing.T) {
+		input := store.New().Type("PTR").Name("10.0.168.192.in-addr.arpa").Build()
+		m := new(dns.Msg)
+		wants := "10.0.168.192.in-addr.arpa.	3600	IN	PTR	not.a.dom.ain."
+
+		s.AnswerDNS(ctx, input, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+			return
+		}
+		if m.Answer[0].String() != wants {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, m.Answer[0])
+		}
+	})
+
+	t.Run("AnswerIPv6", func(t *testing.T) {
+		name, err := dns.ReverseAddr(v6.Addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		input := store.New().Type("PTR").Name(name[:len(name)-1]).Build()
+		m := new(dns.Msg)
+
+		s.AnswerDNS(ctx, input, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+			return
+		}
+		if ptr, ok := m.Answer[0].(*dns.PTR); !ok || ptr.Ptr != dns.Fqdn(v6.Name) {
+			t.Errorf("output mismatch error: wanted PTR to %s ; got %v", v6.Name, m.Answer[0])
+		}
+	})
+
+	t.Run("NXDomainPrivateAddress", func(t *testing.T) {
+		input := store.New().Type("PTR").Name("99.0.168.192.in-addr.arpa").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+		}
+
+		if len(m.Answer) != 0 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 0, len(m.Answer))
+		}
+	})
+
+	t.Run("AnswerNonCanonicalIPv6", func(t *testing.T) {
+		long := store.New().Type("AAAA").Name("long.not.a.dom.ain").Addr("FD00:0:0:0::20").Build()
+		if err := s.AddRecord(ctx, long); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		name, err := dns.ReverseAddr("fd00::20")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		input := store.New().Type("PTR").Name(name).Build()
+		m := new(dns.Msg)
+
+		s.AnswerDNS(ctx, input, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+			return
+		}
+		if ptr, ok := m.Answer[0].(*dns.PTR); !ok || ptr.Ptr != dns.Fqdn(long.Name) {
+			t.Errorf("output mismatch error: wanted PTR to %s ; got %v", long.Name, m.Answer[0])
+		}
+	})
+
+	t.Run("NoDataPrivatePrefix", func(t *testing.T) {
+		for _, name := range []string{"168.192.in-addr.arpa", "0.168.192.in-addr.arpa", "d.f.ip6.arpa"} {
+			input := store.New().Type("PTR").Name(name).Build()
+			m := new(dns.Msg)
+
+			err := s.AnswerDNS(ctx, input, m)
+			if err != nil {
+				t.Errorf("unexpected error for %s: %v", name, err)
+			}
+			if len(m.Answer) != 0 {
+				t.Errorf("unexpected answers list length for %s: wanted %v ; got %v", name, 0, len(m.Answer))
+			}
+		}
+	})
+
+	t.Run("NXDomainPrivatePrefix", func(t *testing.T) {
+		for _, name := range []string{"10.in-addr.arpa", "16.172.in-addr.arpa", "8.e.f.ip6.arpa"} {
+			input := store.New().Type("PTR").Name(name).Build()
+			m := new(dns.Msg)
+
+			err := s.AnswerDNS(ctx, input, m)
+			if !errors.Is(err, dnsr.ErrNXDomain) {
+				t.Errorf("unexpected error for %s: wanted %v ; got %v", name, dnsr.ErrNXDomain, err)
+			}
+			if len(m.Answer) != 0 {
+				t.Errorf("unexpected answers list length for %s: wanted %v ; got %v", name, 0, len(m.Answer))
+			}
+		}
+	})
+
+	t.Run("NXDomainWithZoneSOA", func(t *testing.T) {
+		soa := store.New().Type("SOA").Name("168.192.in-addr.arpa").Addr("ns.lan").Data(&store.Data{
+			Mbox:   "admin.lan",
+			Serial: 1,
+			Minttl: 60,
+		}).Build()
+		if err := s.AddRecord(ctx, soa); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		input := store.New().Type("PTR").Name("99.0.168.192.in-addr.arpa").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+		}
+
+		if len(m.Ns) != 1 {
+			t.Errorf("unexpected authority list length: wanted %v ; got %v", 1, len(m.Ns))
+			return
+		}
+		if _, ok := m.Ns[0].(*dns.SOA); !ok {
+			t.Errorf("unexpected authority record: wanted SOA ; got %v", m.Ns[0])
+		}
+	})
+}
+
+func TestCNAMEChain(t *testing.T) {
+	ctx := context.Background()
+	s := initializeService()
+
+	err := s.AddRecords(ctx,
+		store.New().Type("CNAME").Name("api.lan").Addr("web.lan").Build(),
+		store.New().Type("CNAME").Name("web.lan").Addr("backend.lan.").Build(),
+		store.New().Type("A").Name("backend.lan").Addr("10.0.0.5").Build(),
+		store.New().Type("CNAME").Name("loop-a.lan").Addr("loop-b.lan").Build(),
+		store.New().Type("CNAME").Name("loop-b.lan").Addr("loop-a.lan").Build(),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("FollowChain", func(t *testing.T) {
+		input := store.New().Type("A").Name("api.lan").Build()
+		m := new(dns.Msg)
+		wants := []string{
+			"api.lan.	3600	IN	CNAME	web.lan.",
+			"web.lan.	3600	IN	CNAME	backend.lan.",
+			"backend.lan.	3600	IN	A	10.0.0.5",
+		}
+
+		s.AnswerDNS(ctx, input, m)
+
+		if len(m.Answer) != len(wants) {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", len(wants), len(m.Answer))
+			return
+		}
+		for idx, rr := range m.Answer {
+			if rr.String() != wants[idx] {
+				t.Errorf("output mismatch error on answer #%v: wanted %v ; got %v", idx, wants[idx], rr)
+			}
+		}
+	})
+
+	t.Run("CNAMEQuestion", func(t *testing.T) {
+		input := store.New().Type("CNAME").Name("api.lan").Build()
+		m := new(dns.Msg)
+
+		s.AnswerDNS(ctx, input, m)
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+		}
+	})
+
+	t.Run("FailLoop", func(t *testing.T) {
+		input := store.New().Type("A").Name("loop-a.lan").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if !errors.Is(err, dnsr.ErrServFail) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrServFail, err)
+		}
+	})
+}
+
+func TestAuthoritativeZone(t *testing.T) {
+	ctx := context.Background()
+	s := initializeService()
+
+	err := s.AddZone(ctx, &store.Zone{
+		Name:    "corp.lan",
+		NS:      []string{"ns1.corp.lan", "ns2.corp.lan"},
+		Serial:  2023010101,
+		Minimum: 300,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	err = s.AddRecord(ctx, store.New().Type("A").Name("web.corp.lan").Addr("10.0.0.5").Build())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("AnswerAuthoritative", func(t *testing.T) {
+		input := store.New().Type("A").Name("web.corp.lan").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if !m.Authoritative {
+			t.Errorf("expected an authoritative answer")
+		}
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+		}
+	})
+
+	t.Run("AnswerNameServers", func(t *testing.T) {
+		input := store.New().Type("NS").Name("corp.lan").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 2 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 2, len(m.Answer))
+		}
+	})
+
+	t.Run("NoData", func(t *testing.T) {
+		input := store.New().Type("AAAA").Name("web.corp.lan").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if !m.Authoritative {
+			t.Errorf("expected an authoritative answer")
+		}
+		if len(m.Answer) != 0 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 0, len(m.Answer))
+		}
+		if len(m.Ns) != 1 {
+			t.Errorf("unexpected authority list length: wanted %v ; got %v", 1, len(m.Ns))
+		}
+	})
+
+	t.Run("NXDomain", func(t *testing.T) {
+		input := store.New().Type("A").Name("missing.corp.lan").Build()
+		m := new(dns.Msg)
+
+		err := s.AnswerDNS(ctx, input, m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+		}
+
+		if !m.Authoritative {
+			t.Errorf("expected an authoritative answer")
+		}
+		if len(m.Ns) != 1 {
+			t.Errorf("unexpected authority list length: wanted %v ; got %v", 1, len(m.Ns))
+			return
+		}
+		soa, ok := m.Ns[0].(*dns.SOA)
+		if !ok {
+			t.Errorf("unexpected authority record: wanted SOA ; got %v", m.Ns[0])
+			return
+		}
+		if soa.Serial != 2023010101 || soa.Ns != "ns1.corp.lan." || soa.Hdr.Ttl != 300 {
+			t.Errorf("unexpected SOA record: %v", soa)
+		}
+	})
+
+	t.Run("FailNoName", func(t *testing.T) {
+		err := s.AddZone(ctx, &store.Zone{})
+		if !errors.Is(err, service.ErrNoName) {
+			t.Errorf("unexpected error: wanted %v ; got %v", service.ErrNoName, err)
+		}
+	})
+}
+
+func TestDNSSEC(t *testing.T) {
+	ctx := context.Background()
+	s := service.WithDNSSEC(initializeService(), dnssec.New(t.TempDir()))
+
+	err := s.AddZone(ctx, &store.Zone{Name: "corp.lan", Minimum: 300})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	err = s.AddRecord(ctx, store.New().Type("A").Name("web.corp.lan").Addr("10.0.0.5").Build())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	q := new(dns.Msg)
+	q.SetQuestion("web.corp.lan.", dns.TypeA)
+	q.SetEdns0(4096, true)
+	doCtx := dnsr.WithQuery(ctx, q)
+
+	keys := new(dns.Msg)
+	err = s.AnswerDNS(doCtx, store.New().Type("DNSKEY").Name("corp.lan").Build(), keys)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// verify checks that each RRset in `rrs` is signed by one of the zone's keys
+	verify := func(t *testing.T, rrs []dns.RR) {
+		sets := map[uint16][]dns.RR{}
+		sigs := map[uint16]*dns.RRSIG{}
+		for _, rr := range rrs {
+			if sig, ok := rr.(*dns.RRSIG); ok {
+				sigs[sig.TypeCovered] = sig
+				continue
+			}
+			sets[rr.Header().Rrtype] = append(sets[rr.Header().Rrtype], rr)
+		}
+
+		for rtype, rrset := range sets {
+			sig, ok := sigs[rtype]
+			if !ok {
+				t.Errorf("missing RRSIG for the %s RRset", dns.TypeToString[rtype])
+				continue
+			}
+			var verified bool
+			for _, rr := range keys.Answer {
+				if key, ok := rr.(*dns.DNSKEY); ok && key.KeyTag() == sig.KeyTag {
+					verified = sig.Verify(key, rrset) == nil && sig.ValidityPeriod(time.Now())
+				}
+			}
+			if !verified {
+				t.Errorf("invalid RRSIG for the %s RRset: %v", dns.TypeToString[rtype], sig)
+			}
+		}
+	}
+
+	t.Run("DNSKEY", func(t *testing.T) {
+		if len(keys.Answer) != 3 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 3, len(keys.Answer))
+			return
+		}
+		verify(t, keys.Answer)
+	})
+
+	t.Run("SignedAnswer", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := s.AnswerDNS(doCtx, store.New().Type("A").Name("web.corp.lan").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 2 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 2, len(m.Answer))
+			return
+		}
+		verify(t, m.Answer)
+	})
+
+	t.Run("UnsignedWithoutDO", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := s.AnswerDNS(ctx, store.New().Type("A").Name("web.corp.lan").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(m.Answer) != 1 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 1, len(m.Answer))
+		}
+	})
+
+	t.Run("NoData", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := s.AnswerDNS(doCtx, store.New().Type("AAAA").Name("web.corp.lan").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		var nsec *dns.NSEC
+		for _, rr := range m.Ns {
+			if n, ok := rr.(*dns.NSEC); ok {
+				nsec = n
+			}
+		}
+		if nsec == nil || nsec.Hdr.Name != "web.corp.lan." || nsec.NextDomain != "corp.lan." {
+			t.Errorf("unexpected NSEC record: %v", nsec)
+			return
+		}
+		for _, rtype := range nsec.TypeBitMap {
+			if rtype == dns.TypeAAAA {
+				t.Errorf("expected the NSEC record not to list the AAAA type: %v", nsec)
+			}
+		}
+		verify(t, m.Ns)
+	})
+
+	t.Run("NXDomain", func(t *testing.T) {
+		m := new(dns.Msg)
+		err := s.AnswerDNS(doCtx, store.New().Type("A").Name("missing.corp.lan").Build(), m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+			return
+		}
+
+		var nsecs []string
+		for _, rr := range m.Ns {
+			if n, ok := rr.(*dns.NSEC); ok {
+				nsecs = append(nsecs, n.Hdr.Name+" "+n.NextDomain)
+			}
+		}
+		// corp.lan. covers both missing.corp.lan. and *.corp.lan.
+		if len(nsecs) != 1 || nsecs[0] != "corp.lan. web.corp.lan." {
+			t.Errorf("unexpected NSEC records: %v", nsecs)
+			return
+		}
+		verify(t, m.Ns)
+	})
+
+	t.Run("NXDomainAfterChange", func(t *testing.T) {
+		err := s.AddRecord(ctx, store.New().Type("A").Name("mail.corp.lan").Addr("10.0.0.25").Build())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		m := new(dns.Msg)
+		err = s.AnswerDNS(doCtx, store.New().Type("A").Name("missing.corp.lan").Build(), m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+			return
+		}
+
+		var nsecs []string
+		for _, rr := range m.Ns {
+			if n, ok := rr.(*dns.NSEC); ok {
+				nsecs = append(nsecs, n.Hdr.Name+" "+n.NextDomain)
+			}
+		}
+		// the cached NSEC chain is rebuilt with mail.corp.lan., which covers missing.corp.lan.
+		if len(nsecs) != 2 || nsecs[0] != "mail.corp.lan. web.corp.lan." || nsecs[1] != "corp.lan. mail.corp.lan." {
+			t.Errorf("unexpected NSEC records: %v", nsecs)
+		}
+	})
+
+	t.Run("DS", func(t *testing.T) {
+		ds, err := s.DS(ctx, "corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(ds) != 1 {
+			t.Errorf("unexpected DS records length: wanted %v ; got %v", 1, len(ds))
+			return
+		}
+		for _, rr := range keys.Answer {
+			if key, ok := rr.(*dns.DNSKEY); ok && key.Flags&dns.SEP != 0 {
+				if wants := key.ToDS(dns.SHA256); wants.String() != ds[0].String() {
+					t.Errorf("output mismatch error: wanted %v ; got %v", wants, ds[0])
+				}
+			}
+		}
+	})
+
+	t.Run("FailDSNotAZone", func(t *testing.T) {
+		_, err := s.DS(ctx, "web.corp.lan")
+		if !errors.Is(err, service.ErrNoZone) {
+			t.Errorf("unexpected error: wanted %v ; got %v", service.ErrNoZone, err)
+		}
+	})
+
+	t.Run("FailDSWithoutDNSSEC", func(t *testing.T) {
+		_, err := initializeService().DS(ctx, "corp.lan")
+		if !errors.Is(err, service.ErrNoDNSSEC) {
+			t.Errorf("unexpected error: wanted %v ; got %v", service.ErrNoDNSSEC, err)
+		}
+	})
+
+	// nsecsOf returns the owner and next domain names of the NSEC records in `rrs`
+	nsecsOf := func(rrs []dns.RR) []string {
+		var nsecs []string
+		for _, rr := range rrs {
+			if n, ok := rr.(*dns.NSEC); ok {
+				nsecs = append(nsecs, n.Hdr.Name+" "+n.NextDomain)
+			}
+		}
+		return nsecs
+	}
+
+	t.Run("NoDataWildcard", func(t *testing.T) {
+		err := s.AddZone(ctx, &store.Zone{Name: "preview.lan", Minimum: 300})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		err = s.AddRecords(ctx,
+			store.New().Type("A").Name("*.preview.lan").Addr("10.0.0.1").Build(),
+			store.New().Type("A").Name("main.preview.lan").Addr("10.0.0.3").Build(),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		m := new(dns.Msg)
+		err = s.AnswerDNS(doCtx, store.New().Type("AAAA").Name("pr-123.preview.lan").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		// the NSEC record covering pr-123.preview.lan. and the wildcard's own NSEC record
+		nsecs := nsecsOf(m.Ns)
+		if len(nsecs) != 2 || nsecs[0] != "main.preview.lan. preview.lan." || nsecs[1] != "*.preview.lan. main.preview.lan." {
+			t.Errorf("unexpected NSEC records: %v", nsecs)
+		}
+	})
+
+	t.Run("CNAMETarget", func(t *testing.T) {
+		err := s.AddZone(ctx, &store.Zone{Name: "apps.lan", Minimum: 300})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		err = s.AddRecords(ctx,
+			store.New().Type("A").Name("app.apps.lan").Addr("10.0.0.8").Build(),
+			store.New().Type("CNAME").Name("alias.apps.lan").Addr("app.apps.lan").Build(),
+			store.New().Type("CNAME").Name("broken.apps.lan").Addr("gone.apps.lan").Build(),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		t.Run("NoData", func(t *testing.T) {
+			m := new(dns.Msg)
+			err := s.AnswerDNS(doCtx, store.New().Type("AAAA").Name("alias.apps.lan").Build(), m)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			nsecs := nsecsOf(m.Ns)
+			if len(nsecs) != 1 || nsecs[0] != "app.apps.lan. broken.apps.lan." {
+				t.Errorf("unexpected NSEC records: %v", nsecs)
+			}
+		})
+
+		t.Run("NXDomain", func(t *testing.T) {
+			m := new(dns.Msg)
+			err := s.AnswerDNS(doCtx, store.New().Type("A").Name("broken.apps.lan").Build(), m)
+			if !errors.Is(err, dnsr.ErrNXDomain) {
+				t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+				return
+			}
+
+			// broken.apps.lan. covers gone.apps.lan.; apps.lan. covers *.apps.lan.
+			nsecs := nsecsOf(m.Ns)
+			if len(nsecs) != 2 || nsecs[0] != "broken.apps.lan. apps.lan." || nsecs[1] != "apps.lan. alias.apps.lan." {
+				t.Errorf("unexpected NSEC records: %v", nsecs)
+			}
+		})
+	})
+}
+
+func TestBlocklist(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/adblock.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("! blocklist\n||dom.ain^\n||ads.example^\n"))
+	}))
+	defer srv.Close()
+
+	// newService returns a Service with a blocklist for the list served by `srv`, answering
+	// the blocked queries according to `answer`
+	newService := func(t *testing.T, answer string, sources ...string) service.Service {
+		list := blocklist.New(sources, blocklist.Answer(answer), blocklist.Allow("also.not.a.dom.ain"))
+		_ = list.Load(ctx)
+
+		s := service.WithBlocklist(initializeService(), list)
+		if err := s.AddRecords(ctx, record1, record2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return s
+	}
+
+	t.Run("BlockedNXDomain", func(t *testing.T) {
+		s := newService(t, "nxdomain", srv.URL+"/adblock.txt")
+
+		m := new(dns.Msg)
+		err := s.AnswerDNS(ctx, store.New().Type(record1.Type).Name(record1.Name).Build(), m)
+		if !errors.Is(err, dnsr.ErrNXDomain) {
+			t.Errorf("unexpected error: wanted %v ; got %v", dnsr.ErrNXDomain, err)
+			return
+		}
+		if len(m.Answer) != 0 {
+			t.Errorf("unexpected answers list length: wanted %v ; got %v", 0, len(m.Answer))
+		}
+	})
+
+	t.Run("BlockedSinkhole", func(t *testing.T) {
+		s := newService(t, "10.0.0.53", srv.URL+"/adblock.txt")
+		wants := "tracker.ads.example.	60	IN	A	10.0.0.53"
+
+		m := new(dns.Msg)
+		err := s.AnswerDNS(ctx, store.New().Type("A").Name("tracker.ads.example").Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(m.Answer) != 1 || m.Answer[0].String() != wants {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, m.Answer)
+		}
+	})
+
+	t.Run("Allowed", func(t *testing.T) {
+		s := newService(t, "nxdomain", srv.URL+"/adblock.txt")
+		wants := "also.not.a.dom.ain.	3600	IN	A	192.168.0.15"
+
+		m := new(dns.Msg)
+		err := s.AnswerDNS(ctx, store.New().Type(record2.Type).Name(record2.Name).Build(), m)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(m.Answer) != 1 || m.Answer[0].String() != wants {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, m.Answer)
+		}
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		s := newService(t, "nxdomain", srv.URL+"/adblock.txt")
+		_ = s.AnswerDNS(ctx, store.New().Type(record1.Type).Name(record1.Name).Build(), new(dns.Msg))
+
+		report := s.Health(ctx)
+		if report == nil || report.BlocklistReport == nil {
+			t.Errorf("expected a blocklist report")
+			return
+		}
+		if report.BlocklistReport.Status != health.Healthy ||
+			report.BlocklistReport.Domains != 2 ||
+			report.BlocklistReport.Allowed != 1 ||
+			report.BlocklistReport.Blocked != 1 {
+			t.Errorf("unexpected blocklist report: %+v", report.BlocklistReport)
+		}
+	})
+
+	t.Run("HealthFailedList", func(t *testing.T) {
+		s := newService(t, "nxdomain", srv.URL+"/adblock.txt", srv.URL+"/missing.txt")
+
+		report := s.Health(ctx)
+		if report == nil || report.BlocklistReport == nil {
+			t.Errorf("expected a blocklist report")
+			return
+		}
+		if report.BlocklistReport.Status != health.Unhealthy || len(report.BlocklistReport.Lists) != 2 {
+			t.Errorf("unexpected blocklist report: %+v", report.BlocklistReport)
+			return
+		}
+		if report.BlocklistReport.Lists[1].Error == "" {
+			t.Errorf("expected an error for the missing list")
+		}
+	})
 }
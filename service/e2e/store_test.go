@@ -1,11 +1,16 @@
 package e2e
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/zalgonoise/dns/service"
 	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/zonefile"
 )
 
 func TestStore(t *testing.T) {
@@ -57,14 +62,14 @@ func TestStore(t *testing.T) {
 		t.Run("Success", func(t *testing.T) {
 			ctx := context.Background()
 
-			r, err := s.GetRecordByTypeAndDomain(ctx, record1.Type, record1.Name)
+			rs, err := s.GetRecordByTypeAndDomain(ctx, record1.Type, record1.Name)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
 			}
 
-			if !reflect.DeepEqual(record1, r) {
-				t.Errorf("output mismatch error: wanted %v ; got %v", record1, r)
+			if !reflect.DeepEqual([]*store.Record{record1}, rs) {
+				t.Errorf("output mismatch error: wanted %v ; got %v", record1, rs)
 				return
 			}
 		})
@@ -99,14 +104,14 @@ func TestStore(t *testing.T) {
 				t.Errorf("unexpected error: %v", err)
 				return
 			}
-			r, err := s.GetRecordByTypeAndDomain(ctx, record3.Type, record3.Name)
+			rs, err := s.GetRecordByTypeAndDomain(ctx, record3.Type, record3.Name)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
 			}
 
-			if !reflect.DeepEqual(record3, r) {
-				t.Errorf("output mismatch error: wanted %v ; got %v", record3, r)
+			if !reflect.DeepEqual([]*store.Record{record3}, rs) {
+				t.Errorf("output mismatch error: wanted %v ; got %v", record3, rs)
 				return
 			}
 		})
@@ -139,3 +144,85 @@ func TestStore(t *testing.T) {
 	})
 
 }
+
+func TestZoneFile(t *testing.T) {
+	s := initializeService()
+
+	t.Run("ImportZone", func(t *testing.T) {
+		t.Run("Success", func(t *testing.T) {
+			ctx := context.Background()
+			zone := `$TTL 300
+@	IN	SOA	ns1 hostmaster ( 1 7200 3600 1209600 300 )
+	IN	NS	ns1
+ns1	IN	A	10.0.0.53
+www	IN	A	10.0.0.1
+sub	IN	NS	ns1.sub
+ns1.sub	IN	A	10.0.1.53
+sub	IN	SOA	ns1.sub hostmaster ( 1 7200 3600 1209600 300 )
+host.sub	IN	A	10.0.1.1
+`
+
+			rs, err := s.ImportZone(ctx, "corp.lan", strings.NewReader(zone))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(rs) != 8 {
+				t.Errorf("unexpected returned records length: wanted %v ; got %v", 8, len(rs))
+			}
+
+			wants := store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").TTL(300).Build()
+			rs, err = s.GetRecordByTypeAndDomain(ctx, "A", "www.corp.lan")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if !reflect.DeepEqual([]*store.Record{wants}, rs) {
+				t.Errorf("output mismatch error: wanted %v ; got %v", wants, rs)
+			}
+		})
+
+		t.Run("FailInvalidZoneFile", func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.ImportZone(ctx, "corp.lan", strings.NewReader("www IN A not-an-address\n"))
+			if !errors.Is(err, zonefile.ErrParse) {
+				t.Errorf("unexpected error: wanted %v ; got %v", zonefile.ErrParse, err)
+			}
+		})
+	})
+
+	t.Run("ExportZone", func(t *testing.T) {
+		t.Run("Success", func(t *testing.T) {
+			ctx := context.Background()
+			buf := &bytes.Buffer{}
+
+			err := s.ExportZone(ctx, "corp.lan", buf)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			out := buf.String()
+			if !strings.HasPrefix(out, "$ORIGIN corp.lan.\n@\t300\tIN\tSOA\t") {
+				t.Errorf("unexpected zone file header: %s", out)
+			}
+			if !strings.Contains(out, "\nsub\t300\tIN\tNS\tns1.sub.corp.lan.\n") ||
+				!strings.Contains(out, "\nns1.sub\t300\tIN\tA\t10.0.1.53\n") {
+				t.Errorf("expected delegation to child zone and its glue in zone file: %s", out)
+			}
+			if strings.Contains(out, "host.sub") {
+				t.Errorf("expected child zone records to be left out: %s", out)
+			}
+		})
+
+		t.Run("FailNoZone", func(t *testing.T) {
+			ctx := context.Background()
+
+			err := s.ExportZone(ctx, "example.com", &bytes.Buffer{})
+			if !errors.Is(err, service.ErrNoZone) {
+				t.Errorf("unexpected error: wanted %v ; got %v", service.ErrNoZone, err)
+			}
+		})
+	})
+}
@@ -10,14 +10,28 @@ import (
 )
 
 // StoreHealth uses the health.Repository to generate a health.StoreReport
+//
+// If the store.Repository is a store.Syncer which failed to write its records to its
+// backing file, the error is included in the report, with an Unhealthy status
 func (s *service) StoreHealth(ctx context.Context) *health.StoreReport {
 	before := time.Now()
 	r, err := s.store.List(ctx)
 	after := time.Since(before)
+
+	var report *health.StoreReport
 	if err != nil {
-		return s.health.Store(ctx, 0, 0)
+		report = s.health.Store(ctx, 0, 0)
+	} else {
+		report = s.health.Store(ctx, len(r), after)
+	}
+
+	if sync, ok := s.store.(store.Syncer); ok {
+		if err := sync.Err(); err != nil {
+			report.SyncError = err.Error()
+			report.Status = health.Unhealthy
+		}
 	}
-	return s.health.Store(ctx, len(r), after)
+	return report
 }
 
 // DNSHealth uses the health.Repository to generate a health.DNSReport
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+const (
+	reverseIPv4Suffix = ".in-addr.arpa"
+	reverseIPv6Suffix = ".ip6.arpa"
+)
+
+// answerReverse answers PTR questions for reverse-lookup domain names (in-addr.arpa and
+// ip6.arpa) with the names of the A and AAAA records in the store holding that address,
+// if the service is configured to do so
+//
+// Returns true if the question was handled: either answered from the store, or failed
+// with dns.ErrNXDomain for private addresses which are not in the store, so that these
+// are not leaked to the fallback DNS. Partial reverse-lookup domain names for private
+// addresses (such as `168.192.in-addr.arpa`) are answered with no records if the store
+// holds an address within them (NODATA), or with dns.ErrNXDomain otherwise, unless they
+// are inside a zone owned by this server
+func (s *service) answerReverse(ctx context.Context, r *store.Record, m *dnsr.Msg) (bool, error) {
+	if s.conf == nil || s.conf.DNS == nil || !s.conf.DNS.Reverse {
+		return false, nil
+	}
+
+	network, ok := networkFromReverse(r.Name)
+	if !ok {
+		return false, nil
+	}
+	if ones, bits := network.Mask.Size(); ones != bits {
+		return s.answerReversePrefix(ctx, r.Name, network)
+	}
+
+	var answered bool
+	ip := network.IP
+	records, err := s.store.FilterByDest(ctx, ip.String())
+	if err == nil {
+		for _, record := range records {
+			if record.Type != store.TypeA.String() && record.Type != store.TypeAAAA.String() {
+				continue
+			}
+
+			err := s.dns.Answer(ctx, store.New().
+				Type(store.TypePTR.String()).
+				Name(r.Name).
+				Addr(record.Name).
+				TTL(record.TTL).
+				Build(),
+				m,
+			)
+			if err == nil {
+				answered = true
+			}
+		}
+	}
+	if answered {
+		return true, nil
+	}
+
+	if isPrivate(ip) {
+		return true, dns.ErrNXDomain
+	}
+	return false, nil
+}
+
+// answerReversePrefix answers PTR questions for the partial reverse-lookup domain name
+// `name`, covering the addresses in `network`, if all of them are private addresses
+func (s *service) answerReversePrefix(ctx context.Context, name string, network *net.IPNet) (bool, error) {
+	if !isPrivate(network.IP) || !isPrivate(lastAddr(network)) {
+		return false, nil
+	}
+	if s.findSOA(ctx, name) != nil {
+		return false, nil
+	}
+
+	if ok, err := store.HasNetwork(ctx, s.store, network); err == nil && ok {
+		return true, nil
+	}
+	return true, dns.ErrNXDomain
+}
+
+// isPrivate returns true if the IP address `ip` is a private, loopback or link-local
+// address, which is never sent to the fallback DNS
+func isPrivate(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
+
+// lastAddr returns the last IP address in the network `network`
+func lastAddr(network *net.IPNet) net.IP {
+	ip := make(net.IP, len(network.IP))
+	for i := range ip {
+		ip[i] = network.IP[i] | ^network.Mask[i]
+	}
+	return ip
+}
+
+// networkFromReverse parses the network in the reverse-lookup domain name `name`, such
+// as `10.0.168.192.in-addr.arpa` (192.168.0.10/32), `168.192.in-addr.arpa`
+// (192.168.0.0/16) or `(...).ip6.arpa`
+//
+// Returns the network and true if `name` holds a valid (and possibly partial) IPv4 or
+// IPv6 address; where complete addresses are returned with a full mask
+func networkFromReverse(name string) (*net.IPNet, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	switch {
+	case strings.HasSuffix(name, reverseIPv4Suffix):
+		labels := strings.Split(strings.TrimSuffix(name, reverseIPv4Suffix), ".")
+		if len(labels) > net.IPv4len {
+			return nil, false
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		for i := range labels {
+			n, err := strconv.ParseUint(labels[len(labels)-1-i], 10, 8)
+			if err != nil {
+				return nil, false
+			}
+			ip[i] = byte(n)
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(labels)*8, net.IPv4len*8)}, true
+
+	case strings.HasSuffix(name, reverseIPv6Suffix):
+		labels := strings.Split(strings.TrimSuffix(name, reverseIPv6Suffix), ".")
+		if len(labels) > net.IPv6len*2 {
+			return nil, false
+		}
+
+		ip := make(net.IP, net.IPv6len)
+		for i := range labels {
+			label := labels[len(labels)-1-i]
+			if len(label) != 1 {
+				return nil, false
+			}
+			n, err := strconv.ParseUint(label, 16, 4)
+			if err != nil {
+				return nil, false
+			}
+			if i%2 == 0 {
+				n <<= 4
+			}
+			ip[i/2] |= byte(n)
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(labels)*4, net.IPv6len*8)}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// maxRotations is the maximum number of record sets the rotator keeps a counter for;
+// once it is reached, all of the counters start over
+const maxRotations = 10000
+
+// rotator keeps a per-key counter used to rotate the order of the records in
+// a record set, for each query that is answered with it (round-robin)
+type rotator struct {
+	mtx     sync.Mutex
+	offsets map[string]int
+}
+
+func newRotator() *rotator {
+	return &rotator{
+		offsets: map[string]int{},
+	}
+}
+
+// next returns the offset to apply to a record set of length `n` identified by
+// string `key`, advancing its counter for the next query
+func (r *rotator) next(key string, n int) int {
+	if n < 2 {
+		return 0
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	offset, ok := r.offsets[key]
+	if !ok && len(r.offsets) >= maxRotations {
+		r.offsets = map[string]int{}
+	}
+	offset %= n
+	r.offsets[key] = offset + 1
+	return offset
+}
+
+// setKey returns the rotator key for the record set `records` of type `rtype`, from the
+// addresses of its members rather than its domain name; so that all of the names a
+// wildcard record set answers for share the same counter
+func setKey(rtype string, records []*store.Record) string {
+	var sb strings.Builder
+	sb.WriteString(rtype)
+	for _, record := range records {
+		sb.WriteByte(' ')
+		sb.WriteString(record.Addr)
+	}
+	return sb.String()
+}
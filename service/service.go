@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"sync"
 
 	dnsr "github.com/miekg/dns"
 	"github.com/zalgonoise/dns/cmd/config"
@@ -29,6 +31,10 @@ var (
 type Service interface {
 	StoreService
 	DNSService
+	CacheService
+	DNSSECService
+	TransferService
+	UpdateService
 	HealthService
 }
 
@@ -40,9 +46,9 @@ type StoreService interface {
 	AddRecords(ctx context.Context, rs ...*store.Record) error
 	// ListRecord uses the store.Repository to return all DNS Records
 	ListRecords(ctx context.Context) ([]*store.Record, error)
-	// GetRecordByDomain uses the store.Repository to return the DNS Record associated with
+	// GetRecordByDomain uses the store.Repository to return the DNS Records associated with
 	// the domain name and record type found in store.Record `r`
-	GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error)
+	GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error)
 	// GetRecordByDomain uses the store.Repository to return the DNS Records associated with
 	// the IP address found in store.Record `r`
 	GetRecordByAddress(ctx context.Context, address string) ([]*store.Record, error)
@@ -51,13 +57,68 @@ type StoreService interface {
 	UpdateRecord(ctx context.Context, domain string, r *store.Record) error
 	// DeleteRecord uses the store.Repository to remove the store.Record based on input `r`
 	DeleteRecord(ctx context.Context, r *store.Record) error
+	// AddZone uses the store.Repository to create the SOA and NS records for the
+	// store.Zone `z`, which is then answered authoritatively
+	AddZone(ctx context.Context, z *store.Zone) error
+	// ImportZone uses the store.Repository to create the DNS Records in the master zone
+	// file read from io.Reader `r`, with the origin `origin` for its relative domain names
+	ImportZone(ctx context.Context, origin string, r io.Reader) ([]*store.Record, error)
+	// ExportZone uses the store.Repository to write the DNS Records in the zone `zone` to
+	// io.Writer `w`, as a master zone file
+	ExportZone(ctx context.Context, zone string, w io.Writer) error
+	// FlushRecords uses the store.Repository to write its pending changes to its backing
+	// file right away, if it is a store.Syncer
+	FlushRecords(ctx context.Context) error
 }
 
 // DNSService interface joins the set of methods leveraging the dns.Repository
 type DNSService interface {
 	// AnswerDNS uses the dns.Repository to reply to the dns.Msg `m` with the answer
 	// in store.Record `r`
-	AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg)
+	//
+	// Returns dns.ErrNXDomain if the domain name does not exist, or an error wrapping
+	// dns.ErrServFail if the query could not be resolved
+	AnswerDNS(ctx context.Context, r *store.Record, m *dnsr.Msg) error
+}
+
+// CacheService interface joins the set of methods leveraging the cache for fallback
+// DNS answers, if the dns.Repository is a dns.Cache
+type CacheService interface {
+	// FlushCache removes the cached fallback DNS answers for the domain name `name`, or all
+	// of them if `name` is empty, returning the number of removed entries
+	FlushCache(ctx context.Context, name string) (int, error)
+	// CacheStats returns the usage statistics of the cache for fallback DNS answers
+	CacheStats(ctx context.Context) (*dns.CacheStats, error)
+}
+
+// DNSSECService interface joins the set of methods leveraging the DNSSEC keys of the
+// zones owned by this server, if the Service signs its answers
+type DNSSECService interface {
+	// DS returns the DS records for the DNSSEC keys of the zone `zone`, to be published
+	// in its parent zone
+	DS(ctx context.Context, zone string) ([]*dnsr.DS, error)
+}
+
+// TransferService interface joins the set of methods for the transfers of the zones owned
+// by this server to their secondary servers, and from their primary servers
+type TransferService interface {
+	// TransferZone returns the records of the zone `zone` for a full zone transfer (AXFR),
+	// starting and ending with its SOA record
+	TransferZone(ctx context.Context, zone string) ([]dnsr.RR, error)
+	// TransferChanges returns the records for an incremental zone transfer (IXFR) of the
+	// zone `zone`, with the changes since the serial `serial`
+	TransferChanges(ctx context.Context, zone string, serial uint32) ([]dnsr.RR, error)
+	// NotifyZone signals that the zone `zone` changed in its primary DNS server, to the
+	// serial `serial`, if the store.Repository is a store.Secondary holding it
+	NotifyZone(ctx context.Context, zone string, serial uint32) error
+}
+
+// UpdateService interface joins the set of methods for the dynamic updates to the zones
+// owned by this server
+type UpdateService interface {
+	// UpdateZone applies the dynamic update (RFC 2136) with the prerequisites `prereqs` and
+	// the updates `updates` to the zone `zone`, atomically
+	UpdateZone(ctx context.Context, zone string, prereqs, updates []dnsr.RR) error
 }
 
 // HealthService interface joins the set of methods leveraging the health.Repository
@@ -85,8 +146,8 @@ type StoreWithHealth interface {
 //
 //	granular scope of which operations can a certain module access
 type Answering interface {
-	GetRecordByTypeAndDomain(context.Context, string, string) (*store.Record, error)
-	AnswerDNS(context.Context, *store.Record, *dnsr.Msg)
+	GetRecordByTypeAndDomain(context.Context, string, string) ([]*store.Record, error)
+	AnswerDNS(context.Context, *store.Record, *dnsr.Msg) error
 }
 
 type service struct {
@@ -94,6 +155,9 @@ type service struct {
 	store  store.Repository
 	health health.Repository
 	conf   *config.Config
+	rr     *rotator
+
+	updates sync.Mutex
 }
 
 type withTrace struct {
@@ -129,6 +193,7 @@ func New(
 		store:  storeR,
 		health: healthR,
 		conf:   conf,
+		rr:     newRotator(),
 	}
 }
 
@@ -39,12 +39,12 @@ func (s *service) ListRecords(ctx context.Context) ([]*store.Record, error) {
 	return rs, nil
 }
 
-// GetRecordByDomain uses the store.Repository to return the DNS Record associated with
+// GetRecordByDomain uses the store.Repository to return the DNS Records associated with
 // the domain name and record type found in store.Record `r`
 //
 // Returns a NoName error if no domain name is provided
 // Returns a NoType if no record type is provided
-func (s *service) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error) {
+func (s *service) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
 	if domain == "" {
 		return nil, ErrNoName
 	}
@@ -52,12 +52,12 @@ func (s *service) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain st
 		return nil, ErrNoType
 	}
 
-	r, err := s.store.FindByTypeAndDomain(ctx, rtype, domain)
+	rs, err := s.store.FindByTypeAndDomain(ctx, rtype, domain)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't fetch target record: %w", err)
 	}
 
-	return r, nil
+	return rs, nil
 }
 
 // GetRecordByDomain uses the store.Repository to return the DNS Records associated with
@@ -110,6 +110,9 @@ func (s *service) UpdateRecord(ctx context.Context, domain string, r *store.Reco
 }
 
 // DeleteRecord uses the store.Repository to remove the store.Record based on input `r`
+//
+// If `r` holds a domain name, record type and address (or data), only that record is
+// removed from its set; otherwise all records matching the set fields are removed
 func (s *service) DeleteRecord(ctx context.Context, r *store.Record) error {
 	if r == nil {
 		return ErrEmtpyRecord
@@ -125,6 +128,9 @@ func (s *service) DeleteRecord(ctx context.Context, r *store.Record) error {
 	if r.Addr != "" {
 		delFn = func() error { return s.store.DeleteByAddress(ctx, r.Addr) }
 	}
+	if r.Name != "" && r.Type != "" && (r.Addr != "" || r.Data != nil) {
+		delFn = func() error { return s.store.Delete(ctx, r) }
+	}
 
 	err := delFn()
 	if err != nil {
@@ -132,3 +138,36 @@ func (s *service) DeleteRecord(ctx context.Context, r *store.Record) error {
 	}
 	return nil
 }
+
+// AddZone uses the store.Repository to create the SOA and NS records for the
+// store.Zone `z`, which is then answered authoritatively
+//
+// Returns a NoName error if the zone has no name
+func (s *service) AddZone(ctx context.Context, z *store.Zone) error {
+	if z == nil || z.Name == "" {
+		return ErrNoName
+	}
+
+	err := s.store.Create(ctx, z.Records()...)
+	if err != nil {
+		return fmt.Errorf("couldn't add zone records: %w", err)
+	}
+
+	return nil
+}
+
+// FlushRecords uses the store.Repository to write its pending changes to its backing
+// file right away, if it is a store.Syncer (such as before shutting down)
+func (s *service) FlushRecords(ctx context.Context) error {
+	sync, ok := s.store.(store.Syncer)
+	if !ok {
+		return nil
+	}
+
+	err := sync.Flush(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't flush records: %w", err)
+	}
+
+	return nil
+}
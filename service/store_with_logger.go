@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/zalgonoise/attr"
 	"github.com/zalgonoise/dns/store"
@@ -45,10 +46,10 @@ func (l withLogger) ListRecords(ctx context.Context) ([]*store.Record, error) {
 	return records, err
 }
 
-// GetRecordByDomain uses the store.Repository to return the DNS Record associated with
+// GetRecordByDomain uses the store.Repository to return the DNS Records associated with
 // the domain name and record type found in store.Record `r`
-func (l withLogger) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error) {
-	r, err := l.s.GetRecordByTypeAndDomain(ctx, rtype, domain)
+func (l withLogger) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
+	records, err := l.s.GetRecordByTypeAndDomain(ctx, rtype, domain)
 	if err != nil {
 		l.log.Error("failed to get record by type and domain",
 			attr.String("error", err.Error()),
@@ -57,7 +58,7 @@ func (l withLogger) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain
 		)
 	}
 
-	return r, err
+	return records, err
 }
 
 // GetRecordByDomain uses the store.Repository to return the DNS Records associated with
@@ -100,3 +101,58 @@ func (l withLogger) DeleteRecord(ctx context.Context, r *store.Record) error {
 
 	return err
 }
+
+// AddZone uses the store.Repository to create the SOA and NS records for the
+// store.Zone `z`, which is then answered authoritatively
+func (l withLogger) AddZone(ctx context.Context, z *store.Zone) error {
+	err := l.s.AddZone(ctx, z)
+	if err != nil {
+		l.log.Error("failed to add zone",
+			attr.String("error", err.Error()),
+			attr.New("input", z),
+		)
+	}
+
+	return err
+}
+
+// ImportZone uses the store.Repository to create the DNS Records in the master zone
+// file read from io.Reader `r`, with the origin `origin` for its relative domain names
+func (l withLogger) ImportZone(ctx context.Context, origin string, r io.Reader) ([]*store.Record, error) {
+	records, err := l.s.ImportZone(ctx, origin, r)
+	if err != nil {
+		l.log.Error("failed to import zone",
+			attr.String("error", err.Error()),
+			attr.String("input", origin),
+		)
+	}
+
+	return records, err
+}
+
+// ExportZone uses the store.Repository to write the DNS Records in the zone `zone` to
+// io.Writer `w`, as a master zone file
+func (l withLogger) ExportZone(ctx context.Context, zone string, w io.Writer) error {
+	err := l.s.ExportZone(ctx, zone, w)
+	if err != nil {
+		l.log.Error("failed to export zone",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+		)
+	}
+
+	return err
+}
+
+// FlushRecords uses the store.Repository to write its pending changes to its backing
+// file right away, if it is a store.Syncer
+func (l withLogger) FlushRecords(ctx context.Context) error {
+	err := l.s.FlushRecords(ctx)
+	if err != nil {
+		l.log.Error("failed to flush records",
+			attr.String("error", err.Error()),
+		)
+	}
+
+	return err
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/zalgonoise/attr"
 	"github.com/zalgonoise/dns/store"
@@ -51,9 +52,9 @@ func (t withTrace) ListRecords(ctx context.Context) ([]*store.Record, error) {
 	return records, err
 }
 
-// GetRecordByDomain uses the store.Repository to return the DNS Record associated with
+// GetRecordByDomain uses the store.Repository to return the DNS Records associated with
 // the domain name and record type found in store.Record `r`
-func (t withTrace) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error) {
+func (t withTrace) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
 	ctx, s := spanner.Start(ctx, "service.GetRecordByTypeAndDomain")
 	defer s.End()
 	s.Add(
@@ -61,14 +62,17 @@ func (t withTrace) GetRecordByTypeAndDomain(ctx context.Context, rtype, domain s
 		attr.String("domain", domain),
 	)
 
-	r, err := t.s.GetRecordByTypeAndDomain(ctx, rtype, domain)
+	records, err := t.s.GetRecordByTypeAndDomain(ctx, rtype, domain)
 	if err != nil {
 		s.Event("error fetching record", attr.New("error", err.Error()))
 	} else {
-		s.Add(attr.New("record", r))
+		s.Add(
+			attr.Int("records_length", len(records)),
+			attr.New("records", records),
+		)
 	}
 
-	return r, err
+	return records, err
 }
 
 // GetRecordByDomain uses the store.Repository to return the DNS Records associated with
@@ -119,3 +123,64 @@ func (t withTrace) DeleteRecord(ctx context.Context, r *store.Record) error {
 
 	return err
 }
+
+// AddZone uses the store.Repository to create the SOA and NS records for the
+// store.Zone `z`, which is then answered authoritatively
+func (t withTrace) AddZone(ctx context.Context, z *store.Zone) error {
+	ctx, s := spanner.Start(ctx, "service.AddZone")
+	defer s.End()
+	s.Add(attr.New("zone", z))
+
+	err := t.s.AddZone(ctx, z)
+	if err != nil {
+		s.Event("error adding zone", attr.New("error", err.Error()))
+	}
+
+	return err
+}
+
+// ImportZone uses the store.Repository to create the DNS Records in the master zone
+// file read from io.Reader `r`, with the origin `origin` for its relative domain names
+func (t withTrace) ImportZone(ctx context.Context, origin string, r io.Reader) ([]*store.Record, error) {
+	ctx, s := spanner.Start(ctx, "service.ImportZone")
+	defer s.End()
+	s.Add(attr.String("origin", origin))
+
+	records, err := t.s.ImportZone(ctx, origin, r)
+	if err != nil {
+		s.Event("error importing zone", attr.New("error", err.Error()))
+		return nil, err
+	}
+	s.Add(attr.Int("len", len(records)))
+
+	return records, nil
+}
+
+// ExportZone uses the store.Repository to write the DNS Records in the zone `zone` to
+// io.Writer `w`, as a master zone file
+func (t withTrace) ExportZone(ctx context.Context, zone string, w io.Writer) error {
+	ctx, s := spanner.Start(ctx, "service.ExportZone")
+	defer s.End()
+	s.Add(attr.String("zone", zone))
+
+	err := t.s.ExportZone(ctx, zone, w)
+	if err != nil {
+		s.Event("error exporting zone", attr.New("error", err.Error()))
+	}
+
+	return err
+}
+
+// FlushRecords uses the store.Repository to write its pending changes to its backing
+// file right away, if it is a store.Syncer
+func (t withTrace) FlushRecords(ctx context.Context) error {
+	ctx, s := spanner.Start(ctx, "service.FlushRecords")
+	defer s.End()
+
+	err := t.s.FlushRecords(ctx)
+	if err != nil {
+		s.Event("error flushing records", attr.New("error", err.Error()))
+	}
+
+	return err
+}
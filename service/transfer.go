@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+// TransferZone returns the records of the zone `zone` for a full zone transfer (AXFR,
+// RFC 5936), starting and ending with its SOA record
+//
+// The records inside the zones nested in it are left out, except for the NS records
+// delegating them and their glue records. Returns a NoName error if the zone has no name,
+// or an ErrNoZone error if the zone is not owned by this server
+func (s *service) TransferZone(ctx context.Context, zone string) ([]dnsr.RR, error) {
+	if zone == "" {
+		return nil, ErrNoName
+	}
+	zone = strings.TrimSuffix(zone, ".")
+
+	soa, err := s.zoneSOA(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list any records: %w", err)
+	}
+
+	transfer := make([]*store.Record, 0, len(records)+2)
+	transfer = append(transfer, soa)
+	for _, r := range store.ZoneRecords(zone, records) {
+		if r.Type != store.TypeSOA.String() || !strings.EqualFold(r.Name, soa.Name) {
+			transfer = append(transfer, r)
+		}
+	}
+	transfer = append(transfer, soa)
+
+	return s.toRR(ctx, transfer)
+}
+
+// TransferChanges returns the records for an incremental zone transfer (IXFR, RFC 1995)
+// of the zone `zone`, from the serial `serial` to its current serial
+//
+// The records start and end with the zone's current SOA record, and hold each change
+// since `serial` as the SOA record before it, the deleted records, the SOA record after
+// it and the added records. If the serial `serial` is not older than the zone's current
+// serial, only the current SOA record is returned; and if the changes since `serial` are
+// not journaled, the records for a full zone transfer are returned instead
+//
+// Returns a NoName error if the zone has no name, or an ErrNoZone error if the zone is not
+// owned by this server
+func (s *service) TransferChanges(ctx context.Context, zone string, serial uint32) ([]dnsr.RR, error) {
+	if zone == "" {
+		return nil, ErrNoName
+	}
+	zone = strings.TrimSuffix(zone, ".")
+
+	soa, err := s.zoneSOA(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	if current := soa.Data.Serial; current == serial || int32(current-serial) < 0 {
+		return s.toRR(ctx, []*store.Record{soa})
+	}
+
+	j, ok := s.store.(store.Journal)
+	if !ok {
+		return s.TransferZone(ctx, zone)
+	}
+	changes, err := j.Changes(ctx, zone, serial)
+	if err != nil {
+		if errors.Is(err, store.ErrNotJournaled) {
+			return s.TransferZone(ctx, zone)
+		}
+		return nil, fmt.Errorf("couldn't fetch zone changes: %w", err)
+	}
+
+	transfer := []*store.Record{soa}
+	for _, change := range changes {
+		transfer = append(transfer, change.From)
+		transfer = append(transfer, change.Deleted...)
+		transfer = append(transfer, change.To)
+		transfer = append(transfer, change.Added...)
+	}
+	transfer = append(transfer, soa)
+
+	return s.toRR(ctx, transfer)
+}
+
+// NotifyZone signals that the zone `zone` changed in its primary DNS server, to the serial
+// `serial` (RFC 1996), so that the store.Secondary holding it is refreshed right away
+//
+// Returns a NoName error if the zone has no name, or an ErrNoZone error if the
+// store.Repository is not a store.Secondary holding the zone
+func (s *service) NotifyZone(ctx context.Context, zone string, serial uint32) error {
+	if zone == "" {
+		return ErrNoName
+	}
+
+	sec, ok := s.store.(store.Secondary)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoZone, zone)
+	}
+	if err := sec.Notify(ctx, zone, serial); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrNoZone, zone)
+		}
+		return fmt.Errorf("couldn't notify zone change: %w", err)
+	}
+
+	return nil
+}
+
+// zoneSOA returns the SOA record of the zone `zone`, or an ErrNoZone error if the zone is
+// not owned by this server
+func (s *service) zoneSOA(ctx context.Context, zone string) (*store.Record, error) {
+	records, err := s.store.FindByTypeAndDomain(ctx, store.TypeSOA.String(), zone)
+	if err != nil || len(records) == 0 || records[0].Data == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoZone, zone)
+	}
+	return records[0], nil
+}
+
+// toRR uses the dns.Repository to convert the store.Records `records` into DNS records
+func (s *service) toRR(ctx context.Context, records []*store.Record) ([]dnsr.RR, error) {
+	m := new(dnsr.Msg)
+	m.Answer = make([]dnsr.RR, 0, len(records))
+
+	for _, r := range records {
+		if err := s.dns.Answer(ctx, r, m); err != nil {
+			return nil, fmt.Errorf("couldn't convert record %s %s: %w", r.Type, r.Name, err)
+		}
+	}
+	return m.Answer, nil
+}
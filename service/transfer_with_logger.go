@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+)
+
+// TransferZone returns the records of the zone `zone` for a full zone transfer (AXFR),
+// starting and ending with its SOA record
+func (l withLogger) TransferZone(ctx context.Context, zone string) ([]dnsr.RR, error) {
+	rrs, err := l.s.TransferZone(ctx, zone)
+	if err != nil {
+		l.log.Error("failed to transfer zone",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+		)
+	}
+
+	return rrs, err
+}
+
+// TransferChanges returns the records for an incremental zone transfer (IXFR) of the
+// zone `zone`, with the changes since the serial `serial`
+func (l withLogger) TransferChanges(ctx context.Context, zone string, serial uint32) ([]dnsr.RR, error) {
+	rrs, err := l.s.TransferChanges(ctx, zone, serial)
+	if err != nil {
+		l.log.Error("failed to transfer zone changes",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+			attr.Int("serial", int(serial)),
+		)
+	}
+
+	return rrs, err
+}
+
+// NotifyZone signals that the zone `zone` changed in its primary DNS server, to the
+// serial `serial`, if the store.Repository is a store.Secondary holding it
+func (l withLogger) NotifyZone(ctx context.Context, zone string, serial uint32) error {
+	err := l.s.NotifyZone(ctx, zone, serial)
+	if err != nil {
+		l.log.Error("failed to notify zone change",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+			attr.Int("serial", int(serial)),
+		)
+	}
+
+	return err
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+// TransferZone returns the records of the zone `zone` for a full zone transfer (AXFR),
+// starting and ending with its SOA record
+func (t withTrace) TransferZone(ctx context.Context, zone string) ([]dnsr.RR, error) {
+	ctx, s := spanner.Start(ctx, "service.TransferZone")
+	defer s.End()
+	s.Add(attr.String("zone", zone))
+
+	rrs, err := t.s.TransferZone(ctx, zone)
+	if err != nil {
+		s.Event("error transferring zone", attr.New("error", err.Error()))
+		return nil, err
+	}
+	s.Add(attr.Int("len", len(rrs)))
+
+	return rrs, nil
+}
+
+// TransferChanges returns the records for an incremental zone transfer (IXFR) of the
+// zone `zone`, with the changes since the serial `serial`
+func (t withTrace) TransferChanges(ctx context.Context, zone string, serial uint32) ([]dnsr.RR, error) {
+	ctx, s := spanner.Start(ctx, "service.TransferChanges")
+	defer s.End()
+	s.Add(
+		attr.String("zone", zone),
+		attr.Int("serial", int(serial)),
+	)
+
+	rrs, err := t.s.TransferChanges(ctx, zone, serial)
+	if err != nil {
+		s.Event("error transferring zone changes", attr.New("error", err.Error()))
+		return nil, err
+	}
+	s.Add(attr.Int("len", len(rrs)))
+
+	return rrs, nil
+}
+
+// NotifyZone signals that the zone `zone` changed in its primary DNS server, to the
+// serial `serial`, if the store.Repository is a store.Secondary holding it
+func (t withTrace) NotifyZone(ctx context.Context, zone string, serial uint32) error {
+	ctx, s := spanner.Start(ctx, "service.NotifyZone")
+	defer s.End()
+	s.Add(
+		attr.String("zone", zone),
+		attr.Int("serial", int(serial)),
+	)
+
+	err := t.s.NotifyZone(ctx, zone, serial)
+	if err != nil {
+		s.Event("error notifying zone change", attr.New("error", err.Error()))
+	}
+
+	return err
+}
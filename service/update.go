@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/zonefile"
+)
+
+var (
+	ErrUpdateFormat = errors.New("malformed dynamic update")
+	ErrNotZone      = errors.New("domain name is not within the zone")
+	ErrYXDomain     = errors.New("domain name exists when it should not")
+	ErrYXRRSet      = errors.New("RRset exists when it should not")
+	ErrNXRRSet      = errors.New("RRset does not exist when it should")
+)
+
+// UpdateZone applies the dynamic update (RFC 2136) with the prerequisites `prereqs` and
+// the updates `updates` to the zone `zone`, as a single change to the store.Repository
+//
+// The prerequisites are checked against the records in the zone, and the updates are
+// validated, before any record is changed; so that either all of the updates are applied,
+// or none of them are. The zone's serial is incremented (unless the update sets a newer
+// SOA record), and updates to the zone are applied one at a time. All of the changes
+// are applied as a single batch, so the store.Repository must be a store.Batcher
+//
+// Returns a NoName error if the zone has no name, an ErrNoZone error if the zone is not
+// owned by this server, or an error wrapping ErrUpdateFormat, ErrNotZone, ErrYXDomain,
+// ErrYXRRSet, ErrNXRRSet or dns.ErrNXDomain if the update is invalid or its prerequisites
+// are not met. Returns an error wrapping store.ErrNoBatch if the store.Repository can't
+// apply the changes atomically
+func (s *service) UpdateZone(ctx context.Context, zone string, prereqs, updates []dnsr.RR) error {
+	if zone == "" {
+		return ErrNoName
+	}
+	zone = strings.TrimSuffix(zone, ".")
+
+	s.updates.Lock()
+	defer s.updates.Unlock()
+
+	soa, err := s.zoneSOA(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	all, err := s.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't list any records: %w", err)
+	}
+	records := make([]*store.Record, 0, len(all))
+	for _, r := range all {
+		if withinZone(zone, r.Name) {
+			records = append(records, r)
+		}
+	}
+
+	if err := checkPrereqs(zone, records, prereqs); err != nil {
+		return err
+	}
+	if err := checkUpdates(zone, updates); err != nil {
+		return err
+	}
+
+	updated := applyUpdates(zone, records, updates)
+	deleted, added := store.Diff(records, updated)
+	if len(deleted) == 0 && len(added) == 0 {
+		return nil
+	}
+
+	// the zone's serial is incremented, unless the update already set a newer one
+	if next := rrsetOf(updated, zone, store.TypeSOA.String()); len(next) == 1 && next[0].Data.Serial == soa.Data.Serial {
+		bumped := *next[0]
+		data := *next[0].Data
+		data.Serial++
+		bumped.Data = &data
+
+		updated = without(updated, func(r *store.Record) bool { return r == next[0] })
+		updated = append(updated, &bumped)
+		deleted, added = store.Diff(records, updated)
+	}
+
+	b, ok := s.store.(store.Batcher)
+	if !ok {
+		return fmt.Errorf("%w: %T", store.ErrNoBatch, s.store)
+	}
+	return b.Batch(ctx, func(r store.Repository) error {
+		return applyChanges(ctx, r, deleted, added)
+	})
+}
+
+// checkPrereqs checks the prerequisites `prereqs` of a dynamic update to the zone `zone`
+// against its records `records` (RFC 2136, section 3.2)
+//
+// Value-dependent prerequisites (in the zone's class) are gathered into RRsets, which must
+// match the RRsets in the zone exactly (regardless of their TTL)
+func checkPrereqs(zone string, records []*store.Record, prereqs []dnsr.RR) error {
+	var (
+		rrsets = map[string][]*store.Record{}
+		keys   []string
+	)
+
+	for _, rr := range prereqs {
+		hdr := rr.Header()
+		name := strings.TrimSuffix(hdr.Name, ".")
+		rtype := dnsr.TypeToString[hdr.Rrtype]
+
+		if !withinZone(zone, name) {
+			return fmt.Errorf("%w: %s", ErrNotZone, hdr.Name)
+		}
+		if hdr.Ttl != 0 {
+			return fmt.Errorf("%w: prerequisite with a non-zero TTL", ErrUpdateFormat)
+		}
+
+		switch hdr.Class {
+		case dnsr.ClassANY:
+			if hdr.Rdlength != 0 {
+				return fmt.Errorf("%w: prerequisite with data", ErrUpdateFormat)
+			}
+			if hdr.Rrtype == dnsr.TypeANY {
+				if len(rrsetOf(records, name, "")) == 0 {
+					return fmt.Errorf("%w: %s", dns.ErrNXDomain, hdr.Name)
+				}
+				continue
+			}
+			if len(rrsetOf(records, name, rtype)) == 0 {
+				return fmt.Errorf("%w: %s %s", ErrNXRRSet, hdr.Name, rtype)
+			}
+		case dnsr.ClassNONE:
+			if hdr.Rdlength != 0 {
+				return fmt.Errorf("%w: prerequisite with data", ErrUpdateFormat)
+			}
+			if hdr.Rrtype == dnsr.TypeANY {
+				if len(rrsetOf(records, name, "")) > 0 {
+					return fmt.Errorf("%w: %s", ErrYXDomain, hdr.Name)
+				}
+				continue
+			}
+			if len(rrsetOf(records, name, rtype)) > 0 {
+				return fmt.Errorf("%w: %s %s", ErrYXRRSet, hdr.Name, rtype)
+			}
+		case dnsr.ClassINET:
+			r, ok := toRecord(rr)
+			if !ok {
+				return fmt.Errorf("%w: unsupported record type %s", ErrUpdateFormat, rtype)
+			}
+			key := strings.ToLower(name) + " " + rtype
+			if _, ok := rrsets[key]; !ok {
+				keys = append(keys, key)
+			}
+			rrsets[key] = append(rrsets[key], r)
+		default:
+			return fmt.Errorf("%w: prerequisite with class %s", ErrUpdateFormat, dnsr.ClassToString[hdr.Class])
+		}
+	}
+
+	for _, key := range keys {
+		wants := rrsets[key]
+		if !sameRecords(wants, rrsetOf(records, wants[0].Name, wants[0].Type)) {
+			return fmt.Errorf("%w: %s %s", ErrNXRRSet, wants[0].Name, wants[0].Type)
+		}
+	}
+	return nil
+}
+
+// checkUpdates validates the updates `updates` to the zone `zone`, before any of them is
+// applied (RFC 2136, section 3.4.1)
+func checkUpdates(zone string, updates []dnsr.RR) error {
+	for _, rr := range updates {
+		hdr := rr.Header()
+		rtype := dnsr.TypeToString[hdr.Rrtype]
+
+		if !withinZone(zone, strings.TrimSuffix(hdr.Name, ".")) {
+			return fmt.Errorf("%w: %s", ErrNotZone, hdr.Name)
+		}
+
+		switch hdr.Class {
+		case dnsr.ClassINET:
+			if _, ok := toRecord(rr); !ok {
+				return fmt.Errorf("%w: unsupported record type %s", ErrUpdateFormat, rtype)
+			}
+		case dnsr.ClassANY:
+			if hdr.Ttl != 0 || hdr.Rdlength != 0 || isTransferType(hdr.Rrtype) {
+				return fmt.Errorf("%w: invalid RRset deletion for %s %s", ErrUpdateFormat, hdr.Name, rtype)
+			}
+		case dnsr.ClassNONE:
+			if hdr.Ttl != 0 || hdr.Rrtype == dnsr.TypeANY || isTransferType(hdr.Rrtype) {
+				return fmt.Errorf("%w: invalid record deletion for %s %s", ErrUpdateFormat, hdr.Name, rtype)
+			}
+		default:
+			return fmt.Errorf("%w: update with class %s", ErrUpdateFormat, dnsr.ClassToString[hdr.Class])
+		}
+	}
+	return nil
+}
+
+// applyUpdates applies the (validated) updates `updates` to a copy of the records
+// `records` in the zone `zone`, returning the updated records (RFC 2136, section 3.4.2)
+//
+// Records in the zone's class are added, replacing the same record with another TTL; but
+// SOA records are only replaced with a newer serial, and CNAME records are not mixed with
+// other records for the same domain name. RRsets (or all RRsets for a domain name) in the
+// ANY class are deleted, and so are the records in the NONE class; except for the SOA
+// record and the last NS record at the zone's apex
+func applyUpdates(zone string, records []*store.Record, updates []dnsr.RR) []*store.Record {
+	updated := append([]*store.Record{}, records...)
+
+	for _, rr := range updates {
+		hdr := rr.Header()
+		name := strings.ToLower(strings.TrimSuffix(hdr.Name, "."))
+		rtype := dnsr.TypeToString[hdr.Rrtype]
+		apex := strings.EqualFold(name, zone)
+
+		switch hdr.Class {
+		case dnsr.ClassINET:
+			r, _ := toRecord(rr)
+			switch r.Type {
+			case store.TypeSOA.String():
+				current := rrsetOf(updated, name, r.Type)
+				if !apex || (len(current) > 0 && !store.NewerSerial(r.Data.Serial, current[0].Data.Serial)) {
+					continue
+				}
+				updated = without(updated, func(rec *store.Record) bool { return sameSet(rec, name, r.Type) })
+			case store.TypeCNAME.String():
+				if len(rrsetOf(updated, name, "")) > len(rrsetOf(updated, name, r.Type)) {
+					continue
+				}
+				updated = without(updated, func(rec *store.Record) bool { return sameSet(rec, name, r.Type) })
+			default:
+				if len(rrsetOf(updated, name, store.TypeCNAME.String())) > 0 {
+					continue
+				}
+				updated = without(updated, func(rec *store.Record) bool { return sameRecord(rec, r) })
+			}
+			updated = append(updated, r)
+
+		case dnsr.ClassANY:
+			updated = without(updated, func(rec *store.Record) bool {
+				if !sameSet(rec, name, "") || (apex && isApexType(rec.Type)) {
+					return false
+				}
+				return hdr.Rrtype == dnsr.TypeANY || rec.Type == rtype
+			})
+
+		case dnsr.ClassNONE:
+			r, ok := toRecord(rr)
+			if !ok || r.Type == store.TypeSOA.String() {
+				continue
+			}
+			if apex && r.Type == store.TypeNS.String() && len(rrsetOf(updated, name, r.Type)) <= 1 {
+				continue
+			}
+			updated = without(updated, func(rec *store.Record) bool { return sameRecord(rec, r) })
+		}
+	}
+	return updated
+}
+
+// applyChanges deletes the records `deleted` from the store.Repository `r` and creates the
+// records `added`
+//
+// It is meant to be called within a store.Batcher's batch, which discards all of the
+// changes if one of them fails
+func applyChanges(ctx context.Context, r store.Repository, deleted, added []*store.Record) error {
+	for _, rec := range deleted {
+		if err := r.Delete(ctx, rec); err != nil {
+			return fmt.Errorf("couldn't delete target record: %w", err)
+		}
+	}
+
+	if len(added) > 0 {
+		if err := r.Create(ctx, added...); err != nil {
+			return fmt.Errorf("couldn't add target records: %w", err)
+		}
+	}
+	return nil
+}
+
+// toRecord converts the DNS record `rr` into a store.Record, regardless of its class,
+// returning false if its record type is not supported by the store
+func toRecord(rr dnsr.RR) (*store.Record, bool) {
+	if _, ok := rr.(*dnsr.RR_Header); ok {
+		return nil, false
+	}
+
+	rr = dnsr.Copy(rr)
+	rr.Header().Class = dnsr.ClassINET
+	rr.Header().Name = strings.ToLower(rr.Header().Name)
+	return zonefile.FromRR(rr)
+}
+
+// rrsetOf returns the records in `records` with the domain name `name` and the record type
+// `rtype`, or all records for the domain name if `rtype` is empty
+func rrsetOf(records []*store.Record, name, rtype string) []*store.Record {
+	var rrset []*store.Record
+	for _, r := range records {
+		if sameSet(r, name, rtype) {
+			rrset = append(rrset, r)
+		}
+	}
+	return rrset
+}
+
+// without returns the records in `records` for which the function `fn` returns false
+func without(records []*store.Record, fn func(r *store.Record) bool) []*store.Record {
+	kept := make([]*store.Record, 0, len(records))
+	for _, r := range records {
+		if !fn(r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// sameSet returns true if the store.Record `r` has the domain name `name` and the record
+// type `rtype` (or any record type, if `rtype` is empty)
+func sameSet(r *store.Record, name, rtype string) bool {
+	return strings.EqualFold(r.Name, strings.TrimSuffix(name, ".")) && (rtype == "" || r.Type == rtype)
+}
+
+// sameRecord returns true if the store.Records `a` and `b` hold the same record,
+// regardless of their TTL
+func sameRecord(a, b *store.Record) bool {
+	var dataA, dataB store.Data
+	if a.Data != nil {
+		dataA = *a.Data
+	}
+	if b.Data != nil {
+		dataB = *b.Data
+	}
+	return sameSet(a, b.Name, b.Type) &&
+		strings.EqualFold(strings.TrimSuffix(a.Addr, "."), strings.TrimSuffix(b.Addr, ".")) &&
+		fmt.Sprintf("%+v", dataA) == fmt.Sprintf("%+v", dataB)
+}
+
+// sameRecords returns true if the store.Records `a` and `b` hold the same set of records,
+// regardless of their TTL
+func sameRecords(a, b []*store.Record) bool {
+	contains := func(records []*store.Record, r *store.Record) bool {
+		for _, rec := range records {
+			if sameRecord(rec, r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range a {
+		if !contains(b, r) {
+			return false
+		}
+	}
+	for _, r := range b {
+		if !contains(a, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// withinZone returns true if the domain name `name` is the zone `zone` or a subdomain of it
+func withinZone(zone, name string) bool {
+	return dnsr.IsSubDomain(dnsr.Fqdn(zone), dnsr.Fqdn(name))
+}
+
+// isApexType returns true if the record type `rtype` is kept at the zone's apex when
+// deleting its RRsets (SOA and NS)
+func isApexType(rtype string) bool {
+	return rtype == store.TypeSOA.String() || rtype == store.TypeNS.String()
+}
+
+// isTransferType returns true if the record type `rtype` is a zone transfer meta-type,
+// which cannot be updated
+func isTransferType(rtype uint16) bool {
+	return rtype == dnsr.TypeAXFR || rtype == dnsr.TypeIXFR
+}
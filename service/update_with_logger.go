@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+)
+
+// UpdateZone applies the dynamic update (RFC 2136) with the prerequisites `prereqs` and
+// the updates `updates` to the zone `zone`, atomically
+func (l withLogger) UpdateZone(ctx context.Context, zone string, prereqs, updates []dnsr.RR) error {
+	err := l.s.UpdateZone(ctx, zone, prereqs, updates)
+	if err != nil {
+		l.log.Error("failed to update zone",
+			attr.String("error", err.Error()),
+			attr.String("input", zone),
+			attr.Int("prereqs", len(prereqs)),
+			attr.Int("updates", len(updates)),
+		)
+	}
+
+	return err
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+// UpdateZone applies the dynamic update (RFC 2136) with the prerequisites `prereqs` and
+// the updates `updates` to the zone `zone`, atomically
+func (t withTrace) UpdateZone(ctx context.Context, zone string, prereqs, updates []dnsr.RR) error {
+	ctx, s := spanner.Start(ctx, "service.UpdateZone")
+	defer s.End()
+	s.Add(
+		attr.String("zone", zone),
+		attr.Int("prereqs", len(prereqs)),
+		attr.Int("updates", len(updates)),
+	)
+
+	err := t.s.UpdateZone(ctx, zone, prereqs, updates)
+	if err != nil {
+		s.Event("error updating zone", attr.New("error", err.Error()))
+	}
+
+	return err
+}
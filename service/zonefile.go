@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	dnsr "github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/zonefile"
+)
+
+// ImportZone uses the store.Repository to create the DNS Records in the master zone
+// file read from io.Reader `r`, completing its relative domain names with the origin
+// `origin` (unless the zone file sets its own). The records are added to the ones
+// already in the store
+//
+// Returns the created records, or an error wrapping zonefile.ErrParse if the zone file
+// is invalid, in which case no records are created
+func (s *service) ImportZone(ctx context.Context, origin string, r io.Reader) ([]*store.Record, error) {
+	records, err := zonefile.Parse(r, origin)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrEmtpyRecord
+	}
+
+	err = s.store.Create(ctx, records...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't add zone records: %w", err)
+	}
+
+	return records, nil
+}
+
+// ExportZone uses the store.Repository to write the DNS Records in the zone `zone` to
+// io.Writer `w`, as a master zone file with names relative to the zone
+//
+// The records inside the zones nested in it (with their own SOA record) are left out,
+// except for the NS records delegating them and their glue records. Returns a NoName
+// error if the zone has no name, or an ErrNoZone error if there are no records in the zone
+func (s *service) ExportZone(ctx context.Context, zone string, w io.Writer) error {
+	if zone == "" {
+		return ErrNoName
+	}
+	zone = dnsr.CanonicalName(zone)
+
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't list any records: %w", err)
+	}
+
+	inZone := store.ZoneRecords(zone, records)
+	if len(inZone) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoZone, zone)
+	}
+
+	return zonefile.Write(w, zone, inZone)
+}
@@ -0,0 +1,14 @@
+package store
+
+import "context"
+
+// Batcher is a Repository which can apply a set of changes to its records atomically, as
+// a single change
+type Batcher interface {
+	Repository
+
+	// Batch calls the function `fn` with the Repository to change, applying all of the
+	// changes it makes as a single one if it returns nil, or none of them otherwise.
+	// Returns an error wrapping ErrNoBatch if the Repository can't apply them atomically
+	Batch(ctx context.Context, fn func(r Repository) error) error
+}
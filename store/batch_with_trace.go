@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+// Batch calls the function `fn` with the Repository to change, applying all of the
+// changes it makes as a single one, if the wrapped Repository is a Batcher
+//
+// Returns an error wrapping ErrNoBatch otherwise
+func (t withTrace) Batch(ctx context.Context, fn func(r Repository) error) error {
+	ctx, s := spanner.Start(ctx, "store.Batch")
+	defer s.End()
+
+	b, ok := t.r.(Batcher)
+	if !ok {
+		err := fmt.Errorf("%w: %T", ErrNoBatch, t.r)
+		s.Event("error applying batch", attr.New("error", err.Error()))
+		return err
+	}
+
+	err := b.Batch(ctx, fn)
+	if err != nil {
+		s.Event("error applying batch", attr.New("error", err.Error()))
+	}
+
+	return err
+}
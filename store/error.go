@@ -12,4 +12,9 @@ var (
 	ErrZeroBytesWritten error = errors.New("zero bytes written")
 	ErrSync             error = errors.New("sync error")
 	ErrZeroRecords      error = errors.New("zero records in the store")
+	ErrReadOnly         error = errors.New("read-only store")
+	ErrNoBatch          error = errors.New("store does not support batches")
+	ErrUnsupportedType  error = errors.New("unsupported DNS record type")
+	ErrInvalidAddr      error = errors.New("invalid IP address")
+	ErrNoData           error = errors.New("missing record data")
 )
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/zalgonoise/dns/store"
 	"github.com/zalgonoise/dns/store/encoder"
@@ -17,11 +18,21 @@ import (
 // from a file in JSON format
 //
 // The in-memory implementation used is store/memmap
+//
+// The file is replaced atomically on each sync (written to a temporary file which is
+// synced to disk and renamed over it), so a crash mid-write leaves the previous version
+// in place. With a sync delay, the changes within that delay are coalesced into a single
+// write (see SyncDelay), which must be flushed before shutting down
 type FileStore struct {
 	Path  string `json:"path,omitempty" yaml:"path,omitempty"`
 	store store.Repository
 	enc   encoder.EncodeDecoder
 	mtx   sync.RWMutex
+
+	delay   time.Duration
+	timer   *time.Timer
+	pending bool
+	err     error
 }
 
 // Store holds a set of (DNS) Records
@@ -29,15 +40,21 @@ type Store struct {
 	Types []*Type `json:"types,omitempty" yaml:"types,omitempty"`
 }
 
-// Record is labeled by an IP address and contains a slice of (pointers to) Types
+// Record is labeled by an IP address (and any type-specific data and TTL) and
+// contains a slice of Domains
 type Record struct {
-	Address string   `json:"address,omitempty" yaml:"address,omitempty"`
-	Domains []string `json:"domains,omitempty"   yaml:"domains,omitempty"`
+	Address string      `json:"address,omitempty" yaml:"address,omitempty"`
+	TTL     uint32      `json:"ttl,omitempty"     yaml:"ttl,omitempty"`
+	Data    *store.Data `json:"data,omitempty"    yaml:"data,omitempty"`
+	Domains []string    `json:"domains,omitempty"   yaml:"domains,omitempty"`
 }
 
 // Type is labeled by a DNS record type and contains a slice of Domains
+//
+// Its TTL, if set, is applied to all of its Records which do not define one
 type Type struct {
 	RType   string    `json:"type,omitempty"    yaml:"type,omitempty"`
+	TTL     uint32    `json:"ttl,omitempty"     yaml:"ttl,omitempty"`
 	Records []*Record `json:"records,omitempty" yaml:"records,omitempty"`
 }
 
@@ -49,9 +66,10 @@ type Type struct {
 // This initialization function will try to open an existing file, or create it
 // if it does not exist, and also read it if it has content. If any of the critical
 // operations fail, the function will panic since the store will not be able to start.
+// All input Option `opts` are applied to the FileStore, which is also a store.Syncer
 //
 // TODO: decide if it's better to return a naked in-memory record store and log as critical
-func New(encoderType, path string) store.Repository {
+func New(encoderType, path string, opts ...Option) store.Repository {
 	var (
 		mainEnc     encoder.EncodeDecoder
 		altEnc      encoder.EncodeDecoder
@@ -98,9 +116,5 @@ func New(encoderType, path string) store.Repository {
 		}
 	}
 
-	return &FileStore{
-		Path:  path,
-		store: mstore,
-		enc:   mainEnc,
-	}
+	return newFileStore(path, mstore, mainEnc, opts...)
 }
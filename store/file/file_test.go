@@ -113,4 +113,67 @@ types:
 		}
 		rm(t)
 	})
+	t.Run("SuccessWithStructuredItemsInList", func(t *testing.T) {
+		ctx := context.Background()
+		wants := []*store.Record{
+			store.New().Type("MX").Name("not.a.dom.ain").Addr("mail.not.a.dom.ain").
+				Data(&store.Data{Preference: 10}).Build(),
+			store.New().Type("TXT").Name("not.a.dom.ain").
+				Data(&store.Data{Text: []string{"v=spf1 -all", "second string"}}).Build(),
+		}
+
+		repo := New("json", target)
+		if repo == nil {
+			t.Errorf("repository was unexpectedly nil")
+		}
+		err := repo.Create(ctx, wants...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// reload the store from the written file
+		repo = New("json", target)
+		for _, r := range wants {
+			rs, err := repo.FindByTypeAndDomain(ctx, r.Type, r.Name)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if !reflect.DeepEqual([]*store.Record{r}, rs) {
+				t.Errorf("output mismatch error; wanted %v ; got %v", r, rs)
+			}
+		}
+		rm(t)
+	})
+	t.Run("SuccessWithTTLsInList", func(t *testing.T) {
+		ctx := context.Background()
+		wants := []*store.Record{
+			store.New().Addr("192.168.0.10").Type("A").Name("not.a.dom.ain").TTL(60).Build(),
+			store.New().Addr("192.168.0.15").Type("A").Name("also.not.a.dom.ain").TTL(300).Build(),
+		}
+		err := os.WriteFile(
+			target,
+			[]byte(`{"types":[{"type":"A","ttl":300,"records":[{"address":"192.168.0.10","ttl":60,"domains":["not.a.dom.ain"]},{"address":"192.168.0.15","domains":["also.not.a.dom.ain"]}]}]}`),
+			os.FileMode(store.OS_ALL_RW),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		repo := New("json", target)
+		if repo == nil {
+			t.Errorf("repository was unexpectedly nil")
+		}
+		for _, r := range wants {
+			rs, err := repo.FindByTypeAndDomain(ctx, r.Type, r.Name)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if !reflect.DeepEqual([]*store.Record{r}, rs) {
+				t.Errorf("output mismatch error; wanted %v ; got %v", r, rs)
+			}
+		}
+		rm(t)
+	})
 }
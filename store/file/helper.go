@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
 
 	"github.com/zalgonoise/attr"
 	"github.com/zalgonoise/dns/store"
@@ -18,11 +20,17 @@ func toEntity(s *Store) []*store.Record {
 		rtype := recordType.RType
 		for _, record := range recordType.Records {
 			addr := record.Address
+			ttl := record.TTL
+			if ttl == 0 {
+				ttl = recordType.TTL
+			}
 			for _, domain := range record.Domains {
 				out = append(out, store.New().
 					Name(domain).
 					Type(rtype).
 					Addr(addr).
+					TTL(ttl).
+					Data(record.Data).
 					Build())
 			}
 		}
@@ -39,7 +47,9 @@ inputLoop:
 		for _, recordType := range out.Types {
 			if recordType.RType == r.Type {
 				for _, record := range recordType.Records {
-					if record.Address == r.Addr {
+					if record.Address == r.Addr &&
+						record.TTL == r.TTL &&
+						reflect.DeepEqual(record.Data, r.Data) {
 						for _, domain := range record.Domains {
 							if domain == r.Name {
 								continue inputLoop
@@ -51,6 +61,8 @@ inputLoop:
 				}
 				recordType.Records = append(recordType.Records, &Record{
 					Address: r.Addr,
+					TTL:     r.TTL,
+					Data:    r.Data,
 					Domains: []string{r.Name},
 				})
 				continue inputLoop
@@ -61,6 +73,8 @@ inputLoop:
 			Records: []*Record{
 				{
 					Address: r.Addr,
+					TTL:     r.TTL,
+					Data:    r.Data,
 					Domains: []string{r.Name},
 				},
 			},
@@ -101,7 +115,7 @@ func (f *FileStore) sync(ctx context.Context) error {
 
 	// write to file
 	_, s = spanner.Start(ctx, "store.file.Write")
-	err = os.WriteFile(f.Path, b, fs.FileMode(store.OS_ALL_RW))
+	err = writeFile(f.Path, b)
 	if err != nil {
 		s.Event("failed to write store records to file", attr.String("error", err.Error()))
 		s.End()
@@ -110,3 +124,59 @@ func (f *FileStore) sync(ctx context.Context) error {
 	s.End()
 	return nil
 }
+
+// writeFile replaces the file in `path` with the data in `b` atomically, by writing it to
+// a temporary file in the same directory, syncing it to disk and renaming it over `path`
+//
+// The file keeps its permissions (or gets the default ones, if it does not exist); and if
+// `path` is a symbolic link, the file it points to is replaced instead
+func writeFile(path string, b []byte) (err error) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	mode := fs.FileMode(store.OS_ALL_RW)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(b); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory as well, so that the rename is persisted; which is not supported
+	// on every platform, so its errors are ignored
+	if d, derr := os.Open(dir); derr == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	"fmt"
+	"net"
 
 	"github.com/zalgonoise/dns/store"
 )
@@ -10,7 +11,7 @@ import (
 // Create implements the store.Repository interface
 //
 // It will call the in-memory store's method of the same signature, while deferring
-// a `Sync()` call to ensure the records file is up-to-date
+// a sync to ensure the records file is up-to-date
 func (f *FileStore) Create(ctx context.Context, rs ...*store.Record) error {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
@@ -19,7 +20,7 @@ func (f *FileStore) Create(ctx context.Context, rs ...*store.Record) error {
 	if err != nil {
 		return fmt.Errorf("failed to create record: %w", err)
 	}
-	err = f.sync(ctx)
+	err = f.changed(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to sync store to file: %w", err)
 	}
@@ -40,12 +41,12 @@ func (f *FileStore) List(ctx context.Context) ([]*store.Record, error) {
 // FindByTypeAndDomain implements the store.Repository interface
 //
 // It will call the in-memory store's method of the same signature
-func (f *FileStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error) {
-	r, err := f.store.FindByTypeAndDomain(ctx, rtype, domain)
+func (f *FileStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
+	rs, err := f.store.FindByTypeAndDomain(ctx, rtype, domain)
 	if err != nil {
-		return r, fmt.Errorf("failed to get record by domain: %w", err)
+		return rs, fmt.Errorf("failed to get record by domain: %w", err)
 	}
-	return r, nil
+	return rs, nil
 }
 
 // FilterByDomain implements the store.Repository interface
@@ -70,10 +71,21 @@ func (f *FileStore) FilterByDest(ctx context.Context, addr string) ([]*store.Rec
 	return rs, nil
 }
 
+// HasNetwork implements the store.Networker interface
+//
+// It will look up the network in the in-memory store
+func (f *FileStore) HasNetwork(ctx context.Context, network *net.IPNet) (bool, error) {
+	ok, err := store.HasNetwork(ctx, f.store, network)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up network: %w", err)
+	}
+	return ok, nil
+}
+
 // Update implements the store.Repository interface
 //
 // It will call the in-memory store's method of the same signature, while deferring
-// a `Sync()` call to ensure the records file is up-to-date
+// a sync to ensure the records file is up-to-date
 func (f *FileStore) Update(ctx context.Context, domain string, r *store.Record) error {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
@@ -82,7 +94,26 @@ func (f *FileStore) Update(ctx context.Context, domain string, r *store.Record)
 	if err != nil {
 		return fmt.Errorf("failed to update record: %w", err)
 	}
-	err = f.sync(ctx)
+	err = f.changed(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to sync store to file: %w", err)
+	}
+	return nil
+}
+
+// Delete implements the store.Repository interface
+//
+// It will call the in-memory store's method of the same signature, while deferring
+// a sync to ensure the records file is up-to-date
+func (f *FileStore) Delete(ctx context.Context, r *store.Record) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	err := f.store.Delete(ctx, r)
+	if err != nil {
+		return fmt.Errorf("failed to delete record: %w", err)
+	}
+	err = f.changed(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to sync store to file: %w", err)
 	}
@@ -98,7 +129,7 @@ func (f *FileStore) DeleteByAddress(ctx context.Context, addr string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
-	err = f.sync(ctx)
+	err = f.changed(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to sync store to file: %w", err)
 	}
@@ -114,7 +145,7 @@ func (f *FileStore) DeleteByDomain(ctx context.Context, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
-	err = f.sync(ctx)
+	err = f.changed(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to sync store to file: %w", err)
 	}
@@ -130,7 +161,7 @@ func (f *FileStore) DeleteByTypeAndDomain(ctx context.Context, rtype, name strin
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
-	err = f.sync(ctx)
+	err = f.changed(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to sync store to file: %w", err)
 	}
@@ -45,18 +45,18 @@ func TestJSON(t *testing.T) {
 			t.Errorf("output length error: wanted %v ; got %v", 1, len(rs))
 		}
 		if !reflect.DeepEqual(test1, rs[0]) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, rs[0])
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs[0])
 		}
 	})
 	t.Run("FindByTypeAndDomain", func(t *testing.T) {
 		ctx := context.Background()
 
-		r, err := s.FindByTypeAndDomain(ctx, test1.Type, test1.Name)
+		rs, err := s.FindByTypeAndDomain(ctx, test1.Type, test1.Name)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		if !reflect.DeepEqual(test1, r) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, r)
+		if !reflect.DeepEqual([]*store.Record{test1}, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs)
 		}
 	})
 	t.Run("FilterByDest", func(t *testing.T) {
@@ -70,7 +70,7 @@ func TestJSON(t *testing.T) {
 			t.Errorf("output length error: wanted %v ; got %v", 1, len(rs))
 		}
 		if !reflect.DeepEqual(test1, rs[0]) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, rs[0])
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs[0])
 		}
 	})
 	t.Run("Update", func(t *testing.T) {
@@ -81,12 +81,12 @@ func TestJSON(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		r, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
+		rs, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		if !reflect.DeepEqual(test2, r) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test2, r)
+		if !reflect.DeepEqual([]*store.Record{test2}, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", test2, rs)
 		}
 
 		b, err := os.ReadFile(target)
@@ -158,18 +158,18 @@ func TestYAML(t *testing.T) {
 			t.Errorf("output length error: wanted %v ; got %v", 1, len(rs))
 		}
 		if !reflect.DeepEqual(test1, rs[0]) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, rs[0])
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs[0])
 		}
 	})
 	t.Run("FindByTypeAndDomain", func(t *testing.T) {
 		ctx := context.Background()
 
-		r, err := s.FindByTypeAndDomain(ctx, test1.Type, test1.Name)
+		rs, err := s.FindByTypeAndDomain(ctx, test1.Type, test1.Name)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		if !reflect.DeepEqual(test1, r) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, r)
+		if !reflect.DeepEqual([]*store.Record{test1}, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs)
 		}
 	})
 	t.Run("FilterByDest", func(t *testing.T) {
@@ -183,7 +183,7 @@ func TestYAML(t *testing.T) {
 			t.Errorf("output length error: wanted %v ; got %v", 1, len(rs))
 		}
 		if !reflect.DeepEqual(test1, rs[0]) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test1, rs[0])
+			t.Errorf("output mismatch error: wanted %v ; got %v", test1, rs[0])
 		}
 	})
 	t.Run("Update", func(t *testing.T) {
@@ -199,12 +199,12 @@ func TestYAML(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		r, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
+		rs, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		if !reflect.DeepEqual(test2, r) {
-			t.Errorf("output mismatch error: wanted %s ; got %s", test2, r)
+		if !reflect.DeepEqual([]*store.Record{test2}, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", test2, rs)
 		}
 
 		b, err := os.ReadFile(target)
@@ -0,0 +1,144 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/encoder"
+)
+
+// Option describes setter types for a FileStore
+type Option interface {
+	Apply(*FileStore)
+}
+
+// SyncDelay creates an Option setting the time the FileStore waits after a change to its
+// records before writing them to its file, coalescing all of the changes within it into a
+// single write (such as the ones from a bulk import)
+//
+// The changes are then written in the background, so the errors writing them are no
+// longer returned by the write operations, but by the FileStore's Err method. Without a
+// sync delay, the file is written on each change
+//
+// If `d` is zero or negative, it returns nil
+func SyncDelay(d time.Duration) Option {
+	if d <= 0 {
+		return nil
+	}
+	return &syncDelayOpt{
+		d: d,
+	}
+}
+
+type syncDelayOpt struct {
+	d time.Duration
+}
+
+// Apply implements the Option interface
+func (o *syncDelayOpt) Apply(f *FileStore) {
+	f.delay = o.d
+}
+
+func newFileStore(path string, r store.Repository, enc encoder.EncodeDecoder, opts ...Option) *FileStore {
+	f := &FileStore{
+		Path:  path,
+		store: r,
+		enc:   enc,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(f)
+		}
+	}
+	return f
+}
+
+// Batch implements the store.Batcher interface
+//
+// It applies the batch through the in-memory store, writing the changes to the file once
+// they are all applied (or after the sync delay, if set)
+func (f *FileStore) Batch(ctx context.Context, fn func(r store.Repository) error) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	b, ok := f.store.(store.Batcher)
+	if !ok {
+		return fmt.Errorf("%w: %T", store.ErrNoBatch, f.store)
+	}
+	if err := b.Batch(ctx, fn); err != nil {
+		return fmt.Errorf("failed to apply batch: %w", err)
+	}
+	if err := f.changed(ctx); err != nil {
+		return fmt.Errorf("failed to sync store to file: %w", err)
+	}
+	return nil
+}
+
+// Flush implements the store.Syncer interface
+//
+// It writes the pending changes to the file right away, if there are any, stopping the
+// sync delay's timer
+func (f *FileStore) Flush(ctx context.Context) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if !f.pending {
+		return nil
+	}
+	return f.write(ctx)
+}
+
+// Err implements the store.Syncer interface
+//
+// It returns the error from the last write to the file, or nil if it succeeded
+func (f *FileStore) Err() error {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.err
+}
+
+// changed syncs the records to the file after a change, or schedules it for after the sync
+// delay, if set
+//
+// It expects the caller to hold the FileStore's lock
+func (f *FileStore) changed(ctx context.Context) error {
+	f.pending = true
+	if f.delay == 0 {
+		return f.write(ctx)
+	}
+
+	if f.timer == nil {
+		f.timer = time.AfterFunc(f.delay, f.flushPending)
+	}
+	return nil
+}
+
+// flushPending writes the pending changes to the file, once the sync delay is over
+func (f *FileStore) flushPending() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.timer = nil
+	if f.pending {
+		_ = f.write(context.Background())
+	}
+}
+
+// write syncs the records to the file, keeping the error (if any) for the Err method
+//
+// It expects the caller to hold the FileStore's lock
+func (f *FileStore) write(ctx context.Context) error {
+	f.err = f.sync(ctx)
+	if f.err == nil {
+		f.pending = false
+	}
+	return f.err
+}
@@ -0,0 +1,151 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+func TestSyncDelay(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SuccessCoalesceChanges", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dns.list")
+		repo := New("json", path, SyncDelay(time.Hour))
+
+		for _, r := range []*store.Record{test1, test2} {
+			if err := repo.Create(ctx, r); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+		}
+
+		// the changes are pending until the sync delay is over, or they are flushed
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(b) != 0 {
+			t.Errorf("expected no writes before flushing; got %s", string(b))
+		}
+
+		if err := repo.(store.Syncer).Flush(ctx); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		rs, err := New("json", path).List(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rs) != 2 {
+			t.Errorf("unexpected store list length: wanted %v ; got %v", 2, len(rs))
+		}
+	})
+
+	t.Run("SuccessWriteAfterDelay", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dns.list")
+		repo := New("json", path, SyncDelay(10*time.Millisecond))
+
+		if err := repo.Create(ctx, test1); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if b, err := os.ReadFile(path); err == nil && len(b) > 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("timed out waiting for the store to be written")
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("FailSyncError", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "dns.list")
+		repo := New("json", path, SyncDelay(time.Hour))
+
+		// the file's directory is gone, so the changes can't be written
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := repo.Create(ctx, test1); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		err := repo.(store.Syncer).Flush(ctx)
+		if !errors.Is(err, store.ErrSync) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrSync, err)
+		}
+		if err := repo.(store.Syncer).Err(); !errors.Is(err, store.ErrSync) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrSync, err)
+		}
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Run("SuccessReplaceFile", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "dns.list")
+		if err := os.WriteFile(path, []byte("old"), 0o640); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := writeFile(path, []byte("new")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil || string(b) != "new" {
+			t.Errorf("unexpected file contents: %s ; error: %v", string(b), err)
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm() != 0o640 {
+			t.Errorf("unexpected file mode: %v ; error: %v", info.Mode().Perm(), err)
+		}
+
+		// no temporary files are left behind
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 {
+			t.Errorf("unexpected directory entries: %v ; error: %v", entries, err)
+		}
+	})
+
+	t.Run("SuccessFollowSymlink", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "dns.list")
+		link := filepath.Join(dir, "link.list")
+		if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.Symlink(path, link); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+
+		if err := writeFile(link, []byte("new")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("expected the symbolic link to be kept; error: %v", err)
+		}
+		if b, err := os.ReadFile(path); err != nil || string(b) != "new" {
+			t.Errorf("unexpected file contents: %s ; error: %v", string(b), err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/store/zonefile"
+)
+
+// NewZone returns a new FileStore as a store.Repository, backed by the master zone file
+// (RFC 1035) in `path`, with the origin `origin` for its relative domain names (unless
+// it sets its own, with an `$ORIGIN` directive)
+//
+// The zone file's `$INCLUDE` directives are followed when it is read. When the records
+// change, the zone file is rewritten from the store (without its comments or directives
+// other than `$ORIGIN`), with names relative to `origin`; or to the zone in the store if
+// `origin` is not set and the store holds a single SOA record.
+//
+// Like New, it will panic if the file can't be created, and it applies all input Option
+// `opts` to the FileStore
+func NewZone(path, origin string, opts ...Option) store.Repository {
+	mstore := memmap.New()
+
+	f, err := os.OpenFile(path, os.O_CREATE, os.FileMode(store.OS_ALL_RW))
+	if err != nil {
+		panic(err) // panic on init if file can't be saved to disk
+	}
+	f.Close()
+
+	records, err := zonefile.ReadFile(path, origin)
+	if err != nil {
+		log.Printf("failed to read zone file %s: %v\n", path, err)
+	}
+	if len(records) > 0 {
+		err := mstore.Create(context.Background(), records...)
+		if err != nil {
+			log.Printf("error adding entries: %v\n", err)
+		}
+	}
+
+	return newFileStore(path, mstore, zoneEnc{origin: origin}, opts...)
+}
+
+// zoneEnc is an encoder.EncodeDecoder for a Store, in the master zone file format
+type zoneEnc struct {
+	origin string
+}
+
+// Encode writes the records in the Store `v` as a zone file
+func (z zoneEnc) Encode(v any) ([]byte, error) {
+	s, ok := v.(*Store)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported type %T", zonefile.ErrRecord, v)
+	}
+
+	records := toEntity(s)
+	origin := z.origin
+	if origin == "" {
+		origin = zoneOf(records)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := zonefile.Write(buf, origin, records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads the records in a zone file into the Store `v`
+func (z zoneEnc) Decode(b []byte, v any) error {
+	s, ok := v.(*Store)
+	if !ok {
+		return fmt.Errorf("%w: unsupported type %T", zonefile.ErrParse, v)
+	}
+
+	records, err := zonefile.Parse(bytes.NewReader(b), z.origin)
+	if err != nil {
+		return err
+	}
+	*s = *fromEntity(records...)
+	return nil
+}
+
+// zoneOf returns the domain name of the SOA record in `records`, or an empty string if
+// there is not exactly one
+func zoneOf(records []*store.Record) string {
+	var zone string
+	for _, r := range records {
+		if r.Type != store.TypeSOA.String() {
+			continue
+		}
+		if zone != "" {
+			return ""
+		}
+		zone = r.Name
+	}
+	return zone
+}
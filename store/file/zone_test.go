@@ -0,0 +1,69 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+func TestNewZone(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "corp.lan.zone")
+
+	err := os.WriteFile(path, []byte(`$TTL 300
+@	IN	SOA	ns1 hostmaster ( 1 7200 3600 1209600 300 )
+www	IN	A	10.0.0.1
+`), os.FileMode(store.OS_ALL_RW))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("SuccessWithItemsInZoneFile", func(t *testing.T) {
+		wants := store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").TTL(300).Build()
+
+		repo := NewZone(path, "corp.lan")
+		rs, err := repo.FindByTypeAndDomain(ctx, "A", "www.corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual([]*store.Record{wants}, rs) {
+			t.Errorf("output mismatch error; wanted %v ; got %v", wants, rs)
+		}
+	})
+
+	t.Run("SuccessSyncToZoneFile", func(t *testing.T) {
+		wants := store.New().Type("A").Name("mail.corp.lan").Addr("10.0.0.25").TTL(60).Build()
+
+		err := NewZone(path, "corp.lan").Create(ctx, wants)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !strings.HasPrefix(string(b), "$ORIGIN corp.lan.\n@\t300\tIN\tSOA\t") ||
+			!strings.Contains(string(b), "\nmail\t60\tIN\tA\t10.0.0.25\n") {
+			t.Errorf("unexpected zone file contents: %s", string(b))
+		}
+
+		// reload the store from the written file, without an origin
+		rs, err := NewZone(path, "").List(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rs) != 3 {
+			t.Errorf("unexpected store list length: wanted %v ; got %v", 3, len(rs))
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoJournal    error = errors.New("record changes are not journaled")
+	ErrNotJournaled error = errors.New("serial is not in the journal")
+)
+
+// Journal defines the set of operations that a record store keeping a journal of the
+// changes to its zones should expose, on top of the Repository it wraps
+//
+// Each change to the records of a zone (a domain name with an SOA record) increments the
+// serial in its SOA record, and is kept as the records it deleted and added. This allows
+// the zone's secondary servers to transfer only the changes since their own copy of the
+// zone (IXFR, RFC 1995)
+type Journal interface {
+	Repository
+
+	// Changes returns the changes to the zone `zone` since the serial `serial`, oldest
+	// first; or no changes if `serial` is the zone's current serial
+	//
+	// Returns an error wrapping ErrNotJournaled if the journal does not hold the changes
+	// since `serial`, such as when it is too old
+	Changes(ctx context.Context, zone string, serial uint32) ([]*Change, error)
+}
+
+// Change describes a change to the records of a zone, from the SOA record `From` to the
+// SOA record `To`, with the records it deleted and the ones it added (other than the SOA
+// records)
+type Change struct {
+	From    *Record   `json:"from"`
+	To      *Record   `json:"to"`
+	Deleted []*Record `json:"deleted,omitempty"`
+	Added   []*Record `json:"added,omitempty"`
+}
@@ -0,0 +1,301 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// DefaultSize is the default number of changes kept in the journal for each zone
+const DefaultSize = 100
+
+// JournalStore is a store.Journal wrapping a store.Repository, keeping a journal of the
+// changes to the records of each zone in it (the domain names with an SOA record)
+//
+// All changes go through the JournalStore, which lists the records before and after each
+// of them to find the records it deleted and added in each zone. A zone with changed
+// records has the serial in its SOA record incremented (unless the change already set a
+// newer serial), and the change is added to the zone's journal, which keeps up to its
+// last `size` changes. The journal is only kept in memory
+//
+// The functions set with OnChange are called after each change to a zone, as well as when
+// a zone is created, with the zone's new serial
+type JournalStore struct {
+	store    store.Repository
+	size     int
+	onChange []func(ctx context.Context, zone string, serial uint32)
+
+	mtx     sync.RWMutex
+	changes map[string][]*store.Change
+}
+
+// Option describes setter types for a JournalStore
+type Option interface {
+	Apply(*JournalStore)
+}
+
+// Size creates an Option setting the number of changes kept in the journal for each zone
+// to `n`
+//
+// If `n` is zero or negative, it returns nil
+func Size(n int) Option {
+	if n <= 0 {
+		return nil
+	}
+	return &sizeOpt{
+		n: n,
+	}
+}
+
+// OnChange creates an Option adding the function `fn` to the ones called after each change
+// to a zone, with its name and new serial; such as to notify its secondary servers
+//
+// If `fn` is nil, it returns nil
+func OnChange(fn func(ctx context.Context, zone string, serial uint32)) Option {
+	if fn == nil {
+		return nil
+	}
+	return &onChangeOpt{
+		fn: fn,
+	}
+}
+
+type sizeOpt struct {
+	n int
+}
+
+type onChangeOpt struct {
+	fn func(ctx context.Context, zone string, serial uint32)
+}
+
+// Apply implements the Option interface
+func (o *sizeOpt) Apply(j *JournalStore) {
+	j.size = o.n
+}
+
+// Apply implements the Option interface
+func (o *onChangeOpt) Apply(j *JournalStore) {
+	j.onChange = append(j.onChange, o.fn)
+}
+
+// New returns a new JournalStore as a store.Journal, wrapping the store.Repository `r`
+// and applying all input Option `opts`
+func New(r store.Repository, opts ...Option) store.Journal {
+	j := &JournalStore{
+		store:   r,
+		size:    DefaultSize,
+		changes: map[string][]*store.Change{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(j)
+		}
+	}
+
+	return j
+}
+
+// Changes implements the store.Journal interface
+//
+// It returns the changes to the zone `zone` since the serial `serial`, oldest first; or no
+// changes if `serial` is the zone's current serial. Returns an error wrapping
+// store.ErrNotJournaled if there is no change from the serial `serial` in the journal
+func (j *JournalStore) Changes(ctx context.Context, zone string, serial uint32) ([]*store.Change, error) {
+	j.mtx.RLock()
+	defer j.mtx.RUnlock()
+
+	soa, err := j.store.FindByTypeAndDomain(ctx, store.TypeSOA.String(), strings.TrimSuffix(zone, "."))
+	if err != nil {
+		return nil, err
+	}
+	if len(soa) > 0 && serialOf(soa[0]) == serial {
+		return []*store.Change{}, nil
+	}
+
+	changes := j.changes[canonical(zone)]
+	for idx, change := range changes {
+		if serialOf(change.From) == serial {
+			return append([]*store.Change{}, changes[idx:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %d", store.ErrNotJournaled, zone, serial)
+}
+
+// Batch implements the store.Batcher interface
+//
+// It applies the batch through the wrapped store.Repository, journaling all of the changes
+// the function `fn` makes as a single change to each zone, with a single increment of its
+// serial. Returns an error wrapping store.ErrNoBatch if the wrapped store.Repository is not
+// a store.Batcher
+func (j *JournalStore) Batch(ctx context.Context, fn func(r store.Repository) error) error {
+	b, ok := j.store.(store.Batcher)
+	if !ok {
+		return fmt.Errorf("%w: %T", store.ErrNoBatch, j.store)
+	}
+	return j.apply(ctx, func() error { return b.Batch(ctx, fn) })
+}
+
+// Flush implements the store.Syncer interface
+//
+// It flushes the pending changes in the wrapped store.Repository, if it is a store.Syncer
+func (j *JournalStore) Flush(ctx context.Context) error {
+	if s, ok := j.store.(store.Syncer); ok {
+		return s.Flush(ctx)
+	}
+	return nil
+}
+
+// Err implements the store.Syncer interface
+//
+// It returns the error from the last write of the wrapped store.Repository, if it is a
+// store.Syncer
+func (j *JournalStore) Err() error {
+	if s, ok := j.store.(store.Syncer); ok {
+		return s.Err()
+	}
+	return nil
+}
+
+// zoneSerial is the new serial of a changed zone
+type zoneSerial struct {
+	zone   string
+	serial uint32
+}
+
+// apply calls the function `fn`, which changes the records in the wrapped
+// store.Repository, and adds the changes it made to the journal
+//
+// The journal is updated even if `fn` returns an error, as it may have partially changed
+// the records in the store
+func (j *JournalStore) apply(ctx context.Context, fn func() error) error {
+	j.mtx.Lock()
+
+	before, err := j.store.List(ctx)
+	if err != nil {
+		j.mtx.Unlock()
+		return err
+	}
+
+	err = fn()
+
+	var changed []zoneSerial
+	if after, lerr := j.store.List(ctx); lerr == nil {
+		changed = j.record(ctx, before, after)
+	}
+	j.mtx.Unlock()
+
+	for _, z := range changed {
+		for _, fn := range j.onChange {
+			fn(ctx, z.zone, z.serial)
+		}
+	}
+	return err
+}
+
+// record adds the changes between the records `before` and `after` to the journal of each
+// zone, incrementing their serials, and returns the zones which changed
+//
+// It expects the caller to hold the JournalStore's lock
+func (j *JournalStore) record(ctx context.Context, before, after []*store.Record) []zoneSerial {
+	prev := soaByZone(before)
+	next := soaByZone(after)
+
+	for zone := range prev {
+		if _, ok := next[zone]; !ok {
+			delete(j.changes, zone)
+		}
+	}
+
+	var changed []zoneSerial
+	for zone, soa := range next {
+		from, ok := prev[zone]
+		if !ok {
+			// a new zone starts a new journal
+			delete(j.changes, zone)
+			changed = append(changed, zoneSerial{zone: soa.Name, serial: serialOf(soa)})
+			continue
+		}
+
+		deleted, added := store.Diff(store.ZoneRecords(zone, before), store.ZoneRecords(zone, after))
+		deleted, added = withoutSOA(deleted), withoutSOA(added)
+		if len(deleted) == 0 && len(added) == 0 && store.Key(from) == store.Key(soa) {
+			continue
+		}
+
+		if !store.NewerSerial(serialOf(soa), serialOf(from)) {
+			soa = withSerial(soa, serialOf(from)+1)
+			if err := j.store.Update(ctx, soa.Name, soa); err != nil {
+				continue
+			}
+		}
+
+		changes := append(j.changes[zone], &store.Change{
+			From:    from,
+			To:      soa,
+			Deleted: deleted,
+			Added:   added,
+		})
+		if len(changes) > j.size {
+			changes = changes[len(changes)-j.size:]
+		}
+		j.changes[zone] = changes
+		changed = append(changed, zoneSerial{zone: soa.Name, serial: serialOf(soa)})
+	}
+	return changed
+}
+
+// soaByZone returns the SOA records in `records`, by their canonical domain name
+func soaByZone(records []*store.Record) map[string]*store.Record {
+	zones := map[string]*store.Record{}
+	for _, r := range records {
+		if r.Type == store.TypeSOA.String() {
+			zones[canonical(r.Name)] = r
+		}
+	}
+	return zones
+}
+
+// withoutSOA returns the records in `records` which are not SOA records
+func withoutSOA(records []*store.Record) []*store.Record {
+	var out []*store.Record
+	for _, r := range records {
+		if r.Type != store.TypeSOA.String() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// serialOf returns the serial in the SOA record `soa`
+func serialOf(soa *store.Record) uint32 {
+	if soa == nil || soa.Data == nil {
+		return 0
+	}
+	return soa.Data.Serial
+}
+
+// withSerial returns a copy of the SOA record `soa`, with the serial `serial`
+func withSerial(soa *store.Record, serial uint32) *store.Record {
+	var data store.Data
+	if soa.Data != nil {
+		data = *soa.Data
+	}
+	data.Serial = serial
+
+	return store.New().
+		Type(soa.Type).
+		Name(soa.Name).
+		Addr(soa.Addr).
+		TTL(soa.TTL).
+		Data(&data).
+		Build()
+}
+
+// canonical returns the domain name `name` in lowercase and without a trailing dot
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
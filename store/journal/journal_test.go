@@ -0,0 +1,166 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+)
+
+var (
+	zone   = &store.Zone{Name: "corp.lan", NS: []string{"ns1.corp.lan"}, Serial: 10}
+	www    = store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").Build()
+	www2   = store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.2").Build()
+	other  = store.New().Type("A").Name("www.example.com").Addr("10.0.0.3").Build()
+	nested = store.New().Type("A").Name("host.sub.corp.lan").Addr("10.0.1.1").Build()
+)
+
+func soaSerial(t *testing.T, j store.Journal) uint32 {
+	rs, err := j.FindByTypeAndDomain(context.Background(), "SOA", "corp.lan")
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("unexpected SOA lookup result: %v ; %v", rs, err)
+	}
+	return rs[0].Data.Serial
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+
+	var notified []uint32
+	j := New(memmap.New(), Size(2), OnChange(func(ctx context.Context, zone string, serial uint32) {
+		if zone != "corp.lan" {
+			t.Errorf("unexpected zone notified: %s", zone)
+		}
+		notified = append(notified, serial)
+	}))
+
+	t.Run("CreateZone", func(t *testing.T) {
+		if err := j.Create(ctx, zone.Records()...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if serial := soaSerial(t, j); serial != 10 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", 10, serial)
+		}
+
+		changes, err := j.Changes(ctx, "corp.lan", 10)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("expected no changes for the current serial: %v", changes)
+		}
+	})
+
+	t.Run("SuccessIncrementSerial", func(t *testing.T) {
+		if err := j.Create(ctx, www); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if serial := soaSerial(t, j); serial != 11 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", 11, serial)
+		}
+
+		changes, err := j.Changes(ctx, "corp.lan.", 10)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(changes) != 1 {
+			t.Errorf("unexpected changes length: wanted %v ; got %v", 1, len(changes))
+			return
+		}
+		if changes[0].From.Data.Serial != 10 || changes[0].To.Data.Serial != 11 {
+			t.Errorf("unexpected serials in change: %v ; %v", changes[0].From.Data, changes[0].To.Data)
+		}
+		if len(changes[0].Deleted) != 0 || !reflect.DeepEqual([]*store.Record{www}, changes[0].Added) {
+			t.Errorf("unexpected records in change: deleted %v ; added %v", changes[0].Deleted, changes[0].Added)
+		}
+	})
+
+	t.Run("SuccessUpdate", func(t *testing.T) {
+		if err := j.Update(ctx, www.Name, www2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		changes, err := j.Changes(ctx, "corp.lan", 10)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(changes) != 2 {
+			t.Errorf("unexpected changes length: wanted %v ; got %v", 2, len(changes))
+			return
+		}
+		if !reflect.DeepEqual([]*store.Record{www}, changes[1].Deleted) ||
+			!reflect.DeepEqual([]*store.Record{www2}, changes[1].Added) {
+			t.Errorf("unexpected records in change: deleted %v ; added %v", changes[1].Deleted, changes[1].Added)
+		}
+	})
+
+	t.Run("IgnoreOutsideZone", func(t *testing.T) {
+		if err := j.Create(ctx, other); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if serial := soaSerial(t, j); serial != 12 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", 12, serial)
+		}
+	})
+
+	t.Run("FailTrimmedSerial", func(t *testing.T) {
+		if err := j.Create(ctx, nested); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := j.Changes(ctx, "corp.lan", 10)
+		if !errors.Is(err, store.ErrNotJournaled) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrNotJournaled, err)
+		}
+		changes, err := j.Changes(ctx, "corp.lan", 11)
+		if err != nil || len(changes) != 2 {
+			t.Errorf("unexpected changes: %v ; %v", changes, err)
+		}
+	})
+
+	t.Run("KeepNewerSerial", func(t *testing.T) {
+		soa := zone.Records()[0]
+		soa.Data.Serial = 2023010101
+		if err := j.Update(ctx, soa.Name, soa); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if serial := soaSerial(t, j); serial != 2023010101 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", 2023010101, serial)
+		}
+	})
+
+	t.Run("SuccessBatch", func(t *testing.T) {
+		err := j.(store.Batcher).Batch(ctx, func(r store.Repository) error {
+			if err := r.Delete(ctx, www2); err != nil {
+				return err
+			}
+			return r.Create(ctx, www)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if serial := soaSerial(t, j); serial != 2023010102 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", 2023010102, serial)
+		}
+
+		changes, err := j.Changes(ctx, "corp.lan", 2023010101)
+		if err != nil || len(changes) != 1 {
+			t.Errorf("unexpected changes: %v ; %v", changes, err)
+			return
+		}
+		if !reflect.DeepEqual([]*store.Record{www2}, changes[0].Deleted) ||
+			!reflect.DeepEqual([]*store.Record{www}, changes[0].Added) {
+			t.Errorf("unexpected records in change: deleted %v ; added %v", changes[0].Deleted, changes[0].Added)
+		}
+	})
+
+	wants := []uint32{10, 11, 12, 13, 2023010101, 2023010102}
+	if !reflect.DeepEqual(wants, notified) {
+		t.Errorf("unexpected notified serials: wanted %v ; got %v", wants, notified)
+	}
+}
@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"context"
+	"net"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// Create implements the store.Repository interface
+//
+// It adds the records to the wrapped store.Repository, journaling the changes
+func (j *JournalStore) Create(ctx context.Context, rs ...*store.Record) error {
+	return j.apply(ctx, func() error { return j.store.Create(ctx, rs...) })
+}
+
+// List implements the store.Repository interface
+func (j *JournalStore) List(ctx context.Context) ([]*store.Record, error) {
+	return j.store.List(ctx)
+}
+
+// FindByTypeAndDomain implements the store.Repository interface
+func (j *JournalStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
+	return j.store.FindByTypeAndDomain(ctx, rtype, domain)
+}
+
+// FilterByDomain implements the store.Repository interface
+func (j *JournalStore) FilterByDomain(ctx context.Context, domain string) ([]*store.Record, error) {
+	return j.store.FilterByDomain(ctx, domain)
+}
+
+// FilterByDest implements the store.Repository interface
+func (j *JournalStore) FilterByDest(ctx context.Context, addr string) ([]*store.Record, error) {
+	return j.store.FilterByDest(ctx, addr)
+}
+
+// HasNetwork implements the store.Networker interface
+func (j *JournalStore) HasNetwork(ctx context.Context, network *net.IPNet) (bool, error) {
+	return store.HasNetwork(ctx, j.store, network)
+}
+
+// Update implements the store.Repository interface
+//
+// It updates the record in the wrapped store.Repository, journaling the changes
+func (j *JournalStore) Update(ctx context.Context, domain string, r *store.Record) error {
+	return j.apply(ctx, func() error { return j.store.Update(ctx, domain, r) })
+}
+
+// Delete implements the store.Repository interface
+//
+// It removes the record from the wrapped store.Repository, journaling the changes
+func (j *JournalStore) Delete(ctx context.Context, r *store.Record) error {
+	return j.apply(ctx, func() error { return j.store.Delete(ctx, r) })
+}
+
+// DeleteByAddress implements the store.Repository interface
+//
+// It removes the records from the wrapped store.Repository, journaling the changes
+func (j *JournalStore) DeleteByAddress(ctx context.Context, addr string) error {
+	return j.apply(ctx, func() error { return j.store.DeleteByAddress(ctx, addr) })
+}
+
+// DeleteByDomain implements the store.Repository interface
+//
+// It removes the records from the wrapped store.Repository, journaling the changes
+func (j *JournalStore) DeleteByDomain(ctx context.Context, name string) error {
+	return j.apply(ctx, func() error { return j.store.DeleteByDomain(ctx, name) })
+}
+
+// DeleteByTypeAndDomain implements the store.Repository interface
+//
+// It removes the records from the wrapped store.Repository, journaling the changes
+func (j *JournalStore) DeleteByTypeAndDomain(ctx context.Context, rtype, name string) error {
+	return j.apply(ctx, func() error { return j.store.DeleteByTypeAndDomain(ctx, rtype, name) })
+}
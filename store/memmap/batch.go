@@ -0,0 +1,43 @@
+package memmap
+
+import (
+	"context"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// Batch implements the store.Batcher interface
+//
+// It calls the function `fn` with a copy of the MemoryStore, which replaces its records
+// once `fn` returns successfully; so that either all of the changes it makes are applied,
+// or none of them are. The MemoryStore is locked while `fn` runs
+func (m *MemoryStore) Batch(ctx context.Context, fn func(r store.Repository) error) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	tx := &MemoryStore{
+		Records: make(map[string]map[string][]*store.Record, len(m.Records)),
+		names:   make(map[string]int, len(m.names)),
+		addrs:   make(map[string]int, len(m.addrs)),
+	}
+	for name, n := range m.names {
+		tx.names[name] = n
+	}
+	for prefix, n := range m.addrs {
+		tx.addrs[prefix] = n
+	}
+	for rtype, domains := range m.Records {
+		tx.Records[rtype] = make(map[string][]*store.Record, len(domains))
+		for name, records := range domains {
+			tx.Records[rtype][name] = append([]*store.Record{}, records...)
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.Records = tx.Records
+	m.names = tx.names
+	m.addrs = tx.addrs
+	return nil
+}
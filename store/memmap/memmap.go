@@ -1,6 +1,8 @@
 package memmap
 
 import (
+	"net"
+	"reflect"
 	"sync"
 
 	"github.com/zalgonoise/dns/store"
@@ -9,21 +11,77 @@ import (
 // MemoryStore is an in-memory implementation of a DNS record store
 //
 // It uses simple Go maps to represent a relationship of
-// record-type-to-domain-to-IP as a map[string]map[string]string.
+// record-type-to-domain-to-records as a map[string]map[string][]*store.Record,
+// where each domain holds a set of records for a given type (e.g. several A records).
 //
 // This direction is so that DNS queries can be answered faster, while the remaining
 // operations are not as important.
 //
-// It also has a sync.RWMutex to ensure that data races do not occur
+// It also keeps the number of record sets at (or below) each domain name, so that the
+// wildcard lookups can tell whether a name exists without scanning all of the records;
+// the number of A and AAAA records under each prefix of their IP addresses, so that
+// reverse-lookups for a network don't scan them either; and a sync.RWMutex to ensure
+// that data races do not occur
 type MemoryStore struct {
-	// maps a set of record types to domain names to IPs
-	Records map[string]map[string]string
+	// maps a set of record types to domain names to record sets
+	Records map[string]map[string][]*store.Record
+	names   map[string]int
+	addrs   map[string]int
 	mtx     sync.RWMutex
 }
 
 // New returns a new MemoryStore as a store.Repository
 func New() store.Repository {
 	return &MemoryStore{
-		Records: map[string]map[string]string{},
+		Records: map[string]map[string][]*store.Record{},
+		names:   map[string]int{},
+		addrs:   map[string]int{},
 	}
 }
+
+// clone returns a copy of the input store.Record `r`, so that the records
+// held in the MemoryStore are not shared with (or modified by) the caller
+func clone(r *store.Record) *store.Record {
+	var data *store.Data
+	if r.Data != nil {
+		d := *r.Data
+		if r.Data.Text != nil {
+			d.Text = make([]string, len(r.Data.Text))
+			copy(d.Text, r.Data.Text)
+		}
+		data = &d
+	}
+
+	return store.New().
+		Type(r.Type).
+		Name(r.Name).
+		Addr(r.Addr).
+		TTL(r.TTL).
+		Data(data).
+		Build()
+}
+
+// sameValue returns true if both store.Records `a` and `b` hold the same address and
+// type-specific data, meaning they are the same member of a record set
+func sameValue(a, b *store.Record) bool {
+	return a.Addr == b.Addr && reflect.DeepEqual(a.Data, b.Data)
+}
+
+// sameAddr returns true if the address `a` is the same as `b`, comparing them as IP
+// addresses if `ip` (the parsed `b`) is one, so that `fd00::10` matches `FD00:0::10`
+func sameAddr(a, b string, ip net.IP) bool {
+	if a == b {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	other := net.ParseIP(a)
+	return other != nil && other.Equal(ip)
+}
+
+// singleton returns true if the record type `rtype` does not support record sets,
+// but only a single record per domain name (such as CNAME and SOA)
+func singleton(rtype string) bool {
+	return rtype == store.TypeCNAME.String() || rtype == store.TypeSOA.String()
+}
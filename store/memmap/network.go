@@ -0,0 +1,75 @@
+package memmap
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// ipv4Offset is the length (in bits) of the prefix of an IPv4 address in its 16-byte form
+const ipv4Offset = (net.IPv6len - net.IPv4len) * 8
+
+// HasNetwork implements the store.Networker interface
+//
+// The addresses of the A and AAAA records are indexed by each of their prefixes (at each
+// 4-bit boundary), so that networks such as the ones in reverse-lookup domain names
+// (`168.192.in-addr.arpa`) are a single lookup. Other networks are compared with each of
+// the indexed addresses instead
+func (m *MemoryStore) HasNetwork(ctx context.Context, network *net.IPNet) (bool, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	ip := network.IP.To16()
+	ones, bits := network.Mask.Size()
+	if ip == nil || bits == 0 {
+		return false, nil
+	}
+	if bits == net.IPv4len*8 {
+		ones += ipv4Offset
+	}
+	if ones%4 == 0 {
+		return m.addrs[hex.EncodeToString(ip)[:ones/4]] > 0, nil
+	}
+
+	for key := range m.addrs {
+		if len(key) != net.IPv6len*2 {
+			continue
+		}
+		if addr, err := hex.DecodeString(key); err == nil && network.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// indexAddrs adds `delta` to the number of records with an IP address under each prefix
+// of the addresses of the A or AAAA records `records`, removing the prefixes which no
+// longer hold any
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) indexAddrs(records []*store.Record, delta int) {
+	if m.addrs == nil {
+		m.addrs = map[string]int{}
+	}
+
+	for _, record := range records {
+		ip := net.ParseIP(record.Addr)
+		if ip == nil {
+			continue
+		}
+
+		key := hex.EncodeToString(ip.To16())
+		for i := 0; i <= len(key); i++ {
+			if m.addrs[key[:i]] += delta; m.addrs[key[:i]] <= 0 {
+				delete(m.addrs, key[:i])
+			}
+		}
+	}
+}
+
+// addressed returns true if the records of type `rtype` are indexed by their IP address
+func addressed(rtype string) bool {
+	return rtype == store.TypeA.String() || rtype == store.TypeAAAA.String()
+}
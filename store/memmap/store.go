@@ -2,6 +2,7 @@ package memmap
 
 import (
 	"context"
+	"net"
 
 	"github.com/zalgonoise/dns/store"
 )
@@ -9,16 +10,29 @@ import (
 // Create implements the store.Repository interface
 //
 // It will not perform any lookups before writing the new records, and it will simply
-// blindly write the input records to the store.
+// add the input records to the set of records for their type and domain name.
+//
+// If the set already holds a record with the same address and data, it is replaced
+// by the new one (e.g. to update its TTL). CNAME and SOA records are not kept in sets,
+// so the new record replaces the existing one
 func (m *MemoryStore) Create(ctx context.Context, rs ...*store.Record) error {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
+inputLoop:
 	for _, r := range rs {
-		if _, ok := m.Records[r.Type]; !ok {
-			m.Records[r.Type] = map[string]string{}
+		if singleton(r.Type) {
+			m.set(r.Type, r.Name, []*store.Record{clone(r)})
+			continue
+		}
+
+		for idx, record := range m.Records[r.Type][r.Name] {
+			if sameValue(record, r) {
+				m.Records[r.Type][r.Name][idx] = clone(r)
+				continue inputLoop
+			}
 		}
-		m.Records[r.Type][r.Name] = r.Addr
+		m.set(r.Type, r.Name, append(m.Records[r.Type][r.Name], clone(r)))
 	}
 	return nil
 }
@@ -28,21 +42,16 @@ func (m *MemoryStore) Create(ctx context.Context, rs ...*store.Record) error {
 // It will build a list of pointers to store.Record which is returned alongside
 // any errors that are raised (currently there are no scenarios in this implementation)
 func (m *MemoryStore) List(ctx context.Context) ([]*store.Record, error) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
 
 	var output []*store.Record
 
-	for rtype, r := range m.Records {
-		for domain, addr := range r {
-			output = append(
-				output,
-				store.New().
-					Type(rtype).
-					Name(domain).
-					Addr(addr).
-					Build(),
-			)
+	for _, r := range m.Records {
+		for _, records := range r {
+			for _, record := range records {
+				output = append(output, clone(record))
+			}
 		}
 	}
 	return output, nil
@@ -50,23 +59,34 @@ func (m *MemoryStore) List(ctx context.Context) ([]*store.Record, error) {
 
 // FindByTypeAndDomain implements the store.Repository interface
 //
-// It will return a pointer to a store.Record if there is an IP address
+// It will return a list of pointers to store.Record if there is a record set
 // registered to the input store.Record's domain name and record type.
 //
+// If the domain name does not exist in the store, a matching wildcard record set
+// (e.g. `*.example.lan`) is returned instead, with the input domain as its name.
+//
 // It also returns an error in case the record does not exist
-func (m *MemoryStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) (*store.Record, error) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+func (m *MemoryStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
 
 	if _, ok := m.Records[rtype]; !ok {
 		return nil, store.ErrDoesNotExist
 	}
-	dest := m.Records[rtype][domain]
-	if dest == "" {
+	records := m.Records[rtype][domain]
+	if len(records) == 0 {
+		if output := m.wildcard(rtype, domain); len(output) > 0 {
+			return output, nil
+		}
 		return nil, store.ErrDoesNotExist
 	}
 
-	return store.New().Type(rtype).Name(domain).Addr(dest).Build(), nil
+	var output = make([]*store.Record, 0, len(records))
+	for _, record := range records {
+		output = append(output, clone(record))
+	}
+
+	return output, nil
 }
 
 // FilterByDomain implements the store.Repository interface
@@ -74,22 +94,23 @@ func (m *MemoryStore) FindByTypeAndDomain(ctx context.Context, rtype, domain str
 // It will return a list of pointers to store.Record if there records associated
 // with the input domain name, for all record types.
 //
+// If the domain name does not exist in the store, the records in a matching wildcard
+// (e.g. `*.example.lan`) are returned instead, with the input domain as their name.
+//
 // It also returns an error in case the record does not exist
 func (m *MemoryStore) FilterByDomain(ctx context.Context, domain string) ([]*store.Record, error) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
 
 	var out = []*store.Record{}
-	for rtype, domains := range m.Records {
-		for name, addr := range domains {
-			if name == domain {
-				out = append(
-					out,
-					store.New().Type(rtype).Name(name).Addr(addr).Build(),
-				)
-			}
+	for _, domains := range m.Records {
+		for _, record := range domains[domain] {
+			out = append(out, clone(record))
 		}
 	}
+	if len(out) == 0 {
+		out = append(out, m.wildcard("", domain)...)
+	}
 
 	return out, nil
 }
@@ -97,7 +118,8 @@ func (m *MemoryStore) FilterByDomain(ctx context.Context, domain string) ([]*sto
 // FilterByDest implements the store.Repository interface
 //
 // It will return a slice of pointers to store.Records if there are records
-// associated with the input store.Record's IP address.
+// associated with the input store.Record's IP address. IP addresses are compared by
+// their value, regardless of how they are written.
 //
 // If the call is successful but there are no records associated to that addres,
 // returns an empty slice.
@@ -105,22 +127,18 @@ func (m *MemoryStore) FilterByDomain(ctx context.Context, domain string) ([]*sto
 // It also returns an error in case the operation fails (which is currently not
 // a scenario)
 func (m *MemoryStore) FilterByDest(ctx context.Context, addr string) ([]*store.Record, error) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
 
 	var output []*store.Record
+	ip := net.ParseIP(addr)
 
-	for rtype, domains := range m.Records {
-		for domain, ipAddr := range domains {
-			if ipAddr == addr {
-				output = append(
-					output,
-					store.New().
-						Type(rtype).
-						Name(domain).
-						Addr(addr).
-						Build(),
-				)
+	for _, domains := range m.Records {
+		for _, records := range domains {
+			for _, record := range records {
+				if sameAddr(record.Addr, addr, ip) {
+					output = append(output, clone(record))
+				}
 			}
 		}
 	}
@@ -130,8 +148,8 @@ func (m *MemoryStore) FilterByDest(ctx context.Context, addr string) ([]*store.R
 
 // Update implements the store.Repository interface
 //
-// It will target a particular domain name, and update its target IP address
-// based on the input store.Record.
+// It will target a particular domain name, and replace its set of records of the
+// input store.Record's type with the input store.Record.
 //
 // If it targets a domain which does not exist in the store, or if that domain
 // does not have that record type registered, it returns a DoesNotExist error
@@ -148,19 +166,41 @@ func (m *MemoryStore) Update(ctx context.Context, domain string, r *store.Record
 		return store.ErrDoesNotExist
 	}
 	if domain != r.Name {
-		delete(m.Records[r.Type], domain)
+		m.set(r.Type, domain, nil)
 	}
-	m.Records[r.Type][r.Name] = r.Addr
+	m.set(r.Type, r.Name, []*store.Record{clone(r)})
 
 	return nil
 }
 
-// DeleteByAddress removes all records with IP address `addr`
+// Delete removes the record matching the type, domain name, address and data
+// of the input store.Record, leaving any other records in its set untouched
+func (m *MemoryStore) Delete(ctx context.Context, r *store.Record) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	records := m.Records[r.Type][r.Name]
+	for idx, record := range records {
+		if sameValue(record, r) {
+			m.remove(r.Type, r.Name, idx)
+			return nil
+		}
+	}
+	return store.ErrDoesNotExist
+}
+
+// DeleteByAddress removes all records with IP address `addr`, compared by its value
 func (m *MemoryStore) DeleteByAddress(ctx context.Context, addr string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	ip := net.ParseIP(addr)
 	for rtype, rmap := range m.Records {
-		for domain, address := range rmap {
-			if address == addr {
-				delete(m.Records[rtype], domain)
+		for domain, records := range rmap {
+			for idx := len(records) - 1; idx >= 0; idx-- {
+				if sameAddr(records[idx].Addr, addr, ip) {
+					m.remove(rtype, domain, idx)
+				}
 			}
 		}
 	}
@@ -169,26 +209,63 @@ func (m *MemoryStore) DeleteByAddress(ctx context.Context, addr string) error {
 
 // DeleteByDomain removes all records with domain name `name`
 func (m *MemoryStore) DeleteByDomain(ctx context.Context, name string) error {
-	for rtype, domains := range m.Records {
-		for domain := range domains {
-			if domain == name {
-				delete(m.Records[rtype], domain)
-			}
-		}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for rtype := range m.Records {
+		m.set(rtype, name, nil)
 	}
 	return nil
 }
 
 // DeleteByTypeAndDomain removes all records with record type `rtype` and domain name `name`
 func (m *MemoryStore) DeleteByTypeAndDomain(ctx context.Context, rtype, name string) error {
-	for recordtype, domains := range m.Records {
-		if recordtype == rtype {
-			for domain := range domains {
-				if domain == name {
-					delete(m.Records[recordtype], domain)
-				}
-			}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.set(rtype, name, nil)
+	return nil
+}
+
+// remove deletes the record in index `idx` from the set of records with type `rtype`
+// and domain name `name`, removing the set altogether if it becomes empty
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) remove(rtype, name string, idx int) {
+	records := m.Records[rtype][name]
+	m.set(rtype, name, append(records[:idx:idx], records[idx+1:]...))
+}
+
+// set replaces the set of records with type `rtype` and domain name `name` with
+// `records`, removing it if `records` is empty, and keeps the names and addresses indexes
+// up-to-date
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) set(rtype, name string, records []*store.Record) {
+	domains, ok := m.Records[rtype]
+	if !ok {
+		if len(records) == 0 {
+			return
 		}
+		domains = map[string][]*store.Record{}
+		m.Records[rtype] = domains
+	}
+
+	existed := len(domains[name]) > 0
+	if addressed(rtype) {
+		m.indexAddrs(domains[name], -1)
+		m.indexAddrs(records, 1)
+	}
+	if len(records) == 0 {
+		delete(domains, name)
+		if existed {
+			m.index(name, -1)
+		}
+		return
+	}
+
+	domains[name] = records
+	if !existed {
+		m.index(name, 1)
 	}
-	return nil
 }
@@ -3,6 +3,7 @@ package memmap
 import (
 	"context"
 	"errors"
+	"net"
 	"reflect"
 	"testing"
 
@@ -14,6 +15,7 @@ var (
 	test2 = store.New().Name("really.not.a.dom.ain").Type("A").Addr("192.168.0.15").Build()
 	test3 = store.New().Name("really.not.a.dom.ain").Type("CNAME").Addr("am.i.not.a.dom.ain.").Build()
 	test4 = store.New().Name("am.i.not.a.dom.ain").Type("A").Addr("192.168.0.15").Build()
+	test5 = store.New().Name("really.not.a.dom.ain").Type("A").Addr("192.168.0.16").Build()
 )
 
 func TestCreate(t *testing.T) {
@@ -30,12 +32,12 @@ func TestCreate(t *testing.T) {
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but domain is not assigned", test1)
 		}
-		addr, ok := records[test1.Name]
+		set, ok := records[test1.Name]
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but record type is not assigned", test1)
 		}
-		if addr != test1.Addr {
-			t.Errorf("stored address %s is incompatible with %v", addr, test1)
+		if len(set) != 1 || set[0].Addr != test1.Addr {
+			t.Errorf("stored records %v are incompatible with %v", set, test1)
 		}
 	})
 	t.Run("ManyRecords", func(t *testing.T) {
@@ -51,24 +53,24 @@ func TestCreate(t *testing.T) {
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but domain is not assigned", test1)
 		}
-		addr, ok := records[test1.Name]
+		set, ok := records[test1.Name]
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but record type is not assigned", test1)
 		}
-		if addr != test1.Addr {
-			t.Errorf("stored address %s is incompatible with %v", addr, test1)
+		if len(set) != 1 || set[0].Addr != test1.Addr {
+			t.Errorf("stored records %v are incompatible with %v", set, test1)
 		}
 
 		records, ok = s.(*MemoryStore).Records[test2.Type]
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but domain is not assigned", test2)
 		}
-		addr, ok = records[test2.Name]
+		set, ok = records[test2.Name]
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but record type is not assigned", test2)
 		}
-		if addr != test2.Addr {
-			t.Errorf("stored address %s is incompatible with %v", addr, test2)
+		if len(set) != 1 || set[0].Addr != test2.Addr {
+			t.Errorf("stored records %v are incompatible with %v", set, test2)
 		}
 	})
 }
@@ -119,18 +121,37 @@ func TestFilterByDomain(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 
-		r, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
+		rs, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
 
-		if r == nil {
-			t.Errorf("expected a succesful query, returned store.Record is nil")
+		if len(rs) != 1 {
+			t.Errorf("expected a succesful query with one record, got %v records", len(rs))
 			return
 		}
 
-		if r.Addr != test2.Addr {
-			t.Errorf("unexpected output IP address: wanted %s ; got %s", test2.Addr, r.Addr)
+		if rs[0].Addr != test2.Addr {
+			t.Errorf("unexpected output IP address: wanted %s ; got %s", test2.Addr, rs[0].Addr)
+		}
+	})
+	t.Run("SuccessRecordSet", func(t *testing.T) {
+		ctx := context.Background()
+		s := New()
+		wants := []*store.Record{test2, test5}
+
+		err := s.Create(ctx, test1, test2, test5, test2)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		rs, err := s.FindByTypeAndDomain(ctx, test2.Type, test2.Name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(wants, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, rs)
 		}
 	})
 	t.Run("FailDoesNotExistDomain", func(t *testing.T) {
@@ -197,6 +218,30 @@ func TestFilterByDest(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("SuccessNonCanonicalIPv6", func(t *testing.T) {
+		ctx := context.Background()
+		s := New()
+		v6 := store.New().Type("AAAA").Name("v6.not.a.dom.ain").Addr("FD00:0:0::10").Build()
+
+		err := s.Create(ctx, v6)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		rs, err := s.FilterByDest(ctx, "fd00::10")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if len(rs) != 1 {
+			t.Errorf("unexpected results length: wanted %v ; got %v", 1, len(rs))
+			return
+		}
+		if !reflect.DeepEqual(*v6, *rs[0]) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", *v6, *rs[0])
+		}
+	})
 }
 
 func TestUpdate(t *testing.T) {
@@ -219,12 +264,12 @@ func TestUpdate(t *testing.T) {
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but domain is not assigned", updated)
 		}
-		addr, ok := records[updated.Name]
+		set, ok := records[updated.Name]
 		if !ok {
 			t.Errorf("expected entry for %v to be present in the store, but record type is not assigned", updated)
 		}
-		if addr != updated.Addr {
-			t.Errorf("stored address %s is incompatible with %v", addr, updated)
+		if len(set) != 1 || set[0].Addr != updated.Addr {
+			t.Errorf("stored records %v are incompatible with %v", set, updated)
 		}
 	})
 
@@ -309,6 +354,34 @@ func TestDelete(t *testing.T) {
 			t.Errorf("unexpected output length: wanted %v records ; got %v", 3, len(rs))
 		}
 	})
+	t.Run("SuccessRecordSetMember", func(t *testing.T) {
+		ctx := context.Background()
+		s := New()
+
+		err := s.Create(ctx, test1, test2, test5)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		err = s.Delete(ctx, test2)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		rs, err := s.FindByTypeAndDomain(ctx, test5.Type, test5.Name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual([]*store.Record{test5}, rs) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", []*store.Record{test5}, rs)
+		}
+
+		err = s.Delete(ctx, test2)
+		if !errors.Is(err, store.ErrDoesNotExist) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
 	t.Run("SuccessByAddress", func(t *testing.T) {
 		ctx := context.Background()
 		s := New()
@@ -333,3 +406,207 @@ func TestDelete(t *testing.T) {
 		}
 	})
 }
+
+func TestWildcard(t *testing.T) {
+	var (
+		wildcard    = store.New().Name("*.preview.lan").Type("A").Addr("10.0.0.1").Build()
+		subWildcard = store.New().Name("*.team.preview.lan").Type("A").Addr("10.0.0.2").Build()
+		explicit    = store.New().Name("main.preview.lan").Type("A").Addr("10.0.0.3").Build()
+		explicitTXT = store.New().Name("docs.preview.lan").Type("TXT").
+				Data(&store.Data{Text: []string{"docs"}}).Build()
+	)
+
+	ctx := context.Background()
+	s := New()
+
+	err := s.Create(ctx, wildcard, subWildcard, explicit, explicitTXT)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	for _, test := range []struct {
+		name   string
+		domain string
+		wants  string
+	}{
+		{name: "MatchWildcard", domain: "pr-123.preview.lan", wants: wildcard.Addr},
+		{name: "MatchWildcardManyLabels", domain: "a.b.preview.lan", wants: wildcard.Addr},
+		{name: "MostSpecificWildcard", domain: "pr-123.team.preview.lan", wants: subWildcard.Addr},
+		{name: "ExplicitRecordWins", domain: explicit.Name, wants: explicit.Addr},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rs, err := s.FindByTypeAndDomain(ctx, "A", test.domain)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(rs) != 1 {
+				t.Errorf("unexpected results length: wanted %v ; got %v", 1, len(rs))
+				return
+			}
+			if rs[0].Addr != test.wants || rs[0].Name != test.domain {
+				t.Errorf("output mismatch error: wanted %s for %s ; got %v", test.wants, test.domain, rs[0])
+			}
+		})
+	}
+
+	t.Run("ExistingNameOtherType", func(t *testing.T) {
+		_, err := s.FindByTypeAndDomain(ctx, "A", explicitTXT.Name)
+		if !errors.Is(err, store.ErrDoesNotExist) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("NoMatchOutsideZone", func(t *testing.T) {
+		_, err := s.FindByTypeAndDomain(ctx, "A", "pr-123.other.lan")
+		if !errors.Is(err, store.ErrDoesNotExist) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("FilterByDomain", func(t *testing.T) {
+		rs, err := s.FilterByDomain(ctx, "pr-123.preview.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rs) != 1 || rs[0].Addr != wildcard.Addr {
+			t.Errorf("output mismatch error: wanted %s ; got %v", wildcard.Addr, rs)
+		}
+	})
+
+	t.Run("MatchAfterDelete", func(t *testing.T) {
+		if err := s.Delete(ctx, explicit); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if err := s.DeleteByDomain(ctx, explicitTXT.Name); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		rs, err := s.FindByTypeAndDomain(ctx, "A", explicit.Name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rs) != 1 || rs[0].Addr != wildcard.Addr {
+			t.Errorf("output mismatch error: wanted %s ; got %v", wildcard.Addr, rs)
+		}
+	})
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctx := context.Background()
+		s := New()
+		if err := s.Create(ctx, test1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err := s.(store.Batcher).Batch(ctx, func(r store.Repository) error {
+			if err := r.Delete(ctx, test1); err != nil {
+				return err
+			}
+			return r.Create(ctx, test2)
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		records, err := s.List(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual([]*store.Record{test2}, records) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", test2, records)
+		}
+	})
+
+	t.Run("FailRollback", func(t *testing.T) {
+		ctx := context.Background()
+		s := New()
+		if err := s.Create(ctx, test1, test5); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err := s.(store.Batcher).Batch(ctx, func(r store.Repository) error {
+			if err := r.Delete(ctx, test1); err != nil {
+				return err
+			}
+			if err := r.Create(ctx, store.New().Name(test5.Name).Type("A").Addr(test5.Addr).TTL(60).Build()); err != nil {
+				return err
+			}
+			// the second record was already removed
+			return r.Delete(ctx, test1)
+		})
+		if !errors.Is(err, store.ErrDoesNotExist) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrDoesNotExist, err)
+		}
+
+		// none of the changes were applied
+		for _, want := range []*store.Record{test1, test5} {
+			records, err := s.FindByTypeAndDomain(ctx, want.Type, want.Name)
+			if err != nil || !reflect.DeepEqual([]*store.Record{want}, records) {
+				t.Errorf("output mismatch error: wanted %v ; got %v ; error: %v", want, records, err)
+			}
+		}
+	})
+}
+
+func TestHasNetwork(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	if err := s.Create(ctx, test1, test2, store.New().Name("v6.dom.ain").Type("AAAA").Addr("fd00::10").Build()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		network string
+		wants   bool
+	}{
+		{name: "IPv4Prefix", network: "192.168.0.0/16", wants: true},
+		{name: "IPv4PrefixOtherNetwork", network: "10.0.0.0/8", wants: false},
+		{name: "IPv4Unaligned", network: "192.168.0.8/30", wants: true},
+		{name: "IPv4UnalignedOtherNetwork", network: "192.168.0.0/29", wants: false},
+		{name: "IPv6Prefix", network: "fd00::/64", wants: true},
+		{name: "IPv6PrefixOtherNetwork", network: "fd01::/64", wants: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, network, err := net.ParseCIDR(test.network)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ok, err := s.(store.Networker).HasNetwork(ctx, network)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if ok != test.wants {
+				t.Errorf("output mismatch error: wanted %v ; got %v", test.wants, ok)
+			}
+		})
+	}
+
+	t.Run("AfterDelete", func(t *testing.T) {
+		if err := s.DeleteByAddress(ctx, test1.Addr); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		_, network, _ := net.ParseCIDR("192.168.0.8/30")
+		ok, err := s.(store.Networker).HasNetwork(ctx, network)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if ok {
+			t.Errorf("expected no addresses in %s after deleting %s", network, test1.Addr)
+		}
+	})
+}
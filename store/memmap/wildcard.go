@@ -0,0 +1,78 @@
+package memmap
+
+import (
+	"strings"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+const wildcardLabel = "*"
+
+// wildcard looks up the records of type `rtype` (or of all types, if empty) in the
+// wildcard domain which covers the domain name `domain`, following RFC 4592:
+//
+// If `domain` exists in the store (with any record type, or as an empty non-terminal),
+// wildcards do not apply to it. Otherwise, the closest encloser (the longest existing
+// ancestor of `domain`) is found, and the records in its wildcard child (`*.<encloser>`)
+// are returned, with `domain` as their name. This means that explicit records always
+// take precedence over wildcards, and the most specific wildcard is the one used.
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) wildcard(rtype, domain string) []*store.Record {
+	if m.exists(domain) {
+		return nil
+	}
+
+	labels := strings.Split(domain, ".")
+	for i := 1; i < len(labels); i++ {
+		encloser := strings.Join(labels[i:], ".")
+		if !m.exists(encloser) {
+			continue
+		}
+
+		var output []*store.Record
+		source := wildcardLabel + "." + encloser
+		for t, domains := range m.Records {
+			if rtype != "" && t != rtype {
+				continue
+			}
+			for _, record := range domains[source] {
+				r := clone(record)
+				r.Name = domain
+				output = append(output, r)
+			}
+		}
+		return output
+	}
+	return nil
+}
+
+// exists returns true if the domain name `name` holds any records in the store, or
+// if it is an empty non-terminal (a name without records, but with descendants that do)
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) exists(name string) bool {
+	return m.names[name] > 0
+}
+
+// index adds `delta` to the number of record sets at the domain name `name` and at each
+// of its ancestors, removing the names which no longer hold (or enclose) any records
+//
+// It expects the caller to hold the MemoryStore's lock
+func (m *MemoryStore) index(name string, delta int) {
+	if m.names == nil {
+		m.names = map[string]int{}
+	}
+
+	for {
+		if m.names[name] += delta; m.names[name] <= 0 {
+			delete(m.names, name)
+		}
+
+		idx := strings.IndexByte(name, '.')
+		if idx < 0 {
+			return
+		}
+		name = name[idx+1:]
+	}
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"net"
+)
+
+// Networker is a Repository which indexes the IP addresses of its A and AAAA records, so
+// that it can tell whether it holds any within a network without listing all of its records
+type Networker interface {
+	Repository
+
+	// HasNetwork returns true if the Repository holds A or AAAA records with an IP address
+	// within the network `network`
+	HasNetwork(ctx context.Context, network *net.IPNet) (bool, error)
+}
+
+// HasNetwork returns true if the Repository `r` holds A or AAAA records with an IP address
+// within the network `network`, using its index if it is a Networker, or listing all of
+// its records otherwise
+func HasNetwork(ctx context.Context, r Repository, network *net.IPNet) (bool, error) {
+	if n, ok := r.(Networker); ok {
+		return n.HasNetwork(ctx, network)
+	}
+
+	records, err := r.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records {
+		if record.Type != TypeA.String() && record.Type != TypeAAAA.String() {
+			continue
+		}
+		if ip := net.ParseIP(record.Addr); ip != nil && network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"context"
+	"net"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+// HasNetwork returns true if the Repository holds A or AAAA records with an IP address
+// within the network `network`
+func (t withTrace) HasNetwork(ctx context.Context, network *net.IPNet) (bool, error) {
+	ctx, s := spanner.Start(ctx, "store.HasNetwork")
+	defer s.End()
+	s.Add(
+		attr.String("network", network.String()),
+	)
+
+	ok, err := HasNetwork(ctx, t.r, network)
+	if err != nil {
+		s.Event("error looking up network", attr.New("error", err.Error()))
+	}
+
+	return ok, err
+}
@@ -1,10 +1,48 @@
 package store
 
 // Record defines the basic elements of a DNS Record
+//
+// Addr holds the record's main value: the IP address for A and AAAA records;
+// the target domain name for CNAME, NS, PTR, MX and SRV records; the primary
+// name server for SOA records; and the property value for CAA records.
+//
+// Any type-specific fields that do not fit in Addr are kept in Data.
+//
+// TTL is the time-to-live (in seconds) for the record when it is answered; if
+// unset (zero), the DNS server's default TTL is used instead
 type Record struct {
 	Type string `json:"type,omitempty"`
 	Name string `json:"name,omitempty"`
 	Addr string `json:"address,omitempty"`
+	TTL  uint32 `json:"ttl,omitempty"`
+	Data *Data  `json:"data,omitempty"`
+}
+
+// Data holds the type-specific fields of a DNS Record, for the record types
+// which require more than a single value (such as MX, TXT, SRV, SOA and CAA)
+type Data struct {
+	// MX
+	Preference uint16 `json:"preference,omitempty" yaml:"preference,omitempty"`
+
+	// SRV
+	Priority uint16 `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Weight   uint16 `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Port     uint16 `json:"port,omitempty" yaml:"port,omitempty"`
+
+	// TXT
+	Text []string `json:"text,omitempty" yaml:"text,omitempty"`
+
+	// SOA
+	Mbox    string `json:"mbox,omitempty" yaml:"mbox,omitempty"`
+	Serial  uint32 `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Refresh uint32 `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+	Retry   uint32 `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Expire  uint32 `json:"expire,omitempty" yaml:"expire,omitempty"`
+	Minttl  uint32 `json:"minttl,omitempty" yaml:"minttl,omitempty"`
+
+	// CAA
+	Flag uint8  `json:"flag,omitempty" yaml:"flag,omitempty"`
+	Tag  string `json:"tag,omitempty" yaml:"tag,omitempty"`
 }
 
 type RecordWithTarget struct {
@@ -17,6 +55,8 @@ type RecordBuilder struct {
 	t    string
 	name string
 	addr string
+	ttl  uint32
+	data *Data
 }
 
 // New returns a new pointer to a RecordBuilder
@@ -48,12 +88,30 @@ func (b *RecordBuilder) Addr(s string) *RecordBuilder {
 	return b
 }
 
+// TTL sets the record's time-to-live in seconds, in the RecordBuilder.
+//
+// Returns itself to allow method chaining
+func (b *RecordBuilder) TTL(ttl uint32) *RecordBuilder {
+	b.ttl = ttl
+	return b
+}
+
+// Data sets the record's type-specific fields, in the RecordBuilder.
+//
+// Returns itself to allow method chaining
+func (b *RecordBuilder) Data(d *Data) *RecordBuilder {
+	b.data = d
+	return b
+}
+
 // Build returns a record with the set variables in the builder
 func (b *RecordBuilder) Build() *Record {
 	return &Record{
 		Name: b.name,
 		Type: b.t,
 		Addr: b.addr,
+		TTL:  b.ttl,
+		Data: b.data,
 	}
 }
 
@@ -62,11 +120,18 @@ func (b *RecordBuilder) Build() *Record {
 type RecordType uint16
 
 const (
-	TypeNone  RecordType = 0  // Unset
-	TypeA     RecordType = 1  // A record
-	TypeCNAME RecordType = 5  // AAAA record
-	TypeAAAA  RecordType = 28 // CNAME record
-	TypeANY   RecordType = 255
+	TypeNone  RecordType = 0   // Unset
+	TypeA     RecordType = 1   // A record
+	TypeNS    RecordType = 2   // NS record
+	TypeCNAME RecordType = 5   // CNAME record
+	TypeSOA   RecordType = 6   // SOA record
+	TypePTR   RecordType = 12  // PTR record
+	TypeMX    RecordType = 15  // MX record
+	TypeTXT   RecordType = 16  // TXT record
+	TypeAAAA  RecordType = 28  // AAAA record
+	TypeSRV   RecordType = 33  // SRV record
+	TypeANY   RecordType = 255 // ANY (all records)
+	TypeCAA   RecordType = 257 // CAA record
 )
 
 var (
@@ -74,33 +139,61 @@ var (
 	RecordTypeKeys = map[RecordType]uint16{
 		TypeNone:  0,
 		TypeA:     1,
+		TypeNS:    2,
 		TypeCNAME: 5,
+		TypeSOA:   6,
+		TypePTR:   12,
+		TypeMX:    15,
+		TypeTXT:   16,
 		TypeAAAA:  28,
+		TypeSRV:   33,
 		TypeANY:   255,
+		TypeCAA:   257,
 	}
 	// RecordTypeKeys converts a RecordType to string
 	RecordTypeStrings = map[RecordType]string{
 		TypeNone:  "",
 		TypeA:     "A",
+		TypeNS:    "NS",
 		TypeCNAME: "CNAME",
+		TypeSOA:   "SOA",
+		TypePTR:   "PTR",
+		TypeMX:    "MX",
+		TypeTXT:   "TXT",
 		TypeAAAA:  "AAAA",
+		TypeSRV:   "SRV",
 		TypeANY:   "ANY",
+		TypeCAA:   "CAA",
 	}
 	// RecordTypeKeys converts a string to RecordType
 	RecordTypeVals = map[string]RecordType{
 		"":      TypeNone,
 		"A":     TypeA,
+		"NS":    TypeNS,
 		"CNAME": TypeCNAME,
+		"SOA":   TypeSOA,
+		"PTR":   TypePTR,
+		"MX":    TypeMX,
+		"TXT":   TypeTXT,
 		"AAAA":  TypeAAAA,
+		"SRV":   TypeSRV,
 		"ANY":   TypeANY,
+		"CAA":   TypeCAA,
 	}
 	// RecordTypeKeys converts a RecordType string to uint16
 	RecordTypeInts = map[string]uint16{
 		"":      0,
 		"A":     1,
+		"NS":    2,
 		"CNAME": 5,
+		"SOA":   6,
+		"PTR":   12,
+		"MX":    15,
+		"TXT":   16,
 		"AAAA":  28,
+		"SRV":   33,
 		"ANY":   255,
+		"CAA":   257,
 	}
 )
 
@@ -17,10 +17,10 @@ type Repository interface {
 	// List will fetch all records in the key-value store
 	List(context.Context) ([]*Record, error)
 
-	// FindByTypeAndDomain will fetch an address based on its address and type strings
+	// FindByTypeAndDomain will fetch the set of records based on its domain name and type strings
 	//
-	// FindByTypeAndDomain(ctx, "A", "service.mydomain") -> { "127.0.0.1", nil }
-	FindByTypeAndDomain(context.Context, string, string) (*Record, error)
+	// FindByTypeAndDomain(ctx, "A", "service.mydomain") -> { ["127.0.0.1", "127.0.0.2"], nil }
+	FindByTypeAndDomain(context.Context, string, string) ([]*Record, error)
 
 	// FilterByDomain will fetch an address based on its address for all types
 	//
@@ -34,8 +34,15 @@ type Repository interface {
 
 	// Update will modify an existing record by targetting its domain string,
 	// and by supplying a new version of the Record to update. Returns an error
+	//
+	// The set of records with the same type under the target domain is replaced by
+	// the input Record
 	Update(context.Context, string, *Record) error
 
+	// Delete removes the record matching the type, domain name, address and data
+	// of the input Record, leaving any other records in its set untouched
+	Delete(ctx context.Context, r *Record) error
+
 	// DeleteByAddress removes all records with IP address `addr`
 	DeleteByAddress(ctx context.Context, addr string) error
 
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ToRR converts the Record `r` into a dns.RR (from github.com/miekg/dns), according to
+// its record type and with the record's TTL
+//
+// Returns an error wrapping ErrUnsupportedType if its record type is not supported, or
+// ErrInvalidAddr or ErrNoData if it does not hold the fields its type requires
+func ToRR(r *Record) (dns.RR, error) {
+	rtype, ok := RecordTypeInts[r.Type]
+	if !ok || rtype == 0 || rtype == dns.TypeANY || r.Name == "" {
+		return nil, fmt.Errorf("%w: %s %q", ErrUnsupportedType, r.Name, r.Type)
+	}
+
+	hdr := dns.RR_Header{
+		Name:   dns.Fqdn(r.Name),
+		Rrtype: rtype,
+		Class:  dns.ClassINET,
+		Ttl:    r.TTL,
+	}
+	data := r.Data
+	if data == nil {
+		data = &Data{}
+	}
+
+	switch rtype {
+	case dns.TypeA:
+		ip := net.ParseIP(r.Addr).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %q is not an IPv4 address for %s", ErrInvalidAddr, r.Addr, r.Name)
+		}
+		return &dns.A{Hdr: hdr, A: ip}, nil
+	case dns.TypeAAAA:
+		ip := net.ParseIP(r.Addr)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("%w: %q is not an IPv6 address for %s", ErrInvalidAddr, r.Addr, r.Name)
+		}
+		return &dns.AAAA{Hdr: hdr, AAAA: ip}, nil
+	case dns.TypeCNAME:
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(r.Addr)}, nil
+	case dns.TypeNS:
+		return &dns.NS{Hdr: hdr, Ns: dns.Fqdn(r.Addr)}, nil
+	case dns.TypePTR:
+		return &dns.PTR{Hdr: hdr, Ptr: dns.Fqdn(r.Addr)}, nil
+	case dns.TypeMX:
+		return &dns.MX{Hdr: hdr, Preference: data.Preference, Mx: dns.Fqdn(r.Addr)}, nil
+	case dns.TypeTXT:
+		if len(data.Text) == 0 {
+			return nil, fmt.Errorf("%w: TXT record for %s requires text", ErrNoData, r.Name)
+		}
+		return &dns.TXT{Hdr: hdr, Txt: data.Text}, nil
+	case dns.TypeSRV:
+		return &dns.SRV{
+			Hdr:      hdr,
+			Priority: data.Priority,
+			Weight:   data.Weight,
+			Port:     data.Port,
+			Target:   dns.Fqdn(r.Addr),
+		}, nil
+	case dns.TypeSOA:
+		return &dns.SOA{
+			Hdr:     hdr,
+			Ns:      dns.Fqdn(r.Addr),
+			Mbox:    dns.Fqdn(data.Mbox),
+			Serial:  data.Serial,
+			Refresh: data.Refresh,
+			Retry:   data.Retry,
+			Expire:  data.Expire,
+			Minttl:  data.Minttl,
+		}, nil
+	case dns.TypeCAA:
+		if data.Tag == "" {
+			return nil, fmt.Errorf("%w: CAA record for %s requires a tag", ErrNoData, r.Name)
+		}
+		return &dns.CAA{Hdr: hdr, Flag: data.Flag, Tag: data.Tag, Value: r.Addr}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s %q", ErrUnsupportedType, r.Name, r.Type)
+	}
+}
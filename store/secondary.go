@@ -0,0 +1,16 @@
+package store
+
+import "context"
+
+// Secondary is a read-only Repository holding a copy of a zone owned by another DNS
+// server (its primary), kept in sync with zone transfers
+//
+// Its write operations return ErrReadOnly
+type Secondary interface {
+	Repository
+
+	// Notify signals that the zone `zone` changed in its primary DNS server, to the serial
+	// `serial` (RFC 1996), so that it is refreshed without waiting for its refresh timer.
+	// Returns an error wrapping ErrNotFound if the Secondary does not hold the zone
+	Notify(ctx context.Context, zone string, serial uint32) error
+}
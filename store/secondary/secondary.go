@@ -0,0 +1,273 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/spanner"
+)
+
+const (
+	// DefaultTimeout is the default time to wait for the replies from the primary DNS server
+	DefaultTimeout = 5 * time.Second
+	// DefaultRetry is the default time to wait before retrying a failed refresh, until the
+	// zone is transferred for the first time (and its SOA record sets the retry timer)
+	DefaultRetry = time.Minute
+	// tsigFudge is the allowed time difference (in seconds) for the TSIG signatures
+	tsigFudge = 300
+)
+
+var (
+	ErrNoZone    = errors.New("no zone provided")
+	ErrNoPrimary = errors.New("no primary DNS server provided")
+	ErrNoSOA     = errors.New("primary DNS server did not return the zone's SOA record")
+	ErrTransfer  = errors.New("invalid zone transfer")
+)
+
+// SecondaryStore is a read-only store.Secondary holding a copy of a zone owned by another
+// DNS server (its primary), kept in an in-memory store (store/memmap)
+//
+// The zone is transferred in full (AXFR) on start-up, and refreshed according to the
+// timers in its SOA record: every `refresh` seconds, the SOA record is queried from the
+// primary DNS server, and the zone is transferred incrementally (IXFR) if its serial is
+// newer. Failed refreshes are retried every `retry` seconds; and if the zone cannot be
+// refreshed for `expire` seconds, its records are dropped until the next successful
+// transfer. A NOTIFY message for the zone triggers a refresh right away
+//
+// Its write operations return store.ErrReadOnly
+type SecondaryStore struct {
+	zone    string
+	primary string
+	timeout time.Duration
+	key     *key
+
+	mtx     sync.RWMutex
+	store   store.Repository
+	soa     *dns.SOA
+	loaded  bool
+	expires time.Time
+	err     error
+
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// key is a TSIG key, with its name and algorithm in canonical form
+type key struct {
+	name      string
+	algorithm string
+	secret    string
+}
+
+// Option describes setter types for a SecondaryStore
+type Option interface {
+	Apply(*SecondaryStore)
+}
+
+// TSIG creates an Option setting the TSIG key used to sign the queries and zone transfer
+// requests to the primary DNS server, with the name `name`, the algorithm `algorithm`
+// (`hmac-sha256` if empty) and the base64-encoded secret `secret`
+//
+// If the name or the secret are empty, it returns nil
+func TSIG(name, algorithm, secret string) Option {
+	if name == "" || secret == "" {
+		return nil
+	}
+	if algorithm == "" {
+		algorithm = dns.HmacSHA256
+	}
+	return &tsigOpt{
+		key: &key{
+			name:      dns.CanonicalName(name),
+			algorithm: dns.CanonicalName(algorithm),
+			secret:    secret,
+		},
+	}
+}
+
+// Timeout creates an Option setting the time to wait for the replies from the primary
+// DNS server
+//
+// If `d` is zero or negative, it returns nil
+func Timeout(d time.Duration) Option {
+	if d <= 0 {
+		return nil
+	}
+	return &timeoutOpt{
+		d: d,
+	}
+}
+
+type tsigOpt struct {
+	key *key
+}
+
+type timeoutOpt struct {
+	d time.Duration
+}
+
+// Apply implements the Option interface
+func (o *tsigOpt) Apply(s *SecondaryStore) {
+	s.key = o.key
+}
+
+// Apply implements the Option interface
+func (o *timeoutOpt) Apply(s *SecondaryStore) {
+	s.timeout = o.d
+}
+
+// New returns a new SecondaryStore as a store.Secondary, for the zone `zone` owned by the
+// primary DNS server `primary` (as `host` or `host:port`, with port 53 by default),
+// applying all input Option `opts`
+//
+// The zone is transferred in the background, so the store is empty until the first
+// transfer succeeds. If `zone` is empty, the store stays empty
+func New(primary, zone string, opts ...Option) store.Secondary {
+	s := &SecondaryStore{
+		primary: strings.TrimSpace(primary),
+		timeout: DefaultTimeout,
+		store:   memmap.New(),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if zone != "" {
+		s.zone = dns.CanonicalName(zone)
+	}
+	if _, _, err := net.SplitHostPort(s.primary); err != nil && s.primary != "" {
+		s.primary = net.JoinHostPort(strings.Trim(s.primary, "[]"), "53")
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(s)
+		}
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Notify implements the store.Secondary interface
+//
+// It triggers a refresh of the zone, which checks its serial in the primary DNS server
+// before transferring it; so NOTIFY messages which are spoofed (or for an outdated serial)
+// cause no more than a query for the SOA record. Notifications received while a refresh
+// is already pending are merged with it
+func (s *SecondaryStore) Notify(ctx context.Context, zone string, serial uint32) error {
+	if s.zone == "" || !strings.EqualFold(dns.CanonicalName(zone), s.zone) {
+		return fmt.Errorf("%w: %s", store.ErrNotFound, zone)
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Err returns the error from the last refresh of the zone, or nil if it succeeded
+func (s *SecondaryStore) Err() error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.err
+}
+
+// Close stops refreshing the zone
+func (s *SecondaryStore) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// run refreshes the zone until the SecondaryStore is closed, waiting for the refresh or
+// retry timer in between (or for a NOTIFY message)
+func (s *SecondaryStore) run() {
+	for {
+		timer := time.NewTimer(s.refresh(context.Background()))
+
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-s.notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh checks the zone's serial in the primary DNS server, transferring the zone if it
+// is newer (or if it was never transferred), and returns the time to wait until the next
+// refresh
+func (s *SecondaryStore) refresh(ctx context.Context) time.Duration {
+	ctx, sp := spanner.Start(ctx, "secondary.refresh")
+	defer sp.End()
+	sp.Add(
+		attr.String("zone", s.zone),
+		attr.String("primary", s.primary),
+	)
+
+	soa, err := s.querySOA(ctx)
+	if err == nil {
+		s.mtx.RLock()
+		current, loaded := s.soa, s.loaded
+		s.mtx.RUnlock()
+
+		if !loaded || store.NewerSerial(soa.Serial, current.Serial) {
+			soa, err = s.transfer(ctx, current, loaded)
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.err = err
+	if err != nil {
+		sp.Event("failed to refresh zone", attr.String("error", err.Error()))
+
+		if s.loaded && time.Now().After(s.expires) {
+			sp.Event("zone expired")
+			s.store = memmap.New()
+			s.loaded = false
+		}
+		if s.soa == nil {
+			return DefaultRetry
+		}
+		return seconds(s.soa.Retry, DefaultRetry)
+	}
+
+	// the SOA record of a zone which was not transferred (as it is up-to-date) keeps the
+	// current one in the store, but its timers are updated
+	s.soa = soa
+	s.expires = time.Now().Add(seconds(soa.Expire, DefaultRetry))
+	sp.Add(attr.Int("serial", int(soa.Serial)))
+
+	return seconds(soa.Refresh, DefaultRetry)
+}
+
+// repo returns the in-memory store holding the zone's records
+func (s *SecondaryStore) repo() store.Repository {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.store
+}
+
+// seconds returns `n` seconds as a time.Duration, or `fallback` if `n` is zero
+func seconds(n uint32, fallback time.Duration) time.Duration {
+	if n == 0 {
+		return fallback
+	}
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,263 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+// primary is a DNS server owning a single zone, serving its SOA record and zone transfers
+type primary struct {
+	addr string
+	srvs []*dns.Server
+
+	mtx     sync.Mutex
+	soa     *dns.SOA
+	records []dns.RR
+	ixfr    []dns.RR
+	queries []uint16
+}
+
+func newSOA(serial, refresh, retry, expire uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: "corp.lan.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+		Ns:      "ns1.corp.lan.",
+		Mbox:    "hostmaster.corp.lan.",
+		Serial:  serial,
+		Refresh: refresh,
+		Retry:   retry,
+		Expire:  expire,
+		Minttl:  300,
+	}
+}
+
+func newA(name, addr string) dns.RR {
+	return &dns.A{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.ParseIP(addr).To4(),
+	}
+}
+
+func startPrimary(t *testing.T, soa *dns.SOA, records ...dns.RR) *primary {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := &primary{
+		addr:    pc.LocalAddr().String(),
+		soa:     soa,
+		records: records,
+	}
+	p.srvs = []*dns.Server{
+		{PacketConn: pc, Handler: dns.HandlerFunc(p.handle)},
+		{Listener: l, Handler: dns.HandlerFunc(p.handle)},
+	}
+	for _, srv := range p.srvs {
+		go srv.ActivateAndServe()
+	}
+	t.Cleanup(p.stop)
+
+	return p
+}
+
+func (p *primary) handle(w dns.ResponseWriter, r *dns.Msg) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	qtype := r.Question[0].Qtype
+	p.queries = append(p.queries, qtype)
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	switch qtype {
+	case dns.TypeSOA:
+		m.Answer = []dns.RR{p.soa}
+	case dns.TypeIXFR:
+		if p.ixfr != nil {
+			m.Answer = p.ixfr
+			break
+		}
+		fallthrough
+	case dns.TypeAXFR:
+		m.Answer = append(append([]dns.RR{p.soa}, p.records...), p.soa)
+	default:
+		m.SetRcode(r, dns.RcodeRefused)
+	}
+
+	_ = w.WriteMsg(m)
+}
+
+func (p *primary) update(soa *dns.SOA, ixfr []dns.RR, records ...dns.RR) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.soa = soa
+	p.ixfr = ixfr
+	p.records = records
+}
+
+func (p *primary) queried(qtype uint16) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, q := range p.queries {
+		if q == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *primary) stop() {
+	for _, srv := range p.srvs {
+		_ = srv.Shutdown()
+	}
+}
+
+// waitFor polls the function `fn` until it returns true, failing the test after a while
+func waitFor(t *testing.T, fn func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the secondary store")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func findA(t *testing.T, s store.Repository, name string) string {
+	t.Helper()
+
+	records, err := s.FindByTypeAndDomain(context.Background(), "A", name)
+	if err != nil || len(records) == 0 {
+		return ""
+	}
+	return records[0].Addr
+}
+
+func TestSecondary(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SuccessTransferAndNotify", func(t *testing.T) {
+		soa := newSOA(1, 3600, 600, 86400)
+		www := newA("www.corp.lan.", "10.0.0.1")
+		p := startPrimary(t, soa, newA("ns1.corp.lan.", "10.0.0.53"), www)
+
+		s := New(p.addr, "corp.lan", Timeout(time.Second))
+		defer s.(*SecondaryStore).Close()
+
+		waitFor(t, func() bool { return findA(t, s, "www.corp.lan") == "10.0.0.1" })
+
+		records, err := s.List(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(records) != 3 {
+			t.Errorf("unexpected store list length: wanted %v ; got %v", 3, len(records))
+		}
+
+		// the zone changes in the primary, which sends a NOTIFY message
+		newSOA := newSOA(2, 3600, 600, 86400)
+		mail := newA("mail.corp.lan.", "10.0.0.25")
+		p.update(newSOA, []dns.RR{newSOA, soa, www, newSOA, mail, newSOA}, mail)
+
+		if err := s.Notify(ctx, "corp.lan.", 2); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		waitFor(t, func() bool { return findA(t, s, "mail.corp.lan") == "10.0.0.25" })
+
+		if !p.queried(dns.TypeIXFR) {
+			t.Errorf("expected an incremental zone transfer")
+		}
+		if addr := findA(t, s, "www.corp.lan"); addr != "" {
+			t.Errorf("expected deleted record to be removed; got %v", addr)
+		}
+		if addr := findA(t, s, "ns1.corp.lan"); addr != "10.0.0.53" {
+			t.Errorf("expected unchanged record to be kept; got %v", addr)
+		}
+		soas, err := s.FindByTypeAndDomain(ctx, "SOA", "corp.lan")
+		if err != nil || len(soas) != 1 || soas[0].Data.Serial != 2 {
+			t.Errorf("unexpected SOA records: %v ; error: %v", soas, err)
+		}
+	})
+
+	t.Run("SuccessFullTransferOnIXFR", func(t *testing.T) {
+		p := startPrimary(t, newSOA(1, 3600, 600, 86400), newA("www.corp.lan.", "10.0.0.1"))
+
+		s := New(p.addr, "corp.lan", Timeout(time.Second))
+		defer s.(*SecondaryStore).Close()
+
+		waitFor(t, func() bool { return findA(t, s, "www.corp.lan") == "10.0.0.1" })
+
+		// the primary replies to the IXFR request with the full zone
+		p.update(newSOA(2, 3600, 600, 86400), nil, newA("www.corp.lan.", "10.0.0.2"))
+		if err := s.Notify(ctx, "corp.lan", 2); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		waitFor(t, func() bool { return findA(t, s, "www.corp.lan") == "10.0.0.2" })
+	})
+
+	t.Run("SuccessExpire", func(t *testing.T) {
+		p := startPrimary(t, newSOA(1, 1, 1, 1), newA("www.corp.lan.", "10.0.0.1"))
+
+		s := New(p.addr, "corp.lan", Timeout(100*time.Millisecond))
+		defer s.(*SecondaryStore).Close()
+
+		waitFor(t, func() bool { return findA(t, s, "www.corp.lan") == "10.0.0.1" })
+
+		// the primary is unreachable for longer than the zone's expire timer
+		p.stop()
+		waitFor(t, func() bool {
+			records, err := s.List(ctx)
+			return err == nil && len(records) == 0
+		})
+		if err := s.(*SecondaryStore).Err(); err == nil {
+			t.Errorf("expected an error from the last refresh")
+		}
+	})
+
+	t.Run("FailReadOnly", func(t *testing.T) {
+		s := New("", "corp.lan")
+		defer s.(*SecondaryStore).Close()
+
+		err := s.Create(ctx, store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").Build())
+		if !errors.Is(err, store.ErrReadOnly) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrReadOnly, err)
+		}
+		err = s.DeleteByDomain(ctx, "www.corp.lan")
+		if !errors.Is(err, store.ErrReadOnly) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrReadOnly, err)
+		}
+	})
+
+	t.Run("FailNotifyOtherZone", func(t *testing.T) {
+		s := New("", "corp.lan")
+		defer s.(*SecondaryStore).Close()
+
+		err := s.Notify(ctx, "example.com", 1)
+		if !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("unexpected error: wanted %v ; got %v", store.ErrNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package secondary
+
+import (
+	"context"
+	"net"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+// Create implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) Create(ctx context.Context, rs ...*store.Record) error {
+	return store.ErrReadOnly
+}
+
+// List implements the store.Repository interface
+func (s *SecondaryStore) List(ctx context.Context) ([]*store.Record, error) {
+	return s.repo().List(ctx)
+}
+
+// FindByTypeAndDomain implements the store.Repository interface
+func (s *SecondaryStore) FindByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*store.Record, error) {
+	return s.repo().FindByTypeAndDomain(ctx, rtype, domain)
+}
+
+// FilterByDomain implements the store.Repository interface
+func (s *SecondaryStore) FilterByDomain(ctx context.Context, domain string) ([]*store.Record, error) {
+	return s.repo().FilterByDomain(ctx, domain)
+}
+
+// FilterByDest implements the store.Repository interface
+func (s *SecondaryStore) FilterByDest(ctx context.Context, addr string) ([]*store.Record, error) {
+	return s.repo().FilterByDest(ctx, addr)
+}
+
+// HasNetwork implements the store.Networker interface
+func (s *SecondaryStore) HasNetwork(ctx context.Context, network *net.IPNet) (bool, error) {
+	return store.HasNetwork(ctx, s.repo(), network)
+}
+
+// Update implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) Update(ctx context.Context, domain string, r *store.Record) error {
+	return store.ErrReadOnly
+}
+
+// Delete implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) Delete(ctx context.Context, r *store.Record) error {
+	return store.ErrReadOnly
+}
+
+// DeleteByAddress implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) DeleteByAddress(ctx context.Context, addr string) error {
+	return store.ErrReadOnly
+}
+
+// DeleteByDomain implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) DeleteByDomain(ctx context.Context, name string) error {
+	return store.ErrReadOnly
+}
+
+// DeleteByTypeAndDomain implements the store.Repository interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) DeleteByTypeAndDomain(ctx context.Context, rtype, name string) error {
+	return store.ErrReadOnly
+}
+
+// Batch implements the store.Batcher interface
+//
+// The SecondaryStore is read-only, so it returns store.ErrReadOnly
+func (s *SecondaryStore) Batch(ctx context.Context, fn func(r store.Repository) error) error {
+	return store.ErrReadOnly
+}
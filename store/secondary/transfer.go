@@ -0,0 +1,212 @@
+package secondary
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/store/zonefile"
+	"github.com/zalgonoise/spanner"
+)
+
+// querySOA queries the primary DNS server for the zone's SOA record
+func (s *SecondaryStore) querySOA(ctx context.Context) (*dns.SOA, error) {
+	ctx, sp := spanner.Start(ctx, "secondary.querySOA")
+	defer sp.End()
+
+	switch {
+	case s.zone == "":
+		return nil, ErrNoZone
+	case s.primary == "":
+		return nil, ErrNoPrimary
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(s.zone, dns.TypeSOA)
+
+	client := &dns.Client{
+		Net:     "udp",
+		Timeout: s.timeout,
+	}
+	if s.key != nil {
+		client.TsigSecret = map[string]string{s.key.name: s.key.secret}
+		m.SetTsig(s.key.name, s.key.algorithm, tsigFudge, time.Now().Unix())
+	}
+
+	r, _, err := client.ExchangeContext(ctx, m, s.primary)
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("%w: %s", ErrNoSOA, dns.RcodeToString[r.Rcode])
+	}
+
+	for _, rr := range r.Answer {
+		if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(dns.CanonicalName(soa.Hdr.Name), s.zone) {
+			sp.Add(attr.Int("serial", int(soa.Serial)))
+			return soa, nil
+		}
+	}
+	return nil, ErrNoSOA
+}
+
+// transfer transfers the zone from the primary DNS server, replacing the records in the
+// store, and returns its new SOA record
+//
+// If the zone was `loaded` before, it requests an incremental zone transfer (IXFR) from
+// the serial in the SOA record `current`, applying the changes to the records in the
+// store; unless the primary DNS server replies with a full zone transfer instead.
+// Otherwise, it requests a full zone transfer (AXFR)
+func (s *SecondaryStore) transfer(ctx context.Context, current *dns.SOA, loaded bool) (*dns.SOA, error) {
+	ctx, sp := spanner.Start(ctx, "secondary.transfer")
+	defer sp.End()
+
+	m := new(dns.Msg)
+	if loaded {
+		m.SetIxfr(s.zone, current.Serial, current.Ns, current.Mbox)
+	} else {
+		m.SetAxfr(s.zone)
+	}
+	sp.Add(attr.String("type", dns.TypeToString[m.Question[0].Qtype]))
+
+	t := &dns.Transfer{
+		DialTimeout:  s.timeout,
+		ReadTimeout:  s.timeout,
+		WriteTimeout: s.timeout,
+	}
+	if s.key != nil {
+		t.TsigSecret = map[string]string{s.key.name: s.key.secret}
+		m.SetTsig(s.key.name, s.key.algorithm, tsigFudge, time.Now().Unix())
+	}
+
+	envelopes, err := t.In(m, s.primary)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrs []dns.RR
+	for env := range envelopes {
+		if env.Error != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTransfer, env.Error)
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	sp.Add(attr.Int("records", len(rrs)))
+
+	return s.apply(ctx, rrs, loaded)
+}
+
+// apply replaces the records in the store with the ones in the zone transfer `rrs`,
+// returning the zone's new SOA record
+//
+// The records of an incremental zone transfer (with an SOA record after the first one)
+// are applied to the records in the store, if the zone was `loaded` before; otherwise
+// they hold the full zone. A zone transfer with a single SOA record means the zone is
+// up-to-date, and leaves the store untouched
+func (s *SecondaryStore) apply(ctx context.Context, rrs []dns.RR, loaded bool) (*dns.SOA, error) {
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("%w: no records", ErrTransfer)
+	}
+	soa, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return nil, fmt.Errorf("%w: first record is not an SOA record", ErrTransfer)
+	}
+	if len(rrs) == 1 {
+		return soa, nil
+	}
+	if last, ok := rrs[len(rrs)-1].(*dns.SOA); !ok || last.Serial != soa.Serial {
+		return nil, fmt.Errorf("%w: last record is not the zone's SOA record", ErrTransfer)
+	}
+
+	var records []*store.Record
+	if _, incremental := rrs[1].(*dns.SOA); incremental && loaded {
+		current, err := s.repo().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		records = applyChanges(current, rrs[1:len(rrs)-1])
+	} else {
+		records = toRecords(rrs[1 : len(rrs)-1])
+	}
+
+	// the zone's SOA record is replaced with the one from the transfer
+	kept := records[:0]
+	for _, r := range records {
+		if r.Type != store.TypeSOA.String() || !strings.EqualFold(dns.Fqdn(r.Name), s.zone) {
+			kept = append(kept, r)
+		}
+	}
+	records = append(kept, toRecords([]dns.RR{soa})...)
+
+	repo := memmap.New()
+	if err := repo.Create(ctx, records...); err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	s.store = repo
+	s.loaded = true
+	s.mtx.Unlock()
+
+	return soa, nil
+}
+
+// applyChanges applies the changes in the incremental zone transfer records `rrs` to the
+// store.Records `records`, returning the resulting records
+//
+// Each change is a sequence of the SOA record before it, the deleted records, the SOA
+// record after it and the added records (RFC 1995)
+func applyChanges(records []*store.Record, rrs []dns.RR) []*store.Record {
+	var deleting bool
+	for _, rr := range rrs {
+		if _, ok := rr.(*dns.SOA); ok {
+			deleting = !deleting
+			continue
+		}
+
+		r, ok := zonefile.FromRR(rr)
+		if !ok {
+			continue
+		}
+		if !deleting {
+			records = append(records, r)
+			continue
+		}
+
+		kept := records[:0]
+		for _, existing := range records {
+			if !same(existing, r) {
+				kept = append(kept, existing)
+			}
+		}
+		records = kept
+	}
+	return records
+}
+
+// toRecords converts the DNS records `rrs` into store.Records, skipping the ones which
+// are not supported by the store
+func toRecords(rrs []dns.RR) []*store.Record {
+	records := make([]*store.Record, 0, len(rrs))
+	for _, rr := range rrs {
+		if r, ok := zonefile.FromRR(rr); ok {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// same returns true if the store.Records `a` and `b` hold the same record, regardless of
+// their TTL
+func same(a, b *store.Record) bool {
+	return a.Type == b.Type &&
+		strings.EqualFold(a.Name, b.Name) &&
+		a.Addr == b.Addr &&
+		reflect.DeepEqual(a.Data, b.Data)
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+type secondaryWithTrace struct {
+	withTrace
+	sec Secondary
+}
+
+// Notify signals that the zone `zone` changed in its primary DNS server, to the serial
+// `serial`, so that it is refreshed without waiting for its refresh timer
+func (t secondaryWithTrace) Notify(ctx context.Context, zone string, serial uint32) error {
+	ctx, s := spanner.Start(ctx, "store.Notify")
+	defer s.End()
+	s.Add(
+		attr.String("zone", zone),
+		attr.Int("serial", int(serial)),
+	)
+
+	err := t.sec.Notify(ctx, zone, serial)
+	if err != nil {
+		s.Event("error notifying zone change", attr.New("error", err.Error()))
+	}
+
+	return err
+}
@@ -11,7 +11,29 @@ type withTrace struct {
 	r Repository
 }
 
+// WithTrace wraps the Repository `r` with a tracer, adding a span to each of its calls
+//
+// If `r` is a Secondary (or a Syncer), the returned Repository is a Secondary (or a
+// Syncer) as well. It is always a Batcher, which applies the batches through `r` if it is
+// one as well
 func WithTrace(r Repository) Repository {
+	switch v := r.(type) {
+	case Secondary:
+		return secondaryWithTrace{
+			withTrace: withTrace{
+				r: r,
+			},
+			sec: v,
+		}
+	case Syncer:
+		return syncerWithTrace{
+			withTrace: withTrace{
+				r: r,
+			},
+			sync: v,
+		}
+	}
+
 	return withTrace{
 		r: r,
 	}
@@ -53,10 +75,10 @@ func (t withTrace) List(ctx context.Context) ([]*Record, error) {
 	return records, err
 }
 
-// FindByTypeAndDomain will fetch an address based on its address and type strings
+// FindByTypeAndDomain will fetch the set of records based on its domain name and type strings
 //
-// FindByTypeAndDomain(ctx, "A", "service.mydomain") -> { "127.0.0.1", nil }
-func (t withTrace) FindByTypeAndDomain(ctx context.Context, rtype string, domain string) (*Record, error) {
+// FindByTypeAndDomain(ctx, "A", "service.mydomain") -> { ["127.0.0.1", "127.0.0.2"], nil }
+func (t withTrace) FindByTypeAndDomain(ctx context.Context, rtype string, domain string) ([]*Record, error) {
 	ctx, s := spanner.Start(ctx, "store.FindByTypeAndDomain")
 	defer s.End()
 	s.Add(
@@ -64,14 +86,17 @@ func (t withTrace) FindByTypeAndDomain(ctx context.Context, rtype string, domain
 		attr.String("domain", domain),
 	)
 
-	r, err := t.r.FindByTypeAndDomain(ctx, rtype, domain)
+	records, err := t.r.FindByTypeAndDomain(ctx, rtype, domain)
 	if err != nil {
 		s.Event("error finding records", attr.New("error", err.Error()))
 	} else {
-		s.Add(attr.New("record", r))
+		s.Add(
+			attr.Int("records_length", len(records)),
+			attr.New("records", records),
+		)
 	}
 
-	return r, err
+	return records, err
 }
 
 // FilterByDomain will fetch an address based on its address for all types
@@ -138,6 +163,21 @@ func (t withTrace) Update(ctx context.Context, domain string, r *Record) error {
 	return err
 }
 
+// Delete removes the record matching the type, domain name, address and data
+// of the input Record, leaving any other records in its set untouched
+func (t withTrace) Delete(ctx context.Context, r *Record) error {
+	ctx, s := spanner.Start(ctx, "store.Delete")
+	defer s.End()
+	s.Add(attr.New("record", r))
+
+	err := t.r.Delete(ctx, r)
+	if err != nil {
+		s.Event("error deleting record", attr.New("error", err.Error()))
+	}
+
+	return err
+}
+
 // DeleteByAddress removes all records with IP address `addr`
 func (t withTrace) DeleteByAddress(ctx context.Context, addr string) error {
 	ctx, s := spanner.Start(ctx, "store.DeleteByAddress")
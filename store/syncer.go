@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// Syncer is a Repository which writes its records to a backing file, such as a store
+// which coalesces bursts of changes into a single write
+type Syncer interface {
+	Repository
+
+	// Flush writes the pending changes to the records right away, returning an error
+	// wrapping ErrSync if they could not be written
+	Flush(ctx context.Context) error
+	// Err returns the error from the last write of the records, or nil if it succeeded
+	Err() error
+}
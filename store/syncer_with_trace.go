@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/spanner"
+)
+
+type syncerWithTrace struct {
+	withTrace
+	sync Syncer
+}
+
+// Flush writes the pending changes to the records right away, returning an error
+// wrapping ErrSync if they could not be written
+func (t syncerWithTrace) Flush(ctx context.Context) error {
+	ctx, s := spanner.Start(ctx, "store.Flush")
+	defer s.End()
+
+	err := t.sync.Flush(ctx)
+	if err != nil {
+		s.Event("error flushing records", attr.New("error", err.Error()))
+	}
+
+	return err
+}
+
+// Err returns the error from the last write of the records, or nil if it succeeded
+func (t syncerWithTrace) Err() error {
+	return t.sync.Err()
+}
@@ -22,7 +22,7 @@ func (u unimplemented) List(ctx context.Context) ([]*Record, error) {
 }
 
 // FindByTypeAndDomain implements the store.Repository interface
-func (u unimplemented) FindByTypeAndDomain(ctx context.Context, rtype, domain string) (*Record, error) {
+func (u unimplemented) FindByTypeAndDomain(ctx context.Context, rtype, domain string) ([]*Record, error) {
 	return nil, ErrUnimplemented
 }
 
@@ -41,6 +41,11 @@ func (u unimplemented) Update(ctx context.Context, domain string, r *Record) err
 	return ErrUnimplemented
 }
 
+// Delete removes the record matching the input Record `r`
+func (u unimplemented) Delete(ctx context.Context, r *Record) error {
+	return ErrUnimplemented
+}
+
 // DeleteByAddress removes all records with IP address `addr`
 func (u unimplemented) DeleteByAddress(ctx context.Context, addr string) error {
 	return ErrUnimplemented
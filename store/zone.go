@@ -0,0 +1,189 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultZoneRefresh uint32 = 7200
+	defaultZoneRetry   uint32 = 3600
+	defaultZoneExpire  uint32 = 1209600
+	defaultZoneMinimum uint32 = 3600
+)
+
+// Zone describes a DNS zone owned by this server, such as `corp.lan`. Domain names
+// inside an owned zone are answered authoritatively, and are never sent to the fallback
+// DNS: if they are not in the store, the query is answered with NXDOMAIN
+//
+// A Zone is kept in the store as its SOA record and its NS records, which are created
+// from the Zone's fields by its Records method
+type Zone struct {
+	Name    string   `json:"name" yaml:"name"`
+	NS      []string `json:"ns,omitempty" yaml:"ns,omitempty"`
+	Mbox    string   `json:"mbox,omitempty" yaml:"mbox,omitempty"`
+	Serial  uint32   `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Refresh uint32   `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+	Retry   uint32   `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Expire  uint32   `json:"expire,omitempty" yaml:"expire,omitempty"`
+	Minimum uint32   `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	TTL     uint32   `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// Records returns the SOA record and the NS records for the Zone
+//
+// Unset fields are populated with defaults: the name server is `ns.<zone>`, the
+// mailbox is `hostmaster.<zone>`, the serial is 1, and the refresh, retry, expire
+// and minimum timers are 7200, 3600, 1209600 and 3600 seconds, respectively
+func (z *Zone) Records() []*Record {
+	name := strings.TrimSuffix(z.Name, ".")
+	ns := z.NS
+	if len(ns) == 0 {
+		ns = []string{"ns." + name}
+	}
+
+	data := &Data{
+		Mbox:    z.Mbox,
+		Serial:  z.Serial,
+		Refresh: z.Refresh,
+		Retry:   z.Retry,
+		Expire:  z.Expire,
+		Minttl:  z.Minimum,
+	}
+	if data.Mbox == "" {
+		data.Mbox = "hostmaster." + name
+	}
+	if data.Serial == 0 {
+		data.Serial = 1
+	}
+	if data.Refresh == 0 {
+		data.Refresh = defaultZoneRefresh
+	}
+	if data.Retry == 0 {
+		data.Retry = defaultZoneRetry
+	}
+	if data.Expire == 0 {
+		data.Expire = defaultZoneExpire
+	}
+	if data.Minttl == 0 {
+		data.Minttl = defaultZoneMinimum
+	}
+
+	records := make([]*Record, 0, len(ns)+1)
+	records = append(records, New().Type(TypeSOA.String()).Name(name).Addr(ns[0]).TTL(z.TTL).Data(data).Build())
+	for _, server := range ns {
+		records = append(records, New().Type(TypeNS.String()).Name(name).Addr(server).TTL(z.TTL).Build())
+	}
+
+	return records
+}
+
+// ZoneRecords returns the records in `records` which belong to the zone `zone`, with a
+// domain name inside it
+//
+// The records inside the zones nested in it (the domain names with their own SOA record)
+// are left out, except for the NS records delegating them and the A and AAAA records for
+// the name servers in those NS records (glue records)
+func ZoneRecords(zone string, records []*Record) []*Record {
+	zone = canonical(zone)
+
+	var children []string
+	for _, r := range records {
+		name := canonical(r.Name)
+		if r.Type == TypeSOA.String() && name != zone && inZone(zone, name) {
+			children = append(children, name)
+		}
+	}
+
+	childOf := func(name string) (string, bool) {
+		for _, child := range children {
+			if inZone(child, name) {
+				return child, true
+			}
+		}
+		return "", false
+	}
+
+	glue := map[string]struct{}{}
+	for _, r := range records {
+		name := canonical(r.Name)
+		if child, ok := childOf(name); ok && name == child && r.Type == TypeNS.String() {
+			glue[canonical(r.Addr)] = struct{}{}
+		}
+	}
+
+	inZoneRecords := make([]*Record, 0, len(records))
+	for _, r := range records {
+		name := canonical(r.Name)
+		if !inZone(zone, name) {
+			continue
+		}
+		child, ok := childOf(name)
+		if !ok {
+			inZoneRecords = append(inZoneRecords, r)
+			continue
+		}
+
+		switch r.Type {
+		case TypeNS.String():
+			if name == child {
+				inZoneRecords = append(inZoneRecords, r)
+			}
+		case TypeA.String(), TypeAAAA.String():
+			if _, ok := glue[name]; ok {
+				inZoneRecords = append(inZoneRecords, r)
+			}
+		}
+	}
+	return inZoneRecords
+}
+
+// Diff returns the records in `before` which are not in `after`, and the ones in `after`
+// which are not in `before`, comparing them by their Key
+func Diff(before, after []*Record) (deleted, added []*Record) {
+	keys := make(map[string]struct{}, len(before))
+	for _, r := range before {
+		keys[Key(r)] = struct{}{}
+	}
+	for _, r := range after {
+		k := Key(r)
+		if _, ok := keys[k]; ok {
+			delete(keys, k)
+			continue
+		}
+		added = append(added, r)
+	}
+	for _, r := range before {
+		if _, ok := keys[Key(r)]; ok {
+			deleted = append(deleted, r)
+		}
+	}
+	return deleted, added
+}
+
+// Key returns a string identifying the Record `r` by all of its fields, regardless of the
+// case of its domain name or its trailing dot
+func Key(r *Record) string {
+	var data Data
+	if r.Data != nil {
+		data = *r.Data
+	}
+	return fmt.Sprintf("%s %s %s %d %+v", r.Type, canonical(r.Name), r.Addr, r.TTL, data)
+}
+
+// NewerSerial returns true if the zone serial `a` is newer than the serial `b`, in serial
+// number arithmetic (RFC 1982)
+func NewerSerial(a, b uint32) bool {
+	return a != b && int32(a-b) > 0
+}
+
+// canonical returns the domain name `name` in lowercase and without a trailing dot
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// inZone returns true if the canonical domain name `name` is the zone `zone` or one of
+// its subdomains
+func inZone(zone, name string) bool {
+	return zone == "" || name == zone || strings.HasSuffix(name, "."+zone)
+}
@@ -0,0 +1,76 @@
+package zonefile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+// FromRR converts the dns.RR `rr` into a store.Record, returning false if its class or
+// record type is not supported by the store
+func FromRR(rr dns.RR) (*store.Record, bool) {
+	hdr := rr.Header()
+	if hdr.Class != dns.ClassINET {
+		return nil, false
+	}
+
+	b := store.New().
+		Type(dns.TypeToString[hdr.Rrtype]).
+		Name(trim(hdr.Name)).
+		TTL(hdr.Ttl)
+
+	switch v := rr.(type) {
+	case *dns.A:
+		b.Addr(v.A.String())
+	case *dns.AAAA:
+		b.Addr(v.AAAA.String())
+	case *dns.CNAME:
+		b.Addr(trim(v.Target))
+	case *dns.NS:
+		b.Addr(trim(v.Ns))
+	case *dns.PTR:
+		b.Addr(trim(v.Ptr))
+	case *dns.MX:
+		b.Addr(trim(v.Mx)).Data(&store.Data{Preference: v.Preference})
+	case *dns.TXT:
+		b.Data(&store.Data{Text: v.Txt})
+	case *dns.SRV:
+		b.Addr(trim(v.Target)).Data(&store.Data{Priority: v.Priority, Weight: v.Weight, Port: v.Port})
+	case *dns.SOA:
+		b.Addr(trim(v.Ns)).Data(&store.Data{
+			Mbox:    trim(v.Mbox),
+			Serial:  v.Serial,
+			Refresh: v.Refresh,
+			Retry:   v.Retry,
+			Expire:  v.Expire,
+			Minttl:  v.Minttl,
+		})
+	case *dns.CAA:
+		b.Addr(v.Value).Data(&store.Data{Flag: v.Flag, Tag: v.Tag})
+	default:
+		return nil, false
+	}
+	return b.Build(), true
+}
+
+// toRR converts the store.Record `r` into a dns.RR, to be written to a zone file
+//
+// Returns an error wrapping ErrRecord if its record type is not supported, or if it does
+// not hold the fields its type requires
+func toRR(r *store.Record) (dns.RR, error) {
+	rr, err := store.ToRR(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRecord, err)
+	}
+	return rr, nil
+}
+
+// trim returns the domain name `name` without its trailing dot, as kept in the store
+func trim(name string) string {
+	if name == "." {
+		return name
+	}
+	return strings.TrimSuffix(name, ".")
+}
@@ -0,0 +1,177 @@
+package zonefile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+var (
+	ErrParse  = errors.New("invalid zone file")
+	ErrRecord = errors.New("invalid record")
+)
+
+// Parse reads the master zone file (RFC 1035) from io.Reader `r`, returning its records
+// as store.Records. Relative domain names (and `@`) are completed with the origin
+// `origin`, until it is changed with an `$ORIGIN` directive; records without a TTL take
+// the one in the last `$TTL` directive
+//
+// Records of types which are not supported by the store are skipped. `$INCLUDE`
+// directives are rejected, as the zone file may come from an untrusted source; use
+// ReadFile to follow them
+func Parse(r io.Reader, origin string) ([]*store.Record, error) {
+	return parse(r, origin, "", false)
+}
+
+// ReadFile reads the master zone file (RFC 1035) in `path`, returning its records as
+// store.Records, like Parse. `$INCLUDE` directives are followed, with relative paths
+// being relative to the directory of the file including them
+func ReadFile(path, origin string) ([]*store.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parse(f, origin, path, true)
+}
+
+func parse(r io.Reader, origin, path string, includes bool) ([]*store.Record, error) {
+	if origin != "" {
+		origin = dns.Fqdn(origin)
+	}
+
+	zp := dns.NewZoneParser(r, origin, path)
+	zp.SetIncludeAllowed(includes)
+
+	var records []*store.Record
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if record, ok := FromRR(rr); ok {
+			records = append(records, record)
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	return records, nil
+}
+
+// Write writes the store.Records `records` to io.Writer `w` as a master zone file
+// (RFC 1035), starting with an `$ORIGIN` directive for the origin `origin`
+//
+// Domain names inside the origin are written relative to it (with `@` for the origin
+// itself), and the ones outside of it are written in full; if `origin` is empty, all
+// domain names are written in full. The SOA record comes first, followed by the records
+// for the origin, the ones inside it and the ones outside of it, sorted by domain name and
+// type. Records without a TTL are written without one, taking the reader's default
+//
+// Returns an error wrapping ErrRecord if a record cannot be written, in which case no
+// records are written
+func Write(w io.Writer, origin string, records []*store.Record) error {
+	if origin != "" {
+		origin = dns.CanonicalName(origin)
+	}
+
+	type line struct {
+		name  string
+		rank  int
+		rtype uint16
+		text  string
+	}
+
+	lines := make([]line, 0, len(records))
+	for _, r := range records {
+		rr, err := toRR(r)
+		if err != nil {
+			return err
+		}
+		hdr := rr.Header()
+		name := dns.CanonicalName(hdr.Name)
+
+		rank := 3
+		switch {
+		case hdr.Rrtype == dns.TypeSOA && name == origin:
+			rank = 0
+		case name == origin:
+			rank = 1
+		case origin == "" || dns.IsSubDomain(origin, name):
+			rank = 2
+		}
+
+		lines = append(lines, line{
+			name:  name,
+			rank:  rank,
+			rtype: hdr.Rrtype,
+			text:  format(rr, r.TTL, origin),
+		})
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].rank != lines[j].rank {
+			return lines[i].rank < lines[j].rank
+		}
+		if lines[i].name != lines[j].name {
+			return lines[i].name < lines[j].name
+		}
+		return lines[i].rtype < lines[j].rtype
+	})
+
+	bw := bufio.NewWriter(w)
+	if origin != "" {
+		fmt.Fprintf(bw, "$ORIGIN %s\n", origin)
+	}
+	for _, l := range lines {
+		bw.WriteString(l.text)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// format returns the dns.RR `rr` as a line in a zone file, with its domain name relative
+// to `origin`, and without a TTL if `ttl` is zero. SOA records are split across multiple
+// lines, one for each of their timers
+func format(rr dns.RR, ttl uint32, origin string) string {
+	hdr := rr.Header()
+	rdata := strings.TrimPrefix(rr.String(), hdr.String())
+
+	fields := []string{relative(hdr.Name, origin)}
+	if ttl != 0 {
+		fields = append(fields, strconv.FormatUint(uint64(ttl), 10))
+	}
+	fields = append(fields, dns.ClassToString[hdr.Class], dns.TypeToString[hdr.Rrtype])
+
+	if soa, ok := rr.(*dns.SOA); ok {
+		rdata = fmt.Sprintf("%s %s (\n\t\t\t\t%d\t; serial\n\t\t\t\t%d\t; refresh\n\t\t\t\t%d\t; retry\n\t\t\t\t%d\t; expire\n\t\t\t\t%d )\t; minimum",
+			soa.Ns, soa.Mbox, soa.Serial, soa.Refresh, soa.Retry, soa.Expire, soa.Minttl,
+		)
+	}
+	return strings.Join(append(fields, rdata), "\t")
+}
+
+// relative returns the domain name `name` relative to the origin `origin`: `@` for the
+// origin itself, the labels before the origin for its subdomains, or the fully-qualified
+// name otherwise
+func relative(name, origin string) string {
+	if origin == "" {
+		return name
+	}
+	canonical := dns.CanonicalName(name)
+	switch {
+	case canonical == origin:
+		return "@"
+	case origin == ".":
+		return name
+	case dns.IsSubDomain(origin, canonical):
+		return name[:len(name)-len(origin)-1]
+	default:
+		return name
+	}
+}
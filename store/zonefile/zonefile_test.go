@@ -0,0 +1,190 @@
+package zonefile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zalgonoise/dns/store"
+)
+
+const testZone = `$ORIGIN corp.lan.
+$TTL 300
+; the zone's SOA record, across multiple lines
+@	IN	SOA	ns1 hostmaster (
+		2023010101	; serial
+		7200		; refresh
+		3600		; retry
+		1209600		; expire
+		300 )		; minimum
+	IN	NS	ns1
+ns1	IN	A	10.0.0.53
+www	60	IN	A	10.0.0.1
+www	IN	AAAA	fd00::1
+mail	IN	MX	10 mx.example.com.
+@	IN	TXT	"v=spf1 mx -all" "second string"
+_sip._tcp	IN	SRV	10 20 5060 sip
+@	IN	CAA	0 issue "letsencrypt.org"
+alias	IN	CNAME	www
+@	IN	HINFO	"amd64" "linux"
+$ORIGIN sub.corp.lan.
+host	IN	A	10.0.1.1
+`
+
+var testRecords = []*store.Record{
+	store.New().Type("SOA").Name("corp.lan").Addr("ns1.corp.lan").TTL(300).Data(&store.Data{
+		Mbox: "hostmaster.corp.lan", Serial: 2023010101, Refresh: 7200, Retry: 3600, Expire: 1209600, Minttl: 300,
+	}).Build(),
+	store.New().Type("NS").Name("corp.lan").Addr("ns1.corp.lan").TTL(300).Build(),
+	store.New().Type("A").Name("ns1.corp.lan").Addr("10.0.0.53").TTL(300).Build(),
+	store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").TTL(60).Build(),
+	store.New().Type("AAAA").Name("www.corp.lan").Addr("fd00::1").TTL(300).Build(),
+	store.New().Type("MX").Name("mail.corp.lan").Addr("mx.example.com").TTL(300).Data(&store.Data{Preference: 10}).Build(),
+	store.New().Type("TXT").Name("corp.lan").TTL(300).Data(&store.Data{Text: []string{"v=spf1 mx -all", "second string"}}).Build(),
+	store.New().Type("SRV").Name("_sip._tcp.corp.lan").Addr("sip.corp.lan").TTL(300).Data(&store.Data{Priority: 10, Weight: 20, Port: 5060}).Build(),
+	store.New().Type("CAA").Name("corp.lan").Addr("letsencrypt.org").TTL(300).Data(&store.Data{Tag: "issue"}).Build(),
+	store.New().Type("CNAME").Name("alias.corp.lan").Addr("www.corp.lan").TTL(300).Build(),
+	store.New().Type("A").Name("host.sub.corp.lan").Addr("10.0.1.1").TTL(300).Build(),
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		records, err := Parse(strings.NewReader(testZone), "")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(testRecords, records) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", testRecords, records)
+		}
+	})
+
+	t.Run("SuccessWithOrigin", func(t *testing.T) {
+		wants := []*store.Record{
+			store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").TTL(3600).Build(),
+			store.New().Type("A").Name("corp.lan").Addr("10.0.0.2").TTL(3600).Build(),
+		}
+
+		records, err := Parse(strings.NewReader("www 3600 IN A 10.0.0.1\n@ 3600 IN A 10.0.0.2\n"), "corp.lan")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(wants, records) {
+			t.Errorf("output mismatch error: wanted %v ; got %v", wants, records)
+		}
+	})
+
+	t.Run("FailInvalidRecord", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("www IN A not-an-address\n"), "corp.lan")
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrParse, err)
+		}
+	})
+
+	t.Run("FailInclude", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("$INCLUDE /etc/hosts\n"), "corp.lan")
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrParse, err)
+		}
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "hosts.zone"), []byte("www IN A 10.0.0.1\n"), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "corp.lan.zone"), []byte("$TTL 300\n$INCLUDE hosts.zone\nmail IN A 10.0.0.25\n"), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wants := []*store.Record{
+		store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").TTL(300).Build(),
+		store.New().Type("A").Name("mail.corp.lan").Addr("10.0.0.25").TTL(300).Build(),
+	}
+
+	records, err := ReadFile(filepath.Join(dir, "corp.lan.zone"), "corp.lan")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(wants, records) {
+		t.Errorf("output mismatch error: wanted %v ; got %v", wants, records)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := Write(buf, "corp.lan", testRecords); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		lines := strings.Split(buf.String(), "\n")
+		if lines[0] != "$ORIGIN corp.lan." || !strings.HasPrefix(lines[1], "@\t300\tIN\tSOA\tns1.corp.lan. hostmaster.corp.lan. (") {
+			t.Errorf("unexpected zone file header: %q", lines[:2])
+		}
+		if !strings.Contains(buf.String(), "\nhost.sub\t300\tIN\tA\t10.0.1.1\n") {
+			t.Errorf("expected relative domain names in zone file: %s", buf.String())
+		}
+
+		records, err := Parse(buf, "")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(records) != len(testRecords) {
+			t.Errorf("unexpected records list length: wanted %v ; got %v", len(testRecords), len(records))
+			return
+		}
+		for _, wants := range testRecords {
+			var found bool
+			for _, r := range records {
+				if reflect.DeepEqual(wants, r) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("missing record %v in %v", wants, records)
+			}
+		}
+	})
+
+	t.Run("NoTTLAndOutsideOrigin", func(t *testing.T) {
+		wants := "$ORIGIN corp.lan.\nwww\tIN\tA\t10.0.0.1\nexample.com.\t60\tIN\tCNAME\twww.corp.lan.\n"
+
+		buf := &bytes.Buffer{}
+		err := Write(buf, "corp.lan.", []*store.Record{
+			store.New().Type("CNAME").Name("example.com").Addr("www.corp.lan").TTL(60).Build(),
+			store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").Build(),
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if buf.String() != wants {
+			t.Errorf("output mismatch error: wanted %q ; got %q", wants, buf.String())
+		}
+	})
+
+	t.Run("FailInvalidRecord", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := Write(buf, "corp.lan", []*store.Record{
+			store.New().Type("A").Name("www.corp.lan").Addr("fd00::1").Build(),
+		})
+		if !errors.Is(err, ErrRecord) {
+			t.Errorf("unexpected error: wanted %v ; got %v", ErrRecord, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected nothing to be written: %q", buf.String())
+		}
+	})
+}
@@ -179,7 +179,7 @@ func TestTransport(t *testing.T) {
 		})
 		t.Run("Store", func(t *testing.T) {
 			t.Run("AddRecord", func(t *testing.T) {
-				wants := `{"success":true,"message":"added record successfully","data":{"type":"A","name":"not.a.dom.ain","address":"192.168.0.10"}}`
+				wants := `{"success":true,"message":"added record successfully","data":[{"type":"A","name":"not.a.dom.ain","address":"192.168.0.10"}]}`
 
 				b, status, err := httpReq(dnsC.HTTPURI, "/records/add", []byte(`{"name":"not.a.dom.ain","type":"A","address":"192.168.0.10"}`))
 				if err != nil {
@@ -221,7 +221,7 @@ func TestTransport(t *testing.T) {
 				t.Log("[ok] /records")
 			})
 			t.Run("GetRecordByDomainAndType", func(t *testing.T) {
-				wants := `{"success":true,"message":"fetched record successfully","data":{"type":"A","name":"not.a.dom.ain","address":"192.168.0.10"}}`
+				wants := `{"success":true,"message":"fetched record successfully","data":[{"type":"A","name":"not.a.dom.ain","address":"192.168.0.10"}]}`
 
 				b, status, err := httpReq(dnsC.HTTPURI, "/records/getAddress", []byte(`{"name":"not.a.dom.ain","type":"A"}`))
 				if err != nil {
@@ -263,7 +263,7 @@ func TestTransport(t *testing.T) {
 				t.Log("[ok] /records/getDomains")
 			})
 			t.Run("UpdateRecord", func(t *testing.T) {
-				wants := `{"success":true,"message":"updated record successfully","data":{"type":"A","name":"really.not.a.dom.ain","address":"192.168.0.10"}}`
+				wants := `{"success":true,"message":"updated record successfully","data":[{"type":"A","name":"really.not.a.dom.ain","address":"192.168.0.10"}]}`
 
 				b, status, err := httpReq(dnsC.HTTPURI, "/records/update", []byte(`{"target":"not.a.dom.ain","record":{"name":"really.not.a.dom.ain","type":"A","address":"192.168.0.10"}}`))
 				if err != nil {
@@ -15,6 +15,17 @@ type HTTPAPI interface {
 	GetRecordByAddress(w http.ResponseWriter, r *http.Request)
 	UpdateRecord(w http.ResponseWriter, r *http.Request)
 	DeleteRecord(w http.ResponseWriter, r *http.Request)
+	FlushRecords(w http.ResponseWriter, r *http.Request)
+
+	ImportZone(w http.ResponseWriter, r *http.Request)
+	ExportZone(w http.ResponseWriter, r *http.Request)
+
+	CacheStats(w http.ResponseWriter, r *http.Request)
+	FlushCache(w http.ResponseWriter, r *http.Request)
+
+	DS(w http.ResponseWriter, r *http.Request)
 
 	Health(w http.ResponseWriter, r *http.Request)
+
+	DNSQuery(w http.ResponseWriter, r *http.Request)
 }
@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/store"
+)
+
+func (e *endpoints) CacheStats(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.CacheStats")
+	defer s.End()
+
+	stats, err := e.cache.CacheStats(ctx)
+	if err != nil {
+		res := NewResponse[dns.CacheStats](500, "failed to fetch cache stats", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	res := NewResponse(200, "fetched cache stats successfully", nil, stats)
+	res.WriteHTTP(ctx, w)
+}
+
+func (e *endpoints) FlushCache(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.FlushCache")
+	defer s.End()
+
+	// an empty body flushes all cached answers
+	var name string
+	if r.Body != nil && r.ContentLength != 0 {
+		record, err := readBody[store.Record](ctx, r)
+		if err != nil {
+			res := NewResponse[int](400, "failed to read record from body", err, nil)
+			res.WriteHTTP(ctx, w)
+			return
+		}
+		name = record.Name
+	}
+
+	n, err := e.cache.FlushCache(ctx, name)
+	if err != nil {
+		res := NewResponse[int](500, "failed to flush cache", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	res := NewResponse(200, "flushed cache successfully", nil, &n)
+	res.WriteHTTP(ctx, w)
+}
@@ -0,0 +1,45 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+)
+
+// DS replies with the DS records for the DNSSEC keys of the zone in the request's body
+// (as the record's name), in their text format, to be published in its parent zone
+func (e *endpoints) DS(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.DS")
+	defer s.End()
+
+	record, err := readBody[store.Record](ctx, r)
+	if err != nil {
+		res := NewResponse[[]string](400, "failed to read zone from body", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	ds, err := e.sec.DS(ctx, record.Name)
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, service.ErrNoName):
+			status = 400
+		case errors.Is(err, service.ErrNoZone), errors.Is(err, service.ErrNoDNSSEC):
+			status = 404
+		}
+		res := NewResponse[[]string](status, "failed to fetch DS records", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	records := make([]string, 0, len(ds))
+	for _, rr := range ds {
+		records = append(records, rr.String())
+	}
+
+	res := NewResponse(200, "fetched DS records successfully", nil, &records)
+	res.WriteHTTP(ctx, w)
+}
@@ -0,0 +1,283 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/transport/udp/miekgdns"
+	"github.com/zalgonoise/spanner"
+)
+
+const (
+	dnsMessageType = "application/dns-message"
+	dnsJSONType    = "application/dns-json"
+
+	// defaultPrefix is the DNS prefix trimmed from the domain names in DNS-over-HTTPS
+	// queries, if none is configured
+	defaultPrefix = "."
+	// maxDNSMessageSize is the maximum size for a DNS message in a POST request body
+	maxDNSMessageSize = dns.MaxMsgSize
+)
+
+var (
+	ErrInvalidDNSQuery  = errors.New("invalid DNS query")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+	ErrQueryTooLarge    = errors.New("DNS query is too large")
+)
+
+// dnsJSONResponse is the JSON representation of a DNS response, for the
+// application/dns-json media type
+type dnsJSONResponse struct {
+	Status    int               `json:"Status"`
+	TC        bool              `json:"TC"`
+	RD        bool              `json:"RD"`
+	RA        bool              `json:"RA"`
+	AD        bool              `json:"AD"`
+	CD        bool              `json:"CD"`
+	Question  []dnsJSONQuestion `json:"Question"`
+	Answer    []dnsJSONRecord   `json:"Answer,omitempty"`
+	Authority []dnsJSONRecord   `json:"Authority,omitempty"`
+}
+
+type dnsJSONQuestion struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+}
+
+type dnsJSONRecord struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+	TTL  uint32 `json:"TTL"`
+	Data string `json:"data"`
+}
+
+// DNSQuery answers DNS-over-HTTPS queries (RFC 8484), through the same service.Answering
+// path as the DNS server:
+//   - GET requests with a base64url-encoded DNS message in the `dns` query parameter
+//   - POST requests with a DNS message body, with the application/dns-message content type
+//
+// The DNS prefix of the DNS server is trimmed from the domain names in the queries, as it
+// is by the DNS server itself. Both are replied to with a DNS message, padded if the query has an EDNS0 OPT record
+// (RFC 8467). For debugging, GET requests with `name` and (optionally) `type` query
+// parameters, or with an application/dns-json Accept header, are replied to with the JSON
+// representation of the DNS response
+func (e *endpoints) DNSQuery(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.DNSQuery")
+	defer s.End()
+
+	query, asJSON, status, err := readDNSQuery(ctx, r)
+	if err != nil {
+		if status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", "GET, POST")
+		}
+		res := NewResponse[string](status, "failed to read DNS query", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	m := miekgdns.Reply(ctx, e.ans, e.prefix, query)
+
+	if asJSON {
+		writeDNSJSON(ctx, w, m)
+		return
+	}
+	miekgdns.Pad(m)
+	writeDNSMessage(ctx, w, m)
+}
+
+// readDNSQuery reads the DNS message in the *http.Request `r`, returning it alongside
+// a boolean on whether the response should be encoded as JSON
+//
+// If the request is invalid, it returns the HTTP status code for the error
+func readDNSQuery(ctx context.Context, r *http.Request) (*dns.Msg, bool, int, error) {
+	_, s := spanner.Start(ctx, "http.readDNSQuery")
+	defer s.End()
+	s.Add(attr.String("method", r.Method))
+
+	var (
+		asJSON = strings.Contains(r.Header.Get("Accept"), dnsJSONType) ||
+			r.URL.Query().Get("ct") == dnsJSONType
+		buf []byte
+		err error
+	)
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+
+		if name := query.Get("name"); name != "" {
+			qtype, err := parseQType(query.Get("type"))
+			if err != nil {
+				return nil, false, http.StatusBadRequest, err
+			}
+
+			m := new(dns.Msg)
+			m.SetQuestion(dns.Fqdn(name), qtype)
+			return m, true, 0, nil
+		}
+
+		param := query.Get("dns")
+		if param == "" {
+			return nil, false, http.StatusBadRequest, fmt.Errorf("%w: missing dns query parameter", ErrInvalidDNSQuery)
+		}
+		buf, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(param, "="))
+		if err != nil {
+			return nil, false, http.StatusBadRequest, fmt.Errorf("%w: %v", ErrInvalidDNSQuery, err)
+		}
+	case http.MethodPost:
+		if ct := r.Header.Get("Content-Type"); ct != dnsMessageType {
+			return nil, false, http.StatusUnsupportedMediaType, fmt.Errorf("%w: %q", ErrUnsupportedMedia, ct)
+		}
+
+		buf, err = io.ReadAll(io.LimitReader(r.Body, maxDNSMessageSize+1))
+		if err != nil {
+			return nil, false, http.StatusBadRequest, fmt.Errorf("%w: %v", ErrInvalidBody, err)
+		}
+		if len(buf) > maxDNSMessageSize {
+			return nil, false, http.StatusRequestEntityTooLarge, ErrQueryTooLarge
+		}
+	default:
+		return nil, false, http.StatusMethodNotAllowed, fmt.Errorf("%w: %s", ErrMethodNotAllowed, r.Method)
+	}
+
+	m := new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		s.Event("error unpacking DNS message", attr.String("error", err.Error()))
+		return nil, false, http.StatusBadRequest, fmt.Errorf("%w: %v", ErrInvalidDNSQuery, err)
+	}
+	if m.Response || len(m.Question) == 0 {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("%w: message is not a query", ErrInvalidDNSQuery)
+	}
+
+	return m, asJSON, 0, nil
+}
+
+// parseQType parses the record type in string `t`, either as its name (e.g. "AAAA")
+// or as its number (e.g. "28"). It defaults to the A record type
+func parseQType(t string) (uint16, error) {
+	if t == "" {
+		return dns.TypeA, nil
+	}
+	if qtype, ok := dns.StringToType[strings.ToUpper(t)]; ok {
+		return qtype, nil
+	}
+
+	qtype, err := strconv.ParseUint(t, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid record type %q", ErrInvalidDNSQuery, t)
+	}
+	return uint16(qtype), nil
+}
+
+// writeDNSMessage writes the DNS message `m` to the http.ResponseWriter `w`, with a
+// Cache-Control header based on the lowest TTL in the message
+func writeDNSMessage(ctx context.Context, w http.ResponseWriter, m *dns.Msg) {
+	_, s := spanner.Start(ctx, "http.writeDNSMessage")
+	defer s.End()
+
+	buf, err := m.Pack()
+	if err != nil {
+		s.Event("failed to pack DNS message", attr.String("error", err.Error()))
+		res := NewResponse[string](500, "failed to pack DNS message", fmt.Errorf("%w: %v", ErrInternal, err), nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", dnsMessageType)
+	if ttl, ok := minTTL(m); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", ttl))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	n, err := w.Write(buf)
+	if err != nil {
+		s.Event("failed to write response", attr.String("error", err.Error()))
+		return
+	}
+	s.Event("response written successfully", attr.Int("bytes_written", n))
+}
+
+// writeDNSJSON writes the JSON representation of the DNS message `m` to the
+// http.ResponseWriter `w`
+func writeDNSJSON(ctx context.Context, w http.ResponseWriter, m *dns.Msg) {
+	_, s := spanner.Start(ctx, "http.writeDNSJSON")
+	defer s.End()
+
+	res := dnsJSONResponse{
+		Status:    m.Rcode,
+		TC:        m.Truncated,
+		RD:        m.RecursionDesired,
+		RA:        m.RecursionAvailable,
+		AD:        m.AuthenticatedData,
+		CD:        m.CheckingDisabled,
+		Question:  make([]dnsJSONQuestion, 0, len(m.Question)),
+		Answer:    toJSONRecords(m.Answer),
+		Authority: toJSONRecords(m.Ns),
+	}
+	for _, q := range m.Question {
+		res.Question = append(res.Question, dnsJSONQuestion{Name: q.Name, Type: q.Qtype})
+	}
+
+	buf, err := enc(ctx).Encode(res)
+	if err != nil {
+		s.Event("failed to encode response", attr.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dnsJSONType)
+	w.WriteHeader(http.StatusOK)
+
+	n, err := w.Write(buf)
+	if err != nil {
+		s.Event("failed to write response", attr.String("error", err.Error()))
+		return
+	}
+	s.Event("response written successfully", attr.String("raw", string(buf)), attr.Int("bytes_written", n))
+}
+
+func toJSONRecords(rrs []dns.RR) []dnsJSONRecord {
+	if len(rrs) == 0 {
+		return nil
+	}
+
+	records := make([]dnsJSONRecord, 0, len(rrs))
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		records = append(records, dnsJSONRecord{
+			Name: hdr.Name,
+			Type: hdr.Rrtype,
+			TTL:  hdr.Ttl,
+			Data: strings.TrimPrefix(rr.String(), hdr.String()),
+		})
+	}
+	return records
+}
+
+// minTTL returns the lowest TTL in the answer and authority sections of the DNS
+// message `m`, and false if there are no records in either section
+func minTTL(m *dns.Msg) (uint32, bool) {
+	var (
+		ttl uint32
+		ok  bool
+	)
+
+	for _, section := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range section {
+			if !ok || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+				ok = true
+			}
+		}
+	}
+	return ttl, ok
+}
@@ -0,0 +1,161 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/health/simplehealth"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+)
+
+func TestDNSQuery(t *testing.T) {
+	svc := service.New(core.New(), memmap.New(), simplehealth.New(), config.Default())
+	err := svc.AddRecord(context.Background(), store.New().Type("A").Name("web.lan").Addr("10.0.0.5").TTL(60).Build())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	api := NewAPI(svc, nil, "")
+
+	query := new(dns.Msg)
+	query.SetQuestion("web.lan.", dns.TypeA)
+	packed, err := query.Pack()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	verify := func(t *testing.T, rec *httptest.ResponseRecorder) {
+		if rec.Code != http.StatusOK {
+			t.Errorf("unexpected status: wanted %v ; got %v", http.StatusOK, rec.Code)
+			return
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != dnsMessageType {
+			t.Errorf("unexpected content type: wanted %v ; got %v", dnsMessageType, ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "max-age=60" {
+			t.Errorf("unexpected cache control: wanted %v ; got %v", "max-age=60", cc)
+		}
+
+		m := new(dns.Msg)
+		if err := m.Unpack(rec.Body.Bytes()); err != nil {
+			t.Errorf("unexpected error unpacking response: %v", err)
+			return
+		}
+		if m.Id != query.Id || len(m.Answer) != 1 {
+			t.Errorf("unexpected response: %v", m)
+			return
+		}
+		if a, ok := m.Answer[0].(*dns.A); !ok || a.A.String() != "10.0.0.5" {
+			t.Errorf("unexpected answer: %v", m.Answer[0])
+		}
+	}
+
+	t.Run("ConfiguredPrefix", func(t *testing.T) {
+		if prefix := NewAPI(svc, nil, "-").(*endpoints).prefix; prefix != "-" {
+			t.Errorf("unexpected DNS prefix: wanted %q ; got %q", "-", prefix)
+		}
+		if prefix := api.(*endpoints).prefix; prefix != defaultPrefix {
+			t.Errorf("unexpected DNS prefix: wanted %q ; got %q", defaultPrefix, prefix)
+		}
+	})
+
+	t.Run("GET", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(packed), nil)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+		verify(t, rec)
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", dnsMessageType)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+		verify(t, rec)
+	})
+
+	t.Run("PaddedWithEDNS0", func(t *testing.T) {
+		q := query.Copy()
+		q.SetEdns0(4096, false)
+		packed, err := q.Pack()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", dnsMessageType)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+		verify(t, rec)
+		if n := rec.Body.Len(); n%468 != 0 {
+			t.Errorf("expected the response to be padded to a multiple of %v bytes; got %v", 468, n)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/dns-query?name=web.lan&type=A", nil)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("unexpected status: wanted %v ; got %v", http.StatusOK, rec.Code)
+			return
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != dnsJSONType {
+			t.Errorf("unexpected content type: wanted %v ; got %v", dnsJSONType, ct)
+		}
+		if !strings.Contains(rec.Body.String(), `"data":"10.0.0.5"`) {
+			t.Errorf("unexpected response body: %s", rec.Body.String())
+		}
+	})
+
+	t.Run("FailUnsupportedMedia", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("unexpected status: wanted %v ; got %v", http.StatusUnsupportedMediaType, rec.Code)
+		}
+	})
+
+	t.Run("FailInvalidQuery", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/dns-query?dns=not-a-dns-message", nil)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("unexpected status: wanted %v ; got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("FailMethodNotAllowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/dns-query", nil)
+		rec := httptest.NewRecorder()
+
+		api.DNSQuery(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("unexpected status: wanted %v ; got %v", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
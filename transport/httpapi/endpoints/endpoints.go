@@ -8,15 +8,31 @@ import (
 )
 
 type endpoints struct {
-	s   service.StoreWithHealth
-	UDP udp.Server
-	enc encoder.EncodeDecoder
+	s     service.StoreWithHealth
+	ans   service.Answering
+	cache service.CacheService
+	sec   service.DNSSECService
+	UDP   udp.Server
+	enc   encoder.EncodeDecoder
+
+	prefix string
 }
 
-func NewAPI(s service.Service, udps udp.Server) httpapi.HTTPAPI {
+// NewAPI returns the HTTP API for the service.Service `s` and the DNS server `udps`,
+// answering the DNS-over-HTTPS queries with the DNS prefix `prefix` (or "." if empty),
+// like the DNS server does
+func NewAPI(s service.Service, udps udp.Server, prefix string) httpapi.HTTPAPI {
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+
 	return &endpoints{
-		s:   s,
-		UDP: udps,
-		enc: encoder.New("json"),
+		s:      s,
+		ans:    s,
+		cache:  s,
+		sec:    s,
+		UDP:    udps,
+		enc:    encoder.New("json"),
+		prefix: prefix,
 	}
 }
@@ -1,6 +1,7 @@
 package endpoints
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/zalgonoise/dns/store"
@@ -19,19 +20,19 @@ func (e *endpoints) AddRecord(w http.ResponseWriter, r *http.Request) {
 
 	err = e.s.AddRecord(ctx, record)
 	if err != nil {
-		res := NewResponse[store.Record](500, "failed to add record", err, nil)
+		res := NewResponse[store.Record](storeStatus(err), "failed to add record", err, nil)
 		res.WriteHTTP(ctx, w)
 		return
 	}
 
-	newRecord, err := e.s.GetRecordByTypeAndDomain(ctx, record.Type, record.Name)
+	newRecords, err := e.s.GetRecordByTypeAndDomain(ctx, record.Type, record.Name)
 	if err != nil {
 		res := NewResponse[store.Record](500, "failed to get new record", err, nil)
 		res.WriteHTTP(ctx, w)
 		return
 	}
 
-	res := NewResponse(200, "added record successfully", nil, newRecord)
+	res := NewResponse(200, "added record successfully", nil, &newRecords)
 	res.WriteHTTP(ctx, w)
 }
 
@@ -68,7 +69,7 @@ func (e *endpoints) GetRecordByDomain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res := NewResponse(200, "fetched record successfully", nil, output)
+	res := NewResponse(200, "fetched record successfully", nil, &output)
 	res.WriteHTTP(ctx, w)
 }
 
@@ -107,7 +108,7 @@ func (e *endpoints) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 
 	err = e.s.UpdateRecord(ctx, rwt.Target, &rwt.Record)
 	if err != nil {
-		res := NewResponse[store.Record](500, "failed to update record", err, nil)
+		res := NewResponse[store.Record](storeStatus(err), "failed to update record", err, nil)
 		res.WriteHTTP(ctx, w)
 		return
 	}
@@ -119,7 +120,7 @@ func (e *endpoints) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res := NewResponse(200, "updated record successfully", nil, output)
+	res := NewResponse(200, "updated record successfully", nil, &output)
 	res.WriteHTTP(ctx, w)
 }
 
@@ -136,7 +137,7 @@ func (e *endpoints) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 
 	err = e.s.DeleteRecord(ctx, record)
 	if err != nil {
-		res := NewResponse[store.Record](500, "failed to get record by address", err, nil)
+		res := NewResponse[store.Record](storeStatus(err), "failed to get record by address", err, nil)
 		res.WriteHTTP(ctx, w)
 		return
 	}
@@ -144,3 +145,28 @@ func (e *endpoints) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 	res := NewResponse(200, "deleted record successfully", nil, record)
 	res.WriteHTTP(ctx, w)
 }
+
+func (e *endpoints) FlushRecords(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.FlushRecords")
+	defer s.End()
+
+	err := e.s.FlushRecords(ctx)
+	if err != nil {
+		res := NewResponse[store.Record](500, "failed to flush records", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	res := NewResponse[store.Record](200, "flushed records successfully", nil, nil)
+	res.WriteHTTP(ctx, w)
+}
+
+// storeStatus returns the HTTP status code for the error `err` from a change to the
+// records in the store: 403 if the store is read-only (such as a secondary store), or 500
+// otherwise
+func storeStatus(err error) int {
+	if errors.Is(err, store.ErrReadOnly) {
+		return 403
+	}
+	return 500
+}
@@ -0,0 +1,104 @@
+package endpoints
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/zonefile"
+)
+
+const (
+	// zoneFileType is the media type for master zone files (RFC 4027)
+	zoneFileType = "text/dns"
+	// maxZoneFileSize is the maximum size for a zone file in a POST request body
+	maxZoneFileSize = 16 << 20
+)
+
+var (
+	ErrZoneTooLarge = errors.New("zone file is too large")
+)
+
+// ImportZone adds the records in the master zone file in the request's body to the store,
+// completing its relative domain names with the `origin` query parameter (unless the zone
+// file sets its own). `$INCLUDE` directives are not allowed
+func (e *endpoints) ImportZone(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.ImportZone")
+	defer s.End()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		res := NewResponse[[]*store.Record](405, "failed to read zone file", fmt.Errorf("%w: %s", ErrMethodNotAllowed, r.Method), nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxZoneFileSize+1))
+	if err != nil {
+		res := NewResponse[[]*store.Record](400, "failed to read zone file", fmt.Errorf("%w: %v", ErrInvalidBody, err), nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+	if len(buf) > maxZoneFileSize {
+		res := NewResponse[[]*store.Record](413, "failed to read zone file", ErrZoneTooLarge, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	origin := r.URL.Query().Get("origin")
+	s.Add(attr.String("origin", origin), attr.Int("bytes_length", len(buf)))
+
+	records, err := e.s.ImportZone(ctx, origin, bytes.NewReader(buf))
+	if err != nil {
+		status := storeStatus(err)
+		if errors.Is(err, zonefile.ErrParse) || errors.Is(err, service.ErrEmtpyRecord) {
+			status = 400
+		}
+		res := NewResponse[[]*store.Record](status, "failed to import zone", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	res := NewResponse(200, "imported zone successfully", nil, &records)
+	res.WriteHTTP(ctx, w)
+}
+
+// ExportZone replies with the records in the zone in the `zone` query parameter, as a
+// master zone file
+func (e *endpoints) ExportZone(w http.ResponseWriter, r *http.Request) {
+	ctx, s := e.newCtxAndSpan(r, "http.ExportZone")
+	defer s.End()
+
+	zone := r.URL.Query().Get("zone")
+	s.Add(attr.String("zone", zone))
+
+	buf := &bytes.Buffer{}
+	err := e.s.ExportZone(ctx, zone, buf)
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, service.ErrNoName):
+			status = 400
+		case errors.Is(err, service.ErrNoZone):
+			status = 404
+		}
+		res := NewResponse[string](status, "failed to export zone", err, nil)
+		res.WriteHTTP(ctx, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", zoneFileType)
+	w.WriteHeader(http.StatusOK)
+
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		s.Event("failed to write response", attr.String("error", err.Error()))
+		return
+	}
+	s.Event("response written successfully", attr.Int("bytes_written", n))
+}
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -40,7 +41,14 @@ func NewServer(api HTTPAPI, port int) Server {
 	mux.HandleFunc("/records/getDomains", srv.ep.GetRecordByAddress)
 	mux.HandleFunc("/records/update", srv.ep.UpdateRecord)
 	mux.HandleFunc("/records/delete", srv.ep.DeleteRecord)
+	mux.HandleFunc("/records/flush", srv.ep.FlushRecords)
+	mux.HandleFunc("/zones/import", srv.ep.ImportZone)
+	mux.HandleFunc("/zones/export", srv.ep.ExportZone)
+	mux.HandleFunc("/cache", srv.ep.CacheStats)
+	mux.HandleFunc("/cache/flush", srv.ep.FlushCache)
+	mux.HandleFunc("/dnssec/ds", srv.ep.DS)
 	mux.HandleFunc("/health", srv.ep.Health)
+	mux.HandleFunc("/dns-query", srv.ep.DNSQuery)
 
 	return srv
 }
@@ -50,7 +58,7 @@ func (s *server) Start(ctx context.Context) error {
 	defer span.End()
 
 	err := s.srv.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		span.Event("failed to start HTTP server", attr.New("error", err.Error()))
 		return err
 	}
@@ -76,5 +84,17 @@ func (s *server) Stop(ctx context.Context) error {
 		span.Event("failed to stop HTTP server", attr.New("error", err.Error()))
 		return err
 	}
+
+	// the pending changes to the store are written once no more requests are served
+	rw = &responseWriter{}
+	s.ep.FlushRecords(rw, &http.Request{})
+
+	if rw.header != 200 {
+		span.Event("issue flushing the store",
+			attr.Int("status", rw.header),
+			attr.String("response", rw.response),
+		)
+		return fmt.Errorf("failed to flush the store: %s", rw.response)
+	}
 	return nil
 }
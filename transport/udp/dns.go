@@ -1,25 +1,73 @@
 package udp
 
+import (
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
 const (
-	addr   string = ":53"
-	proto  string = "udp"
-	prefix string = "."
+	addr    string = ":53"
+	tlsAddr string = ":853"
+	proto   string = "udp"
+	prefix  string = "."
+
+	// dnsMinSize is the minimum size of a DNS message that must be supported (RFC 1035)
+	dnsMinSize uint16 = 512
 )
 
+// DefaultUDPSize is the default maximum UDP payload size for the DNS server's responses,
+// advertised in its EDNS0 OPT records. It avoids IP fragmentation on most networks
+// (DNS Flag Day 2020)
+const DefaultUDPSize uint16 = 1232
+
 // DNS defines the structure of a DNS server, composed of its
 // address, prefix character, and protocol
+//
+// The protocol may be a comma-separated list of protocols ("udp", "tcp" and "tcp-tls"),
+// to run several listeners at once. The UDP and TCP listeners share the same address, while
+// the DNS-over-TLS ("tcp-tls") listener uses the TLS address, certificate and key
+//
+// UDP responses are limited to the lesser of the UDP payload size and the client's
+// EDNS0 buffer size (or 512 bytes, if the client's query has no EDNS0 OPT record)
+//
+// Zone transfers (AXFR and IXFR) are only allowed to the clients with an IP address in one
+// of the TransferAllow networks, or with a request signed with one of the TSIG keys, which
+// map the keys' names (in canonical form) to their base64-encoded secrets. A TSIG key may
+// be scoped to a set of zones in KeyZones (by the key's name, with the zones in canonical
+// form), limiting its transfers to those zones
+//
+// Dynamic updates (RFC 2136) are only allowed with a request signed with one of the TSIG
+// keys, to the zones the key is scoped to in KeyZones
 type DNS struct {
-	Addr   string
-	Prefix string
-	Proto  string
+	Addr     string
+	Prefix   string
+	Proto    string
+	TLSAddr  string
+	CertFile string
+	KeyFile  string
+	UDPSize  uint16
+
+	TransferAllow []*net.IPNet
+	TSIG          map[string]string
+	KeyZones      map[string][]string
 }
 
 // DNSBuilder is a builder type for DNS, allowing method chaining to
 // set different properties, ended by a .Build() call
 type DNSBuilder struct {
-	addr   string
-	prefix string
-	proto  string
+	addr     string
+	prefix   string
+	proto    string
+	tlsAddr  string
+	certFile string
+	keyFile  string
+	udpSize  uint16
+
+	transferAllow []*net.IPNet
+	tsig          map[string]string
+	keyZones      map[string][]string
 }
 
 // NewDNS returns a new DNSBuilder
@@ -52,11 +100,87 @@ func (b *DNSBuilder) Prefix(s string) *DNSBuilder {
 }
 
 // Proto sets the protocol used for the DNS server (defaults to "udp")
+//
+// It can be a comma-separated list of protocols, such as "udp,tcp"
 func (b *DNSBuilder) Proto(s string) *DNSBuilder {
 	b.proto = s
 	return b
 }
 
+// TLSAddr sets the DNS-over-TLS address as IP:Port (defaults to ":853")
+func (b *DNSBuilder) TLSAddr(s string) *DNSBuilder {
+	b.tlsAddr = s
+	return b
+}
+
+// CertFile sets the path to the TLS certificate used by the DNS-over-TLS listener
+func (b *DNSBuilder) CertFile(s string) *DNSBuilder {
+	b.certFile = s
+	return b
+}
+
+// KeyFile sets the path to the TLS key used by the DNS-over-TLS listener
+func (b *DNSBuilder) KeyFile(s string) *DNSBuilder {
+	b.keyFile = s
+	return b
+}
+
+// UDPSize sets the maximum UDP payload size for the DNS server's responses, in bytes
+// (defaults to 1232). Values under 512 bytes are ignored
+func (b *DNSBuilder) UDPSize(n uint16) *DNSBuilder {
+	b.udpSize = n
+	return b
+}
+
+// TransferAllow adds the IP addresses or networks (in CIDR notation) in `addrs` to the ones
+// allowed to transfer the zones owned by the DNS server. Invalid entries are ignored
+func (b *DNSBuilder) TransferAllow(addrs ...string) *DNSBuilder {
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if _, network, err := net.ParseCIDR(addr); err == nil {
+			b.transferAllow = append(b.transferAllow, network)
+			continue
+		}
+		if ip := net.ParseIP(addr); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			b.transferAllow = append(b.transferAllow, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return b
+}
+
+// TSIG adds a TSIG key (RFC 8945) with the name `name` and the base64-encoded secret
+// `secret`, to verify the signed requests to the DNS server and to sign their replies,
+// scoped to the zones in `zones`
+//
+// Requests signed with the key may transfer the zones in `zones`, and apply dynamic
+// updates to them. A key without zones may transfer any zone owned by the DNS server (such
+// as a key shared with its secondary servers), but not update them
+func (b *DNSBuilder) TSIG(name, secret string, zones ...string) *DNSBuilder {
+	if name == "" || secret == "" {
+		return b
+	}
+	if b.tsig == nil {
+		b.tsig = map[string]string{}
+	}
+	name = dns.CanonicalName(name)
+	b.tsig[name] = secret
+
+	for _, zone := range zones {
+		if zone = strings.TrimSpace(zone); zone == "" {
+			continue
+		}
+		if b.keyZones == nil {
+			b.keyZones = map[string][]string{}
+		}
+		b.keyZones[name] = append(b.keyZones[name], dns.CanonicalName(zone))
+	}
+	return b
+}
+
 // Build will return a DNS based on the defined configuration, with
 // defaults applied where unset
 func (b *DNSBuilder) Build() *DNS {
@@ -69,9 +193,36 @@ func (b *DNSBuilder) Build() *DNS {
 	if b.proto == "" {
 		b.proto = proto
 	}
+	if b.tlsAddr == "" {
+		b.tlsAddr = tlsAddr
+	}
+	if b.udpSize < dnsMinSize {
+		b.udpSize = DefaultUDPSize
+	}
 	return &DNS{
-		Addr:   b.addr,
-		Prefix: b.prefix,
-		Proto:  b.proto,
+		Addr:     b.addr,
+		Prefix:   b.prefix,
+		Proto:    b.proto,
+		TLSAddr:  b.tlsAddr,
+		CertFile: b.certFile,
+		KeyFile:  b.keyFile,
+		UDPSize:  b.udpSize,
+
+		TransferAllow: b.transferAllow,
+		TSIG:          b.tsig,
+		KeyZones:      b.keyZones,
+	}
+}
+
+// Protos returns the list of protocols in the DNS' Proto
+func (d *DNS) Protos() []string {
+	var protos []string
+	for _, p := range strings.Split(d.Proto, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		protos = append(protos, p)
 	}
+	return protos
 }
@@ -0,0 +1,185 @@
+package miekgdns
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	clientCookieLen    = 8
+	serverCookieMinLen = 8
+	serverCookieMaxLen = 32
+	// serverCookieLen is the length of the server cookies issued by the DNS server: a
+	// version, three reserved bytes, a timestamp and an 8-byte hash (RFC 9018)
+	serverCookieLen = 16
+	cookieVersion   = 1
+
+	// cookieMaxAge is the age after which a server cookie is no longer valid
+	cookieMaxAge = time.Hour
+	// cookieMaxSkew is how far in the future a server cookie's timestamp can be, to
+	// allow for clock differences between servers sharing the same secret
+	cookieMaxSkew = 5 * time.Minute
+	// cookieRefresh is the age after which a valid server cookie is replaced with a new one
+	cookieRefresh = 30 * time.Minute
+)
+
+// cookieStatus is the outcome of checking the DNS cookie in a query (RFC 7873)
+type cookieStatus uint8
+
+const (
+	// cookieNone means the query has no DNS cookie
+	cookieNone cookieStatus = iota
+	// cookieMalformed means the query's DNS cookie has an invalid length
+	cookieMalformed
+	// cookieClientOnly means the query only has a client cookie
+	cookieClientOnly
+	// cookieInvalid means the query has a server cookie which was not issued by this
+	// server to this client, or which has expired
+	cookieInvalid
+	// cookieValid means the query has a valid server cookie
+	cookieValid
+)
+
+// String implements the fmt.Stringer interface
+func (c cookieStatus) String() string {
+	switch c {
+	case cookieMalformed:
+		return "malformed"
+	case cookieClientOnly:
+		return "client-only"
+	case cookieInvalid:
+		return "invalid"
+	case cookieValid:
+		return "valid"
+	default:
+		return "none"
+	}
+}
+
+// cookies issues and validates the server cookies sent to DNS clients (RFC 7873), in
+// the format described in RFC 9018
+//
+// The cookies' hash is an HMAC-SHA256 of the client cookie, the server cookie's version,
+// reserved bytes and timestamp, and the client's IP address; truncated to 8 bytes. The
+// secret is random, and generated when the DNS server is created
+type cookies struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func newCookies() *cookies {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+
+	return &cookies{
+		secret: secret,
+		now:    time.Now,
+	}
+}
+
+// cookieFrom returns the DNS cookie option in the query `r`, if any
+func cookieFrom(r *dns.Msg) *dns.EDNS0_COOKIE {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, option := range opt.Option {
+		if cookie, ok := option.(*dns.EDNS0_COOKIE); ok {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// check validates the DNS cookie in the query `r`, sent from the IP address `ip`
+func (c *cookies) check(r *dns.Msg, ip net.IP) cookieStatus {
+	option := cookieFrom(r)
+	if option == nil {
+		return cookieNone
+	}
+
+	cookie, err := hex.DecodeString(option.Cookie)
+	if err != nil {
+		return cookieMalformed
+	}
+
+	switch n := len(cookie) - clientCookieLen; {
+	case n == 0:
+		return cookieClientOnly
+	case n < serverCookieMinLen || n > serverCookieMaxLen:
+		return cookieMalformed
+	case n != serverCookieLen || cookie[clientCookieLen] != cookieVersion:
+		return cookieInvalid
+	}
+
+	ts := time.Unix(int64(binary.BigEndian.Uint32(cookie[clientCookieLen+4:])), 0)
+	now := c.now()
+	if ts.Before(now.Add(-cookieMaxAge)) || ts.After(now.Add(cookieMaxSkew)) {
+		return cookieInvalid
+	}
+
+	if !hmac.Equal(cookie[clientCookieLen:], c.serverCookie(cookie[:clientCookieLen], ip, ts)) {
+		return cookieInvalid
+	}
+	return cookieValid
+}
+
+// set adds a DNS cookie option to the reply `m` to the query `r` (sent from the IP address
+// `ip`), with the query's client cookie and a server cookie. Valid server cookies are kept
+// until they are due for a refresh
+func (c *cookies) set(r, m *dns.Msg, ip net.IP, status cookieStatus) {
+	opt := m.IsEdns0()
+	option := cookieFrom(r)
+	if opt == nil || option == nil {
+		return
+	}
+
+	cookie, err := hex.DecodeString(option.Cookie)
+	if err != nil || len(cookie) < clientCookieLen {
+		return
+	}
+
+	if status == cookieValid {
+		ts := time.Unix(int64(binary.BigEndian.Uint32(cookie[clientCookieLen+4:])), 0)
+		if c.now().Sub(ts) < cookieRefresh {
+			opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{
+				Code:   dns.EDNS0COOKIE,
+				Cookie: option.Cookie,
+			})
+			return
+		}
+	}
+
+	client := cookie[:clientCookieLen]
+	opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{
+		Code:   dns.EDNS0COOKIE,
+		Cookie: hex.EncodeToString(client) + hex.EncodeToString(c.serverCookie(client, ip, c.now())),
+	})
+}
+
+// serverCookie returns the server cookie for the client cookie `client` and IP address
+// `ip`, with the timestamp `ts`
+func (c *cookies) serverCookie(client []byte, ip net.IP, ts time.Time) []byte {
+	cookie := make([]byte, 8, serverCookieLen)
+	cookie[0] = cookieVersion
+	binary.BigEndian.PutUint32(cookie[4:], uint32(ts.Unix()))
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(client)
+	h.Write(cookie)
+	h.Write(ip)
+	return h.Sum(cookie)[:serverCookieLen]
+}
@@ -11,8 +11,10 @@ type udps struct {
 	on   bool
 	ans  service.Answering
 	conf *udp.DNS
-	srv  *dns.Server
+	srvs []*dns.Server
 	err  error
+
+	cookies *cookies
 }
 
 // NewServer returns a github.com/miekg/dns implementation of udp.Server
@@ -25,7 +27,8 @@ func NewServer(conf *udp.DNS, s service.Answering) udp.Server {
 		conf = udp.NewDNS().Build()
 	}
 	return &udps{
-		conf: conf,
-		ans:  s,
+		conf:    conf,
+		ans:     s,
+		cookies: newCookies(),
 	}
 }
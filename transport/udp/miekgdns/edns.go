@@ -0,0 +1,105 @@
+package miekgdns
+
+import (
+	"github.com/miekg/dns"
+)
+
+// paddingBlock is the block size that responses over encrypted transports are padded to,
+// as recommended in RFC 8467
+const paddingBlock = 468
+
+// setEDNS0 adds an EDNS0 OPT record to the reply `m` if the query `r` has one, advertising
+// the UDP payload size `udpSize` and echoing the query's DO bit
+//
+// The query's Client Subnet option is echoed (RFC 7871) with the scope of the fallback
+// DNS's answer, if it was forwarded and the reply carries the fallback DNS's option; or
+// with a scope of zero otherwise, as the answers do not vary with the client's network
+func setEDNS0(r, m *dns.Msg, udpSize uint16) {
+	scope := upstreamScope(m)
+
+	opt := r.IsEdns0()
+	if opt == nil {
+		return
+	}
+
+	o := &dns.OPT{
+		Hdr: dns.RR_Header{
+			Name:   ".",
+			Rrtype: dns.TypeOPT,
+		},
+	}
+	o.SetUDPSize(udpSize)
+	o.SetDo(opt.Do())
+
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			o.Option = append(o.Option, &dns.EDNS0_SUBNET{
+				Code:          dns.EDNS0SUBNET,
+				Family:        subnet.Family,
+				SourceNetmask: subnet.SourceNetmask,
+				SourceScope:   scope,
+				Address:       subnet.Address,
+			})
+		}
+	}
+
+	m.Extra = append(m.Extra, o)
+}
+
+// upstreamScope removes the OPT records carrying the fallback DNS's Client Subnet option
+// from the reply `m`, returning the (most specific) scope in them
+func upstreamScope(m *dns.Msg) uint8 {
+	var (
+		scope uint8
+		extra = m.Extra[:0]
+	)
+	for _, rr := range m.Extra {
+		opt, ok := rr.(*dns.OPT)
+		if !ok {
+			extra = append(extra, rr)
+			continue
+		}
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok && subnet.SourceScope > scope {
+				scope = subnet.SourceScope
+			}
+		}
+	}
+	m.Extra = extra
+	return scope
+}
+
+// payloadSize returns the maximum size for the UDP response to the query `r`, which is the
+// lesser of the client's EDNS0 buffer size and the server's UDP payload size `udpSize`,
+// and at least 512 bytes (RFC 6891)
+func payloadSize(r *dns.Msg, udpSize uint16) int {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return dns.MinMsgSize
+	}
+
+	size := opt.UDPSize()
+	if size > udpSize {
+		size = udpSize
+	}
+	if size < dns.MinMsgSize {
+		size = dns.MinMsgSize
+	}
+	return int(size)
+}
+
+// Pad adds an EDNS0 Padding option (RFC 7830) to the reply `m`, if it has an OPT record,
+// so that its length is a multiple of 468 bytes (RFC 8467). It hides the size of the
+// responses sent over encrypted transports, such as DNS-over-TLS and DNS-over-HTTPS
+func Pad(m *dns.Msg) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return
+	}
+
+	// the padding option's code and length take 4 bytes
+	size := m.Len() + 4
+	opt.Option = append(opt.Option, &dns.EDNS0_PADDING{
+		Padding: make([]byte, (paddingBlock-size%paddingBlock)%paddingBlock),
+	})
+}
@@ -2,10 +2,14 @@ package miekgdns
 
 import (
 	"context"
+	"net"
 
 	"github.com/miekg/dns"
 	"github.com/zalgonoise/attr"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/service"
 	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/transport/udp"
 	"github.com/zalgonoise/spanner"
 )
 
@@ -13,16 +17,61 @@ func (u *udps) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	ctx, s := u.newCtxAndSpan(w, "udp.handleRequest")
 	defer s.End()
 
-	m := new(dns.Msg)
-	m.SetReply(r)
-	m.Compress = false
+	var (
+		ip    net.IP
+		isUDP bool
+	)
+	switch addr := w.RemoteAddr().(type) {
+	case *net.UDPAddr:
+		ip, isUDP = addr.IP, true
+	case *net.TCPAddr:
+		ip = addr.IP
+	}
+
+	switch {
+	case isTransfer(r):
+		u.transfer(ctx, w, r, ip, isUDP)
+		return
+	case isNotify(r):
+		u.notify(ctx, w, r)
+		return
+	case isUpdate(r):
+		u.update(ctx, w, r)
+		return
+	}
+
+	// queries with a malformed DNS cookie are rejected; and so are UDP queries with an
+	// invalid server cookie, which are replied to with a new one for the client to retry
+	// (RFC 7873). Queries over TCP are not spoofed, so they are answered regardless
+	cookie := u.cookies.check(r, ip)
+	if cookie != cookieNone {
+		s.Add(attr.String("cookie", cookie.String()))
+	}
 
-	switch r.Opcode {
-	case dns.OpcodeQuery:
-		u.parseQuery(ctx, m)
-		if u.err != nil {
-			return
+	var m *dns.Msg
+	switch {
+	case cookie == cookieMalformed:
+		m = errorReply(r, dns.RcodeFormatError, u.conf.UDPSize)
+	case cookie == cookieInvalid && isUDP:
+		m = errorReply(r, dns.RcodeBadCookie, u.conf.UDPSize)
+	default:
+		m = reply(ctx, u.ans, u.conf.Prefix, r, u.conf.UDPSize)
+	}
+	if cookie != cookieMalformed {
+		u.cookies.set(r, m, ip, cookie)
+	}
+
+	switch {
+	case isUDP:
+		// UDP responses larger than the negotiated payload size are truncated, with the TC
+		// bit set, so that the client retries the query over TCP
+		size := payloadSize(r, u.conf.UDPSize)
+		m.Truncate(size)
+		if m.Truncated {
+			s.Event("truncated UDP response", attr.Int("size", size))
 		}
+	case isTLS(w):
+		Pad(m)
 	}
 
 	err := w.WriteMsg(m)
@@ -32,44 +81,96 @@ func (u *udps) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
-func (u *udps) parseQuery(ctx context.Context, m *dns.Msg) {
+// isTLS returns true if the dns.ResponseWriter `w` writes to a DNS-over-TLS connection
+func isTLS(w dns.ResponseWriter) bool {
+	cs, ok := w.(dns.ConnectionStater)
+	return ok && cs.ConnectionState() != nil
+}
+
+// errorReply builds a reply to the DNS message `r` with the response code `rcode`,
+// without answering its questions
+func errorReply(r *dns.Msg, rcode int, udpSize uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	setEDNS0(r, m, udpSize)
+	return m
+}
+
+// Reply builds the reply to the DNS message `r`, answering its questions with the
+// service.Answering `ans`. The string `prefix` is the DNS prefix character trimmed from
+// the end of the questions' domain names before looking them up
+//
+// The reply's response code reflects the outcome of the first question which could not
+// be answered; messages with opcodes other than QUERY are replied to with NOTIMP. The
+// DNS message `r` is carried in the context, so its flags and EDNS0 options can be
+// forwarded to the fallback DNS
+//
+// If the DNS message `r` has an EDNS0 OPT record, so does the reply; and messages with
+// an EDNS version other than zero are replied to with BADVERS (RFC 6891)
+//
+// It is used by the DNS server as well as by other transports answering DNS messages,
+// such as DNS-over-HTTPS
+func Reply(ctx context.Context, ans service.Answering, prefix string, r *dns.Msg) *dns.Msg {
+	return reply(ctx, ans, prefix, r, udp.DefaultUDPSize)
+}
+
+func reply(ctx context.Context, ans service.Answering, prefix string, r *dns.Msg, udpSize uint16) *dns.Msg {
+	ctx, s := spanner.Start(dnsrepo.WithQuery(ctx, r), "udp.Reply")
+	defer s.End()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Compress = false
+
+	switch opt := r.IsEdns0(); {
+	case opt != nil && opt.Version() != 0:
+		s.Event("unsupported EDNS version", attr.Int("version", int(opt.Version())))
+		m.Rcode = dns.RcodeBadVers
+	case r.Opcode == dns.OpcodeQuery:
+		parseQuery(ctx, ans, prefix, m)
+	default:
+		s.Event("unsupported opcode", attr.String("opcode", dns.OpcodeToString[r.Opcode]))
+		m.Rcode = dns.RcodeNotImplemented
+	}
+
+	setEDNS0(r, m, udpSize)
+	return m
+}
+
+func parseQuery(ctx context.Context, ans service.Answering, prefix string, m *dns.Msg) {
 	ctx, s := spanner.Start(ctx, "udp.parseQuery")
 	defer s.End()
 
 	for _, question := range m.Question {
 		s.Event("answering question", attr.String("domain", question.Name))
 
-		r := store.New().Name(question.Name)
-		switch question.Qtype {
-		case dns.TypeA:
-			u.answer(
-				ctx,
-				r.Type(store.TypeA.String()).Build(),
-				m,
-			)
-		case dns.TypeAAAA:
-			u.answer(
-				ctx,
-				r.Type(store.TypeAAAA.String()).Build(),
-				m,
-			)
-		case dns.TypeCNAME:
-			u.answer(
-				ctx,
-				r.Type(store.TypeCNAME.String()).Build(),
-				m,
-			)
-		case dns.TypeANY:
-			u.answer(
-				ctx,
-				r.Build(),
-				m,
+		rtype, ok := store.RecordTypeStrings[store.RecordType(question.Qtype)]
+		if !ok || rtype == "" {
+			// types unknown to the store can still be answered by the fallback DNS
+			rtype = dns.Type(question.Qtype).String()
+		}
+
+		err := answer(
+			ctx,
+			ans,
+			prefix,
+			store.New().Name(question.Name).Type(rtype).Build(),
+			m,
+		)
+		if err != nil {
+			s.Event("failed to answer question",
+				attr.String("domain", question.Name),
+				attr.String("error", err.Error()),
 			)
+			// keep the response code of the first failed question
+			if m.Rcode == dns.RcodeSuccess {
+				m.Rcode = dnsrepo.Rcode(err)
+			}
 		}
 	}
 }
 
-func (u *udps) answer(ctx context.Context, r *store.Record, m *dns.Msg) {
+func answer(ctx context.Context, ans service.Answering, prefix string, r *store.Record, m *dns.Msg) error {
 	ctx, s := spanner.Start(ctx, "udp.answer")
 	defer s.End()
 	s.Add(
@@ -78,11 +179,11 @@ func (u *udps) answer(ctx context.Context, r *store.Record, m *dns.Msg) {
 	)
 
 	name := r.Name
-	if r.Name[len(r.Name)-1] == u.conf.Prefix[0] {
+	if prefix != "" && len(r.Name) > 0 && r.Name[len(r.Name)-1] == prefix[0] {
 		name = r.Name[:len(r.Name)-1]
 	}
 
-	u.ans.AnswerDNS(
+	return ans.AnswerDNS(
 		ctx,
 		store.New().Name(name).Type(r.Type).Build(),
 		m,
@@ -0,0 +1,76 @@
+package miekgdns
+
+import (
+	"context"
+	"errors"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/spanner"
+)
+
+// isNotify returns true if the DNS message `r` is a NOTIFY message for a zone (RFC 1996)
+func isNotify(r *dns.Msg) bool {
+	return r.Opcode == dns.OpcodeNotify &&
+		!r.Response &&
+		len(r.Question) == 1 &&
+		r.Question[0].Qtype == dns.TypeSOA
+}
+
+// notify replies to the NOTIFY message `r`, signaling the change to the zone in its
+// question to the service.TransferService, so that it is refreshed from its primary DNS
+// server right away
+//
+// NOTIFY messages are accepted from any IP address, as they only trigger a check of the
+// zone's serial in its primary DNS server; but the ones with an invalid TSIG signature are
+// replied to with NOTAUTH, and so are the ones for zones which are not held by a secondary
+// store
+func (u *udps) notify(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+	ctx, s := spanner.Start(ctx, "udp.notify")
+	defer s.End()
+
+	q := r.Question[0]
+	s.Add(attr.String("zone", q.Name))
+
+	xfr, ok := u.ans.(service.TransferService)
+	if !ok {
+		u.writeTransferError(ctx, w, r, dns.RcodeNotImplemented)
+		return
+	}
+
+	if r.IsTsig() != nil && (len(u.conf.TSIG) == 0 || w.TsigStatus() != nil) {
+		s.Event("invalid TSIG signature")
+		u.writeTransferError(ctx, w, r, dns.RcodeNotAuth)
+		return
+	}
+
+	// the NOTIFY message may hold the zone's new SOA record, as a hint of its serial
+	var serial uint32
+	for _, rr := range r.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			serial = soa.Serial
+			s.Add(attr.Int("serial", int(serial)))
+		}
+	}
+
+	if err := xfr.NotifyZone(ctx, q.Name, serial); err != nil {
+		s.Event("failed to notify zone change", attr.String("error", err.Error()))
+		rcode := dns.RcodeServerFailure
+		if errors.Is(err, service.ErrNoZone) {
+			rcode = dns.RcodeNotAuth
+		}
+		u.writeTransferError(ctx, w, r, rcode)
+		return
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	signReply(w, r, m)
+
+	if err := w.WriteMsg(m); err != nil {
+		s.Event("error answering NOTIFY message", attr.String("error", err.Error()))
+		u.err = err
+	}
+}
@@ -0,0 +1,94 @@
+package miekgdns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/health/simplehealth"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/store/secondary"
+	"github.com/zalgonoise/dns/transport/udp"
+)
+
+// notifyServer starts a DNS server over UDP for a service with the store.Repository `r`,
+// with the test TSIG key
+func notifyServer(t *testing.T, r store.Repository) string {
+	ctx := context.Background()
+
+	svc := service.New(core.New(), r, simplehealth.New(), config.Default())
+
+	addr := freeAddr(t)
+	srv := NewServer(udp.NewDNS().Addr(addr).Proto("udp").TSIG(testKey, testSecret).Build(), svc)
+	go func() {
+		_ = srv.Start(ctx)
+	}()
+	t.Cleanup(func() { srv.Stop(ctx) })
+
+	for i := 0; i < 50 && !srv.Running(ctx); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr
+}
+
+func TestNotify(t *testing.T) {
+	// the secondary store has no primary DNS server, so it is never refreshed
+	sec := secondary.New("", "corp.lan")
+	t.Cleanup(func() { sec.(*secondary.SecondaryStore).Close() })
+
+	notify := func(t *testing.T, addr, zone string, signed bool) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetNotify(zone)
+		client := new(dns.Client)
+		if signed {
+			client.TsigSecret = map[string]string{testKey: testSecret}
+			m.SetTsig(testKey, dns.HmacSHA256, 300, time.Now().Unix())
+		}
+
+		r, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return r
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		r := notify(t, notifyServer(t, sec), "corp.lan.", false)
+		if r.Rcode != dns.RcodeSuccess || r.Opcode != dns.OpcodeNotify || !r.Authoritative {
+			t.Errorf("unexpected reply: %v", r)
+		}
+	})
+
+	t.Run("SuccessWithTSIG", func(t *testing.T) {
+		r := notify(t, notifyServer(t, sec), "corp.lan.", true)
+		if r.Rcode != dns.RcodeSuccess || r.IsTsig() == nil {
+			t.Errorf("unexpected reply: %v", r)
+		}
+	})
+
+	t.Run("FailNotSecondaryZone", func(t *testing.T) {
+		r := notify(t, notifyServer(t, sec), "example.com.", false)
+		if r.Rcode != dns.RcodeNotAuth {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeNotAuth], dns.RcodeToString[r.Rcode])
+		}
+	})
+
+	t.Run("SuccessWithTracedStore", func(t *testing.T) {
+		r := notify(t, notifyServer(t, store.WithTrace(sec)), "corp.lan.", false)
+		if r.Rcode != dns.RcodeSuccess {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeSuccess], dns.RcodeToString[r.Rcode])
+		}
+	})
+
+	t.Run("FailNotSecondaryStore", func(t *testing.T) {
+		r := notify(t, notifyServer(t, memmap.New()), "corp.lan.", false)
+		if r.Rcode != dns.RcodeNotAuth {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeNotAuth], dns.RcodeToString[r.Rcode])
+		}
+	})
+}
@@ -2,6 +2,9 @@ package miekgdns
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
 
 	"github.com/miekg/dns"
 	"github.com/zalgonoise/attr"
@@ -10,6 +13,9 @@ import (
 )
 
 // Start launches the DNS server, returning an error
+//
+// A listener is opened for each of the configured protocols before any of them starts
+// serving, and Start blocks until all of them are stopped
 func (u *udps) Start(ctx context.Context) error {
 	_, s := spanner.Start(ctx, "udp.Start")
 	defer s.End()
@@ -19,19 +25,29 @@ func (u *udps) Start(ctx context.Context) error {
 		return udp.ErrAlreadyRunning
 	}
 
-	dns.HandleFunc(u.conf.Prefix, u.handleRequest)
-	u.srv = &dns.Server{
-		Addr: u.conf.Addr,
-		Net:  u.conf.Proto,
-	}
-	u.on = true
-
-	err := u.srv.ListenAndServe()
+	servers, err := u.newServers()
 	if err != nil {
 		s.Event("failed to start UDP server", attr.String("error", err.Error()))
 		return err
 	}
-	return nil
+	u.srvs = servers
+	u.on = true
+
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *dns.Server) {
+			errs <- srv.ActivateAndServe()
+		}(srv)
+	}
+
+	var serveErr error
+	for range servers {
+		if err := <-errs; err != nil && serveErr == nil {
+			s.Event("DNS listener failed", attr.String("error", err.Error()))
+			serveErr = err
+		}
+	}
+	return serveErr
 }
 
 // Stop gracefully stops the DNS server, returning an error
@@ -45,9 +61,11 @@ func (u *udps) Stop(ctx context.Context) error {
 	}
 
 	u.on = false
-	err := u.srv.Shutdown()
-	if err != nil {
-		s.Event("failed to stop UDP server", attr.String("error", err.Error()))
+	for _, srv := range u.srvs {
+		err := srv.Shutdown()
+		if err != nil {
+			s.Event("failed to stop UDP server", attr.String("net", srv.Net), attr.String("error", err.Error()))
+		}
 	}
 	return nil
 }
@@ -60,3 +78,74 @@ func (u *udps) Running(ctx context.Context) bool {
 
 	return u.on
 }
+
+// newServers opens a listener for each of the protocols in the configuration, returning
+// a dns.Server for each of them, all sharing the same handler
+//
+// If any of the listeners cannot be opened, the ones already opened are closed
+func (u *udps) newServers() (servers []*dns.Server, err error) {
+	mux := dns.NewServeMux()
+	mux.HandleFunc(u.conf.Prefix, u.handleRequest)
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, srv := range servers {
+			if srv.PacketConn != nil {
+				_ = srv.PacketConn.Close()
+			}
+			if srv.Listener != nil {
+				_ = srv.Listener.Close()
+			}
+		}
+		servers = nil
+	}()
+
+	for _, proto := range u.conf.Protos() {
+		srv := &dns.Server{
+			Net:           proto,
+			Handler:       mux,
+			TsigSecret:    u.conf.TSIG,
+			MsgAcceptFunc: acceptMsg,
+		}
+
+		switch proto {
+		case "udp":
+			srv.Addr = u.conf.Addr
+			srv.PacketConn, err = net.ListenPacket(proto, srv.Addr)
+		case "tcp":
+			srv.Addr = u.conf.Addr
+			srv.Listener, err = net.Listen(proto, srv.Addr)
+		case "tcp-tls":
+			if u.conf.CertFile == "" || u.conf.KeyFile == "" {
+				return servers, udp.ErrNoCertificate
+			}
+
+			var cert tls.Certificate
+			cert, err = tls.LoadX509KeyPair(u.conf.CertFile, u.conf.KeyFile)
+			if err != nil {
+				return servers, fmt.Errorf("failed to load TLS certificate: %w", err)
+			}
+
+			srv.Addr = u.conf.TLSAddr
+			srv.TLSConfig = &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			}
+			srv.Listener, err = tls.Listen("tcp", srv.Addr, srv.TLSConfig)
+		default:
+			return servers, fmt.Errorf("%w: %q", udp.ErrInvalidProto, proto)
+		}
+
+		if err != nil {
+			return servers, err
+		}
+		servers = append(servers, srv)
+	}
+
+	if len(servers) == 0 {
+		return servers, fmt.Errorf("%w: %q", udp.ErrInvalidProto, u.conf.Proto)
+	}
+	return servers, nil
+}
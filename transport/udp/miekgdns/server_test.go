@@ -0,0 +1,439 @@
+package miekgdns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/transport/udp"
+)
+
+const testAnswers = 60
+
+// largeAnswer is a service.Answering replying to any question with a large
+// number of A records
+type largeAnswer struct{}
+
+func (largeAnswer) GetRecordByTypeAndDomain(context.Context, string, string) ([]*store.Record, error) {
+	return nil, nil
+}
+
+func (largeAnswer) AnswerDNS(_ context.Context, r *store.Record, m *dns.Msg) error {
+	for i := 0; i < testAnswers; i++ {
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: dns.Fqdn(r.Name), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.IPv4(10, 0, byte(i/256), byte(i%256)),
+		})
+	}
+	return nil
+}
+
+// scopedAnswer is a service.Answering replying to any question as the fallback DNS does
+// when it tailors its answer to the client subnet, carrying its scope in an OPT record
+type scopedAnswer struct{}
+
+func (scopedAnswer) GetRecordByTypeAndDomain(context.Context, string, string) ([]*store.Record, error) {
+	return nil, nil
+}
+
+func (scopedAnswer) AnswerDNS(_ context.Context, r *store.Record, m *dns.Msg) error {
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(r.Name), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.IPv4(10, 0, 0, 1),
+	})
+	m.Extra = append(m.Extra, &dns.OPT{
+		Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT},
+		Option: []dns.EDNS0{&dns.EDNS0_SUBNET{
+			Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("192.0.2.0").To4(),
+		}},
+	})
+	return nil
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("UDPAndTCP", func(t *testing.T) {
+		addr := freeAddr(t)
+		srv := NewServer(udp.NewDNS().Addr(addr).Proto("udp,tcp").Build(), largeAnswer{})
+
+		go func() {
+			if err := srv.Start(ctx); err != nil {
+				t.Errorf("unexpected error starting server: %v", err)
+			}
+		}()
+		defer srv.Stop(ctx)
+
+		for i := 0; i < 50 && !srv.Running(ctx); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		query := new(dns.Msg)
+		query.SetQuestion("many.lan.", dns.TypeA)
+
+		in, err := dns.Exchange(query, addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		if !in.Truncated {
+			t.Errorf("expected a truncated UDP response")
+		}
+		if len(in.Answer) >= testAnswers {
+			t.Errorf("unexpected answer length: wanted <%v ; got %v", testAnswers, len(in.Answer))
+		}
+
+		client := &dns.Client{Net: "tcp"}
+		in, _, err = client.Exchange(query, addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over TCP: %v", err)
+			return
+		}
+		if in.Truncated {
+			t.Errorf("unexpected truncated TCP response")
+		}
+		if len(in.Answer) != testAnswers {
+			t.Errorf("unexpected answer length: wanted %v ; got %v", testAnswers, len(in.Answer))
+		}
+	})
+
+	t.Run("UDPWithEDNS0BufferSize", func(t *testing.T) {
+		addr := freeAddr(t)
+		srv := NewServer(udp.NewDNS().Addr(addr).Proto("udp").UDPSize(4096).Build(), largeAnswer{})
+
+		go func() {
+			_ = srv.Start(ctx)
+		}()
+		defer srv.Stop(ctx)
+
+		for i := 0; i < 50 && !srv.Running(ctx); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		query := new(dns.Msg)
+		query.SetQuestion("many.lan.", dns.TypeA)
+		query.SetEdns0(4096, false)
+
+		in, err := dns.Exchange(query, addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		if in.Truncated {
+			t.Errorf("unexpected truncated UDP response")
+		}
+		if len(in.Answer) != testAnswers {
+			t.Errorf("unexpected answer length: wanted %v ; got %v", testAnswers, len(in.Answer))
+		}
+	})
+
+	t.Run("FailTLSWithoutCertificate", func(t *testing.T) {
+		srv := NewServer(udp.NewDNS().Addr(freeAddr(t)).Proto("tcp-tls").Build(), largeAnswer{})
+
+		err := srv.Start(ctx)
+		if !errors.Is(err, udp.ErrNoCertificate) {
+			t.Errorf("unexpected error: wanted %v ; got %v", udp.ErrNoCertificate, err)
+		}
+		if srv.Running(ctx) {
+			t.Errorf("server should not be running")
+		}
+	})
+
+	t.Run("FailInvalidProto", func(t *testing.T) {
+		srv := NewServer(udp.NewDNS().Addr(freeAddr(t)).Proto("sctp").Build(), largeAnswer{})
+
+		err := srv.Start(ctx)
+		if !errors.Is(err, udp.ErrInvalidProto) {
+			t.Errorf("unexpected error: wanted %v ; got %v", udp.ErrInvalidProto, err)
+		}
+	})
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its key to
+// a temporary directory, returning the paths to both files
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error creating certificate: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600); err != nil {
+		t.Fatalf("unexpected error writing certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("unexpected error writing key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func cookieOf(m *dns.Msg) string {
+	if c := cookieFrom(m); c != nil {
+		return c.Cookie
+	}
+	return ""
+}
+
+func TestEDNS0(t *testing.T) {
+	ctx := context.Background()
+	addr := freeAddr(t)
+	srv := NewServer(udp.NewDNS().Addr(addr).Proto("udp,tcp").Build(), largeAnswer{})
+
+	go func() {
+		_ = srv.Start(ctx)
+	}()
+	defer srv.Stop(ctx)
+
+	for i := 0; i < 50 && !srv.Running(ctx); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	newQuery := func(options ...dns.EDNS0) *dns.Msg {
+		query := new(dns.Msg)
+		query.SetQuestion("many.lan.", dns.TypeA)
+		query.SetEdns0(4096, true)
+		query.IsEdns0().Option = options
+		return query
+	}
+	clientCookie := "0102030405060708"
+
+	t.Run("AdvertiseUDPSize", func(t *testing.T) {
+		in, err := dns.Exchange(newQuery(), addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		opt := in.IsEdns0()
+		if opt == nil || opt.UDPSize() != udp.DefaultUDPSize || !opt.Do() {
+			t.Errorf("unexpected OPT record: wanted UDP size %v and the DO bit ; got %v", udp.DefaultUDPSize, opt)
+		}
+	})
+
+	t.Run("CapToServerUDPSize", func(t *testing.T) {
+		addr := freeAddr(t)
+		srv := NewServer(udp.NewDNS().Addr(addr).Proto("udp").UDPSize(512).Build(), largeAnswer{})
+
+		go func() {
+			_ = srv.Start(ctx)
+		}()
+		defer srv.Stop(ctx)
+
+		for i := 0; i < 50 && !srv.Running(ctx); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		in, err := dns.Exchange(newQuery(), addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		if !in.Truncated {
+			t.Errorf("expected the response to be truncated to the server's UDP payload size")
+		}
+		if opt := in.IsEdns0(); opt == nil || opt.UDPSize() != 512 {
+			t.Errorf("unexpected OPT record: wanted UDP size %v ; got %v", 512, opt)
+		}
+	})
+
+	t.Run("NoOPTWithoutEDNS0", func(t *testing.T) {
+		query := new(dns.Msg)
+		query.SetQuestion("many.lan.", dns.TypeA)
+
+		in, err := dns.Exchange(query, addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		if opt := in.IsEdns0(); opt != nil {
+			t.Errorf("unexpected OPT record: %v", opt)
+		}
+	})
+
+	t.Run("BadVersion", func(t *testing.T) {
+		query := newQuery()
+		query.IsEdns0().SetVersion(1)
+
+		in, err := dns.Exchange(query, addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeBadVers || len(in.Answer) != 0 {
+			t.Errorf("unexpected response: wanted %v without answers ; got %v", dns.RcodeToString[dns.RcodeBadVers], in)
+		}
+	})
+
+	t.Run("ClientSubnet", func(t *testing.T) {
+		in, err := dns.Exchange(newQuery(&dns.EDNS0_SUBNET{
+			Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0").To4(),
+		}), addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+
+		var subnet *dns.EDNS0_SUBNET
+		if opt := in.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+			subnet, _ = opt.Option[0].(*dns.EDNS0_SUBNET)
+		}
+		if subnet == nil || subnet.SourceNetmask != 24 || subnet.SourceScope != 0 {
+			t.Errorf("expected the client subnet to be echoed with a zero scope; got %v", subnet)
+		}
+	})
+
+	t.Run("ClientSubnetUpstreamScope", func(t *testing.T) {
+		in := Reply(ctx, scopedAnswer{}, ".", newQuery(&dns.EDNS0_SUBNET{
+			Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4(),
+		}))
+
+		var opts int
+		for _, rr := range in.Extra {
+			if _, ok := rr.(*dns.OPT); ok {
+				opts++
+			}
+		}
+		var subnet *dns.EDNS0_SUBNET
+		if opt := in.IsEdns0(); opt != nil && len(opt.Option) == 1 {
+			subnet, _ = opt.Option[0].(*dns.EDNS0_SUBNET)
+		}
+		if opts != 1 || subnet == nil || subnet.SourceScope != 16 {
+			t.Errorf("expected a single OPT record echoing the fallback DNS's scope; got %v", in.Extra)
+		}
+	})
+
+	t.Run("Cookies", func(t *testing.T) {
+		in, err := dns.Exchange(newQuery(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: clientCookie}), addr)
+		if err != nil {
+			t.Errorf("unexpected error querying over UDP: %v", err)
+			return
+		}
+		cookie := cookieOf(in)
+		if len(cookie) != 2*(clientCookieLen+serverCookieLen) || cookie[:16] != clientCookie {
+			t.Errorf("expected the client cookie to be echoed with a server cookie; got %q", cookie)
+			return
+		}
+
+		t.Run("Valid", func(t *testing.T) {
+			in, err := dns.Exchange(newQuery(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie}), addr)
+			if err != nil {
+				t.Errorf("unexpected error querying over UDP: %v", err)
+				return
+			}
+			if in.Rcode != dns.RcodeSuccess || len(in.Answer) == 0 {
+				t.Errorf("unexpected response: %v", in)
+			}
+			if c := cookieOf(in); c != cookie {
+				t.Errorf("unexpected cookie: wanted %q ; got %q", cookie, c)
+			}
+		})
+
+		invalid := cookie[:len(cookie)-2] + "00"
+		if invalid == cookie {
+			invalid = cookie[:len(cookie)-2] + "ff"
+		}
+
+		t.Run("InvalidOverUDP", func(t *testing.T) {
+			in, err := dns.Exchange(newQuery(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: invalid}), addr)
+			if err != nil {
+				t.Errorf("unexpected error querying over UDP: %v", err)
+				return
+			}
+			if in.Rcode != dns.RcodeBadCookie || len(in.Answer) != 0 {
+				t.Errorf("unexpected response: wanted %v without answers ; got %v", dns.RcodeToString[dns.RcodeBadCookie], in)
+			}
+			if c := cookieOf(in); c == invalid || len(c) != len(cookie) || c[:16] != clientCookie {
+				t.Errorf("expected a new server cookie; got %q", c)
+			}
+		})
+
+		t.Run("InvalidOverTCP", func(t *testing.T) {
+			client := &dns.Client{Net: "tcp"}
+			in, _, err := client.Exchange(newQuery(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: invalid}), addr)
+			if err != nil {
+				t.Errorf("unexpected error querying over TCP: %v", err)
+				return
+			}
+			if in.Rcode != dns.RcodeSuccess || len(in.Answer) != testAnswers {
+				t.Errorf("unexpected response: %v", in)
+			}
+		})
+
+		t.Run("Malformed", func(t *testing.T) {
+			in, err := dns.Exchange(newQuery(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: clientCookie + "0102"}), addr)
+			if err != nil {
+				t.Errorf("unexpected error querying over UDP: %v", err)
+				return
+			}
+			if in.Rcode != dns.RcodeFormatError || len(in.Answer) != 0 {
+				t.Errorf("unexpected response: wanted %v without answers ; got %v", dns.RcodeToString[dns.RcodeFormatError], in)
+			}
+		})
+	})
+
+	t.Run("PaddingOverTLS", func(t *testing.T) {
+		certFile, keyFile := writeTestCertificate(t)
+		tlsAddr := freeAddr(t)
+		srv := NewServer(
+			udp.NewDNS().Addr(freeAddr(t)).Proto("tcp-tls").TLSAddr(tlsAddr).CertFile(certFile).KeyFile(keyFile).Build(),
+			largeAnswer{},
+		)
+
+		go func() {
+			_ = srv.Start(ctx)
+		}()
+		defer srv.Stop(ctx)
+
+		for i := 0; i < 50 && !srv.Running(ctx); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		client := &dns.Client{Net: "tcp-tls", TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+		in, _, err := client.Exchange(newQuery(), tlsAddr)
+		if err != nil {
+			t.Errorf("unexpected error querying over TLS: %v", err)
+			return
+		}
+		if in.Len()%paddingBlock != 0 {
+			t.Errorf("expected the response to be padded to a multiple of %v bytes; got %v", paddingBlock, in.Len())
+		}
+	})
+}
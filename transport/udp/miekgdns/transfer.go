@@ -0,0 +1,203 @@
+package miekgdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/spanner"
+)
+
+// transferMsgSize is the size after which the records of a zone transfer are split into
+// another DNS message
+const transferMsgSize = 16 << 10
+
+// isTransfer returns true if the DNS message `r` is a zone transfer request (AXFR or IXFR)
+func isTransfer(r *dns.Msg) bool {
+	return r.Opcode == dns.OpcodeQuery &&
+		len(r.Question) == 1 &&
+		(r.Question[0].Qtype == dns.TypeAXFR || r.Question[0].Qtype == dns.TypeIXFR)
+}
+
+// transfer replies to the zone transfer request `r` from the IP address `ip`, with the
+// records from the service.TransferService
+//
+// Transfers are only allowed to the IP addresses in the allowlist, or to requests signed
+// with one of the DNS server's TSIG keys; other requests are refused, and the ones with an
+// invalid TSIG signature are replied to with NOTAUTH. Full zone transfers (AXFR) are only
+// served over TCP, split across as many DNS messages as needed (RFC 5936); while
+// incremental zone transfers (IXFR) over UDP are replied to with the zone's current SOA
+// record, for the client to retry over TCP if its copy of the zone is outdated (RFC 1995)
+func (u *udps) transfer(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, ip net.IP, isUDP bool) {
+	ctx, s := spanner.Start(ctx, "udp.transfer")
+	defer s.End()
+
+	q := r.Question[0]
+	s.Add(
+		attr.String("zone", q.Name),
+		attr.String("type", dns.TypeToString[q.Qtype]),
+	)
+
+	xfr, ok := u.ans.(service.TransferService)
+	if !ok {
+		u.writeTransferError(ctx, w, r, dns.RcodeNotImplemented)
+		return
+	}
+
+	if rcode, ok := u.allowTransfer(w, r, ip, q.Name); !ok {
+		s.Event("zone transfer not allowed", attr.String("rcode", dns.RcodeToString[rcode]))
+		u.writeTransferError(ctx, w, r, rcode)
+		return
+	}
+
+	var (
+		rrs []dns.RR
+		err error
+	)
+	switch {
+	case q.Qtype == dns.TypeAXFR && isUDP:
+		s.Event("AXFR is not supported over UDP")
+		u.writeTransferError(ctx, w, r, dns.RcodeFormatError)
+		return
+	case q.Qtype == dns.TypeAXFR:
+		rrs, err = xfr.TransferZone(ctx, q.Name)
+	default:
+		soa, ok := ixfrSOA(r)
+		if !ok {
+			s.Event("IXFR request without an SOA record")
+			u.writeTransferError(ctx, w, r, dns.RcodeFormatError)
+			return
+		}
+		s.Add(attr.Int("serial", int(soa.Serial)))
+
+		rrs, err = xfr.TransferChanges(ctx, q.Name, soa.Serial)
+		if isUDP && len(rrs) > 1 {
+			rrs = rrs[:1]
+		}
+	}
+	if err != nil {
+		s.Event("failed to transfer zone", attr.String("error", err.Error()))
+		rcode := dns.RcodeServerFailure
+		if errors.Is(err, service.ErrNoZone) {
+			rcode = dns.RcodeNotAuth
+		}
+		u.writeTransferError(ctx, w, r, rcode)
+		return
+	}
+	s.Add(attr.Int("records", len(rrs)))
+
+	for _, chunk := range split(rrs, transferMsgSize) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		m.Answer = chunk
+		signReply(w, r, m)
+
+		if err := w.WriteMsg(m); err != nil {
+			s.Event("error writing zone transfer", attr.String("error", err.Error()))
+			u.err = err
+			return
+		}
+		w.TsigTimersOnly(true)
+	}
+}
+
+// allowTransfer returns true if the zone transfer request `r` for the zone `zone` from the
+// IP address `ip` is allowed, or the response code to reply to it with otherwise
+//
+// Requests with a TSIG record are allowed if it is valid and its key is scoped to the zone
+// (or to no zones at all), refused if the key is scoped to other zones, and replied to
+// with NOTAUTH if it is not valid (or if the DNS server has no TSIG keys); other requests
+// are allowed if `ip` is in the allowlist, and refused if it is not
+func (u *udps) allowTransfer(w dns.ResponseWriter, r *dns.Msg, ip net.IP, zone string) (int, bool) {
+	if tsig := r.IsTsig(); tsig != nil {
+		if len(u.conf.TSIG) == 0 || w.TsigStatus() != nil {
+			return dns.RcodeNotAuth, false
+		}
+		if zones, ok := u.conf.KeyZones[dns.CanonicalName(tsig.Hdr.Name)]; ok && !inZones(zones, zone) {
+			return dns.RcodeRefused, false
+		}
+		return dns.RcodeSuccess, true
+	}
+
+	for _, network := range u.conf.TransferAllow {
+		if ip != nil && network.Contains(ip) {
+			return dns.RcodeSuccess, true
+		}
+	}
+	return dns.RcodeRefused, false
+}
+
+// inZones returns true if the zone `zone` is one of the zones `zones`, in canonical form
+func inZones(zones []string, zone string) bool {
+	zone = dns.CanonicalName(zone)
+	for _, z := range zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTransferError replies to the zone transfer request (or NOTIFY message, or dynamic
+// update request) `r` with the response code `rcode`
+func (u *udps) writeTransferError(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, rcode int) {
+	_, s := spanner.Start(ctx, "udp.writeTransferError")
+	defer s.End()
+
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	if rcode != dns.RcodeNotAuth {
+		signReply(w, r, m)
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		s.Event("error answering query", attr.String("error", err.Error()))
+		u.err = err
+	}
+}
+
+// signReply signs the reply `m` with the TSIG key of the request `r`, if it was signed
+// with a valid one
+func signReply(w dns.ResponseWriter, r, m *dns.Msg) {
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	}
+}
+
+// ixfrSOA returns the SOA record in the authority section of the IXFR request `r`, which
+// holds the serial of the client's copy of the zone
+func ixfrSOA(r *dns.Msg) (*dns.SOA, bool) {
+	for _, rr := range r.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa, true
+		}
+	}
+	return nil, false
+}
+
+// split splits the DNS records `rrs` into chunks of up to `size` bytes, each of them
+// holding at least one record
+func split(rrs []dns.RR, size int) [][]dns.RR {
+	var (
+		chunks [][]dns.RR
+		start  int
+		length int
+	)
+	for idx, rr := range rrs {
+		n := dns.Len(rr)
+		if idx > start && length+n > size {
+			chunks = append(chunks, rrs[start:idx])
+			start, length = idx, 0
+		}
+		length += n
+	}
+	if start < len(rrs) {
+		chunks = append(chunks, rrs[start:])
+	}
+	return chunks
+}
@@ -0,0 +1,297 @@
+package miekgdns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/health/simplehealth"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/journal"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/transport/udp"
+)
+
+const (
+	testKey    = "transfer."
+	testSecret = "so6ZGir4GPAqINNh9U5c3A=="
+)
+
+// transferServer starts a DNS server over UDP and TCP for a service owning the zone
+// `corp.lan`, allowing zone transfers to the networks in `allow` and to the requests
+// signed with the test TSIG key (and to `example.com` signed with another key)
+func transferServer(t *testing.T, allow ...string) (string, service.Service) {
+	ctx := context.Background()
+
+	svc := service.New(core.New(), journal.New(memmap.New()), simplehealth.New(), config.Default())
+	err := svc.AddZone(ctx, &store.Zone{Name: "corp.lan", NS: []string{"ns1.corp.lan"}, Serial: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = svc.AddRecords(ctx,
+		store.New().Type("A").Name("ns1.corp.lan").Addr("10.0.0.53").Build(),
+		store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").Build(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	addr := freeAddr(t)
+	srv := NewServer(
+		udp.NewDNS().Addr(addr).Proto("udp,tcp").TransferAllow(allow...).
+			TSIG(testKey, testSecret).
+			TSIG(otherKey, testSecret, "example.com").
+			Build(),
+		svc,
+	)
+	go func() {
+		_ = srv.Start(ctx)
+	}()
+	t.Cleanup(func() { srv.Stop(ctx) })
+
+	for i := 0; i < 50 && !srv.Running(ctx); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr, svc
+}
+
+// transferIn requests the transfer in DNS message `m` from the DNS server in `addr`,
+// returning all of the transferred records
+func transferIn(tr *dns.Transfer, m *dns.Msg, addr string) ([]dns.RR, error) {
+	envelopes, err := tr.In(m, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrs []dns.RR
+	for env := range envelopes {
+		if env.Error != nil {
+			return nil, env.Error
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	return rrs, nil
+}
+
+func serialOf(rr dns.RR) uint32 {
+	if soa, ok := rr.(*dns.SOA); ok {
+		return soa.Serial
+	}
+	return 0
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SuccessAXFRFromAllowedIP", func(t *testing.T) {
+		addr, _ := transferServer(t, "127.0.0.0/8")
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+
+		rrs, err := transferIn(new(dns.Transfer), m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rrs) != 5 {
+			t.Errorf("unexpected records length: wanted %v ; got %v: %v", 5, len(rrs), rrs)
+			return
+		}
+		if rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+			t.Errorf("expected the transfer to start and end with the SOA record: %v", rrs)
+		}
+	})
+
+	t.Run("SuccessAXFRWithTSIG", func(t *testing.T) {
+		addr, _ := transferServer(t)
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+		m.SetTsig(testKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+		rrs, err := transferIn(&dns.Transfer{TsigSecret: map[string]string{testKey: testSecret}}, m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rrs) != 5 {
+			t.Errorf("unexpected records length: wanted %v ; got %v: %v", 5, len(rrs), rrs)
+		}
+	})
+
+	t.Run("FailAXFRNotAllowed", func(t *testing.T) {
+		addr, _ := transferServer(t, "10.0.0.0/8")
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+
+		client := &dns.Client{Net: "tcp"}
+		in, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeRefused || len(in.Answer) != 0 {
+			t.Errorf("unexpected response: wanted REFUSED ; got %v", in)
+		}
+	})
+
+	t.Run("FailAXFRInvalidTSIG", func(t *testing.T) {
+		addr, _ := transferServer(t)
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+		m.SetTsig(testKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+		client := &dns.Client{Net: "tcp", TsigSecret: map[string]string{testKey: "c2VjcmV0"}}
+		in, _, err := client.Exchange(m, addr)
+		if in == nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeNotAuth || len(in.Answer) != 0 {
+			t.Errorf("unexpected response: wanted NOTAUTH ; got %v", in)
+		}
+	})
+
+	t.Run("FailAXFRKeyNotScopedToZone", func(t *testing.T) {
+		addr, _ := transferServer(t)
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+		m.SetTsig(otherKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+		client := &dns.Client{Net: "tcp", TsigSecret: map[string]string{otherKey: testSecret}}
+		in, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeRefused || len(in.Answer) != 0 {
+			t.Errorf("unexpected response: wanted REFUSED ; got %v", in)
+		}
+	})
+
+	t.Run("FailAXFROverUDP", func(t *testing.T) {
+		addr, _ := transferServer(t, "127.0.0.1")
+
+		m := new(dns.Msg)
+		m.SetAxfr("corp.lan.")
+
+		in, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeFormatError {
+			t.Errorf("unexpected response: wanted FORMERR ; got %v", in)
+		}
+	})
+
+	t.Run("FailAXFRNotOwnedZone", func(t *testing.T) {
+		addr, _ := transferServer(t, "127.0.0.1")
+
+		m := new(dns.Msg)
+		m.SetAxfr("example.com.")
+
+		client := &dns.Client{Net: "tcp"}
+		in, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if in.Rcode != dns.RcodeNotAuth {
+			t.Errorf("unexpected response: wanted NOTAUTH ; got %v", in)
+		}
+	})
+
+	t.Run("SuccessIXFR", func(t *testing.T) {
+		addr, svc := transferServer(t, "127.0.0.1")
+
+		// the service's records were added in a single change, after creating the zone
+		err := svc.UpdateRecord(ctx, "www.corp.lan", store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.2").Build())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		m := new(dns.Msg)
+		m.SetIxfr("corp.lan.", 2, "ns1.corp.lan.", "hostmaster.corp.lan.")
+
+		rrs, err := transferIn(new(dns.Transfer), m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		// current SOA, old SOA, deleted A, new SOA, added A, current SOA
+		if len(rrs) != 6 {
+			t.Errorf("unexpected records length: wanted %v ; got %v: %v", 6, len(rrs), rrs)
+			return
+		}
+		if serialOf(rrs[0]) != 3 || serialOf(rrs[1]) != 2 || serialOf(rrs[3]) != 3 || serialOf(rrs[5]) != 3 {
+			t.Errorf("unexpected SOA records in IXFR: %v", rrs)
+		}
+		if a, ok := rrs[2].(*dns.A); !ok || a.A.String() != "10.0.0.1" {
+			t.Errorf("unexpected deleted record: %v", rrs[2])
+		}
+		if a, ok := rrs[4].(*dns.A); !ok || a.A.String() != "10.0.0.2" {
+			t.Errorf("unexpected added record: %v", rrs[4])
+		}
+	})
+
+	t.Run("SuccessIXFROverUDP", func(t *testing.T) {
+		addr, _ := transferServer(t, "127.0.0.1")
+
+		m := new(dns.Msg)
+		m.SetIxfr("corp.lan.", 1, "ns1.corp.lan.", "hostmaster.corp.lan.")
+
+		in, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(in.Answer) != 1 || serialOf(in.Answer[0]) != 2 {
+			t.Errorf("expected a single SOA record with the current serial: %v", in)
+		}
+	})
+
+	t.Run("SuccessIXFRFallbackToAXFR", func(t *testing.T) {
+		addr, _ := transferServer(t, "127.0.0.1")
+
+		m := new(dns.Msg)
+		m.SetIxfr("corp.lan.", 0xFFFFFFFF-10, "ns1.corp.lan.", "hostmaster.corp.lan.")
+
+		rrs, err := transferIn(new(dns.Transfer), m, addr)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(rrs) != 5 {
+			t.Errorf("unexpected records length: wanted %v ; got %v: %v", 5, len(rrs), rrs)
+		}
+	})
+}
+
+func TestSplit(t *testing.T) {
+	rrs := make([]dns.RR, 10)
+	for i := range rrs {
+		rrs[i] = &dns.A{
+			Hdr: dns.RR_Header{Name: "www.corp.lan.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   []byte{10, 0, 0, byte(i)},
+		}
+	}
+	size := dns.Len(rrs[0])
+
+	chunks := split(rrs, 3*size)
+	if len(chunks) != 4 || len(chunks[0]) != 3 || len(chunks[3]) != 1 {
+		t.Errorf("unexpected chunks: %v", chunks)
+	}
+	if chunks := split(rrs, 1); len(chunks) != 10 {
+		t.Errorf("expected one record per chunk: %v", chunks)
+	}
+}
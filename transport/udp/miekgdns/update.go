@@ -0,0 +1,133 @@
+package miekgdns
+
+import (
+	"context"
+	"errors"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/attr"
+	dnsrepo "github.com/zalgonoise/dns/dns"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/spanner"
+)
+
+// isUpdate returns true if the DNS message `r` is a dynamic update request (RFC 2136)
+func isUpdate(r *dns.Msg) bool {
+	return r.Opcode == dns.OpcodeUpdate && !r.Response
+}
+
+// acceptMsg is the dns.MsgAcceptFunc for the DNS server, which accepts dynamic update
+// requests (holding prerequisites and updates in their answer and authority sections),
+// deferring to the dns.DefaultMsgAcceptFunc for any other DNS message
+func acceptMsg(dh dns.Header) dns.MsgAcceptAction {
+	const qr = 1 << 15
+
+	if dh.Bits&qr == 0 && int(dh.Bits>>11)&0xF == dns.OpcodeUpdate {
+		if dh.Qdcount != 1 {
+			return dns.MsgReject
+		}
+		return dns.MsgAccept
+	}
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// update replies to the dynamic update request `r`, applying its prerequisites and
+// updates to the zone in its question with the service.UpdateService
+//
+// Updates are only allowed for requests signed with one of the DNS server's TSIG keys,
+// scoped to the zone being updated; unsigned requests (or signed with a key which is not
+// scoped to the zone) are refused, and the ones with an invalid TSIG signature are replied
+// to with NOTAUTH. The reply's response code reflects the prerequisite or update which
+// could not be applied, in which case none of them is
+func (u *udps) update(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) {
+	ctx, s := spanner.Start(ctx, "udp.update")
+	defer s.End()
+
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		s.Event("dynamic update without a zone")
+		u.writeTransferError(ctx, w, r, dns.RcodeFormatError)
+		return
+	}
+
+	q := r.Question[0]
+	s.Add(
+		attr.String("zone", q.Name),
+		attr.Int("prereqs", len(r.Answer)),
+		attr.Int("updates", len(r.Ns)),
+	)
+
+	upd, ok := u.ans.(service.UpdateService)
+	if !ok {
+		u.writeTransferError(ctx, w, r, dns.RcodeNotImplemented)
+		return
+	}
+
+	if rcode, ok := u.allowUpdate(w, r, q.Name); !ok {
+		s.Event("dynamic update not allowed", attr.String("rcode", dns.RcodeToString[rcode]))
+		u.writeTransferError(ctx, w, r, rcode)
+		return
+	}
+
+	if err := upd.UpdateZone(ctx, q.Name, r.Answer, r.Ns); err != nil {
+		s.Event("failed to update zone", attr.String("error", err.Error()))
+		u.writeTransferError(ctx, w, r, updateRcode(err))
+		return
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	signReply(w, r, m)
+
+	if err := w.WriteMsg(m); err != nil {
+		s.Event("error answering dynamic update", attr.String("error", err.Error()))
+		u.err = err
+	}
+}
+
+// allowUpdate returns true if the dynamic update request `r` for the zone `zone` is
+// allowed, or the response code to reply to it with otherwise
+//
+// Requests are allowed if they are signed with a valid TSIG key scoped to the zone. Requests
+// with an invalid TSIG record (or if the DNS server has no TSIG keys) are replied to with
+// NOTAUTH, and any other request is refused
+func (u *udps) allowUpdate(w dns.ResponseWriter, r *dns.Msg, zone string) (int, bool) {
+	tsig := r.IsTsig()
+	if tsig == nil {
+		return dns.RcodeRefused, false
+	}
+	if len(u.conf.TSIG) == 0 || w.TsigStatus() != nil {
+		return dns.RcodeNotAuth, false
+	}
+
+	if zones, ok := u.conf.KeyZones[dns.CanonicalName(tsig.Hdr.Name)]; ok && inZones(zones, zone) {
+		return dns.RcodeSuccess, true
+	}
+	return dns.RcodeRefused, false
+}
+
+// updateRcode returns the response code for the error `err` from applying a dynamic update
+func updateRcode(err error) int {
+	switch {
+	case errors.Is(err, service.ErrUpdateFormat), errors.Is(err, service.ErrNoName):
+		return dns.RcodeFormatError
+	case errors.Is(err, service.ErrNotZone):
+		return dns.RcodeNotZone
+	case errors.Is(err, service.ErrYXDomain):
+		return dns.RcodeYXDomain
+	case errors.Is(err, service.ErrYXRRSet):
+		return dns.RcodeYXRrset
+	case errors.Is(err, service.ErrNXRRSet):
+		return dns.RcodeNXRrset
+	case errors.Is(err, dnsrepo.ErrNXDomain):
+		return dns.RcodeNameError
+	case errors.Is(err, service.ErrNoZone):
+		return dns.RcodeNotAuth
+	case errors.Is(err, store.ErrReadOnly):
+		return dns.RcodeRefused
+	case errors.Is(err, store.ErrNoBatch):
+		return dns.RcodeNotImplemented
+	default:
+		return dns.RcodeServerFailure
+	}
+}
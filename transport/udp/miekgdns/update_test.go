@@ -0,0 +1,300 @@
+package miekgdns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zalgonoise/dns/cmd/config"
+	"github.com/zalgonoise/dns/dns/core"
+	"github.com/zalgonoise/dns/health/simplehealth"
+	"github.com/zalgonoise/dns/service"
+	"github.com/zalgonoise/dns/store"
+	"github.com/zalgonoise/dns/store/journal"
+	"github.com/zalgonoise/dns/store/memmap"
+	"github.com/zalgonoise/dns/transport/udp"
+)
+
+const otherKey = "other."
+
+// updateServer starts a DNS server over UDP for a service owning the zone `corp.lan`,
+// allowing dynamic updates to it signed with the test TSIG key (and to `example.com`
+// signed with another key)
+func updateServer(t *testing.T) (string, service.Service) {
+	ctx := context.Background()
+
+	svc := service.New(core.New(), journal.New(memmap.New()), simplehealth.New(), config.Default())
+	err := svc.AddZone(ctx, &store.Zone{Name: "corp.lan", NS: []string{"ns1.corp.lan"}, Serial: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = svc.AddRecords(ctx,
+		store.New().Type("A").Name("ns1.corp.lan").Addr("10.0.0.53").Build(),
+		store.New().Type("A").Name("www.corp.lan").Addr("10.0.0.1").Build(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	addr := freeAddr(t)
+	srv := NewServer(
+		udp.NewDNS().Addr(addr).Proto("udp").
+			TSIG(testKey, testSecret, "corp.lan").
+			TSIG(otherKey, testSecret, "example.com").
+			Build(),
+		svc,
+	)
+	go func() {
+		_ = srv.Start(ctx)
+	}()
+	t.Cleanup(func() { srv.Stop(ctx) })
+
+	for i := 0; i < 50 && !srv.Running(ctx); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr, svc
+}
+
+func newRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rr
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	update := func(t *testing.T, addr, key string, m *dns.Msg) *dns.Msg {
+		client := new(dns.Client)
+		if key != "" {
+			client.TsigSecret = map[string]string{key: testSecret}
+			m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+		}
+
+		r, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return r
+	}
+
+	addrs := func(t *testing.T, svc service.Service, name string) []string {
+		records, err := svc.GetRecordByTypeAndDomain(ctx, "A", name)
+		if err != nil {
+			return nil
+		}
+		var out []string
+		for _, r := range records {
+			out = append(out, r.Addr)
+		}
+		return out
+	}
+
+	serial := func(t *testing.T, svc service.Service) uint32 {
+		records, err := svc.GetRecordByTypeAndDomain(ctx, "SOA", "corp.lan")
+		if err != nil || len(records) != 1 {
+			t.Fatalf("unexpected SOA records: %v ; error: %v", records, err)
+		}
+		return records[0].Data.Serial
+	}
+
+	t.Run("SuccessAddAndDelete", func(t *testing.T) {
+		addr, svc := updateServer(t)
+		from := serial(t, svc)
+
+		m := new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.NameUsed([]dns.RR{newRR(t, "www.corp.lan. A 0.0.0.0")})
+		m.RRsetNotUsed([]dns.RR{newRR(t, "mail.corp.lan. A 0.0.0.0")})
+		m.RemoveRRset([]dns.RR{newRR(t, "www.corp.lan. A 0.0.0.0")})
+		m.Insert([]dns.RR{
+			newRR(t, "www.corp.lan. 60 IN A 10.0.0.2"),
+			newRR(t, "mail.corp.lan. 60 IN A 10.0.0.25"),
+			newRR(t, "mail.corp.lan. 60 IN A 10.0.0.26"),
+		})
+
+		r := update(t, addr, testKey, m)
+		if r.Rcode != dns.RcodeSuccess || r.Opcode != dns.OpcodeUpdate || r.IsTsig() == nil {
+			t.Fatalf("unexpected reply: %v", r)
+		}
+		if got := addrs(t, svc, "www.corp.lan"); len(got) != 1 || got[0] != "10.0.0.2" {
+			t.Errorf("unexpected www.corp.lan addresses: %v", got)
+		}
+		if got := addrs(t, svc, "mail.corp.lan"); len(got) != 2 {
+			t.Errorf("unexpected mail.corp.lan addresses: %v", got)
+		}
+		if got := serial(t, svc); got != from+1 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", from+1, got)
+		}
+
+		// the update is journaled as a single change
+		rrs, err := svc.TransferChanges(ctx, "corp.lan", from)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var soas int
+		for _, rr := range rrs {
+			if _, ok := rr.(*dns.SOA); ok {
+				soas++
+			}
+		}
+		if soas != 4 {
+			t.Errorf("expected a single journaled change; got %v", rrs)
+		}
+
+		m = new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.Remove([]dns.RR{newRR(t, "mail.corp.lan. A 10.0.0.26")})
+		m.RemoveName([]dns.RR{newRR(t, "www.corp.lan. A 0.0.0.0")})
+
+		r = update(t, addr, testKey, m)
+		if r.Rcode != dns.RcodeSuccess {
+			t.Fatalf("unexpected reply: %v", r)
+		}
+		if got := addrs(t, svc, "www.corp.lan"); len(got) != 0 {
+			t.Errorf("expected deleted name to be removed; got %v", got)
+		}
+		if got := addrs(t, svc, "mail.corp.lan"); len(got) != 1 || got[0] != "10.0.0.25" {
+			t.Errorf("unexpected mail.corp.lan addresses: %v", got)
+		}
+		if got := serial(t, svc); got != from+2 {
+			t.Errorf("unexpected serial: wanted %v ; got %v", from+2, got)
+		}
+	})
+
+	t.Run("SuccessKeepApexRecords", func(t *testing.T) {
+		addr, svc := updateServer(t)
+		from := serial(t, svc)
+
+		m := new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.RemoveName([]dns.RR{newRR(t, "corp.lan. A 0.0.0.0")})
+		m.Remove([]dns.RR{newRR(t, "corp.lan. NS ns1.corp.lan.")})
+
+		r := update(t, addr, testKey, m)
+		if r.Rcode != dns.RcodeSuccess {
+			t.Fatalf("unexpected reply: %v", r)
+		}
+		ns, err := svc.GetRecordByTypeAndDomain(ctx, "NS", "corp.lan")
+		if err != nil || len(ns) != 1 {
+			t.Errorf("expected the zone's NS record to be kept: %v ; error: %v", ns, err)
+		}
+		if got := serial(t, svc); got != from {
+			t.Errorf("unexpected serial: wanted %v ; got %v", from, got)
+		}
+	})
+
+	t.Run("FailPrerequisites", func(t *testing.T) {
+		addr, svc := updateServer(t)
+		from := serial(t, svc)
+
+		for _, test := range []struct {
+			name  string
+			set   func(m *dns.Msg)
+			rcode int
+		}{
+			{
+				name:  "NameNotUsed",
+				set:   func(m *dns.Msg) { m.NameNotUsed([]dns.RR{newRR(t, "www.corp.lan. A 0.0.0.0")}) },
+				rcode: dns.RcodeYXDomain,
+			},
+			{
+				name:  "NameUsed",
+				set:   func(m *dns.Msg) { m.NameUsed([]dns.RR{newRR(t, "mail.corp.lan. A 0.0.0.0")}) },
+				rcode: dns.RcodeNameError,
+			},
+			{
+				name:  "RRsetUsed",
+				set:   func(m *dns.Msg) { m.RRsetUsed([]dns.RR{newRR(t, "www.corp.lan. MX 10 mail.corp.lan.")}) },
+				rcode: dns.RcodeNXRrset,
+			},
+			{
+				name:  "RRsetNotUsed",
+				set:   func(m *dns.Msg) { m.RRsetNotUsed([]dns.RR{newRR(t, "www.corp.lan. A 0.0.0.0")}) },
+				rcode: dns.RcodeYXRrset,
+			},
+			{
+				name:  "Used",
+				set:   func(m *dns.Msg) { m.Used([]dns.RR{newRR(t, "www.corp.lan. A 10.0.0.9")}) },
+				rcode: dns.RcodeNXRrset,
+			},
+		} {
+			m := new(dns.Msg)
+			m.SetUpdate("corp.lan.")
+			test.set(m)
+			m.Insert([]dns.RR{newRR(t, "www.corp.lan. 60 IN A 10.0.0.2")})
+
+			r := update(t, addr, testKey, m)
+			if r.Rcode != test.rcode {
+				t.Errorf("%s: unexpected rcode: wanted %v ; got %v", test.name, dns.RcodeToString[test.rcode], dns.RcodeToString[r.Rcode])
+			}
+		}
+
+		// none of the updates was applied
+		if got := addrs(t, svc, "www.corp.lan"); len(got) != 1 || got[0] != "10.0.0.1" {
+			t.Errorf("unexpected www.corp.lan addresses: %v", got)
+		}
+		if got := serial(t, svc); got != from {
+			t.Errorf("unexpected serial: wanted %v ; got %v", from, got)
+		}
+	})
+
+	t.Run("FailNotZone", func(t *testing.T) {
+		addr, _ := updateServer(t)
+
+		m := new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.Insert([]dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("10.0.0.1").To4(),
+		}})
+
+		r := update(t, addr, testKey, m)
+		if r.Rcode != dns.RcodeNotZone {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeNotZone], dns.RcodeToString[r.Rcode])
+		}
+	})
+
+	t.Run("FailUnsigned", func(t *testing.T) {
+		addr, _ := updateServer(t)
+
+		m := new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.Insert([]dns.RR{newRR(t, "www.corp.lan. 60 IN A 10.0.0.2")})
+
+		r := update(t, addr, "", m)
+		if r.Rcode != dns.RcodeRefused {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeRefused], dns.RcodeToString[r.Rcode])
+		}
+	})
+
+	t.Run("FailKeyNotScopedToZone", func(t *testing.T) {
+		addr, _ := updateServer(t)
+
+		m := new(dns.Msg)
+		m.SetUpdate("corp.lan.")
+		m.Insert([]dns.RR{newRR(t, "www.corp.lan. 60 IN A 10.0.0.2")})
+
+		r := update(t, addr, otherKey, m)
+		if r.Rcode != dns.RcodeRefused {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeRefused], dns.RcodeToString[r.Rcode])
+		}
+	})
+
+	t.Run("FailZoneNotOwned", func(t *testing.T) {
+		addr, _ := updateServer(t)
+
+		m := new(dns.Msg)
+		m.SetUpdate("example.com.")
+		m.Insert([]dns.RR{newRR(t, "www.example.com. 60 IN A 10.0.0.2")})
+
+		r := update(t, addr, otherKey, m)
+		if r.Rcode != dns.RcodeNotAuth {
+			t.Errorf("unexpected rcode: wanted %v ; got %v", dns.RcodeToString[dns.RcodeNotAuth], dns.RcodeToString[r.Rcode])
+		}
+	})
+}
@@ -8,6 +8,8 @@ import (
 var (
 	ErrAlreadyRunning error = errors.New("DNS server is already running")
 	ErrNotRunning     error = errors.New("DNS server is not running, yet")
+	ErrInvalidProto   error = errors.New("unsupported DNS server protocol")
+	ErrNoCertificate  error = errors.New("DNS-over-TLS requires a certificate and a key")
 )
 
 // Server interface allows launching a UDP server